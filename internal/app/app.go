@@ -1,15 +1,28 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"github.com/Vic07Region/avito-shop/internal/app/docs"
 	"github.com/Vic07Region/avito-shop/internal/app/handlers"
 	"github.com/Vic07Region/avito-shop/internal/app/mw"
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/outbox"
+	"github.com/Vic07Region/avito-shop/internal/realtime"
 	"github.com/Vic07Region/avito-shop/internal/service"
 	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/cache"
+	"github.com/Vic07Region/avito-shop/internal/storage/memory"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/Vic07Region/avito-shop/internal/webhook"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"go.uber.org/zap"
+	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,22 +31,32 @@ type Handlers interface {
 	WalletInfo(c *gin.Context)
 	SendCoin(c *gin.Context)
 	BuyMerch(c *gin.Context)
+	GiftMerch(c *gin.Context)
 }
 
 type AvitoShop struct {
-	storage  service.StorageInterface
-	service  handlers.ServiceInterface
-	handlers Handlers
-	gin      *gin.Engine
-	logger   *zap.Logger
+	storage         service.StorageInterface
+	service         handlers.ServiceInterface
+	handlers        Handlers
+	webhooks        *webhook.Service
+	realtimeBus     *realtime.PostgresTransport
+	gin             *gin.Engine
+	logger          *zap.Logger
+	shutdownTracing func(context.Context) error
+	stopReplicas    func()
+	relay           *outbox.Relay
+	onboarding      *service.Service
+	vestInterval    time.Duration
 }
 
 func New() (*AvitoShop, error) {
 	app := &AvitoShop{}
 
-	app.gin = gin.Default()
+	app.gin = gin.New()
 
 	mode := os.Getenv("APP_MODE")
+	dbDriver := os.Getenv("DB_DRIVER")
+	dbPath := os.Getenv("DB_PATH")
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbHost := os.Getenv("DB_HOST")
@@ -44,6 +67,29 @@ func New() (*AvitoShop, error) {
 	dbMaxOpenConns := os.Getenv("DB_MAXOPENCONNS")
 	dbMsxIdleConns := os.Getenv("DB_MSXIDLECONNS")
 	dbMaxLifeTime := os.Getenv("DB_MAXLIFETIME")
+	dbReplicaHosts := os.Getenv("DB_REPLICA_HOSTS")
+	dbReplicaMaxLag := os.Getenv("DB_REPLICA_MAX_LAG")
+	dbReplicaCheckInterval := os.Getenv("DB_REPLICA_CHECK_INTERVAL")
+	tracesExporter := os.Getenv("OTEL_TRACES_EXPORTER")
+	tracesFile := os.Getenv("OTEL_TRACES_FILE")
+	serviceName := os.Getenv("OTEL_SERVICE_NAME")
+	cacheSize := os.Getenv("CACHE_SIZE")
+	cacheTTL := os.Getenv("CACHE_TTL")
+	cacheWalletTTL := os.Getenv("CACHE_WALLET_TTL")
+	outboxPublisher := os.Getenv("OUTBOX_PUBLISHER")
+	outboxFile := os.Getenv("OUTBOX_FILE")
+	outboxWebhookURL := os.Getenv("OUTBOX_WEBHOOK_URL")
+	outboxPollInterval := os.Getenv("OUTBOX_POLL_INTERVAL")
+	adminUsernames := os.Getenv("ADMIN_USERNAMES")
+	adminPassword := os.Getenv("ADMIN_PASSWORD")
+	webhookMaxAttempts := os.Getenv("WEBHOOK_MAX_ATTEMPTS")
+	webhookBackoff := os.Getenv("WEBHOOK_BACKOFF")
+	webhookMaxBackoff := os.Getenv("WEBHOOK_MAX_BACKOFF")
+	webhookPollInterval := os.Getenv("WEBHOOK_POLL_INTERVAL")
+	webhookLowBalance := os.Getenv("WEBHOOK_LOW_BALANCE")
+	realtimeHeartbeat := os.Getenv("REALTIME_HEARTBEAT")
+	budgetPeriod := os.Getenv("BUDGET_PERIOD")
+	onboardingVestInterval := os.Getenv("ONBOARDING_VEST_INTERVAL")
 
 	connStr := fmt.Sprintf("user=%s password=%s port=%s dbname=%s",
 		dbUser, dbPassword, dbPort, dbName)
@@ -86,8 +132,153 @@ func New() (*AvitoShop, error) {
 		maxLifeTime = time.Second * time.Duration(mft)
 	}
 
-	if maxOpenConns != 0 {
-		connStr += fmt.Sprintf(" max_open_conns=%d", maxOpenConns)
+	replicaConfig := storage.ReplicaConfig{MaxLag: 5 * time.Second, CheckInterval: time.Second}
+
+	if dbReplicaMaxLag != "" {
+		rml, err := strconv.Atoi(dbReplicaMaxLag)
+		if err != nil {
+			app.logger.Error("ReplicaMaxLag strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		replicaConfig.MaxLag = time.Second * time.Duration(rml)
+	}
+	if dbReplicaCheckInterval != "" {
+		rci, err := strconv.Atoi(dbReplicaCheckInterval)
+		if err != nil {
+			app.logger.Error("ReplicaCheckInterval strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		replicaConfig.CheckInterval = time.Second * time.Duration(rci)
+	}
+
+	outboxConfig := outbox.Config{BatchSize: 100, PollInterval: time.Second, Lease: 30 * time.Second}
+
+	if outboxPollInterval != "" {
+		opi, err := strconv.Atoi(outboxPollInterval)
+		if err != nil {
+			app.logger.Error("OutboxPollInterval strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		outboxConfig.PollInterval = time.Second * time.Duration(opi)
+	}
+
+	webhookConfig := webhook.Config{
+		MaxAttempts:  8,
+		Backoff:      10 * time.Second,
+		MaxBackoff:   time.Hour,
+		BatchSize:    100,
+		PollInterval: time.Second,
+		Lease:        30 * time.Second,
+	}
+	lowBalanceThreshold := 100
+
+	if webhookMaxAttempts != "" {
+		wma, err := strconv.Atoi(webhookMaxAttempts)
+		if err != nil {
+			app.logger.Error("WebhookMaxAttempts strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		webhookConfig.MaxAttempts = wma
+	}
+	if webhookBackoff != "" {
+		wb, err := strconv.Atoi(webhookBackoff)
+		if err != nil {
+			app.logger.Error("WebhookBackoff strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		webhookConfig.Backoff = time.Second * time.Duration(wb)
+	}
+	if webhookMaxBackoff != "" {
+		wmb, err := strconv.Atoi(webhookMaxBackoff)
+		if err != nil {
+			app.logger.Error("WebhookMaxBackoff strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		webhookConfig.MaxBackoff = time.Second * time.Duration(wmb)
+	}
+	if webhookPollInterval != "" {
+		wpi, err := strconv.Atoi(webhookPollInterval)
+		if err != nil {
+			app.logger.Error("WebhookPollInterval strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		webhookConfig.PollInterval = time.Second * time.Duration(wpi)
+	}
+	if webhookLowBalance != "" {
+		wlb, err := strconv.Atoi(webhookLowBalance)
+		if err != nil {
+			app.logger.Error("WebhookLowBalance strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		lowBalanceThreshold = wlb
+	}
+
+	heartbeat := 25 * time.Second
+
+	if realtimeHeartbeat != "" {
+		rh, err := strconv.Atoi(realtimeHeartbeat)
+		if err != nil {
+			app.logger.Error("RealtimeHeartbeat strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		heartbeat = time.Second * time.Duration(rh)
+	}
+
+	app.vestInterval = time.Minute
+
+	if onboardingVestInterval != "" {
+		ovi, err := strconv.Atoi(onboardingVestInterval)
+		if err != nil {
+			app.logger.Error("OnboardingVestInterval strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		app.vestInterval = time.Second * time.Duration(ovi)
+	}
+
+	switch budgetPeriod {
+	case "":
+		budgetPeriod = service.BudgetPeriodMonth
+	case service.BudgetPeriodMonth, service.BudgetPeriodWeek:
+	default:
+		err := fmt.Errorf("unknown budget period %q", budgetPeriod)
+		app.logger.Error("budget period error", zap.Error(err))
+		return nil, err
+	}
+
+	var admins []string
+	for _, admin := range strings.Split(adminUsernames, ",") {
+		if admin = strings.TrimSpace(admin); admin != "" {
+			admins = append(admins, admin)
+		}
+	}
+
+	cacheCapacity := 10000
+	cacheTTLs := cache.TTL{Merch: time.Minute, User: time.Minute, Wallet: 5 * time.Second}
+
+	if cacheSize != "" {
+		cs, err := strconv.Atoi(cacheSize)
+		if err != nil {
+			app.logger.Error("CacheSize strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		cacheCapacity = cs
+	}
+	if cacheTTL != "" {
+		ct, err := strconv.Atoi(cacheTTL)
+		if err != nil {
+			app.logger.Error("CacheTTL strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		cacheTTLs.Merch = time.Second * time.Duration(ct)
+		cacheTTLs.User = time.Second * time.Duration(ct)
+	}
+	if cacheWalletTTL != "" {
+		cwt, err := strconv.Atoi(cacheWalletTTL)
+		if err != nil {
+			app.logger.Error("CacheWalletTTL strconv.Atoi error", zap.Error(err))
+			return nil, err
+		}
+		cacheTTLs.Wallet = time.Second * time.Duration(cwt)
 	}
 
 	if dbSslRootCert != "" {
@@ -115,37 +306,284 @@ func New() (*AvitoShop, error) {
 		app.logger = logger
 		gin.SetMode(gin.ReleaseMode)
 	}
-	app.logger.Info("connection string", zap.String("connection_string", connStr))
-	dbConn, err := storage.NewDBConection(storage.ConnectionParams{
-		DbDriver:         storage.DBPostgres,
-		ConnectionString: connStr,
-		MaxOpenConns:     maxOpenConns,
-		MsxIdleConns:     msxIdleConns,
-		MaxLifeTime:      maxLifeTime,
+	// глобальный логгер для контекстов без логгера запроса (outbox relay, webhooks)
+	zap.ReplaceGlobals(app.logger)
+	if serviceName == "" {
+		serviceName = "avito-shop"
+	}
+	if tracesExporter == tracing.ExporterFile && tracesFile == "" {
+		tracesFile = "traces.json"
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: serviceName,
+		Exporter:    tracesExporter,
+		FilePath:    tracesFile,
 	})
 	if err != nil {
-		app.logger.Error("DB connection error", zap.Error(err))
+		app.logger.Error("tracing init error", zap.Error(err))
+		return nil, err
+	}
+	app.shutdownTracing = shutdownTracing
+
+	var outboxStorage outbox.Storage
+	var webhookStorage webhook.Storage
+	broker := realtime.NewBroker(16)
+	var realtimeTransport realtime.Transport = broker
+
+	if dbDriver == storage.DBMemory {
+		app.logger.Warn("in-memory storage is used, all data will be lost on restart")
+		memoryStorage := memory.New()
+		app.storage = memoryStorage
+		outboxStorage = memoryStorage
+		webhookStorage = memoryStorage
+	} else if dbDriver == storage.DBSQLite {
+		if dbPath == "" {
+			dbPath = "avito_shop.db"
+		}
+		app.logger.Info("sqlite storage is used", zap.String("path", dbPath))
+		dbConn, err := storage.NewDBConection(storage.ConnectionParams{
+			DbDriver:         storage.DBSQLite,
+			ConnectionString: storage.SQLiteConnectionString(dbPath),
+			MaxOpenConns:     maxOpenConns,
+			MsxIdleConns:     msxIdleConns,
+			MaxLifeTime:      maxLifeTime,
+		})
+		if err != nil {
+			app.logger.Error("DB connection error", zap.Error(err))
+			return nil, err
+		}
+
+		if err := storage.MigrateSQLite(context.Background(), dbConn); err != nil {
+			app.logger.Error("sqlite migration error", zap.Error(err))
+			return nil, err
+		}
+
+		if err := metrics.RegisterDBStats(dbConn, dbPath); err != nil {
+			app.logger.Error("DB stats metrics register error", zap.Error(err))
+			return nil, err
+		}
+
+		queries := storage.NewWithDriver(dbConn, storage.DBSQLite, app.logger)
+		app.storage = queries
+		outboxStorage = queries
+		webhookStorage = queries
+	} else {
+		app.logger.Info("connection string", zap.String("connection_string", connStr))
+		pool, err := storage.NewPostgresPool(context.Background(), storage.ConnectionParams{
+			DbDriver:         storage.DBPostgres,
+			ConnectionString: connStr,
+			MaxOpenConns:     maxOpenConns,
+			MsxIdleConns:     msxIdleConns,
+			MaxLifeTime:      maxLifeTime,
+		})
+		if err != nil {
+			app.logger.Error("DB connection error", zap.Error(err))
+			return nil, err
+		}
+
+		queries := storage.NewPostgres(pool, app.logger)
+
+		if err := metrics.RegisterDBStats(queries.DB(), dbName); err != nil {
+			app.logger.Error("DB stats metrics register error", zap.Error(err))
+			return nil, err
+		}
+		if err := metrics.RegisterPgxPoolStats(pool); err != nil {
+			app.logger.Error("pgxpool stats metrics register error", zap.Error(err))
+			return nil, err
+		}
+
+		if dbReplicaHosts != "" {
+			var replicas []storage.Replica
+			for _, replicaHost := range strings.Split(dbReplicaHosts, ",") {
+				replicaHost = strings.TrimSpace(replicaHost)
+				host, port, found := strings.Cut(replicaHost, ":")
+				if !found {
+					port = dbPort
+				}
+				// повторные параметры в строке подключения переопределяют предыдущие
+				replicaPool, err := storage.NewPostgresPool(context.Background(), storage.ConnectionParams{
+					DbDriver:         storage.DBPostgres,
+					ConnectionString: fmt.Sprintf("%s host=%s port=%s", connStr, host, port),
+					MaxOpenConns:     maxOpenConns,
+					MsxIdleConns:     msxIdleConns,
+					MaxLifeTime:      maxLifeTime,
+				})
+				if err != nil {
+					app.logger.Error("DB replica connection error", zap.String("replica", replicaHost), zap.Error(err))
+					return nil, err
+				}
+				replicas = append(replicas, storage.NewReplica(replicaHost, replicaPool))
+			}
+			app.logger.Info("read replicas are used", zap.String("replicas", dbReplicaHosts))
+			app.stopReplicas = queries.UseReplicas(replicas, replicaConfig)
+		}
+
+		// события пользователя должны доходить до инстанса, где открыт его поток
+		app.realtimeBus = realtime.NewPostgresTransport(pool, broker, app.logger)
+		realtimeTransport = app.realtimeBus
+
+		app.storage = queries
+		outboxStorage = queries
+		webhookStorage = queries
+	}
+
+	var publisher outbox.Publisher
+	switch outboxPublisher {
+	case "", outbox.PublisherNone:
+		app.logger.Info("outbox relay is disabled, set OUTBOX_PUBLISHER to deliver domain events")
+	case outbox.PublisherLog:
+		publisher = outbox.NewLogPublisher(app.logger)
+	case outbox.PublisherFile:
+		if outboxFile == "" {
+			outboxFile = "outbox.jsonl"
+		}
+		filePublisher, err := outbox.NewFilePublisher(outboxFile)
+		if err != nil {
+			app.logger.Error("outbox file publisher error", zap.Error(err))
+			return nil, err
+		}
+		publisher = filePublisher
+	case outbox.PublisherWebhook:
+		if outboxWebhookURL == "" {
+			err := fmt.Errorf("OUTBOX_WEBHOOK_URL is required for webhook publisher")
+			app.logger.Error("outbox webhook publisher error", zap.Error(err))
+			return nil, err
+		}
+		publisher = outbox.NewWebhookPublisher(outboxWebhookURL, &http.Client{Timeout: 10 * time.Second})
+	default:
+		err := fmt.Errorf("unknown outbox publisher %q", outboxPublisher)
+		app.logger.Error("outbox publisher error", zap.Error(err))
 		return nil, err
 	}
+	if publisher != nil {
+		app.relay = outbox.NewRelay(outboxStorage, publisher, outboxConfig, app.logger)
+	}
 
-	app.storage = storage.New(dbConn, app.logger)
+	if cacheCapacity > 0 {
+		app.storage = cache.New(app.storage, cache.NewLRU(cacheCapacity), cacheTTLs, app.logger)
+	}
 
-	app.service = service.New(app.storage, app.logger)
+	app.webhooks = webhook.New(webhookStorage, &http.Client{Timeout: 10 * time.Second}, webhookConfig, app.logger)
+
+	srv := service.New(app.storage, app.logger)
+	srv.Notifier = service.Notifiers{app.webhooks, realtime.NewNotifier(realtimeTransport, app.logger)}
+	srv.LowBalanceThreshold = lowBalanceThreshold
+	srv.BudgetPeriod = budgetPeriod
+	srv.Admins = admins
+	app.service = srv
+	if adminPassword != "" {
+		if err := srv.ProvisionAdmins(context.Background(), adminPassword); err != nil {
+			app.logger.Error("ProvisionAdmins error", zap.Error(err))
+			return nil, err
+		}
+	}
+	app.onboarding = srv
 	app.handlers = handlers.New(app.service, app.logger)
+	webhookHandlers := handlers.NewWebhookHandlers(app.webhooks)
+	eventsHandlers := handlers.NewEventsHandlers(broker, heartbeat)
+	orderHandlers := handlers.NewOrderHandlers(srv)
+	promotionHandlers := handlers.NewPromotionHandlers(srv)
+	merchHandlers := handlers.NewMerchHandlers(srv)
+	inventoryHandlers := handlers.NewInventoryHandlers(srv)
+	wishlistHandlers := handlers.NewWishlistHandlers(srv)
+	teamHandlers := handlers.NewTeamHandlers(srv)
+	budgetHandlers := handlers.NewBudgetHandlers(srv)
+	tenantHandlers := handlers.NewTenantHandlers(srv)
 	middleware := mw.New(app.storage)
 
+	if len(admins) == 0 {
+		app.logger.Info("admin api is disabled, set ADMIN_USERNAMES to manage webhooks")
+	}
+
+	app.gin.Use(
+		otelgin.Middleware(serviceName),
+		mw.RequestLogger(app.logger),
+		// метрики снаружи Recovery, чтобы запрос с паникой посчитался как 500
+		mw.MetricsMiddleware(),
+		mw.Recovery(app.logger),
+		mw.ErrorRenderer(),
+	)
+	app.gin.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	app.gin.GET("/openapi.json", docs.SpecHandler)
+	app.gin.GET("/swagger", docs.SwaggerUIHandler)
+
 	app.gin.POST("/api/auth", app.handlers.AuthUser)
 	mwGroupapp := app.gin.Group("/api/").Use(middleware.AuthMiddleware())
 	{
 		mwGroupapp.GET("/info", app.handlers.WalletInfo)
 		mwGroupapp.POST("/sendCoin", app.handlers.SendCoin)
 		mwGroupapp.GET("/buy/:merchName", app.handlers.BuyMerch)
+		mwGroupapp.POST("/gift/:merchName", app.handlers.GiftMerch)
+		mwGroupapp.POST("/sendItem", inventoryHandlers.SendItem)
+		mwGroupapp.GET("/inventory/history", inventoryHandlers.History)
+		mwGroupapp.GET("/wishlist", wishlistHandlers.GetWishlist)
+		mwGroupapp.POST("/wishlist", wishlistHandlers.AddWish)
+		mwGroupapp.DELETE("/wishlist/:item", wishlistHandlers.RemoveWish)
+		mwGroupapp.POST("/wishlist/contribute", wishlistHandlers.Contribute)
+		mwGroupapp.GET("/teams", teamHandlers.ListTeams)
+		mwGroupapp.GET("/teams/:id", teamHandlers.GetTeam)
+		mwGroupapp.GET("/teams/:id/history", teamHandlers.History)
+		mwGroupapp.POST("/teams/:id/distribute", teamHandlers.Distribute)
+		mwGroupapp.POST("/teams/:id/buy/:merchName", teamHandlers.Buy)
+		mwGroupapp.GET("/merch/:name", merchHandlers.GetMerch)
+		mwGroupapp.GET("/events", eventsHandlers.Stream)
+		mwGroupapp.GET("/orders", orderHandlers.ListUserOrders)
+		mwGroupapp.PUT("/orders/:id/address", orderHandlers.SetShippingAddress)
+		mwGroupapp.POST("/orders/:id/cancel", orderHandlers.CancelOrder)
+	}
+	adminGroup := app.gin.Group("/api/admin/").Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(admins))
+	{
+		adminGroup.POST("/webhooks", webhookHandlers.CreateWebhook)
+		adminGroup.GET("/webhooks", webhookHandlers.ListWebhooks)
+		adminGroup.DELETE("/webhooks/:id", webhookHandlers.DeleteWebhook)
+		adminGroup.GET("/webhooks/:id/deliveries", webhookHandlers.ListDeliveries)
+		adminGroup.POST("/deliveries/:id/redeliver", webhookHandlers.Redeliver)
+		adminGroup.GET("/orders", orderHandlers.ListOrders)
+		adminGroup.POST("/orders/:id/status", orderHandlers.UpdateOrderStatus)
+		adminGroup.POST("/promotions", promotionHandlers.CreatePromotion)
+		adminGroup.GET("/promotions", promotionHandlers.ListPromotions)
+		adminGroup.DELETE("/promotions/:id", promotionHandlers.EndPromotion)
+		adminGroup.PUT("/merch/:name/price", merchHandlers.SetPrice)
+		adminGroup.POST("/merch/:name/variants", merchHandlers.CreateVariant)
+		adminGroup.PUT("/merch/:name/variants/:sku", merchHandlers.UpdateVariant)
+		adminGroup.GET("/merch/:name/prices", merchHandlers.PriceHistory)
+		adminGroup.GET("/reports/sales", merchHandlers.SalesReport)
+		adminGroup.POST("/teams", teamHandlers.CreateTeam)
+		adminGroup.PUT("/teams/:id/members/:username", teamHandlers.SetMember)
+		adminGroup.DELETE("/teams/:id/members/:username", teamHandlers.RemoveMember)
+		adminGroup.POST("/teams/:id/deposit", teamHandlers.Deposit)
+		adminGroup.PUT("/budgets/:username", budgetHandlers.SetBudget)
+		adminGroup.POST("/tenants", tenantHandlers.CreateTenant)
+		adminGroup.GET("/tenants", tenantHandlers.ListTenants)
+		adminGroup.GET("/tenant", tenantHandlers.GetTenant)
+		adminGroup.PUT("/tenant", tenantHandlers.UpdateTenant)
+		adminGroup.POST("/users", tenantHandlers.CreateUser)
 	}
 
 	return app, nil
 }
 
 func (app *AvitoShop) Run() error {
+	if app.stopReplicas != nil {
+		defer app.stopReplicas()
+	}
+	defer func() {
+		if err := app.shutdownTracing(context.Background()); err != nil {
+			app.logger.Error("tracing shutdown error", zap.Error(err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if app.relay != nil {
+		go app.relay.Run(ctx)
+	}
+	go app.webhooks.Run(ctx)
+	go app.onboarding.RunVesting(ctx, app.vestInterval)
+	if app.realtimeBus != nil {
+		go app.realtimeBus.Run(ctx)
+	}
+
 	ginAddr := os.Getenv("SERVER_ADDR")
 	if ginAddr == "" {
 		ginAddr = ":8080"
@@ -0,0 +1,22 @@
+package docs
+
+import (
+	_ "embed" //nolint:revive
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+//go:embed swagger.html
+var swaggerUI []byte
+
+func SpecHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", Spec)
+}
+
+func SwaggerUIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerUI)
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/service" //nolint:gci
+	"github.com/gin-gonic/gin"
+)
+
+type BudgetServiceInterface interface {
+	SetBudget(ctx context.Context, username string, limit int) (*service.Budget, error)
+}
+
+// BudgetHandlers админские ручки бюджетов на благодарности
+type BudgetHandlers struct {
+	Service BudgetServiceInterface
+}
+
+func NewBudgetHandlers(srv BudgetServiceInterface) *BudgetHandlers {
+	return &BudgetHandlers{Service: srv}
+}
+
+// SetBudgetRequest limit 0 отключает бюджет
+type SetBudgetRequest struct {
+	Limit *int `json:"limit" binding:"required,min=0"`
+}
+
+// SetBudget назначает сотруднику бюджет на благодарности на каждый период
+func (h *BudgetHandlers) SetBudget(c *gin.Context) {
+	var req SetBudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	budget, err := h.Service.SetBudget(c.Request.Context(), c.Param("username"), *req.Limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, Budget{Limit: budget.Limit, Remaining: budget.Remaining, ResetsAt: budget.ResetsAt})
+}
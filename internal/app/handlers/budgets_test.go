@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubBudgetService struct {
+	err error
+}
+
+func (s *stubBudgetService) SetBudget(_ context.Context, _ string, limit int) (*service.Budget, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &service.Budget{Limit: limit, Remaining: limit, ResetsAt: time.Now()}, nil
+}
+
+// TestBudgetContract проверяет ручку бюджетов по openapi.json
+func TestBudgetContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	cases := []struct {
+		name   string
+		srv    *stubBudgetService
+		body   string
+		status int
+	}{
+		{name: "set ok", srv: &stubBudgetService{}, body: `{"limit":300}`, status: http.StatusOK},
+		{name: "disable", srv: &stubBudgetService{}, body: `{"limit":0}`, status: http.StatusOK},
+		{name: "without limit", srv: &stubBudgetService{}, body: `{}`, status: http.StatusBadRequest},
+		{name: "negative", srv: &stubBudgetService{}, body: `{"limit":-1}`, status: http.StatusBadRequest},
+		{name: "unknown user", srv: &stubBudgetService{err: service.ErrUserNotFound}, body: `{"limit":300}`,
+			status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewBudgetHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(_, admin gin.IRoutes) {
+				admin.PUT("/budgets/:username", h.SetBudget)
+			})
+			serveContract(t, engine, newTestRequest(http.MethodPut, "/api/admin/budgets/alice", tc.body, token), tc.status)
+		})
+	}
+}
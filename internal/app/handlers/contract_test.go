@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/app/docs"
+	"github.com/Vic07Region/avito-shop/internal/app/mw"
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type stubService struct {
+	info        *service.FullInfo
+	loginErr    error
+	sendErr     error
+	purchaseErr error
+}
+
+func (s *stubService) GetWalletInfo(_ context.Context, _ uuid.UUID) (*service.FullInfo, error) {
+	return s.info, nil
+}
+
+func (s *stubService) SendCoins(_ context.Context, _ uuid.UUID, _ string, _ int) error {
+	return s.sendErr
+}
+
+func (s *stubService) PurchaseMerch(_ context.Context, _ uuid.UUID, _ string, _ service.VariantChoice, _ int, _ string) error {
+	return s.purchaseErr
+}
+
+func (s *stubService) GiftMerch(_ context.Context, _ uuid.UUID, _ string, _ string, _ service.VariantChoice, _ string,
+	_ string) error {
+	return s.purchaseErr
+}
+
+func (s *stubService) LoginUser(_ context.Context, _ service.UserData) (string, error) {
+	return "token", s.loginErr
+}
+
+type stubUserStorage struct{}
+
+func (stubUserStorage) GetUser4UserID(_ context.Context, userID uuid.UUID) (*storage.Employee, error) {
+	return &storage.Employee{EmployeeId: userID, Name: "admin", Role: storage.RoleAdmin}, nil
+}
+
+// employeeUserStorage сотрудник с именем из списка администраторов, но без роли admin
+type employeeUserStorage struct {
+	stubUserStorage
+}
+
+func (employeeUserStorage) GetUser4UserID(_ context.Context, userID uuid.UUID) (*storage.Employee, error) {
+	return &storage.Employee{EmployeeId: userID, Name: "admin", Role: storage.RoleEmployee}, nil
+}
+
+// GetTenant у компании по умолчанию администраторы только из списка, переданного в AdminMiddleware
+func (stubUserStorage) GetTenant(_ context.Context, tenantID int64) (*storage.Tenant, error) {
+	if tenantID == storage.DefaultTenantID {
+		return &storage.Tenant{ID: tenantID, Slug: "default", Name: "Default"}, nil
+	}
+	return &storage.Tenant{ID: tenantID, Slug: "acme", Name: "Acme", Admins: []string{"admin"}}, nil
+}
+
+// tenantAdminsStorage компания по умолчанию с администратором admin в настройках
+type tenantAdminsStorage struct {
+	stubUserStorage
+}
+
+func (tenantAdminsStorage) GetTenant(_ context.Context, tenantID int64) (*storage.Tenant, error) {
+	return &storage.Tenant{ID: tenantID, Slug: "default", Name: "Default", Admins: []string{"admin"}}, nil
+}
+
+func newContractRouter(t *testing.T) routers.Router {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(docs.Spec)
+	require.NoError(t, err)
+	require.NoError(t, doc.Validate(context.Background()))
+	router, err := gorillamux.NewRouter(doc)
+	require.NoError(t, err)
+	return router
+}
+
+// testAdmins администраторы AdminMiddleware в тестах, stubUserStorage отдает пользователя admin
+var testAdmins = []string{"admin"}
+
+// newTestEngine собирает gin как app: ErrorRenderer, /api/ под AuthMiddleware, /api/admin/ еще и под
+// AdminMiddleware(admins). routes регистрирует ручки теста в этих группах.
+func newTestEngine(users mw.UserStorage, admins []string, routes func(api, admin gin.IRoutes)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	middleware := mw.New(users)
+	engine := gin.New()
+	engine.Use(mw.ErrorRenderer())
+	routes(engine.Group("/api/").Use(middleware.AuthMiddleware()),
+		engine.Group("/api/admin/").Use(middleware.AuthMiddleware(), middleware.AdminMiddleware(admins)))
+	return engine
+}
+
+// newTestToken задает SECRET_KEY теста и выпускает JWT для user
+func newTestToken(t *testing.T, user utils.User) string {
+	t.Helper()
+	t.Setenv("SECRET_KEY", "contract")
+	token, err := utils.GenerateJWT(user)
+	require.NoError(t, err)
+	return token
+}
+
+// newTestRequest JSON запрос к ручке, без token - без авторизации
+func newTestRequest(method, path, body, token string) *http.Request {
+	req := httptest.NewRequest(method, "http://localhost:8080"+path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// serveContract выполняет req, проверяет статус и ответ по openapi.json
+func serveContract(t *testing.T, engine http.Handler, req *http.Request, status int) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, req)
+	require.Equal(t, status, rec.Code, rec.Body.String())
+	validateResponse(t, req, rec)
+	return rec
+}
+
+// newWalletEngine ручки кошелька и публичный вход
+func newWalletEngine(srv ServiceInterface) *gin.Engine {
+	h := New(srv, nil)
+	engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, _ gin.IRoutes) {
+		api.GET("/info", h.WalletInfo)
+		api.POST("/sendCoin", h.SendCoin)
+		api.GET("/buy/:merchName", h.BuyMerch)
+		api.POST("/gift/:merchName", h.GiftMerch)
+	})
+	engine.POST("/api/auth", h.AuthUser)
+	return engine
+}
+
+// TestContract проверяет, что реальные ответы хендлеров соответствуют openapi.json
+func TestContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	fullInfo := &service.FullInfo{
+		Coins:     900,
+		Budget:    &service.Budget{Limit: 300, Remaining: 120, ResetsAt: time.Now()},
+		Inventory: []service.Inventory{{Type: "cup", Quantity: 2}},
+		CoinHistory: service.CoinHistory{
+			Received: []service.Received{{FromUser: "alice", Amount: 10}},
+			Sent:     []service.Sent{{ToUser: "bob", Amount: 110}},
+		},
+		Wishlist: []service.WishlistGoal{{ID: 1, Item: "powerbank", Price: 200, Goal: 1000, Contributed: 50,
+			Saved: 900, Progress: 90, CreatedAt: time.Now()}},
+	}
+
+	cases := []struct {
+		name   string
+		srv    *stubService
+		method string
+		path   string
+		body   string
+		token  string
+		accept string
+		status int
+	}{
+		{name: "auth ok", srv: &stubService{}, method: http.MethodPost, path: "/api/auth",
+			body: `{"username":"alice","password":"secret1"}`, status: http.StatusOK},
+		{name: "auth empty body", srv: &stubService{}, method: http.MethodPost, path: "/api/auth",
+			status: http.StatusBadRequest},
+		{name: "auth validation", srv: &stubService{}, method: http.MethodPost, path: "/api/auth",
+			body: `{"username":"alice","password":"123"}`, status: http.StatusBadRequest},
+		{name: "auth invalid password", srv: &stubService{loginErr: service.ErrInvelidPassword},
+			method: http.MethodPost, path: "/api/auth",
+			body: `{"username":"alice","password":"secret1"}`, status: http.StatusUnauthorized},
+		{name: "info ok", srv: &stubService{info: fullInfo}, method: http.MethodGet, path: "/api/info",
+			token: token, status: http.StatusOK},
+		{name: "info empty", srv: &stubService{info: &service.FullInfo{Coins: 1000}}, method: http.MethodGet,
+			path: "/api/info", token: token, status: http.StatusOK},
+		{name: "info missing token", srv: &stubService{}, method: http.MethodGet, path: "/api/info",
+			status: http.StatusUnauthorized},
+		{name: "send ok", srv: &stubService{}, method: http.MethodPost, path: "/api/sendCoin",
+			body: `{"toUser":"bob","amount":10}`, token: token, status: http.StatusOK},
+		{name: "send not enough coins", srv: &stubService{sendErr: service.ErrNotEnoughCoins},
+			method: http.MethodPost, path: "/api/sendCoin",
+			body: `{"toUser":"bob","amount":10}`, token: token, status: http.StatusBadRequest},
+		{name: "buy ok", srv: &stubService{}, method: http.MethodGet, path: "/api/buy/cup",
+			token: token, status: http.StatusOK},
+		{name: "buy merch not found problem json", srv: &stubService{purchaseErr: service.ErrMerchNotFound},
+			method: http.MethodGet, path: "/api/buy/unknown", token: token,
+			accept: "application/problem+json", status: http.StatusBadRequest},
+		{name: "buy internal error", srv: &stubService{purchaseErr: errors.New("db is down")},
+			method: http.MethodGet, path: "/api/buy/cup", token: token, status: http.StatusInternalServerError},
+		{name: "buy promo exhausted", srv: &stubService{purchaseErr: service.ErrPromoExhausted},
+			method: http.MethodGet, path: "/api/buy/cup?promoCode=ONCE", token: token, status: http.StatusBadRequest},
+		{name: "gift ok", srv: &stubService{}, method: http.MethodPost, path: "/api/gift/hoody",
+			body: `{"toUser":"bob","message":"С днем рождения!","size":"M"}`, token: token, status: http.StatusOK},
+		{name: "gift without recipient", srv: &stubService{}, method: http.MethodPost, path: "/api/gift/cup",
+			body: `{"message":"hi"}`, token: token, status: http.StatusBadRequest},
+		{name: "gift unknown recipient", srv: &stubService{purchaseErr: service.ErrUserNotFound},
+			method: http.MethodPost, path: "/api/gift/cup", body: `{"toUser":"nobody"}`, token: token,
+			status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := newTestRequest(tc.method, tc.path, tc.body, tc.token)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			serveContract(t, newWalletEngine(tc.srv), req, tc.status)
+		})
+	}
+}
+
+// validateResponse проверяет ответ по openapi.json
+func validateResponse(t *testing.T, req *http.Request, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	route, pathParams, err := newContractRouter(t).FindRoute(req)
+	require.NoError(t, err)
+
+	err = openapi3filter.ValidateResponse(context.Background(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+		},
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   io.NopCloser(bytes.NewReader(rec.Body.Bytes())),
+		Options: &openapi3filter.Options{
+			IncludeResponseStatus: true,
+		},
+	})
+	require.NoError(t, err)
+}
+
+func TestWalletInfoHistory(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	srv := &stubService{info: &service.FullInfo{
+		Coins: 900,
+		CoinHistory: service.CoinHistory{
+			Received: []service.Received{{FromUser: "alice", Amount: 10}},
+			Sent:     []service.Sent{{ToUser: "bob", Amount: 110}},
+		},
+	}}
+
+	rec := serveContract(t, newWalletEngine(srv), newTestRequest(http.MethodGet, "/api/info", "", token), http.StatusOK)
+
+	var info FullInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	require.Equal(t, []Received{{FromUser: "alice", Amount: 10}}, info.CoinHistory.Received)
+	require.Equal(t, []Sent{{ToUser: "bob", Amount: 110}}, info.CoinHistory.Sent)
+	require.Equal(t, []Inventory{}, info.Inventory)
+}
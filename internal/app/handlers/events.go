@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SubscriberInterface interface {
+	Subscribe(userID uuid.UUID) (<-chan events.Event, func())
+}
+
+// EventsHandlers поток событий пользователя в формате Server-Sent Events
+type EventsHandlers struct {
+	Subscriber SubscriberInterface
+	// Heartbeat интервал комментариев, которые не дают прокси закрыть простаивающее соединение
+	Heartbeat time.Duration
+}
+
+func NewEventsHandlers(subscriber SubscriberInterface, heartbeat time.Duration) *EventsHandlers {
+	return &EventsHandlers{Subscriber: subscriber, Heartbeat: heartbeat}
+}
+
+func (h *EventsHandlers) Stream(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	stream, unsubscribe := h.Subscriber.Subscribe(userID.(uuid.UUID))
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// nginx не должен буферизовать поток
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// комментарий сразу отправляет заголовки клиенту
+	_, _ = fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	ctx := c.Request.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(c.Writer, ": ping\n\n")
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			// ответ уже начат, ошибку можно только залогировать, поток закрывается
+			data, err := json.Marshal(event)
+			if err != nil {
+				_ = c.Error(err)
+				return
+			}
+			_, _ = fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		c.Writer.Flush()
+	}
+}
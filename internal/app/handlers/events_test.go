@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/realtime"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsStream(t *testing.T) {
+	userID := uuid.New()
+	token := newTestToken(t, utils.User{UserID: userID})
+
+	broker := realtime.NewBroker(10)
+	engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, _ gin.IRoutes) {
+		api.GET("/events", NewEventsHandlers(broker, 50*time.Millisecond).Stream)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	event, err := events.New(events.TypeBalanceChanged, events.BalanceChanged{UserID: userID, Balance: 990, Delta: -10})
+	require.NoError(t, err)
+	// подписка создается до отправки заголовков, поэтому событие не потеряется
+	require.NoError(t, broker.Publish(ctx, realtime.Message{UserID: userID, Event: event}))
+	require.NoError(t, broker.Publish(ctx, realtime.Message{UserID: uuid.New(), Event: event}))
+
+	// читаем, пока не придут кадр события и хотя бы один heartbeat
+	reader := bufio.NewReader(resp.Body)
+	var frame []string
+	var pings int
+	for len(frame) < 3 || pings == 0 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == ": ping":
+			pings++
+		case line != "" && !strings.HasPrefix(line, ":"):
+			frame = append(frame, line)
+		}
+	}
+
+	require.Len(t, frame, 3)
+	assert.Equal(t, "id: "+event.ID.String(), frame[0])
+	assert.Equal(t, "event: balance.changed", frame[1])
+	assert.True(t, strings.HasPrefix(frame[2], "data: "))
+	assert.Contains(t, frame[2], `"balance":990`)
+}
+
+func TestEventsStreamUnauthorized(t *testing.T) {
+	engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, _ gin.IRoutes) {
+		api.GET("/events", NewEventsHandlers(realtime.NewBroker(1), time.Second).Stream)
+	})
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/events", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
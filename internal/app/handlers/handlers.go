@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"github.com/Vic07Region/avito-shop/internal/apperr"
 	"github.com/Vic07Region/avito-shop/internal/service"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -16,7 +17,10 @@ import (
 type ServiceInterface interface {
 	GetWalletInfo(ctx context.Context, userID uuid.UUID) (*service.FullInfo, error)
 	SendCoins(ctx context.Context, userID uuid.UUID, toUsername string, amount int) error
-	PurchaseMerch(ctx context.Context, userID uuid.UUID, merchName string, quantity int) error
+	PurchaseMerch(ctx context.Context, userID uuid.UUID, merchName string, choice service.VariantChoice,
+		quantity int, promoCode string) error
+	GiftMerch(ctx context.Context, userID uuid.UUID, toUsername string, merchName string, choice service.VariantChoice,
+		promoCode string, message string) error
 	LoginUser(ctx context.Context, userdata service.UserData) (string, error)
 }
 
@@ -32,9 +36,11 @@ func New(srv ServiceInterface, zapLogger *zap.Logger) *Handlers {
 	}
 }
 
+// LoginRequest без tenant сотрудник входит в компанию по умолчанию
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
 	Password string `json:"password" binding:"required,min=6"`
+	Tenant   string `json:"tenant" binding:"max=32"`
 }
 
 type SendCoinRequest struct {
@@ -42,28 +48,21 @@ type SendCoinRequest struct {
 	Amount int    `json:"amount" binding:"required,min=1"`
 }
 
+// GiftRequest вариант выбирается как при покупке: по sku или по размеру и цвету
+type GiftRequest struct {
+	ToUser    string `json:"toUser" binding:"required,alphanum"`
+	Message   string `json:"message" binding:"max=255"`
+	SKU       string `json:"sku"`
+	Size      string `json:"size"`
+	Color     string `json:"color"`
+	PromoCode string `json:"promoCode" binding:"max=32"`
+}
+
 func (h *Handlers) AuthUser(c *gin.Context) {
 	var req LoginRequest
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		if errors.Is(err, io.EOF) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"errors": "request body is empty",
-			})
-			return
-		}
-
-		if vErr, ok := err.(validator.ValidationErrors); ok {
-			for _, e := range vErr {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"errors": e.Error(),
-				})
-				return
-			}
-		}
-		c.JSON(http.StatusBadRequest, gin.H{
-			"errors": err.Error(),
-		})
+		_ = c.Error(bindError(err))
 		return
 	}
 	ctx := c.Request.Context()
@@ -71,17 +70,10 @@ func (h *Handlers) AuthUser(c *gin.Context) {
 	token, err := h.Service.LoginUser(ctx, service.UserData{
 		Username: strings.ToLower(req.Username),
 		Password: req.Password,
+		Tenant:   strings.ToLower(req.Tenant),
 	})
 	if err != nil {
-		if errors.Is(err, service.ErrInvelidPassword) {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"errors": err.Error(),
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"errors": "login service filed",
-		})
+		_ = c.Error(err)
 		return
 	}
 
@@ -94,17 +86,13 @@ func (h *Handlers) AuthUser(c *gin.Context) {
 func (h *Handlers) WalletInfo(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"errors": "unauthorized",
-		})
+		_ = c.Error(apperr.ErrUnauthorized)
 		return
 	}
 	ctx := c.Request.Context()
 	walletInfo, err := h.Service.GetWalletInfo(ctx, userID.(uuid.UUID))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "wallet service failed",
-		})
+		_ = c.Error(err)
 		return
 	}
 
@@ -116,11 +104,14 @@ func (h *Handlers) WalletInfo(c *gin.Context) {
 		for _, i := range walletInfo.Inventory {
 			inventoryList = append(inventoryList, Inventory{
 				Type:     i.Type,
+				SKU:      i.SKU,
+				Size:     i.Size,
+				Color:    i.Color,
 				Quantity: i.Quantity,
 			})
 		}
 	}
-	if walletInfo.CoinHistory.Sent == nil {
+	if walletInfo.CoinHistory.Sent != nil {
 		for _, s := range walletInfo.CoinHistory.Sent {
 			sentList = append(sentList, Sent{
 				ToUser: s.ToUser,
@@ -128,7 +119,7 @@ func (h *Handlers) WalletInfo(c *gin.Context) {
 			})
 		}
 	}
-	if walletInfo.CoinHistory.Received == nil {
+	if walletInfo.CoinHistory.Received != nil {
 		for _, r := range walletInfo.CoinHistory.Received {
 			receivedList = append(receivedList, Received{
 				FromUser: r.FromUser,
@@ -137,13 +128,29 @@ func (h *Handlers) WalletInfo(c *gin.Context) {
 		}
 	}
 
+	var wishlist = []Wish{}
+	for _, w := range walletInfo.Wishlist {
+		wishlist = append(wishlist, newWish(w))
+	}
+
+	var budget *Budget
+	if walletInfo.Budget != nil {
+		budget = &Budget{
+			Limit:     walletInfo.Budget.Limit,
+			Remaining: walletInfo.Budget.Remaining,
+			ResetsAt:  walletInfo.Budget.ResetsAt,
+		}
+	}
+
 	c.JSON(http.StatusOK, FullInfo{
 		Coins:     walletInfo.Coins,
+		Budget:    budget,
 		Inventory: inventoryList,
 		CoinHistory: CoinHistory{
 			Received: receivedList,
 			Sent:     sentList,
 		},
+		Wishlist: wishlist,
 	})
 }
 
@@ -152,31 +159,12 @@ func (h *Handlers) SendCoin(c *gin.Context) {
 
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"errors": "unauthorized",
-		})
+		_ = c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		if errors.Is(err, io.EOF) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"errors": "request body is empty",
-			})
-			return
-		}
-
-		if vErr, ok := err.(validator.ValidationErrors); ok {
-			for _, e := range vErr {
-				c.JSON(http.StatusBadRequest, gin.H{
-					"errors": e.Error(),
-				})
-				return
-			}
-		}
-		c.JSON(http.StatusBadRequest, gin.H{
-			"errors": err.Error(),
-		})
+		_ = c.Error(bindError(err))
 		return
 	}
 
@@ -184,16 +172,7 @@ func (h *Handlers) SendCoin(c *gin.Context) {
 
 	err := h.Service.SendCoins(ctx, userID.(uuid.UUID), req.ToUser, req.Amount)
 	if err != nil {
-		if errors.Is(err, service.ErrUserNotFound) || errors.Is(err, service.ErrNotEnoughCoins) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"errors": err.Error(),
-			})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"errors": "wallet service failed",
-		})
+		_ = c.Error(err)
 		return
 	}
 
@@ -209,28 +188,57 @@ func (h *Handlers) BuyMerch(c *gin.Context) {
 
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"errors": "unauthorized",
-		})
+		_ = c.Error(apperr.ErrUnauthorized)
 		return
 	}
 
 	ctx := c.Request.Context()
 
-	err := h.Service.PurchaseMerch(ctx, userID.(uuid.UUID), merchName, 1)
+	choice := service.VariantChoice{SKU: c.Query("sku"), Size: c.Query("size"), Color: c.Query("color")}
+	err := h.Service.PurchaseMerch(ctx, userID.(uuid.UUID), merchName, choice, 1, c.Query("promoCode"))
 	if err != nil {
-		if errors.Is(err, service.ErrMerchNotFound) || errors.Is(err, service.ErrNotEnoughCoins) {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"errors": err.Error(),
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"errors": "wallet service failed",
-		})
+		_ = c.Error(err)
 		return
 	}
 
 	c.Status(http.StatusOK)
 
 }
+
+// GiftMerch покупка товара в подарок коллеге: платит текущий пользователь
+func (h *Handlers) GiftMerch(c *gin.Context) {
+	var req GiftRequest
+
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	choice := service.VariantChoice{SKU: req.SKU, Size: req.Size, Color: req.Color}
+	err := h.Service.GiftMerch(c.Request.Context(), userID.(uuid.UUID), req.ToUser, c.Param("merchName"), choice,
+		req.PromoCode, req.Message)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// bindError приводит ошибки биндинга запроса к ошибкам apperr
+func bindError(err error) error {
+	if errors.Is(err, io.EOF) {
+		return apperr.ErrEmptyBody
+	}
+	var vErr validator.ValidationErrors
+	if errors.As(err, &vErr) && len(vErr) > 0 {
+		return apperr.ErrValidation.WithDetail(vErr[0].Error())
+	}
+	return apperr.ErrBadRequest.WithDetail(err.Error())
+}
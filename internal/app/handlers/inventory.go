@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type InventoryServiceInterface interface {
+	SendItems(ctx context.Context, userID uuid.UUID, toUsername string, merchName string,
+		choice service.VariantChoice, quantity int) error
+	InventoryLedger(ctx context.Context, userID uuid.UUID) ([]storage.LedgerEntry, error)
+}
+
+// InventoryHandlers ручки инвентаря: передача товара коллеге и журнал движений
+type InventoryHandlers struct {
+	Service InventoryServiceInterface
+}
+
+func NewInventoryHandlers(srv InventoryServiceInterface) *InventoryHandlers {
+	return &InventoryHandlers{Service: srv}
+}
+
+// SendItemRequest вариант выбирается среди купленных: по sku или по размеру и цвету
+type SendItemRequest struct {
+	ToUser   string `json:"toUser" binding:"required,alphanum"`
+	Item     string `json:"item" binding:"required"`
+	SKU      string `json:"sku"`
+	Size     string `json:"size"`
+	Color    string `json:"color"`
+	Quantity int    `json:"quantity" binding:"required,min=1"`
+}
+
+func (h *InventoryHandlers) SendItem(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	var req SendItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	choice := service.VariantChoice{SKU: req.SKU, Size: req.Size, Color: req.Color}
+	err := h.Service.SendItems(c.Request.Context(), userID.(uuid.UUID), req.ToUser, req.Item, choice, req.Quantity)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *InventoryHandlers) History(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	entries, err := h.Service.InventoryLedger(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []LedgerEntry{}
+	for _, e := range entries {
+		response = append(response, LedgerEntry{
+			ID:           e.ID,
+			Kind:         e.Kind,
+			Item:         e.Item,
+			SKU:          e.SKU,
+			Size:         e.Size,
+			Color:        e.Color,
+			Quantity:     e.Quantity,
+			OrderID:      e.OrderID,
+			Counterparty: e.Counterparty,
+			CreatedAt:    e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubInventoryService struct {
+	entries []storage.LedgerEntry
+	err     error
+}
+
+func (s *stubInventoryService) SendItems(_ context.Context, _ uuid.UUID, _ string, _ string,
+	_ service.VariantChoice, _ int) error {
+	return s.err
+}
+
+func (s *stubInventoryService) InventoryLedger(_ context.Context, _ uuid.UUID) ([]storage.LedgerEntry, error) {
+	return s.entries, s.err
+}
+
+// TestInventoryContract проверяет ручки инвентаря по openapi.json
+func TestInventoryContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	ledger := &stubInventoryService{entries: []storage.LedgerEntry{
+		{ID: 3, Kind: storage.LedgerGifted, Item: "hoody", SKU: "HOODY-M", Size: "M", Quantity: -1,
+			Counterparty: "bob", CreatedAt: time.Now()},
+		{ID: 1, Kind: storage.LedgerAcquired, Item: "hoody", SKU: "HOODY-M", Size: "M", Quantity: 2,
+			OrderID: 1, CreatedAt: time.Now()},
+	}}
+
+	cases := []struct {
+		name   string
+		srv    *stubInventoryService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "send ok", srv: &stubInventoryService{}, method: http.MethodPost, path: "/api/sendItem",
+			body: `{"toUser":"bob","item":"hoody","size":"M","quantity":1}`, status: http.StatusOK},
+		{name: "send without quantity", srv: &stubInventoryService{}, method: http.MethodPost, path: "/api/sendItem",
+			body: `{"toUser":"bob","item":"cup"}`, status: http.StatusBadRequest},
+		{name: "send not enough", srv: &stubInventoryService{err: service.ErrNotEnoughItems}, method: http.MethodPost,
+			path: "/api/sendItem", body: `{"toUser":"bob","item":"cup","quantity":5}`, status: http.StatusBadRequest},
+		{name: "send unknown user", srv: &stubInventoryService{err: service.ErrUserNotFound}, method: http.MethodPost,
+			path: "/api/sendItem", body: `{"toUser":"nobody","item":"cup","quantity":1}`, status: http.StatusBadRequest},
+		{name: "history ok", srv: ledger, method: http.MethodGet, path: "/api/inventory/history", status: http.StatusOK},
+		{name: "history empty", srv: &stubInventoryService{}, method: http.MethodGet, path: "/api/inventory/history",
+			status: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewInventoryHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, _ gin.IRoutes) {
+				api.POST("/sendItem", h.SendItem)
+				api.GET("/inventory/history", h.History)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
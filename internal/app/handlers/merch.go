@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// salesReportPeriod период отчета о продажах, если from не задан
+const salesReportPeriod = 30 * 24 * time.Hour
+
+type MerchServiceInterface interface {
+	Merch(ctx context.Context, merchName string) (*storage.MerchItem, error)
+	CreateVariant(ctx context.Context, merchName string, variant storage.MerchVariant) (*storage.MerchItem, error)
+	UpdateVariant(ctx context.Context, merchName string, sku string, price int, stock int) (*storage.MerchItem, error)
+	SetMerchPrice(ctx context.Context, merchName string, price int) (*storage.MerchPrice, error)
+	PriceHistory(ctx context.Context, merchName string) ([]storage.MerchPrice, error)
+	SalesReport(ctx context.Context, from, to time.Time) ([]storage.SalesReportLine, error)
+}
+
+// MerchHandlers ручки каталога: товар с вариантами для пользователя,
+// варианты, цены и отчет о продажах для администратора
+type MerchHandlers struct {
+	Service MerchServiceInterface
+}
+
+func NewMerchHandlers(srv MerchServiceInterface) *MerchHandlers {
+	return &MerchHandlers{Service: srv}
+}
+
+type MerchPriceRequest struct {
+	Price int `json:"price" binding:"required,min=1"`
+}
+
+// CreateVariantRequest Price 0 - цена товара
+type CreateVariantRequest struct {
+	SKU   string `json:"sku" binding:"required,max=64"`
+	Size  string `json:"size" binding:"max=16"`
+	Color string `json:"color" binding:"max=32"`
+	Price int    `json:"price" binding:"min=0"`
+	Stock int    `json:"stock" binding:"min=0"`
+}
+
+type UpdateVariantRequest struct {
+	Price int  `json:"price" binding:"min=0"`
+	Stock *int `json:"stock" binding:"required,min=0"`
+}
+
+func (h *MerchHandlers) GetMerch(c *gin.Context) {
+	merch, err := h.Service.Merch(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchItem(*merch))
+}
+
+func (h *MerchHandlers) CreateVariant(c *gin.Context) {
+	var req CreateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	merch, err := h.Service.CreateVariant(c.Request.Context(), c.Param("name"), storage.MerchVariant{
+		SKU:   req.SKU,
+		Size:  req.Size,
+		Color: req.Color,
+		Price: req.Price,
+		Stock: req.Stock,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, newMerchItem(*merch))
+}
+
+func (h *MerchHandlers) UpdateVariant(c *gin.Context) {
+	var req UpdateVariantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	merch, err := h.Service.UpdateVariant(c.Request.Context(), c.Param("name"), c.Param("sku"), req.Price, *req.Stock)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchItem(*merch))
+}
+
+func (h *MerchHandlers) SetPrice(c *gin.Context) {
+	var req MerchPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	price, err := h.Service.SetMerchPrice(c.Request.Context(), c.Param("name"), req.Price)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newMerchPrice(*price))
+}
+
+func (h *MerchHandlers) PriceHistory(c *gin.Context) {
+	prices, err := h.Service.PriceHistory(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []MerchPrice{}
+	for _, p := range prices {
+		response = append(response, newMerchPrice(p))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SalesReport продажи за период [from, to), по умолчанию за последние 30 дней
+func (h *MerchHandlers) SalesReport(c *gin.Context) {
+	to, err := timeQuery(c, "to", time.Now().UTC())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	from, err := timeQuery(c, "from", to.Add(-salesReportPeriod))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	lines, err := h.Service.SalesReport(c.Request.Context(), from, to)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response := SalesReport{From: from, To: to, Lines: []SalesReportLine{}}
+	for _, l := range lines {
+		response.Lines = append(response.Lines, SalesReportLine(l))
+		response.Gross += l.Gross
+		response.Discount += l.Discount
+		response.Total += l.Total
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// newMerchItem у варианта без собственной цены отдается цена товара
+func newMerchItem(merch storage.MerchItem) MerchItem {
+	item := MerchItem{Name: merch.Name, Price: merch.Price, Variants: []MerchVariant{}}
+	for _, v := range merch.Variants {
+		item.Variants = append(item.Variants, MerchVariant{
+			SKU:   v.SKU,
+			Size:  v.Size,
+			Color: v.Color,
+			Price: v.UnitPrice(merch.Price),
+			Stock: v.Stock,
+		})
+	}
+	return item
+}
+
+func newMerchPrice(p storage.MerchPrice) MerchPrice {
+	return MerchPrice{
+		Price:         p.Price,
+		EffectiveFrom: p.EffectiveFrom,
+		EffectiveTo:   p.EffectiveTo,
+	}
+}
+
+func timeQuery(c *gin.Context, name string, fallback time.Time) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, apperr.ErrValidation.WithDetail(name + " must be an RFC 3339 time")
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubMerchService struct {
+	merch  *storage.MerchItem
+	prices []storage.MerchPrice
+	report []storage.SalesReportLine
+	err    error
+}
+
+func (s *stubMerchService) Merch(_ context.Context, _ string) (*storage.MerchItem, error) {
+	return s.merch, s.err
+}
+
+func (s *stubMerchService) CreateVariant(_ context.Context, _ string, variant storage.MerchVariant) (*storage.MerchItem, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	merch := *s.merch
+	merch.Variants = append(merch.Variants, variant)
+	return &merch, nil
+}
+
+func (s *stubMerchService) UpdateVariant(_ context.Context, _ string, _ string, _ int, _ int) (*storage.MerchItem, error) {
+	return s.merch, s.err
+}
+
+func (s *stubMerchService) SetMerchPrice(_ context.Context, _ string, price int) (*storage.MerchPrice, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.MerchPrice{ItemID: 3, Price: price, EffectiveFrom: time.Now()}, nil
+}
+
+func (s *stubMerchService) PriceHistory(_ context.Context, _ string) ([]storage.MerchPrice, error) {
+	return s.prices, s.err
+}
+
+func (s *stubMerchService) SalesReport(_ context.Context, _, _ time.Time) ([]storage.SalesReportLine, error) {
+	return s.report, s.err
+}
+
+// TestMerchContract проверяет ручки каталога, цен и отчета о продажах по openapi.json
+func TestMerchContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	changedAt := time.Now().Add(-time.Hour)
+	history := &stubMerchService{prices: []storage.MerchPrice{
+		{ItemID: 3, Price: 25, EffectiveFrom: changedAt},
+		{ItemID: 3, Price: 20, EffectiveFrom: changedAt.AddDate(0, -1, 0), EffectiveTo: &changedAt},
+	}}
+	report := &stubMerchService{report: []storage.SalesReportLine{
+		{Item: "cup", UnitPrice: 20, Orders: 2, Quantity: 3, Gross: 60, Discount: 5, Total: 55},
+		{Item: "cup", UnitPrice: 25, Orders: 1, Quantity: 1, Gross: 25, Total: 25},
+	}}
+
+	hoody := &stubMerchService{merch: &storage.MerchItem{Name: "hoody", Price: 300, Variants: []storage.MerchVariant{
+		{SKU: "HOODY-M", Size: "M", Stock: 5},
+		{SKU: "HOODY-XL-RED", Size: "XL", Color: "red", Price: 350},
+	}}}
+
+	cases := []struct {
+		name   string
+		srv    *stubMerchService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "merch ok", srv: hoody, method: http.MethodGet, path: "/api/merch/hoody", status: http.StatusOK},
+		{name: "merch unknown", srv: &stubMerchService{err: service.ErrMerchNotFound}, method: http.MethodGet,
+			path: "/api/merch/unknown", status: http.StatusBadRequest},
+		{name: "create variant ok", srv: hoody, method: http.MethodPost, path: "/api/admin/merch/hoody/variants",
+			body: `{"sku":"HOODY-L","size":"L","stock":3}`, status: http.StatusCreated},
+		{name: "create variant without sku", srv: hoody, method: http.MethodPost, path: "/api/admin/merch/hoody/variants",
+			body: `{"size":"L"}`, status: http.StatusBadRequest},
+		{name: "create variant taken", srv: &stubMerchService{err: service.ErrVariantExists}, method: http.MethodPost,
+			path: "/api/admin/merch/hoody/variants", body: `{"sku":"HOODY-M","size":"M"}`, status: http.StatusConflict},
+		{name: "update variant ok", srv: hoody, method: http.MethodPut, path: "/api/admin/merch/hoody/variants/HOODY-M",
+			body: `{"price":0,"stock":10}`, status: http.StatusOK},
+		{name: "update variant without stock", srv: hoody, method: http.MethodPut,
+			path: "/api/admin/merch/hoody/variants/HOODY-M", body: `{"price":0}`, status: http.StatusBadRequest},
+		{name: "update unknown variant", srv: &stubMerchService{err: service.ErrVariantNotFound}, method: http.MethodPut,
+			path: "/api/admin/merch/hoody/variants/NOPE", body: `{"stock":1}`, status: http.StatusBadRequest},
+		{name: "set price ok", srv: &stubMerchService{}, method: http.MethodPut, path: "/api/admin/merch/cup/price",
+			body: `{"price":25}`, status: http.StatusOK},
+		{name: "set price validation", srv: &stubMerchService{}, method: http.MethodPut, path: "/api/admin/merch/cup/price",
+			body: `{"price":0}`, status: http.StatusBadRequest},
+		{name: "set price unknown merch", srv: &stubMerchService{err: service.ErrMerchNotFound}, method: http.MethodPut,
+			path: "/api/admin/merch/unknown/price", body: `{"price":25}`, status: http.StatusBadRequest},
+		{name: "history ok", srv: history, method: http.MethodGet, path: "/api/admin/merch/cup/prices", status: http.StatusOK},
+		{name: "report ok", srv: report, method: http.MethodGet,
+			path: "/api/admin/reports/sales?from=2025-03-01T00:00:00Z&to=2025-04-01T00:00:00Z", status: http.StatusOK},
+		{name: "report default period", srv: &stubMerchService{}, method: http.MethodGet, path: "/api/admin/reports/sales",
+			status: http.StatusOK},
+		{name: "report bad time", srv: report, method: http.MethodGet, path: "/api/admin/reports/sales?from=yesterday",
+			status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewMerchHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, admin gin.IRoutes) {
+				api.GET("/merch/:name", h.GetMerch)
+				admin.PUT("/merch/:name/price", h.SetPrice)
+				admin.POST("/merch/:name/variants", h.CreateVariant)
+				admin.PUT("/merch/:name/variants/:sku", h.UpdateVariant)
+				admin.GET("/merch/:name/prices", h.PriceHistory)
+				admin.GET("/reports/sales", h.SalesReport)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
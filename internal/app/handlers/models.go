@@ -1,7 +1,17 @@
 package handlers
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid" //nolint:gci
+)
+
 type Inventory struct {
 	Type     string `json:"type" `
+	SKU      string `json:"sku,omitempty"`
+	Size     string `json:"size,omitempty"`
+	Color    string `json:"color,omitempty"`
 	Quantity int    `json:"quantity"`
 }
 
@@ -22,6 +32,198 @@ type CoinHistory struct {
 
 type FullInfo struct {
 	Coins       int         `json:"coins" `
+	Budget      *Budget     `json:"budget,omitempty"`
 	Inventory   []Inventory `json:"inventory"`
 	CoinHistory CoinHistory `json:"coinHistory"`
+	Wishlist    []Wish      `json:"wishlist"`
+}
+
+// Budget бюджет на благодарности коллегам, не отдается сотрудникам без бюджета
+type Budget struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
+
+// Wish товар из вишлиста. Saved и Progress отдаются только владельцу вишлиста
+type Wish struct {
+	ID          int64     `json:"id"`
+	Item        string    `json:"item"`
+	Price       int       `json:"price"`
+	Goal        int       `json:"goal"`
+	Contributed int       `json:"contributed"`
+	Saved       *int      `json:"saved,omitempty"`
+	Progress    *int      `json:"progress,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type Webhook struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	// Secret заполняется только в ответе на создание
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhookId"`
+	EventID        uuid.UUID       `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	LastError      string          `json:"lastError"`
+	LastStatusCode int             `json:"lastStatusCode"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+}
+
+type Order struct {
+	ID              int64     `json:"id"`
+	Item            string    `json:"item"`
+	SKU             string    `json:"sku,omitempty"`
+	Size            string    `json:"size,omitempty"`
+	Color           string    `json:"color,omitempty"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       int       `json:"unitPrice"`
+	Total           int       `json:"total"`
+	Discount        int       `json:"discount"`
+	PromoCode       string    `json:"promoCode,omitempty"`
+	Status          string    `json:"status"`
+	ShippingAddress string    `json:"shippingAddress"`
+	Gift            *Gift     `json:"gift,omitempty"`
+	Team            string    `json:"team,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// Gift у заказа-подарка: кто подарил, кому и с каким сообщением
+type Gift struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message,omitempty"`
+}
+
+// LedgerEntry движение товара в инвентаре: quantity отрицательное при списании
+type LedgerEntry struct {
+	ID           int64     `json:"id"`
+	Kind         string    `json:"kind"`
+	Item         string    `json:"item"`
+	SKU          string    `json:"sku,omitempty"`
+	Size         string    `json:"size,omitempty"`
+	Color        string    `json:"color,omitempty"`
+	Quantity     int       `json:"quantity"`
+	OrderID      int64     `json:"orderId,omitempty"`
+	Counterparty string    `json:"counterparty,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Team командный кошелек с участниками, менеджеры идут первыми
+type Team struct {
+	ID        int64        `json:"id"`
+	Name      string       `json:"name"`
+	Balance   int          `json:"balance"`
+	Members   []TeamMember `json:"members"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type TeamMember struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// TeamEntry движение монет командного кошелька: amount отрицательное при списании
+type TeamEntry struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Amount    int       `json:"amount"`
+	Employee  string    `json:"employee,omitempty"`
+	OrderID   int64     `json:"orderId,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AdminOrder заказ в очереди администратора, с владельцем
+type AdminOrder struct {
+	Order
+	UserID uuid.UUID `json:"userId"`
+}
+
+type Promotion struct {
+	ID             int64     `json:"id"`
+	Code           string    `json:"code,omitempty"`
+	Kind           string    `json:"kind"`
+	Value          int       `json:"value"`
+	Item           string    `json:"item,omitempty"`
+	StartsAt       time.Time `json:"startsAt"`
+	EndsAt         time.Time `json:"endsAt"`
+	MaxRedemptions int       `json:"maxRedemptions"`
+	PerUserLimit   int       `json:"perUserLimit"`
+	Redemptions    int       `json:"redemptions"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// MerchItem товар каталога, у товара с вариантами покупается вариант
+type MerchItem struct {
+	Name     string         `json:"name"`
+	Price    int            `json:"price"`
+	Variants []MerchVariant `json:"variants"`
+}
+
+// MerchVariant Price - цена варианта с учетом цены товара
+type MerchVariant struct {
+	SKU   string `json:"sku"`
+	Size  string `json:"size,omitempty"`
+	Color string `json:"color,omitempty"`
+	Price int    `json:"price"`
+	Stock int    `json:"stock"`
+}
+
+type MerchPrice struct {
+	Price         int        `json:"price"`
+	EffectiveFrom time.Time  `json:"effectiveFrom"`
+	EffectiveTo   *time.Time `json:"effectiveTo,omitempty"`
+}
+
+type SalesReportLine struct {
+	Item      string `json:"item"`
+	UnitPrice int    `json:"unitPrice"`
+	Orders    int    `json:"orders"`
+	Quantity  int    `json:"quantity"`
+	Gross     int    `json:"gross"`
+	Discount  int    `json:"discount"`
+	Total     int    `json:"total"`
+}
+
+// SalesReport продажи за период [From, To) с итогами по всем строкам
+type SalesReport struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Lines    []SalesReportLine `json:"lines"`
+	Gross    int               `json:"gross"`
+	Discount int               `json:"discount"`
+	Total    int               `json:"total"`
+}
+
+// Account учетная запись, созданная администратором
+type Account struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+}
+
+// Tenant компания и ее настройки, admins - администраторы компании. Без adminSignupBonus
+// администраторы получают signupBonus, бонус выдается через bonusVestingDays дней.
+type Tenant struct {
+	ID               int64     `json:"id"`
+	Slug             string    `json:"slug"`
+	Name             string    `json:"name"`
+	SignupBonus      int       `json:"signupBonus"`
+	AdminSignupBonus *int      `json:"adminSignupBonus,omitempty"`
+	BonusVestingDays int       `json:"bonusVestingDays"`
+	BudgetPeriod     string    `json:"budgetPeriod"`
+	Admins           []string  `json:"admins"`
+	CreatedAt        time.Time `json:"createdAt"`
 }
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type OrderServiceInterface interface {
+	Orders(ctx context.Context, userID uuid.UUID) ([]storage.Order, error)
+	SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string) error
+	CancelOrder(ctx context.Context, userID uuid.UUID, orderID int64) (*storage.Order, error)
+	ListOrders(ctx context.Context, status string) ([]storage.Order, error)
+	AdvanceOrder(ctx context.Context, orderID int64, status string) (*storage.Order, error)
+}
+
+// OrderHandlers ручки заказов: история и отмена для пользователя,
+// очередь и смена статуса для администратора
+type OrderHandlers struct {
+	Service OrderServiceInterface
+}
+
+func NewOrderHandlers(srv OrderServiceInterface) *OrderHandlers {
+	return &OrderHandlers{Service: srv}
+}
+
+type ShippingAddressRequest struct {
+	Address string `json:"address" binding:"required,max=500"`
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+func (h *OrderHandlers) ListUserOrders(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	orders, err := h.Service.Orders(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []Order{}
+	for _, o := range orders {
+		response = append(response, newOrder(o))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *OrderHandlers) SetShippingAddress(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	orderID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req ShippingAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	err = h.Service.SetShippingAddress(c.Request.Context(), userID.(uuid.UUID), orderID, req.Address)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *OrderHandlers) CancelOrder(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	orderID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	order, err := h.Service.CancelOrder(c.Request.Context(), userID.(uuid.UUID), orderID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newOrder(*order))
+}
+
+func (h *OrderHandlers) ListOrders(c *gin.Context) {
+	orders, err := h.Service.ListOrders(c.Request.Context(), c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []AdminOrder{}
+	for _, o := range orders {
+		response = append(response, AdminOrder{Order: newOrder(o), UserID: o.UserID})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *OrderHandlers) UpdateOrderStatus(c *gin.Context) {
+	orderID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req OrderStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	order, err := h.Service.AdvanceOrder(c.Request.Context(), orderID, req.Status)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, AdminOrder{Order: newOrder(*order), UserID: order.UserID})
+}
+
+func newOrder(o storage.Order) Order {
+	order := Order{
+		ID:              o.ID,
+		Item:            o.Item,
+		SKU:             o.SKU,
+		Size:            o.Size,
+		Color:           o.Color,
+		Quantity:        o.Quantity,
+		UnitPrice:       o.UnitPrice,
+		Total:           o.Total,
+		Discount:        o.Discount,
+		PromoCode:       o.PromoCode,
+		Status:          o.Status,
+		ShippingAddress: o.ShippingAddress,
+		Team:            o.Team,
+		CreatedAt:       o.CreatedAt,
+		UpdatedAt:       o.UpdatedAt,
+	}
+	if o.Gift != nil {
+		order.Gift = &Gift{From: o.Gift.From, To: o.Gift.To, Message: o.Gift.Message}
+	}
+	return order
+}
+
+func int64Param(c *gin.Context, name string) (int64, error) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		return 0, apperr.ErrValidation.WithDetail(name + " must be a positive integer")
+	}
+	return id, nil
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubOrderService struct {
+	orders []storage.Order
+	err    error
+}
+
+func (s *stubOrderService) Orders(_ context.Context, _ uuid.UUID) ([]storage.Order, error) {
+	return s.orders, s.err
+}
+
+func (s *stubOrderService) SetShippingAddress(_ context.Context, _ uuid.UUID, _ int64, _ string) error {
+	return s.err
+}
+
+func (s *stubOrderService) CancelOrder(_ context.Context, userID uuid.UUID, orderID int64) (*storage.Order, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Order{ID: orderID, UserID: userID, Item: "cup", Quantity: 1, Total: 20,
+		Status: storage.OrderCancelled, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+func (s *stubOrderService) ListOrders(_ context.Context, _ string) ([]storage.Order, error) {
+	return s.orders, s.err
+}
+
+func (s *stubOrderService) AdvanceOrder(_ context.Context, orderID int64, status string) (*storage.Order, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Order{ID: orderID, UserID: uuid.New(), Item: "cup", Quantity: 1, Total: 20,
+		Status: status, ShippingAddress: "Москва", CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+// TestOrderContract проверяет ручки заказов по openapi.json
+func TestOrderContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	listed := &stubOrderService{orders: []storage.Order{
+		{ID: 2, UserID: uuid.New(), Item: "book", Quantity: 1, Total: 50, Status: storage.OrderShipped,
+			ShippingAddress: "Москва", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: 1, UserID: uuid.New(), Item: "cup", Quantity: 2, Total: 40, Status: storage.OrderPlaced,
+			CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}}
+
+	cases := []struct {
+		name   string
+		srv    *stubOrderService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "list ok", srv: listed, method: http.MethodGet, path: "/api/orders", status: http.StatusOK},
+		{name: "list empty", srv: &stubOrderService{}, method: http.MethodGet, path: "/api/orders", status: http.StatusOK},
+		{name: "address ok", srv: &stubOrderService{}, method: http.MethodPut, path: "/api/orders/1/address",
+			body: `{"address":"Москва, Лесная 7"}`, status: http.StatusNoContent},
+		{name: "address empty", srv: &stubOrderService{}, method: http.MethodPut, path: "/api/orders/1/address",
+			body: `{"address":""}`, status: http.StatusBadRequest},
+		{name: "address conflict", srv: &stubOrderService{err: service.ErrOrderStatus}, method: http.MethodPut,
+			path: "/api/orders/1/address", body: `{"address":"Москва"}`, status: http.StatusConflict},
+		{name: "cancel ok", srv: &stubOrderService{}, method: http.MethodPost, path: "/api/orders/1/cancel", status: http.StatusOK},
+		{name: "cancel bad id", srv: &stubOrderService{}, method: http.MethodPost, path: "/api/orders/abc/cancel", status: http.StatusBadRequest},
+		{name: "cancel not found", srv: &stubOrderService{err: service.ErrOrderNotFound}, method: http.MethodPost,
+			path: "/api/orders/1/cancel", status: http.StatusNotFound},
+		{name: "admin list ok", srv: listed, method: http.MethodGet, path: "/api/admin/orders?status=placed", status: http.StatusOK},
+		{name: "admin status ok", srv: &stubOrderService{}, method: http.MethodPost, path: "/api/admin/orders/1/status",
+			body: `{"status":"shipped"}`, status: http.StatusOK},
+		{name: "admin status conflict", srv: &stubOrderService{err: service.ErrOrderStatus}, method: http.MethodPost,
+			path: "/api/admin/orders/1/status", body: `{"status":"delivered"}`, status: http.StatusConflict},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewOrderHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, admin gin.IRoutes) {
+				api.GET("/orders", h.ListUserOrders)
+				api.PUT("/orders/:id/address", h.SetShippingAddress)
+				api.POST("/orders/:id/cancel", h.CancelOrder)
+				admin.GET("/orders", h.ListOrders)
+				admin.POST("/orders/:id/status", h.UpdateOrderStatus)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/gin-gonic/gin"
+)
+
+type PromotionServiceInterface interface {
+	CreatePromotion(ctx context.Context, promotion storage.Promotion) (*storage.Promotion, error)
+	ListPromotions(ctx context.Context) ([]storage.Promotion, error)
+	EndPromotion(ctx context.Context, promotionID int64) error
+}
+
+// PromotionHandlers админские ручки распродаж и промокодов
+type PromotionHandlers struct {
+	Service PromotionServiceInterface
+}
+
+func NewPromotionHandlers(srv PromotionServiceInterface) *PromotionHandlers {
+	return &PromotionHandlers{Service: srv}
+}
+
+type CreatePromotionRequest struct {
+	Code           string    `json:"code" binding:"max=32"`
+	Kind           string    `json:"kind" binding:"required,oneof=percent fixed"`
+	Value          int       `json:"value" binding:"required,min=1"`
+	Item           string    `json:"item"`
+	StartsAt       time.Time `json:"startsAt" binding:"required"`
+	EndsAt         time.Time `json:"endsAt" binding:"required"`
+	MaxRedemptions int       `json:"maxRedemptions" binding:"min=0"`
+	PerUserLimit   int       `json:"perUserLimit" binding:"min=0"`
+}
+
+func (h *PromotionHandlers) CreatePromotion(c *gin.Context) {
+	var req CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	promotion, err := h.Service.CreatePromotion(c.Request.Context(), storage.Promotion{
+		Code:           req.Code,
+		Kind:           req.Kind,
+		Value:          req.Value,
+		Item:           req.Item,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		MaxRedemptions: req.MaxRedemptions,
+		PerUserLimit:   req.PerUserLimit,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, newPromotion(*promotion))
+}
+
+func (h *PromotionHandlers) ListPromotions(c *gin.Context) {
+	promotions, err := h.Service.ListPromotions(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []Promotion{}
+	for _, p := range promotions {
+		response = append(response, newPromotion(p))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EndPromotion досрочно завершает скидку, погашенные покупки не меняются
+func (h *PromotionHandlers) EndPromotion(c *gin.Context) {
+	promotionID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.Service.EndPromotion(c.Request.Context(), promotionID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func newPromotion(p storage.Promotion) Promotion {
+	return Promotion{
+		ID:             p.ID,
+		Code:           p.Code,
+		Kind:           p.Kind,
+		Value:          p.Value,
+		Item:           p.Item,
+		StartsAt:       p.StartsAt,
+		EndsAt:         p.EndsAt,
+		MaxRedemptions: p.MaxRedemptions,
+		PerUserLimit:   p.PerUserLimit,
+		Redemptions:    p.Redemptions,
+		CreatedAt:      p.CreatedAt,
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubPromotionService struct {
+	promotions []storage.Promotion
+	err        error
+}
+
+func (s *stubPromotionService) CreatePromotion(_ context.Context, promotion storage.Promotion) (*storage.Promotion, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	promotion.ID = 1
+	promotion.CreatedAt = time.Now()
+	return &promotion, nil
+}
+
+func (s *stubPromotionService) ListPromotions(_ context.Context) ([]storage.Promotion, error) {
+	return s.promotions, s.err
+}
+
+func (s *stubPromotionService) EndPromotion(_ context.Context, _ int64) error {
+	return s.err
+}
+
+// TestPromotionContract проверяет ручки скидок по openapi.json
+func TestPromotionContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	listed := &stubPromotionService{promotions: []storage.Promotion{
+		{ID: 2, Code: "BLACKFRIDAY", Kind: storage.DiscountFixed, Value: 50, StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour),
+			MaxRedemptions: 100, PerUserLimit: 1, Redemptions: 10, CreatedAt: time.Now()},
+		{ID: 1, Kind: storage.DiscountPercent, Value: 20, Item: "hoody", StartsAt: time.Now(), EndsAt: time.Now().Add(time.Hour),
+			CreatedAt: time.Now()},
+	}}
+	body := `{"code":"blackfriday","kind":"percent","value":30,"item":"hoody",` +
+		`"startsAt":"2026-11-27T00:00:00Z","endsAt":"2026-11-30T00:00:00Z","maxRedemptions":500,"perUserLimit":1}`
+
+	cases := []struct {
+		name   string
+		srv    *stubPromotionService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "create ok", srv: &stubPromotionService{}, method: http.MethodPost, path: "/api/admin/promotions",
+			body: body, status: http.StatusCreated},
+		{name: "create validation", srv: &stubPromotionService{}, method: http.MethodPost, path: "/api/admin/promotions",
+			body: `{"kind":"gift","value":0}`, status: http.StatusBadRequest},
+		{name: "create exists", srv: &stubPromotionService{err: service.ErrPromotionExists}, method: http.MethodPost,
+			path: "/api/admin/promotions", body: body, status: http.StatusConflict},
+		{name: "list ok", srv: listed, method: http.MethodGet, path: "/api/admin/promotions", status: http.StatusOK},
+		{name: "end ok", srv: &stubPromotionService{}, method: http.MethodDelete, path: "/api/admin/promotions/2",
+			status: http.StatusNoContent},
+		{name: "end not found", srv: &stubPromotionService{err: service.ErrPromotionNotFound}, method: http.MethodDelete,
+			path: "/api/admin/promotions/2", status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewPromotionHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(_, admin gin.IRoutes) {
+				admin.POST("/promotions", h.CreatePromotion)
+				admin.GET("/promotions", h.ListPromotions)
+				admin.DELETE("/promotions/:id", h.EndPromotion)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type TeamServiceInterface interface {
+	CreateTeam(ctx context.Context, name string) (*storage.Team, error)
+	SetTeamMember(ctx context.Context, teamID int64, username string, role string) (*storage.Team, error)
+	RemoveTeamMember(ctx context.Context, teamID int64, username string) error
+	DepositTeamCoins(ctx context.Context, adminID uuid.UUID, teamID int64, amount int) (*storage.Team, error)
+	Teams(ctx context.Context, userID uuid.UUID) ([]storage.Team, error)
+	Team(ctx context.Context, userID uuid.UUID, teamID int64) (*storage.Team, error)
+	TeamHistory(ctx context.Context, userID uuid.UUID, teamID int64) ([]storage.TeamEntry, error)
+	DistributeTeamCoins(ctx context.Context, userID uuid.UUID, teamID int64, toUsername string, amount int) error
+	BuyForTeam(ctx context.Context, userID uuid.UUID, teamID int64, merchName string, choice service.VariantChoice,
+		quantity int, promoCode string) error
+}
+
+// TeamHandlers ручки командных кошельков: админ создает команды и пополняет их,
+// менеджеры распределяют монеты участникам и покупают товар на команду
+type TeamHandlers struct {
+	Service TeamServiceInterface
+}
+
+func NewTeamHandlers(srv TeamServiceInterface) *TeamHandlers {
+	return &TeamHandlers{Service: srv}
+}
+
+type CreateTeamRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// TeamMemberRequest без role сотрудник становится обычным участником
+type TeamMemberRequest struct {
+	Role string `json:"role" binding:"omitempty,oneof=member manager"`
+}
+
+type TeamDepositRequest struct {
+	Amount int `json:"amount" binding:"required,min=1"`
+}
+
+type DistributeRequest struct {
+	ToUser string `json:"toUser" binding:"required,alphanum"`
+	Amount int    `json:"amount" binding:"required,min=1"`
+}
+
+// TeamBuyRequest вариант выбирается как при покупке, без quantity покупается одна штука
+type TeamBuyRequest struct {
+	SKU       string `json:"sku"`
+	Size      string `json:"size"`
+	Color     string `json:"color"`
+	Quantity  int    `json:"quantity" binding:"min=0"`
+	PromoCode string `json:"promoCode" binding:"max=32"`
+}
+
+func (h *TeamHandlers) CreateTeam(c *gin.Context) {
+	var req CreateTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	team, err := h.Service.CreateTeam(c.Request.Context(), req.Name)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, newTeam(*team))
+}
+
+// SetMember добавляет сотрудника в команду или меняет его роль
+func (h *TeamHandlers) SetMember(c *gin.Context) {
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req TeamMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	if req.Role == "" {
+		req.Role = storage.TeamRoleMember
+	}
+
+	team, err := h.Service.SetTeamMember(c.Request.Context(), teamID, c.Param("username"), req.Role)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newTeam(*team))
+}
+
+func (h *TeamHandlers) RemoveMember(c *gin.Context) {
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.Service.RemoveTeamMember(c.Request.Context(), teamID, c.Param("username")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Deposit пополнение кошелька команды с баланса администратора
+func (h *TeamHandlers) Deposit(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req TeamDepositRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	team, err := h.Service.DepositTeamCoins(c.Request.Context(), userID.(uuid.UUID), teamID, req.Amount)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newTeam(*team))
+}
+
+// ListTeams команды текущего пользователя
+func (h *TeamHandlers) ListTeams(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	teams, err := h.Service.Teams(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var response = []Team{}
+	for _, t := range teams {
+		response = append(response, newTeam(t))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *TeamHandlers) GetTeam(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	team, err := h.Service.Team(c.Request.Context(), userID.(uuid.UUID), teamID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newTeam(*team))
+}
+
+// History история кошелька команды, новые записи первыми
+func (h *TeamHandlers) History(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	entries, err := h.Service.TeamHistory(c.Request.Context(), userID.(uuid.UUID), teamID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var response = []TeamEntry{}
+	for _, e := range entries {
+		response = append(response, TeamEntry{
+			ID:        e.ID,
+			Kind:      e.Kind,
+			Amount:    e.Amount,
+			Employee:  e.Employee,
+			OrderID:   e.OrderID,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Distribute перевод монет из пула команды участнику, доступен менеджерам
+func (h *TeamHandlers) Distribute(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req DistributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	err = h.Service.DistributeTeamCoins(c.Request.Context(), userID.(uuid.UUID), teamID, req.ToUser, req.Amount)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// Buy покупка товара на команду, доступна менеджерам
+func (h *TeamHandlers) Buy(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	teamID, err := int64Param(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	var req TeamBuyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	choice := service.VariantChoice{SKU: req.SKU, Size: req.Size, Color: req.Color}
+	err = h.Service.BuyForTeam(c.Request.Context(), userID.(uuid.UUID), teamID, c.Param("merchName"), choice,
+		req.Quantity, req.PromoCode)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func newTeam(t storage.Team) Team {
+	team := Team{
+		ID:        t.ID,
+		Name:      t.Name,
+		Balance:   t.Balance,
+		Members:   []TeamMember{},
+		CreatedAt: t.CreatedAt,
+	}
+	for _, m := range t.Members {
+		team.Members = append(team.Members, TeamMember{Username: m.Username, Role: m.Role})
+	}
+	return team
+}
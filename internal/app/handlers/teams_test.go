@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubTeamService struct {
+	err error
+}
+
+func (s *stubTeamService) team() *storage.Team {
+	return &storage.Team{ID: 3, Name: "backend", Balance: 500, CreatedAt: time.Now(), Members: []storage.TeamMember{
+		{EmployeeID: uuid.New(), Username: "alice", Role: storage.TeamRoleManager},
+		{EmployeeID: uuid.New(), Username: "bob", Role: storage.TeamRoleMember},
+	}}
+}
+
+func (s *stubTeamService) CreateTeam(_ context.Context, name string) (*storage.Team, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Team{ID: 3, Name: name, CreatedAt: time.Now()}, nil
+}
+
+func (s *stubTeamService) SetTeamMember(_ context.Context, _ int64, _ string, _ string) (*storage.Team, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.team(), nil
+}
+
+func (s *stubTeamService) RemoveTeamMember(_ context.Context, _ int64, _ string) error {
+	return s.err
+}
+
+func (s *stubTeamService) DepositTeamCoins(_ context.Context, _ uuid.UUID, _ int64, _ int) (*storage.Team, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.team(), nil
+}
+
+func (s *stubTeamService) Teams(_ context.Context, _ uuid.UUID) ([]storage.Team, error) {
+	return []storage.Team{*s.team()}, s.err
+}
+
+func (s *stubTeamService) Team(_ context.Context, _ uuid.UUID, _ int64) (*storage.Team, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.team(), nil
+}
+
+func (s *stubTeamService) TeamHistory(_ context.Context, _ uuid.UUID, _ int64) ([]storage.TeamEntry, error) {
+	return []storage.TeamEntry{
+		{ID: 3, Kind: storage.TeamPurchase, Amount: -40, Employee: "alice", OrderID: 7, CreatedAt: time.Now()},
+		{ID: 2, Kind: storage.TeamDistribution, Amount: -50, Employee: "bob", CreatedAt: time.Now()},
+		{ID: 1, Kind: storage.TeamDeposit, Amount: 500, Employee: "admin", CreatedAt: time.Now()},
+	}, s.err
+}
+
+func (s *stubTeamService) DistributeTeamCoins(_ context.Context, _ uuid.UUID, _ int64, _ string, _ int) error {
+	return s.err
+}
+
+func (s *stubTeamService) BuyForTeam(_ context.Context, _ uuid.UUID, _ int64, _ string, _ service.VariantChoice,
+	_ int, _ string) error {
+	return s.err
+}
+
+// TestTeamContract проверяет ручки командных кошельков по openapi.json
+func TestTeamContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	cases := []struct {
+		name   string
+		srv    *stubTeamService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "list ok", srv: &stubTeamService{}, method: http.MethodGet, path: "/api/teams", status: http.StatusOK},
+		{name: "get ok", srv: &stubTeamService{}, method: http.MethodGet, path: "/api/teams/3", status: http.StatusOK},
+		{name: "get foreign", srv: &stubTeamService{err: service.ErrTeamNotFound}, method: http.MethodGet,
+			path: "/api/teams/4", status: http.StatusNotFound},
+		{name: "get bad id", srv: &stubTeamService{}, method: http.MethodGet, path: "/api/teams/abc",
+			status: http.StatusBadRequest},
+		{name: "history ok", srv: &stubTeamService{}, method: http.MethodGet, path: "/api/teams/3/history",
+			status: http.StatusOK},
+		{name: "distribute ok", srv: &stubTeamService{}, method: http.MethodPost, path: "/api/teams/3/distribute",
+			body: `{"toUser":"bob","amount":50}`, status: http.StatusOK},
+		{name: "distribute not member", srv: &stubTeamService{err: service.ErrNotTeamMember}, method: http.MethodPost,
+			path: "/api/teams/3/distribute", body: `{"toUser":"carol","amount":50}`, status: http.StatusBadRequest},
+		{name: "distribute not manager", srv: &stubTeamService{err: apperr.ErrForbidden}, method: http.MethodPost,
+			path: "/api/teams/3/distribute", body: `{"toUser":"bob","amount":50}`, status: http.StatusForbidden},
+		{name: "distribute not enough coins", srv: &stubTeamService{err: service.ErrNotEnoughCoins},
+			method: http.MethodPost, path: "/api/teams/3/distribute", body: `{"toUser":"bob","amount":5000}`,
+			status: http.StatusBadRequest},
+		{name: "buy ok", srv: &stubTeamService{}, method: http.MethodPost, path: "/api/teams/3/buy/cup",
+			body: `{"quantity":3}`, status: http.StatusOK},
+		{name: "buy negative quantity", srv: &stubTeamService{}, method: http.MethodPost, path: "/api/teams/3/buy/cup",
+			body: `{"quantity":-1}`, status: http.StatusBadRequest},
+		{name: "create ok", srv: &stubTeamService{}, method: http.MethodPost, path: "/api/admin/teams",
+			body: `{"name":"backend"}`, status: http.StatusCreated},
+		{name: "create exists", srv: &stubTeamService{err: service.ErrTeamExists}, method: http.MethodPost,
+			path: "/api/admin/teams", body: `{"name":"backend"}`, status: http.StatusConflict},
+		{name: "set member ok", srv: &stubTeamService{}, method: http.MethodPut, path: "/api/admin/teams/3/members/bob",
+			body: `{"role":"manager"}`, status: http.StatusOK},
+		{name: "set member bad role", srv: &stubTeamService{}, method: http.MethodPut,
+			path: "/api/admin/teams/3/members/bob", body: `{"role":"owner"}`, status: http.StatusBadRequest},
+		{name: "remove member ok", srv: &stubTeamService{}, method: http.MethodDelete,
+			path: "/api/admin/teams/3/members/bob", status: http.StatusNoContent},
+		{name: "deposit ok", srv: &stubTeamService{}, method: http.MethodPost, path: "/api/admin/teams/3/deposit",
+			body: `{"amount":500}`, status: http.StatusOK},
+		{name: "deposit missing team", srv: &stubTeamService{err: service.ErrTeamNotFound}, method: http.MethodPost,
+			path: "/api/admin/teams/4/deposit", body: `{"amount":500}`, status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewTeamHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, admin gin.IRoutes) {
+				api.GET("/teams", h.ListTeams)
+				api.GET("/teams/:id", h.GetTeam)
+				api.GET("/teams/:id/history", h.History)
+				api.POST("/teams/:id/distribute", h.Distribute)
+				api.POST("/teams/:id/buy/:merchName", h.Buy)
+				admin.POST("/teams", h.CreateTeam)
+				admin.PUT("/teams/:id/members/:username", h.SetMember)
+				admin.DELETE("/teams/:id/members/:username", h.RemoveMember)
+				admin.POST("/teams/:id/deposit", h.Deposit)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/service" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+type TenantServiceInterface interface {
+	CreateTenant(ctx context.Context, tenant storage.Tenant, catalog []storage.MerchItem,
+		adminPassword string) (*storage.Tenant, error)
+	ListTenants(ctx context.Context) ([]storage.Tenant, error)
+	Tenant(ctx context.Context) (*storage.Tenant, error)
+	UpdateTenant(ctx context.Context, tenant storage.Tenant) (*storage.Tenant, error)
+	CreateUser(ctx context.Context, username, password string) (*storage.Employee, error)
+}
+
+// TenantHandlers ручки компаний: администраторы компании по умолчанию создают
+// компании, администраторы компании меняют ее настройки и создают учетные записи
+type TenantHandlers struct {
+	Service TenantServiceInterface
+}
+
+func NewTenantHandlers(srv TenantServiceInterface) *TenantHandlers {
+	return &TenantHandlers{Service: srv}
+}
+
+type CatalogItem struct {
+	Name  string `json:"name" binding:"required,max=255"`
+	Price int    `json:"price" binding:"required,min=1"`
+}
+
+// CreateTenantRequest без catalog компания получает копию каталога компании по умолчанию,
+// без signupBonus - бонус по умолчанию. Администраторам из admins создаются учетные
+// записи с паролем adminPassword.
+type CreateTenantRequest struct {
+	Slug             string        `json:"slug" binding:"required,max=32"`
+	Name             string        `json:"name" binding:"required,max=255"`
+	SignupBonus      *int          `json:"signupBonus" binding:"omitempty,min=0"`
+	AdminSignupBonus *int          `json:"adminSignupBonus" binding:"omitempty,min=0"`
+	BonusVestingDays int           `json:"bonusVestingDays" binding:"min=0,max=365"`
+	BudgetPeriod     string        `json:"budgetPeriod" binding:"omitempty,oneof=month week"`
+	Admins           []string      `json:"admins" binding:"required,min=1,dive,alphanum"`
+	AdminPassword    string        `json:"adminPassword" binding:"required,min=6"`
+	Catalog          []CatalogItem `json:"catalog" binding:"dive"`
+}
+
+// UpdateTenantRequest заменяет настройки целиком: без adminSignupBonus отдельный
+// бонус администраторов сбрасывается, сотрудники не из admins теряют роль администратора
+type UpdateTenantRequest struct {
+	Name             string   `json:"name" binding:"required,max=255"`
+	SignupBonus      *int     `json:"signupBonus" binding:"required,min=0"`
+	AdminSignupBonus *int     `json:"adminSignupBonus" binding:"omitempty,min=0"`
+	BonusVestingDays int      `json:"bonusVestingDays" binding:"min=0,max=365"`
+	BudgetPeriod     string   `json:"budgetPeriod" binding:"omitempty,oneof=month week"`
+	Admins           []string `json:"admins" binding:"dive,alphanum"`
+}
+
+func (h *TenantHandlers) CreateTenant(c *gin.Context) {
+	var req CreateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	settings := storage.Tenant{
+		Slug:             req.Slug,
+		Name:             req.Name,
+		SignupBonus:      service.DefaultSignupBonus,
+		AdminSignupBonus: req.AdminSignupBonus,
+		BonusVestingDays: req.BonusVestingDays,
+		BudgetPeriod:     req.BudgetPeriod,
+		Admins:           req.Admins,
+	}
+	if req.SignupBonus != nil {
+		settings.SignupBonus = *req.SignupBonus
+	}
+	var catalog []storage.MerchItem
+	for _, item := range req.Catalog {
+		catalog = append(catalog, storage.MerchItem{Name: item.Name, Price: item.Price})
+	}
+
+	tenant, err := h.Service.CreateTenant(c.Request.Context(), settings, catalog, req.AdminPassword)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, newTenant(*tenant))
+}
+
+func (h *TenantHandlers) ListTenants(c *gin.Context) {
+	tenants, err := h.Service.ListTenants(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response := make([]Tenant, 0, len(tenants))
+	for _, tenant := range tenants {
+		response = append(response, newTenant(tenant))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetTenant настройки компании администратора
+func (h *TenantHandlers) GetTenant(c *gin.Context) {
+	tenant, err := h.Service.Tenant(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newTenant(*tenant))
+}
+
+func (h *TenantHandlers) UpdateTenant(c *gin.Context) {
+	var req UpdateTenantRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	tenant, err := h.Service.UpdateTenant(c.Request.Context(), storage.Tenant{
+		Name:             req.Name,
+		SignupBonus:      *req.SignupBonus,
+		AdminSignupBonus: req.AdminSignupBonus,
+		BonusVestingDays: req.BonusVestingDays,
+		BudgetPeriod:     req.BudgetPeriod,
+		Admins:           req.Admins,
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newTenant(*tenant))
+}
+
+// CreateUserRequest учетная запись в компании администратора, имена администраторов
+// компании можно занять только так
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+func (h *TenantHandlers) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	user, err := h.Service.CreateUser(c.Request.Context(), req.Username, req.Password)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, Account{ID: user.EmployeeId, Username: user.Name, Role: user.Role})
+}
+
+func newTenant(t storage.Tenant) Tenant {
+	admins := t.Admins
+	if admins == nil {
+		admins = []string{}
+	}
+	return Tenant{
+		ID:               t.ID,
+		Slug:             t.Slug,
+		Name:             t.Name,
+		SignupBonus:      t.SignupBonus,
+		AdminSignupBonus: t.AdminSignupBonus,
+		BonusVestingDays: t.BonusVestingDays,
+		BudgetPeriod:     t.BudgetPeriod,
+		Admins:           admins,
+		CreatedAt:        t.CreatedAt,
+	}
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/app/mw"
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/memory"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type stubTenantService struct {
+	err error
+}
+
+func (s *stubTenantService) tenant() *storage.Tenant {
+	return &storage.Tenant{ID: 2, Slug: "acme", Name: "Acme", SignupBonus: 500, CreatedAt: time.Now()}
+}
+
+func (s *stubTenantService) CreateTenant(_ context.Context, tenant storage.Tenant, _ []storage.MerchItem, _ string) (
+	*storage.Tenant, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	tenant.ID = 2
+	tenant.CreatedAt = time.Now()
+	return &tenant, nil
+}
+
+func (s *stubTenantService) ListTenants(_ context.Context) ([]storage.Tenant, error) {
+	return []storage.Tenant{*s.tenant()}, s.err
+}
+
+func (s *stubTenantService) Tenant(_ context.Context) (*storage.Tenant, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.tenant(), nil
+}
+
+func (s *stubTenantService) UpdateTenant(_ context.Context, tenant storage.Tenant) (*storage.Tenant, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	updated := s.tenant()
+	updated.Name = tenant.Name
+	updated.Admins = tenant.Admins
+	return updated, nil
+}
+
+func (s *stubTenantService) CreateUser(_ context.Context, username, _ string) (*storage.Employee, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Employee{EmployeeId: uuid.New(), Name: username, Role: storage.RoleEmployee}, nil
+}
+
+// tenantRoutes админские ручки компаний
+func tenantRoutes(srv TenantServiceInterface) func(_, admin gin.IRoutes) {
+	h := NewTenantHandlers(srv)
+	return func(_, admin gin.IRoutes) {
+		admin.POST("/tenants", h.CreateTenant)
+		admin.GET("/tenants", h.ListTenants)
+		admin.GET("/tenant", h.GetTenant)
+		admin.PUT("/tenant", h.UpdateTenant)
+		admin.POST("/users", h.CreateUser)
+	}
+}
+
+// TestTenantContract проверяет ручки компаний по openapi.json. Пользователь stubUserStorage
+// администратор компании 2, но не компании по умолчанию.
+func TestTenantContract(t *testing.T) {
+	tenantToken := newTestToken(t, utils.User{UserID: uuid.New(), TenantID: 2})
+	defaultToken := newTestToken(t, utils.User{UserID: uuid.New()})
+	createBody := `{"slug":"acme","name":"Acme","admins":["boss"],"adminPassword":"password"}`
+
+	cases := []struct {
+		name   string
+		srv    *stubTenantService
+		users  mw.UserStorage
+		token  string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "get ok", srv: &stubTenantService{}, token: tenantToken, method: http.MethodGet, path: "/api/admin/tenant",
+			status: http.StatusOK},
+		{name: "get without admin role", srv: &stubTenantService{}, users: employeeUserStorage{}, token: tenantToken,
+			method: http.MethodGet, path: "/api/admin/tenant", status: http.StatusForbidden},
+		{name: "create user ok", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/users", body: `{"username":"boss","password":"password"}`, status: http.StatusCreated},
+		{name: "create user exists", srv: &stubTenantService{err: service.ErrUserExists}, token: tenantToken,
+			method: http.MethodPost, path: "/api/admin/users", body: `{"username":"boss","password":"password"}`,
+			status: http.StatusConflict},
+		{name: "create user short password", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/users", body: `{"username":"boss","password":"123"}`, status: http.StatusBadRequest},
+		{name: "get not admin", srv: &stubTenantService{}, token: defaultToken, method: http.MethodGet,
+			path: "/api/admin/tenant", status: http.StatusForbidden},
+		// администраторы из настроек компании по умолчанию добавляются к ADMIN_USERNAMES
+		{name: "get default tenant admin", srv: &stubTenantService{}, users: tenantAdminsStorage{}, token: defaultToken,
+			method: http.MethodGet, path: "/api/admin/tenant", status: http.StatusOK},
+		{name: "update ok", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPut,
+			path: "/api/admin/tenant", body: `{"name":"Acme","signupBonus":0,"budgetPeriod":"week","admins":["admin"]}`,
+			status: http.StatusOK},
+		{name: "update missing bonus", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPut,
+			path: "/api/admin/tenant", body: `{"name":"Acme"}`, status: http.StatusBadRequest},
+		{name: "update bad period", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPut,
+			path: "/api/admin/tenant", body: `{"name":"Acme","signupBonus":10,"budgetPeriod":"year"}`,
+			status: http.StatusBadRequest},
+		{name: "update vesting too long", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPut,
+			path: "/api/admin/tenant", body: `{"name":"Acme","signupBonus":10,"bonusVestingDays":400}`,
+			status: http.StatusBadRequest},
+		{name: "update negative admin bonus", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPut,
+			path: "/api/admin/tenant", body: `{"name":"Acme","signupBonus":10,"adminSignupBonus":-1}`,
+			status: http.StatusBadRequest},
+		{name: "create ok", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/tenants", body: `{"slug":"acme","name":"Acme","admins":["boss"],"adminPassword":"password",` +
+				`"catalog":[{"name":"cup","price":20}]}`,
+			status: http.StatusCreated},
+		{name: "create forbidden", srv: &stubTenantService{err: apperr.ErrForbidden}, token: tenantToken,
+			method: http.MethodPost, path: "/api/admin/tenants", body: createBody, status: http.StatusForbidden},
+		{name: "create exists", srv: &stubTenantService{err: service.ErrTenantExists}, token: tenantToken,
+			method: http.MethodPost, path: "/api/admin/tenants", body: createBody, status: http.StatusConflict},
+		{name: "create bad catalog", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/tenants", body: `{"slug":"acme","name":"Acme","admins":["boss"],"adminPassword":"password",` +
+				`"catalog":[{"name":"cup","price":0}]}`,
+			status: http.StatusBadRequest},
+		// без администраторов в новую компанию некому войти
+		{name: "create without admins", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/tenants", body: `{"slug":"acme","name":"Acme","adminPassword":"password"}`,
+			status: http.StatusBadRequest},
+		{name: "create without admin password", srv: &stubTenantService{}, token: tenantToken, method: http.MethodPost,
+			path: "/api/admin/tenants", body: `{"slug":"acme","name":"Acme","admins":["boss"]}`,
+			status: http.StatusBadRequest},
+		{name: "list ok", srv: &stubTenantService{}, token: tenantToken, method: http.MethodGet,
+			path: "/api/admin/tenants", status: http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := tc.users
+			if users == nil {
+				users = stubUserStorage{}
+			}
+			engine := newTestEngine(users, []string{"root"}, tenantRoutes(tc.srv))
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, tc.token), tc.status)
+		})
+	}
+}
+
+// TestTenantAdminsGrantRole сотрудник, добавленный в admins, сразу проходит в /api/admin,
+// убранный из списка теряет доступ
+func TestTenantAdminsGrantRole(t *testing.T) {
+	store := memory.New()
+	srv := service.New(store, zap.NewNop())
+	srv.Admins = []string{"root"}
+	engine := newTestEngine(store, srv.Admins, tenantRoutes(srv))
+	ctx := context.Background()
+
+	tenant, err := srv.CreateTenant(ctx, storage.Tenant{Slug: "acme", Name: "Acme", Admins: []string{"boss"}}, nil,
+		"password")
+	require.NoError(t, err)
+	tenantCtx := storage.WithTenant(ctx, tenant.ID)
+	boss, err := store.GetUserAuthData(tenantCtx, "boss")
+	require.NoError(t, err)
+	alice, err := srv.CreateUser(tenantCtx, "alice", "password")
+	require.NoError(t, err)
+	bossToken := newTestToken(t, utils.User{UserID: boss.UserID, TenantID: tenant.ID})
+	aliceToken := newTestToken(t, utils.User{UserID: alice.EmployeeId, TenantID: tenant.ID})
+
+	serveContract(t, engine, newTestRequest(http.MethodGet, "/api/admin/tenant", "", aliceToken), http.StatusForbidden)
+	serveContract(t, engine, newTestRequest(http.MethodPut, "/api/admin/tenant",
+		`{"name":"Acme","signupBonus":500,"admins":["boss","alice"]}`, bossToken), http.StatusOK)
+	serveContract(t, engine, newTestRequest(http.MethodGet, "/api/admin/tenant", "", aliceToken), http.StatusOK)
+
+	serveContract(t, engine, newTestRequest(http.MethodPut, "/api/admin/tenant",
+		`{"name":"Acme","signupBonus":500,"admins":["boss"]}`, bossToken), http.StatusOK)
+	serveContract(t, engine, newTestRequest(http.MethodGet, "/api/admin/tenant", "", aliceToken), http.StatusForbidden)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WebhookServiceInterface interface {
+	Register(ctx context.Context, url string, eventTypes []string) (*storage.Webhook, error)
+	List(ctx context.Context) ([]storage.Webhook, error)
+	Delete(ctx context.Context, webhookID uuid.UUID) error
+	Deliveries(ctx context.Context, webhookID uuid.UUID, status string) ([]storage.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+// WebhookHandlers админские ручки управления webhooks
+type WebhookHandlers struct {
+	Service WebhookServiceInterface
+}
+
+func NewWebhookHandlers(srv WebhookServiceInterface) *WebhookHandlers {
+	return &WebhookHandlers{Service: srv}
+}
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"eventTypes" binding:"required,min=1"`
+}
+
+func (h *WebhookHandlers) CreateWebhook(c *gin.Context) {
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	webhook, err := h.Service.Register(c.Request.Context(), req.URL, req.EventTypes)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	// секрет показывается только при создании
+	response := newWebhook(*webhook)
+	response.Secret = webhook.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *WebhookHandlers) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.Service.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []Webhook{}
+	for _, w := range webhooks {
+		response = append(response, newWebhook(w))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *WebhookHandlers) DeleteWebhook(c *gin.Context) {
+	webhookID, err := uuidParam(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.Service.Delete(c.Request.Context(), webhookID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *WebhookHandlers) ListDeliveries(c *gin.Context) {
+	webhookID, err := uuidParam(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	deliveries, err := h.Service.Deliveries(c.Request.Context(), webhookID, c.Query("status"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	var response = []WebhookDelivery{}
+	for _, d := range deliveries {
+		response = append(response, WebhookDelivery{
+			ID:             d.ID,
+			WebhookID:      d.WebhookID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Payload:        json.RawMessage(d.Payload),
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastError:      d.LastError,
+			LastStatusCode: d.LastStatusCode,
+			CreatedAt:      d.CreatedAt,
+			DeliveredAt:    d.DeliveredAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *WebhookHandlers) Redeliver(c *gin.Context) {
+	deliveryID, err := uuidParam(c, "id")
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := h.Service.Redeliver(c.Request.Context(), deliveryID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func newWebhook(w storage.Webhook) Webhook {
+	return Webhook{
+		ID:         w.ID,
+		URL:        w.URL,
+		EventTypes: w.EventTypes,
+		CreatedAt:  w.CreatedAt,
+	}
+}
+
+func uuidParam(c *gin.Context, name string) (uuid.UUID, error) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		return uuid.Nil, apperr.ErrValidation.WithDetail(name + " must be a uuid")
+	}
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/Vic07Region/avito-shop/internal/webhook"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type stubWebhookService struct {
+	webhooks   []storage.Webhook
+	deliveries []storage.WebhookDelivery
+	err        error
+}
+
+func (s *stubWebhookService) Register(_ context.Context, url string, eventTypes []string) (*storage.Webhook, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &storage.Webhook{ID: uuid.New(), URL: url, Secret: "secret", EventTypes: eventTypes, CreatedAt: time.Now()}, nil
+}
+
+func (s *stubWebhookService) List(_ context.Context) ([]storage.Webhook, error) {
+	return s.webhooks, s.err
+}
+
+func (s *stubWebhookService) Delete(_ context.Context, _ uuid.UUID) error {
+	return s.err
+}
+
+func (s *stubWebhookService) Deliveries(_ context.Context, _ uuid.UUID, _ string) ([]storage.WebhookDelivery, error) {
+	return s.deliveries, s.err
+}
+
+func (s *stubWebhookService) Redeliver(_ context.Context, _ uuid.UUID) error {
+	return s.err
+}
+
+// webhookRoutes админские ручки webhooks
+func webhookRoutes(srv WebhookServiceInterface) func(_, admin gin.IRoutes) {
+	h := NewWebhookHandlers(srv)
+	return func(_, admin gin.IRoutes) {
+		admin.POST("/webhooks", h.CreateWebhook)
+		admin.GET("/webhooks", h.ListWebhooks)
+		admin.DELETE("/webhooks/:id", h.DeleteWebhook)
+		admin.GET("/webhooks/:id/deliveries", h.ListDeliveries)
+		admin.POST("/deliveries/:id/redeliver", h.Redeliver)
+	}
+}
+
+// TestWebhookContract проверяет админские ручки webhooks по openapi.json
+func TestWebhookContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	webhookID := uuid.New()
+	deliveredAt := time.Now()
+	listed := &stubWebhookService{
+		webhooks: []storage.Webhook{{ID: webhookID, URL: "http://localhost/hook", EventTypes: []string{"balance.low"}, CreatedAt: time.Now()}},
+		deliveries: []storage.WebhookDelivery{
+			{ID: uuid.New(), WebhookID: webhookID, EventID: uuid.New(), EventType: "balance.low", Payload: []byte(`{"type":"balance.low"}`),
+				Status: storage.DeliveryDelivered, Attempts: 1, NextAttemptAt: time.Now(), LastStatusCode: 200, CreatedAt: time.Now(), DeliveredAt: &deliveredAt},
+			{ID: uuid.New(), WebhookID: webhookID, EventID: uuid.New(), EventType: "balance.low", Payload: []byte(`{"type":"balance.low"}`),
+				Status: storage.DeliveryDead, Attempts: 8, NextAttemptAt: time.Now(), LastError: "status 500", LastStatusCode: 500, CreatedAt: time.Now()},
+		},
+	}
+	admins := []string{"Admin"}
+
+	cases := []struct {
+		name   string
+		srv    *stubWebhookService
+		admins []string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "create ok", srv: &stubWebhookService{}, admins: admins, method: http.MethodPost, path: "/api/admin/webhooks",
+			body: `{"url":"http://localhost/hook","eventTypes":["transfer.received"]}`, status: http.StatusCreated},
+		{name: "create validation", srv: &stubWebhookService{}, admins: admins, method: http.MethodPost, path: "/api/admin/webhooks",
+			body: `{"url":"not a url","eventTypes":[]}`, status: http.StatusBadRequest},
+		{name: "create forbidden", srv: &stubWebhookService{}, method: http.MethodPost, path: "/api/admin/webhooks",
+			body: `{"url":"http://localhost/hook","eventTypes":["transfer.received"]}`, status: http.StatusForbidden},
+		{name: "list ok", srv: listed, admins: admins, method: http.MethodGet, path: "/api/admin/webhooks", status: http.StatusOK},
+		{name: "list empty", srv: &stubWebhookService{}, admins: admins, method: http.MethodGet, path: "/api/admin/webhooks", status: http.StatusOK},
+		{name: "delete ok", srv: &stubWebhookService{}, admins: admins, method: http.MethodDelete,
+			path: "/api/admin/webhooks/" + webhookID.String(), status: http.StatusNoContent},
+		{name: "delete not found", srv: &stubWebhookService{err: webhook.ErrWebhookNotFound}, admins: admins, method: http.MethodDelete,
+			path: "/api/admin/webhooks/" + webhookID.String(), status: http.StatusNotFound},
+		{name: "delete bad id", srv: &stubWebhookService{}, admins: admins, method: http.MethodDelete,
+			path: "/api/admin/webhooks/123", status: http.StatusBadRequest},
+		{name: "deliveries ok", srv: listed, admins: admins, method: http.MethodGet,
+			path: "/api/admin/webhooks/" + webhookID.String() + "/deliveries?status=dead", status: http.StatusOK},
+		{name: "redeliver ok", srv: &stubWebhookService{}, admins: admins, method: http.MethodPost,
+			path: "/api/admin/deliveries/" + uuid.NewString() + "/redeliver", status: http.StatusAccepted},
+		{name: "redeliver not found", srv: &stubWebhookService{err: webhook.ErrDeliveryNotFound}, admins: admins, method: http.MethodPost,
+			path: "/api/admin/deliveries/" + uuid.NewString() + "/redeliver", status: http.StatusNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newTestEngine(stubUserStorage{}, tc.admins, webhookRoutes(tc.srv))
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
+
+func TestCreateWebhookSecret(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+	engine := newTestEngine(stubUserStorage{}, testAdmins, webhookRoutes(&stubWebhookService{
+		webhooks: []storage.Webhook{{ID: uuid.New(), URL: "http://localhost/hook", Secret: "secret", EventTypes: []string{"balance.low"}}},
+	}))
+
+	// секрет возвращается при создании
+	rec := serveContract(t, engine, newTestRequest(http.MethodPost, "/api/admin/webhooks",
+		`{"url":"http://localhost/hook","eventTypes":["balance.low"]}`, token), http.StatusCreated)
+	var created Webhook
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	require.Equal(t, "secret", created.Secret)
+
+	// и не возвращается в списке
+	rec = serveContract(t, engine, newTestRequest(http.MethodGet, "/api/admin/webhooks", "", token), http.StatusOK)
+	require.NotContains(t, rec.Body.String(), "secret")
+}
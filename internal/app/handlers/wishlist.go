@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type WishlistServiceInterface interface {
+	Wishlist(ctx context.Context, userID uuid.UUID) ([]service.WishlistGoal, error)
+	ColleagueWishlist(ctx context.Context, username string) ([]storage.WishlistItem, error)
+	AddToWishlist(ctx context.Context, userID uuid.UUID, merchName string, goal int) (*service.WishlistGoal, error)
+	RemoveFromWishlist(ctx context.Context, userID uuid.UUID, merchName string) error
+	Contribute(ctx context.Context, userID uuid.UUID, toUsername string, merchName string, amount int) error
+}
+
+// WishlistHandlers ручки вишлиста: свои цели накопления и взносы коллегам
+type WishlistHandlers struct {
+	Service WishlistServiceInterface
+}
+
+func NewWishlistHandlers(srv WishlistServiceInterface) *WishlistHandlers {
+	return &WishlistHandlers{Service: srv}
+}
+
+// WishRequest goal - цель в монетах, без нее целью становится цена товара
+type WishRequest struct {
+	Item string `json:"item" binding:"required"`
+	Goal int    `json:"goal" binding:"min=0"`
+}
+
+type ContributeRequest struct {
+	ToUser string `json:"toUser" binding:"required,alphanum"`
+	Item   string `json:"item" binding:"required"`
+	Amount int    `json:"amount" binding:"required,min=1"`
+}
+
+// GetWishlist свой вишлист с прогрессом или вишлист коллеги из ?user=
+func (h *WishlistHandlers) GetWishlist(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	var response = []Wish{}
+	if username := c.Query("user"); username != "" {
+		wishlist, err := h.Service.ColleagueWishlist(c.Request.Context(), username)
+		if err != nil {
+			_ = c.Error(err)
+			return
+		}
+		for _, w := range wishlist {
+			response = append(response, Wish{ID: w.ID, Item: w.Item, Price: w.Price, Goal: w.Goal,
+				Contributed: w.Contributed, CreatedAt: w.CreatedAt})
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	wishlist, err := h.Service.Wishlist(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	for _, w := range wishlist {
+		response = append(response, newWish(w))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *WishlistHandlers) AddWish(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	var req WishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	wish, err := h.Service.AddToWishlist(c.Request.Context(), userID.(uuid.UUID), req.Item, req.Goal)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, newWish(*wish))
+}
+
+func (h *WishlistHandlers) RemoveWish(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+
+	if err := h.Service.RemoveFromWishlist(c.Request.Context(), userID.(uuid.UUID), c.Param("item")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Contribute перевод монет коллеге на товар из его вишлиста
+func (h *WishlistHandlers) Contribute(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		_ = c.Error(apperr.ErrUnauthorized)
+		return
+	}
+	var req ContributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		_ = c.Error(bindError(err))
+		return
+	}
+
+	err := h.Service.Contribute(c.Request.Context(), userID.(uuid.UUID), req.ToUser, req.Item, req.Amount)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func newWish(w service.WishlistGoal) Wish {
+	return Wish{
+		ID:          w.ID,
+		Item:        w.Item,
+		Price:       w.Price,
+		Goal:        w.Goal,
+		Contributed: w.Contributed,
+		Saved:       &w.Saved,
+		Progress:    &w.Progress,
+		CreatedAt:   w.CreatedAt,
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type stubWishlistService struct {
+	err error
+}
+
+func (s *stubWishlistService) Wishlist(_ context.Context, _ uuid.UUID) ([]service.WishlistGoal, error) {
+	return []service.WishlistGoal{{ID: 1, Item: "powerbank", Price: 200, Goal: 300, Contributed: 20, Saved: 150,
+		Progress: 50, CreatedAt: time.Now()}}, s.err
+}
+
+func (s *stubWishlistService) ColleagueWishlist(_ context.Context, _ string) ([]storage.WishlistItem, error) {
+	return []storage.WishlistItem{{ID: 2, ItemID: 3, Item: "book", Price: 50, Goal: 50, CreatedAt: time.Now()}}, s.err
+}
+
+func (s *stubWishlistService) AddToWishlist(_ context.Context, _ uuid.UUID, merchName string, goal int) (*service.WishlistGoal, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &service.WishlistGoal{ID: 1, Item: merchName, Price: 200, Goal: goal, Saved: goal, Progress: 100,
+		CreatedAt: time.Now()}, nil
+}
+
+func (s *stubWishlistService) RemoveFromWishlist(_ context.Context, _ uuid.UUID, _ string) error {
+	return s.err
+}
+
+func (s *stubWishlistService) Contribute(_ context.Context, _ uuid.UUID, _ string, _ string, _ int) error {
+	return s.err
+}
+
+// TestWishlistContract проверяет ручки вишлиста по openapi.json
+func TestWishlistContract(t *testing.T) {
+	token := newTestToken(t, utils.User{UserID: uuid.New()})
+
+	cases := []struct {
+		name   string
+		srv    *stubWishlistService
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{name: "own ok", srv: &stubWishlistService{}, method: http.MethodGet, path: "/api/wishlist", status: http.StatusOK},
+		{name: "colleague ok", srv: &stubWishlistService{}, method: http.MethodGet, path: "/api/wishlist?user=bob",
+			status: http.StatusOK},
+		{name: "colleague unknown", srv: &stubWishlistService{err: service.ErrUserNotFound}, method: http.MethodGet,
+			path: "/api/wishlist?user=nobody", status: http.StatusBadRequest},
+		{name: "add ok", srv: &stubWishlistService{}, method: http.MethodPost, path: "/api/wishlist",
+			body: `{"item":"powerbank","goal":300}`, status: http.StatusOK},
+		{name: "add negative goal", srv: &stubWishlistService{}, method: http.MethodPost, path: "/api/wishlist",
+			body: `{"item":"powerbank","goal":-1}`, status: http.StatusBadRequest},
+		{name: "add unknown merch", srv: &stubWishlistService{err: service.ErrMerchNotFound}, method: http.MethodPost,
+			path: "/api/wishlist", body: `{"item":"car"}`, status: http.StatusBadRequest},
+		{name: "remove ok", srv: &stubWishlistService{}, method: http.MethodDelete, path: "/api/wishlist/book",
+			status: http.StatusNoContent},
+		{name: "remove missing", srv: &stubWishlistService{err: service.ErrWishNotFound}, method: http.MethodDelete,
+			path: "/api/wishlist/book", status: http.StatusNotFound},
+		{name: "contribute ok", srv: &stubWishlistService{}, method: http.MethodPost, path: "/api/wishlist/contribute",
+			body: `{"toUser":"bob","item":"book","amount":10}`, status: http.StatusOK},
+		{name: "contribute not in wishlist", srv: &stubWishlistService{err: service.ErrWishNotFound},
+			method: http.MethodPost, path: "/api/wishlist/contribute",
+			body: `{"toUser":"bob","item":"cup","amount":10}`, status: http.StatusNotFound},
+		{name: "contribute not enough coins", srv: &stubWishlistService{err: service.ErrNotEnoughCoins},
+			method: http.MethodPost, path: "/api/wishlist/contribute",
+			body: `{"toUser":"bob","item":"book","amount":5000}`, status: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewWishlistHandlers(tc.srv)
+			engine := newTestEngine(stubUserStorage{}, testAdmins, func(api, _ gin.IRoutes) {
+				api.GET("/wishlist", h.GetWishlist)
+				api.POST("/wishlist", h.AddWish)
+				api.DELETE("/wishlist/:item", h.RemoveWish)
+				api.POST("/wishlist/contribute", h.Contribute)
+			})
+			serveContract(t, engine, newTestRequest(tc.method, tc.path, tc.body, token), tc.status)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package mw
+
+import (
+	"strings"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/gin-gonic/gin"
+)
+
+const problemJSON = "application/problem+json"
+
+type ErrorResponse struct {
+	Errors string      `json:"errors"`
+	Code   apperr.Code `json:"code"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+// ProblemDetails ответ в формате RFC 7807
+type ProblemDetails struct {
+	Type   string      `json:"type"`
+	Title  string      `json:"title"`
+	Status int         `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+	Code   apperr.Code `json:"code"`
+}
+
+// ErrorRenderer единая точка формирования ответа с ошибкой.
+// Хендлеры и middleware кладут ошибку в c.Error, здесь она
+// превращается в код, HTTP статус и локализованное сообщение.
+func ErrorRenderer() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := apperr.From(c.Errors.Last().Err)
+		lang := apperr.ParseLang(c.GetHeader("Accept-Language"))
+		message := apperr.Message(appErr.Code, lang)
+		status := appErr.Status()
+
+		if strings.Contains(c.GetHeader("Accept"), problemJSON) {
+			// gin не перезаписывает уже выставленный Content-Type
+			c.Header("Content-Type", problemJSON)
+			c.JSON(status, ProblemDetails{
+				Type:   "/errors/" + string(appErr.Code),
+				Title:  message,
+				Status: status,
+				Detail: appErr.Detail,
+				Code:   appErr.Code,
+			})
+			return
+		}
+
+		c.JSON(status, ErrorResponse{
+			Errors: message,
+			Code:   appErr.Code,
+			Detail: appErr.Detail,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package mw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorRenderer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name           string
+		err            error
+		accept         string
+		acceptLanguage string
+		status         int
+		contentType    string
+		body           string
+	}{
+		{
+			name:   "unknown error hides details",
+			err:    errors.New("pq: connection refused to 10.0.0.5"),
+			status: http.StatusInternalServerError, contentType: "application/json; charset=utf-8",
+			body: `{"errors":"internal server error","code":"internal_error"}`,
+		},
+		{
+			name:   "wrapped error with detail",
+			err:    fmt.Errorf("send coins: %w", apperr.ErrValidation.WithDetail("amount must be positive")),
+			status: http.StatusBadRequest, contentType: "application/json; charset=utf-8",
+			body: `{"errors":"request validation failed","code":"validation_error","detail":"amount must be positive"}`,
+		},
+		{
+			name:           "localized message",
+			err:            apperr.New(apperr.CodeNotEnoughCoins, "not enough coins"),
+			acceptLanguage: "ru-RU,ru;q=0.9",
+			status:         http.StatusBadRequest, contentType: "application/json; charset=utf-8",
+			body: `{"errors":"недостаточно монет на балансе","code":"not_enough_coins"}`,
+		},
+		{
+			name:           "unsupported language falls back to english",
+			err:            apperr.New(apperr.CodeNotEnoughCoins, "not enough coins"),
+			acceptLanguage: "de-DE,fr;q=0.8",
+			status:         http.StatusBadRequest, contentType: "application/json; charset=utf-8",
+			body: `{"errors":"not enough coins on balance","code":"not_enough_coins"}`,
+		},
+		{
+			name:           "problem details",
+			err:            apperr.ErrValidation.WithDetail("field amount"),
+			accept:         "application/problem+json",
+			acceptLanguage: "ru",
+			status:         http.StatusBadRequest, contentType: problemJSON,
+			body: `{"type":"/errors/validation_error","title":"запрос не прошел валидацию","status":400,` +
+				`"detail":"field amount","code":"validation_error"}`,
+		},
+		{
+			name:   "problem details for unknown error",
+			err:    errors.New("secret internals"),
+			accept: "application/problem+json",
+			status: http.StatusInternalServerError, contentType: problemJSON,
+			body: `{"type":"/errors/internal_error","title":"internal server error","status":500,` +
+				`"code":"internal_error"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(ErrorRenderer())
+			engine.GET("/", func(c *gin.Context) { _ = c.Error(tc.err) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept", tc.accept)
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, tc.contentType, rec.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.body, rec.Body.String())
+		})
+	}
+}
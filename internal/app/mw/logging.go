@@ -0,0 +1,70 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/logging" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	maxRequestIDLen = 128
+)
+
+// RequestLogger назначает X-Request-ID (или берет его из запроса),
+// кладет в контекст логгер с request_id и route и пишет access лог.
+func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLen {
+			requestID = uuid.NewString()
+		}
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		requestLogger := logger.With(
+			zap.String("request_id", requestID),
+			zap.String("method", c.Request.Method),
+			zap.String("route", c.FullPath()),
+		)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		ctx := c.Request.Context()
+		fields := []zap.Field{
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+
+		log := tracing.Logger(ctx, logging.FromContext(ctx, logger))
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			log.Error("http request", fields...)
+			return
+		}
+		log.Info("http request", fields...)
+	}
+}
+
+// Recovery логирует панику через логгер запроса вместо текстового вывода gin
+func Recovery(logger *zap.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		ctx := c.Request.Context()
+		tracing.Logger(ctx, logging.FromContext(ctx, logger)).
+			Error("panic recovered", zap.Any("panic", recovered), zap.Stack("stack"))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
@@ -0,0 +1,16 @@
+package mw
+
+import (
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/metrics" //nolint:gci
+	"github.com/gin-gonic/gin"
+)
+
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		metrics.ObserveHTTPRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,38 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestMetricsMiddleware_Panic запрос с паникой учитывается как 500: метрики стоят снаружи Recovery
+func TestMetricsMiddleware_Panic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(MetricsMiddleware(), Recovery(zap.NewNop()))
+	engine.GET("/panic", func(*gin.Context) { panic("boom") })
+
+	before := requestCount(t, http.MethodGet, "/panic", "500")
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, before+1, requestCount(t, http.MethodGet, "/panic", "500"))
+}
+
+func requestCount(t *testing.T, method, route, status string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	observer := metrics.HTTPRequestDuration.WithLabelValues(method, route, status)
+	require.NoError(t, observer.(prometheus.Metric).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
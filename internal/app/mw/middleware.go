@@ -4,17 +4,20 @@ import (
 	"context" //nolint:gci
 	"database/sql"
 	"errors"
-	"net/http"
 	"strings"
 
-	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/logging"
+	"github.com/Vic07Region/avito-shop/internal/storage"
 	"github.com/Vic07Region/avito-shop/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 type UserStorage interface {
 	GetUser4UserID(ctx context.Context, userID uuid.UUID) (*storage.Employee, error)
+	GetTenant(ctx context.Context, tenantID int64) (*storage.Tenant, error)
 }
 
 type Middleware struct {
@@ -33,12 +36,12 @@ func (mw *Middleware) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Unauthorized, Missing token"})
+			_ = c.Error(apperr.ErrTokenMissing)
 			c.Abort()
 			return
 		}
 		if !strings.HasPrefix(tokenString, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Invalid token type"})
+			_ = c.Error(apperr.ErrTokenInvalid.WithDetail("invalid token type"))
 			c.Abort()
 			return
 		}
@@ -46,28 +49,77 @@ func (mw *Middleware) AuthMiddleware() gin.HandlerFunc {
 		claims, err := utils.ValidateToken(strings.TrimPrefix(tokenString, "Bearer "))
 		if err != nil {
 			if errors.Is(err, utils.ErrorExpiredOrNotActive) {
-				c.JSON(http.StatusUnauthorized, gin.H{"errors": err.Error()})
+				_ = c.Error(apperr.ErrTokenExpired)
 				c.Abort()
 				return
 			}
-			c.JSON(http.StatusUnauthorized, gin.H{"errors": "Invalid token"})
+			_ = c.Error(apperr.ErrTokenInvalid)
 			c.Abort()
 			return
 		}
 
-		_, err = mw.UserStorage.GetUser4UserID(context.Background(), claims.UserID)
+		// дальше все запросы к хранилищу ограничены компанией пользователя
+		ctx := storage.WithTenant(c.Request.Context(), claims.TenantID)
+		ctx = logging.With(ctx, zap.String("user_id", claims.UserID.String()),
+			zap.Int64("tenant_id", storage.TenantID(ctx)))
+		c.Request = c.Request.WithContext(ctx)
+
+		user, err := mw.UserStorage.GetUser4UserID(ctx, claims.UserID)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
-				c.JSON(http.StatusUnauthorized, gin.H{"errors": "User not found"})
+				_ = c.Error(apperr.ErrUnauthorized.WithDetail("user not found"))
 				c.Abort()
 				return
 			}
-			c.JSON(http.StatusInternalServerError, gin.H{"errors": "storage error"})
+			_ = c.Error(err)
 			c.Abort()
 			return
 		}
 
 		c.Set("userID", claims.UserID)
+		c.Set("username", user.Name)
+		c.Set("role", user.Role)
 		c.Next()
 	}
 }
+
+// AdminMiddleware пропускает только администраторов компании пользователя: у учетной
+// записи должна быть роль admin, а имя - в списке администраторов из настроек компании
+// (для компании по умолчанию к нему добавляются admins), поэтому удаление из списка
+// сразу отзывает права. Ставится после AuthMiddleware. Имена сравниваются без учета
+// регистра, как при поиске пользователя.
+func (mw *Middleware) AdminMiddleware(admins []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != storage.RoleAdmin {
+			_ = c.Error(apperr.ErrForbidden)
+			c.Abort()
+			return
+		}
+
+		ctx := c.Request.Context()
+		tenantID := storage.TenantID(ctx)
+		tenant, err := mw.UserStorage.GetTenant(ctx, tenantID)
+		if err != nil && !errors.Is(err, storage.ErrTenantNotFound) {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+		var allowed []string
+		if tenantID == storage.DefaultTenantID {
+			allowed = append(allowed, admins...)
+		}
+		if tenant != nil {
+			allowed = append(allowed, tenant.Admins...)
+		}
+
+		username := c.GetString("username")
+		for _, admin := range allowed {
+			if username != "" && strings.EqualFold(admin, username) {
+				c.Next()
+				return
+			}
+		}
+		_ = c.Error(apperr.ErrForbidden)
+		c.Abort()
+	}
+}
@@ -0,0 +1,142 @@
+package apperr
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code стабильный машиночитаемый код ошибки, на него может опираться фронтенд
+type Code string
+
+const (
+	CodeInternal         Code = "internal_error"
+	CodeEmptyBody        Code = "empty_body"
+	CodeValidation       Code = "validation_error"
+	CodeBadRequest       Code = "bad_request"
+	CodeUnauthorized     Code = "unauthorized"
+	CodeTokenMissing     Code = "token_missing"
+	CodeTokenInvalid     Code = "token_invalid"
+	CodeTokenExpired     Code = "token_expired"
+	CodeInvalidPassword  Code = "invalid_password"
+	CodeUserNotFound     Code = "user_not_found"
+	CodeMerchNotFound    Code = "merch_not_found"
+	CodeNotEnoughCoins   Code = "not_enough_coins"
+	CodeForbidden        Code = "forbidden"
+	CodeWebhookNotFound  Code = "webhook_not_found"
+	CodeDeliveryNotFound Code = "delivery_not_found"
+	CodeOrderNotFound    Code = "order_not_found"
+	CodeOrderStatus      Code = "order_status_conflict"
+	CodePromoInvalid     Code = "promo_invalid"
+	CodePromoExhausted   Code = "promo_exhausted"
+	CodePromoNotFound    Code = "promotion_not_found"
+	CodePromoExists      Code = "promotion_exists"
+	CodeVariantNotFound  Code = "variant_not_found"
+	CodeVariantRequired  Code = "variant_required"
+	CodeVariantExists    Code = "variant_exists"
+	CodeOutOfStock       Code = "out_of_stock"
+	CodeNotEnoughItems   Code = "not_enough_items"
+	CodeWishNotFound     Code = "wish_not_found"
+	CodeTeamNotFound     Code = "team_not_found"
+	CodeTeamExists       Code = "team_exists"
+	CodeNotTeamMember    Code = "not_team_member"
+	CodeTenantNotFound   Code = "tenant_not_found"
+	CodeTenantExists     Code = "tenant_exists"
+	CodeUserExists       Code = "user_exists"
+)
+
+var statuses = map[Code]int{
+	CodeInternal:         http.StatusInternalServerError,
+	CodeEmptyBody:        http.StatusBadRequest,
+	CodeValidation:       http.StatusBadRequest,
+	CodeBadRequest:       http.StatusBadRequest,
+	CodeUnauthorized:     http.StatusUnauthorized,
+	CodeTokenMissing:     http.StatusUnauthorized,
+	CodeTokenInvalid:     http.StatusUnauthorized,
+	CodeTokenExpired:     http.StatusUnauthorized,
+	CodeInvalidPassword:  http.StatusUnauthorized,
+	CodeUserNotFound:     http.StatusBadRequest,
+	CodeMerchNotFound:    http.StatusBadRequest,
+	CodeNotEnoughCoins:   http.StatusBadRequest,
+	CodeForbidden:        http.StatusForbidden,
+	CodeWebhookNotFound:  http.StatusNotFound,
+	CodeDeliveryNotFound: http.StatusNotFound,
+	CodeOrderNotFound:    http.StatusNotFound,
+	CodeOrderStatus:      http.StatusConflict,
+	CodePromoInvalid:     http.StatusBadRequest,
+	CodePromoExhausted:   http.StatusBadRequest,
+	CodePromoNotFound:    http.StatusNotFound,
+	CodePromoExists:      http.StatusConflict,
+	CodeVariantNotFound:  http.StatusBadRequest,
+	CodeVariantRequired:  http.StatusBadRequest,
+	CodeVariantExists:    http.StatusConflict,
+	CodeOutOfStock:       http.StatusBadRequest,
+	CodeNotEnoughItems:   http.StatusBadRequest,
+	CodeWishNotFound:     http.StatusNotFound,
+	CodeTeamNotFound:     http.StatusNotFound,
+	CodeTeamExists:       http.StatusConflict,
+	CodeNotTeamMember:    http.StatusBadRequest,
+	CodeTenantNotFound:   http.StatusNotFound,
+	CodeTenantExists:     http.StatusConflict,
+	CodeUserExists:       http.StatusConflict,
+}
+
+var (
+	ErrInternal     = New(CodeInternal, "internal error")
+	ErrEmptyBody    = New(CodeEmptyBody, "request body is empty")
+	ErrValidation   = New(CodeValidation, "request validation failed")
+	ErrBadRequest   = New(CodeBadRequest, "bad request")
+	ErrUnauthorized = New(CodeUnauthorized, "unauthorized")
+	ErrTokenMissing = New(CodeTokenMissing, "unauthorized, missing token")
+	ErrTokenInvalid = New(CodeTokenInvalid, "invalid token")
+	ErrTokenExpired = New(CodeTokenExpired, "token is either expired or not active yet")
+	ErrForbidden    = New(CodeForbidden, "forbidden")
+)
+
+type Error struct {
+	Code Code
+	// Detail дополнительная информация для клиента, например поле не прошедшее валидацию
+	Detail  string
+	message string
+	origin  *Error
+}
+
+func New(code Code, message string) *Error {
+	return &Error{Code: code, message: message}
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.message + ": " + e.Detail
+	}
+	return e.message
+}
+
+// Is позволяет сравнивать ошибки с деталями с исходной sentinel ошибкой
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && e.origin != nil && e.origin == t
+}
+
+func (e *Error) WithDetail(detail string) *Error {
+	origin := e
+	if e.origin != nil {
+		origin = e.origin
+	}
+	return &Error{Code: e.Code, Detail: detail, message: e.message, origin: origin}
+}
+
+func (e *Error) Status() int {
+	if status, ok := statuses[e.Code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// From приводит любую ошибку к *Error, неизвестные ошибки становятся internal_error
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal
+}
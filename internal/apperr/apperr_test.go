@@ -0,0 +1,42 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrom(t *testing.T) {
+	errNotFound := New(CodeUserNotFound, "user not found")
+
+	t.Run("wrapped domain error", func(t *testing.T) {
+		appErr := From(fmt.Errorf("send coins: %w", errNotFound))
+		assert.Equal(t, CodeUserNotFound, appErr.Code)
+		assert.Equal(t, http.StatusBadRequest, appErr.Status())
+	})
+
+	t.Run("unknown error", func(t *testing.T) {
+		appErr := From(errors.New("connection refused"))
+		assert.Equal(t, CodeInternal, appErr.Code)
+		assert.Equal(t, http.StatusInternalServerError, appErr.Status())
+	})
+}
+
+func TestWithDetail(t *testing.T) {
+	err := ErrValidation.WithDetail("field amount")
+
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.NotErrorIs(t, err, ErrBadRequest)
+	assert.ErrorIs(t, err.WithDetail("other"), ErrValidation)
+	assert.Equal(t, "request validation failed: field amount", err.Error())
+	assert.NotErrorIs(t, New(CodeInternal, "a"), New(CodeInternal, "b"))
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "недостаточно монет на балансе", Message(CodeNotEnoughCoins, ParseLang("ru-RU,ru;q=0.9,en;q=0.8")))
+	assert.Equal(t, "not enough coins on balance", Message(CodeNotEnoughCoins, ParseLang("de-DE")))
+	assert.Equal(t, "not enough coins on balance", Message(CodeNotEnoughCoins, ParseLang("")))
+}
@@ -0,0 +1,104 @@
+package apperr
+
+import "strings"
+
+const (
+	LangEN = "en"
+	LangRU = "ru"
+)
+
+var messages = map[string]map[Code]string{
+	LangEN: {
+		CodeInternal:         "internal server error",
+		CodeEmptyBody:        "request body is empty",
+		CodeValidation:       "request validation failed",
+		CodeBadRequest:       "bad request",
+		CodeUnauthorized:     "unauthorized",
+		CodeTokenMissing:     "unauthorized, missing token",
+		CodeTokenInvalid:     "invalid token",
+		CodeTokenExpired:     "token is either expired or not active yet",
+		CodeInvalidPassword:  "invalid password",
+		CodeUserNotFound:     "user not found",
+		CodeMerchNotFound:    "merch not found",
+		CodeNotEnoughCoins:   "not enough coins on balance",
+		CodeForbidden:        "access denied",
+		CodeWebhookNotFound:  "webhook not found",
+		CodeDeliveryNotFound: "webhook delivery not found",
+		CodeOrderNotFound:    "order not found",
+		CodeOrderStatus:      "operation is not allowed in current order status",
+		CodePromoInvalid:     "promo code is invalid or expired",
+		CodePromoExhausted:   "promo code redemption limit reached",
+		CodePromoNotFound:    "promotion not found",
+		CodePromoExists:      "promo code already exists",
+		CodeVariantNotFound:  "merch variant not found",
+		CodeVariantRequired:  "merch has variants, choose sku or size and color",
+		CodeVariantExists:    "merch variant already exists",
+		CodeOutOfStock:       "merch variant is out of stock",
+		CodeNotEnoughItems:   "not enough items in inventory",
+		CodeWishNotFound:     "item is not in the wishlist",
+		CodeTeamNotFound:     "team not found",
+		CodeTeamExists:       "team with this name already exists",
+		CodeNotTeamMember:    "employee is not a team member",
+		CodeTenantNotFound:   "tenant not found",
+		CodeTenantExists:     "tenant with this slug already exists",
+		CodeUserExists:       "user with this username already exists",
+	},
+	LangRU: {
+		CodeInternal:         "внутренняя ошибка сервера",
+		CodeEmptyBody:        "пустое тело запроса",
+		CodeValidation:       "запрос не прошел валидацию",
+		CodeBadRequest:       "некорректный запрос",
+		CodeUnauthorized:     "требуется авторизация",
+		CodeTokenMissing:     "требуется авторизация, токен не передан",
+		CodeTokenInvalid:     "неверный токен",
+		CodeTokenExpired:     "срок действия токена истек или еще не наступил",
+		CodeInvalidPassword:  "неверный пароль",
+		CodeUserNotFound:     "пользователь не найден",
+		CodeMerchNotFound:    "товар не найден",
+		CodeNotEnoughCoins:   "недостаточно монет на балансе",
+		CodeForbidden:        "доступ запрещен",
+		CodeWebhookNotFound:  "webhook не найден",
+		CodeDeliveryNotFound: "доставка webhook не найдена",
+		CodeOrderNotFound:    "заказ не найден",
+		CodeOrderStatus:      "операция недоступна в текущем статусе заказа",
+		CodePromoInvalid:     "промокод недействителен или истек",
+		CodePromoExhausted:   "промокод больше нельзя использовать",
+		CodePromoNotFound:    "акция не найдена",
+		CodePromoExists:      "такой промокод уже существует",
+		CodeVariantNotFound:  "вариант товара не найден",
+		CodeVariantRequired:  "у товара есть варианты, укажите sku или размер и цвет",
+		CodeVariantExists:    "такой вариант товара уже существует",
+		CodeOutOfStock:       "вариант товара закончился",
+		CodeNotEnoughItems:   "недостаточно товара в инвентаре",
+		CodeWishNotFound:     "товара нет в вишлисте",
+		CodeTeamNotFound:     "команда не найдена",
+		CodeTeamExists:       "команда с таким названием уже существует",
+		CodeNotTeamMember:    "сотрудник не состоит в команде",
+		CodeTenantNotFound:   "компания не найдена",
+		CodeTenantExists:     "компания с таким коротким именем уже существует",
+		CodeUserExists:       "пользователь с таким именем уже существует",
+	},
+}
+
+// Message возвращает локализованное сообщение для кода, по умолчанию на английском
+func Message(code Code, lang string) string {
+	if msg, ok := messages[lang][code]; ok {
+		return msg
+	}
+	if msg, ok := messages[LangEN][code]; ok {
+		return msg
+	}
+	return string(code)
+}
+
+// ParseLang выбирает поддерживаемый язык из заголовка Accept-Language
+func ParseLang(acceptLanguage string) string {
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if _, ok := messages[base]; ok {
+			return base
+		}
+	}
+	return LangEN
+}
@@ -0,0 +1,215 @@
+// Package events доменные события сервиса. События пишутся в outbox
+// в одной транзакции с изменением данных и доставляются relay.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	TypeUserRegistered       = "user.registered"
+	TypeCoinsTransferred     = "coins.transferred"
+	TypeMerchPurchased       = "merch.purchased"
+	TypeOrderStatusChanged   = "order.status_changed"
+	TypeItemsTransferred     = "items.transferred"
+	TypeWishlistContributed  = "wishlist.contributed"
+	TypeTeamCoinsDeposited   = "team.coins_deposited"
+	TypeTeamCoinsDistributed = "team.coins_distributed"
+)
+
+// уведомления для webhooks и realtime, отправляются сервисом после завершения операции
+const (
+	TypeTransferReceived     = "transfer.received"
+	TypePurchaseMade         = "purchase.made"
+	TypeGiftReceived         = "gift.received"
+	TypeItemReceived         = "item.received"
+	TypeContributionReceived = "contribution.received"
+	TypeTeamCoinsReceived    = "team.coins_received"
+	TypeBalanceLow           = "balance.low"
+	TypeBalanceChanged       = "balance.changed"
+	TypeUserWelcome          = "user.welcome"
+	TypeBonusVested          = "bonus.vested"
+)
+
+// Event конверт события. ID уникален, по нему получатели отбрасывают
+// повторную доставку. TenantID - компания, в которой произошло событие.
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	TenantID   int64           `json:"tenantId,omitempty"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type UserRegistered struct {
+	UserID   uuid.UUID `json:"userId"`
+	Username string    `json:"username"`
+}
+
+type CoinsTransferred struct {
+	SenderID   uuid.UUID `json:"senderId"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	Amount     int       `json:"amount"`
+}
+
+type MerchPurchased struct {
+	UserID   uuid.UUID `json:"userId"`
+	ItemID   int       `json:"itemId"`
+	Item     string    `json:"item"`
+	SKU      string    `json:"sku,omitempty"`
+	Quantity int       `json:"quantity"`
+	Price    int       `json:"price"`
+	Discount int       `json:"discount"`
+	Total    int       `json:"total"`
+	// RecipientID получатель подарка, UserID заплатил за покупку
+	RecipientID *uuid.UUID `json:"recipientId,omitempty"`
+	Message     string     `json:"message,omitempty"`
+	// TeamID команда, с кошелька которой списаны монеты, товар у UserID
+	TeamID *int64 `json:"teamId,omitempty"`
+}
+
+// OrderStatusChanged Refund больше нуля, если отмена вернула монеты
+type OrderStatusChanged struct {
+	OrderID int64     `json:"orderId"`
+	UserID  uuid.UUID `json:"userId"`
+	Status  string    `json:"status"`
+	Refund  int       `json:"refund"`
+}
+
+type ItemsTransferred struct {
+	SenderID   uuid.UUID `json:"senderId"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	ItemID     int       `json:"itemId"`
+	Item       string    `json:"item"`
+	SKU        string    `json:"sku,omitempty"`
+	Quantity   int       `json:"quantity"`
+}
+
+type WishlistContributed struct {
+	SenderID   uuid.UUID `json:"senderId"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	ItemID     int       `json:"itemId"`
+	Item       string    `json:"item"`
+	Amount     int       `json:"amount"`
+}
+
+// TeamCoinsDeposited ActorID - администратор, пополнивший командный кошелек
+type TeamCoinsDeposited struct {
+	TeamID  int64     `json:"teamId"`
+	ActorID uuid.UUID `json:"actorId"`
+	Amount  int       `json:"amount"`
+}
+
+type TeamCoinsDistributed struct {
+	TeamID     int64     `json:"teamId"`
+	ManagerID  uuid.UUID `json:"managerId"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	Amount     int       `json:"amount"`
+}
+
+type TransferReceived struct {
+	SenderID   uuid.UUID `json:"senderId"`
+	Sender     string    `json:"sender"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	Receiver   string    `json:"receiver"`
+	Amount     int       `json:"amount"`
+}
+
+// PurchaseMade Team заполнен у командной покупки, монеты списаны с кошелька команды
+type PurchaseMade struct {
+	UserID   uuid.UUID `json:"userId"`
+	Item     string    `json:"item"`
+	Quantity int       `json:"quantity"`
+	Total    int       `json:"total"`
+	Team     string    `json:"team,omitempty"`
+}
+
+type GiftReceived struct {
+	SenderID    uuid.UUID `json:"senderId"`
+	Sender      string    `json:"sender"`
+	RecipientID uuid.UUID `json:"recipientId"`
+	Recipient   string    `json:"recipient"`
+	Item        string    `json:"item"`
+	SKU         string    `json:"sku,omitempty"`
+	Quantity    int       `json:"quantity"`
+	Message     string    `json:"message,omitempty"`
+}
+
+type ItemReceived struct {
+	SenderID   uuid.UUID `json:"senderId"`
+	Sender     string    `json:"sender"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	Receiver   string    `json:"receiver"`
+	Item       string    `json:"item"`
+	SKU        string    `json:"sku,omitempty"`
+	Quantity   int       `json:"quantity"`
+}
+
+// ContributionReceived Contributed - сумма всех взносов на товар вместе с этим
+type ContributionReceived struct {
+	SenderID    uuid.UUID `json:"senderId"`
+	Sender      string    `json:"sender"`
+	ReceiverID  uuid.UUID `json:"receiverId"`
+	Receiver    string    `json:"receiver"`
+	Item        string    `json:"item"`
+	Amount      int       `json:"amount"`
+	Contributed int       `json:"contributed"`
+	Goal        int       `json:"goal"`
+}
+
+// TeamCoinsReceived менеджер перевел монеты из пула команды участнику
+type TeamCoinsReceived struct {
+	TeamID     int64     `json:"teamId"`
+	Team       string    `json:"team"`
+	ManagerID  uuid.UUID `json:"managerId"`
+	Manager    string    `json:"manager"`
+	ReceiverID uuid.UUID `json:"receiverId"`
+	Receiver   string    `json:"receiver"`
+	Amount     int       `json:"amount"`
+}
+
+type BalanceLow struct {
+	UserID    uuid.UUID `json:"userId"`
+	Balance   int       `json:"balance"`
+	Threshold int       `json:"threshold"`
+}
+
+// BalanceChanged Delta отрицательна при списании
+type BalanceChanged struct {
+	UserID  uuid.UUID `json:"userId"`
+	Balance int       `json:"balance"`
+	Delta   int       `json:"delta"`
+}
+
+// UserWelcome приветствие нового сотрудника. VestsAt - когда Bonus попадет на баланс,
+// nil - бонус уже на балансе. Bonus 0, если по этому имени бонус уже выдавался.
+type UserWelcome struct {
+	UserID   uuid.UUID  `json:"userId"`
+	Username string     `json:"username"`
+	Role     string     `json:"role"`
+	Bonus    int        `json:"bonus"`
+	VestsAt  *time.Time `json:"vestsAt,omitempty"`
+}
+
+// BonusVested отложенный бонус за регистрацию попал на баланс
+type BonusVested struct {
+	UserID uuid.UUID `json:"userId"`
+	Amount int       `json:"amount"`
+}
+
+func New(eventType string, payload any) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Payload:    data,
+	}, nil
+}
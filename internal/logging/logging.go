@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// WithLogger сохраняет логгер запроса в контексте
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext возвращает логгер запроса, если его нет - fallback, без fallback -
+// глобальный логгер zap.L(). Никогда не возвращает nil.
+func FromContext(ctx context.Context, fallback *zap.Logger) *zap.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok && logger != nil {
+		return logger
+	}
+	if fallback != nil {
+		return fallback
+	}
+	return zap.L()
+}
+
+// With добавляет поля к логгеру запроса, без логгера в контексте - к глобальному,
+// чтобы фоновые задачи не теряли логи
+func With(ctx context.Context, fields ...zap.Field) context.Context {
+	return WithLogger(ctx, FromContext(ctx, nil).With(fields...))
+}
@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWith(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	restore := zap.ReplaceGlobals(zap.New(core))
+	defer restore()
+
+	// без логгера в контексте поля добавляются к глобальному логгеру
+	ctx := With(context.Background(), zap.String("user_id", "u1"))
+	FromContext(ctx, nil).Info("background")
+	FromContext(context.Background(), nil).Info("global")
+
+	requestCore, requestLogs := observer.New(zap.InfoLevel)
+	ctx = With(WithLogger(context.Background(), zap.New(requestCore)), zap.String("user_id", "u2"))
+	FromContext(ctx, nil).Info("request")
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "u1", entries[0].ContextMap()["user_id"])
+		assert.Equal(t, "global", entries[1].Message)
+	}
+	assert.Equal(t, 1, requestLogs.FilterField(zap.String("user_id", "u2")).Len())
+}
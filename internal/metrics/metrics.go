@@ -0,0 +1,140 @@
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "avito_shop"
+
+var (
+	// HTTPRequestDuration длительность HTTP-запросов по маршруту, методу и статусу
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// CoinsTransferred сумма монет, переведенных между сотрудниками
+	CoinsTransferred = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coins_transferred_total",
+		Help:      "Total amount of coins transferred between employees.",
+	})
+
+	// Purchases число купленных товаров по названию
+	Purchases = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "purchases_total",
+		Help:      "Number of purchased merch items by item name.",
+	}, []string{"item"})
+
+	// InsufficientFunds операции, отклоненные из-за нехватки монет
+	InsufficientFunds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "insufficient_funds_total",
+		Help:      "Operations rejected because of insufficient balance.",
+	}, []string{"operation"})
+
+	// LoginFailures неудачные входы по причине
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed login attempts by reason.",
+	}, []string{"reason"})
+
+	// CacheRequests обращения к кэшу хранилища по виду данных и результату
+	CacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Storage cache lookups by kind and result.",
+	}, []string{"kind", "result"})
+
+	// OutboxPublished события, опубликованные из outbox, по типу
+	OutboxPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_published_total",
+		Help:      "Domain events published by the outbox relay by event type.",
+	}, []string{"type"})
+
+	// OutboxPublishErrors неудачные попытки публикации событий по типу
+	OutboxPublishErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_publish_errors_total",
+		Help:      "Failed domain event publish attempts by event type.",
+	}, []string{"type"})
+
+	// WebhookDeliveries попытки доставки webhooks по типу события и результату
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Webhook delivery attempts by event type and result (delivered, retry, dead).",
+	}, []string{"type", "result"})
+
+	// RealtimeSubscribers открытые потоки событий
+	RealtimeSubscribers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "realtime_subscribers",
+		Help:      "Open realtime event streams.",
+	})
+
+	// RealtimeDropped события, отброшенные из-за медленного подписчика
+	RealtimeDropped = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "realtime_dropped_total",
+		Help:      "Realtime events dropped because a subscriber did not keep up.",
+	})
+
+	// ReplicaLag отставание реплик по последней проверке
+	ReplicaLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_replica_lag_seconds",
+		Help:      "Replication lag of read replicas measured by the last check.",
+	}, []string{"replica"})
+
+	// ReplicaAvailable используется ли реплика для чтения (1) или пропущена из-за отставания или ошибок (0)
+	ReplicaAvailable = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "db_replica_available",
+		Help:      "Whether a read replica is used for reads (1) or skipped because of lag or errors (0).",
+	}, []string{"replica"})
+)
+
+// значения метки operation для InsufficientFunds
+const (
+	OperationSendCoins     = "send_coins"
+	OperationPurchaseMerch = "purchase_merch"
+	OperationContribute    = "contribute"
+	OperationDistribute    = "team_distribute"
+)
+
+// значения метки result для CacheRequests
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// значения метки reason для LoginFailures
+const (
+	ReasonInvalidPassword = "invalid_password"
+	ReasonInternalError   = "internal_error"
+)
+
+// ObserveHTTPRequest записывает длительность запроса, запросы без маршрута попадают в unmatched
+func ObserveHTTPRequest(method string, route string, status int, duration time.Duration) {
+	if route == "" {
+		route = "unmatched"
+	}
+	HTTPRequestDuration.WithLabelValues(method, route, strconv.Itoa(status)).Observe(duration.Seconds())
+}
+
+// RegisterDBStats отдает sql.DBStats пула соединений как метрики
+func RegisterDBStats(db *sql.DB, dbName string) error {
+	return prometheus.Register(collectors.NewDBStatsCollector(db, dbName))
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type pgxPoolCollector struct {
+	pool *pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	constructingConns    *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	newConnsCount        *prometheus.Desc
+}
+
+func newDesc(name string, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, nil, nil)
+}
+
+// RegisterPgxPoolStats экспортирует pgxpool.Stat пула соединений postgres
+func RegisterPgxPoolStats(pool *pgxpool.Pool) error {
+	return prometheus.Register(&pgxPoolCollector{
+		pool:                 pool,
+		acquiredConns:        newDesc("acquired_conns", "Number of currently acquired connections."),
+		idleConns:            newDesc("idle_conns", "Number of currently idle connections."),
+		constructingConns:    newDesc("constructing_conns", "Number of connections being constructed."),
+		totalConns:           newDesc("total_conns", "Total number of connections in the pool."),
+		maxConns:             newDesc("max_conns", "Maximum size of the pool."),
+		acquireCount:         newDesc("acquire_count_total", "Number of successful acquires from the pool."),
+		acquireDuration:      newDesc("acquire_duration_seconds_total", "Total time spent on successful acquires."),
+		emptyAcquireCount:    newDesc("empty_acquire_count_total", "Acquires that had to wait because the pool was empty."),
+		canceledAcquireCount: newDesc("canceled_acquire_count_total", "Acquires canceled by context."),
+		newConnsCount:        newDesc("new_conns_count_total", "Number of new connections opened."),
+	})
+}
+
+func (c *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.constructingConns, prometheus.GaugeValue, float64(stat.ConstructingConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.newConnsCount, prometheus.CounterValue, float64(stat.NewConnsCount()))
+}
@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"go.uber.org/zap"
+)
+
+// типы публикаторов для настройки через окружение
+const (
+	PublisherNone    = "none"
+	PublisherLog     = "log"
+	PublisherFile    = "file"
+	PublisherWebhook = "webhook"
+)
+
+// LogPublisher пишет события в лог, удобно для отладки
+type LogPublisher struct {
+	log *zap.Logger
+}
+
+func NewLogPublisher(zapLogger *zap.Logger) *LogPublisher {
+	return &LogPublisher{log: zapLogger}
+}
+
+func (p *LogPublisher) Publish(_ context.Context, event events.Event) error {
+	p.log.Info("domain event",
+		zap.String("event_id", event.ID.String()),
+		zap.String("event_type", event.Type),
+		zap.Time("occurred_at", event.OccurredAt),
+		zap.ByteString("payload", event.Payload),
+	)
+	return nil
+}
+
+// FilePublisher дописывает события в файл, по одному json на строку
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+func (p *FilePublisher) Publish(_ context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.file.Write(append(data, '\n'))
+	return err
+}
+
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// WebhookPublisher отправляет событие POST запросом. Любой ответ кроме 2xx
+// считается ошибкой, событие будет отправлено повторно.
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string, client *http.Client) *WebhookPublisher {
+	return &WebhookPublisher{url: url, client: client}
+}
+
+func (p *WebhookPublisher) Publish(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID.String())
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvent(t *testing.T) events.Event {
+	t.Helper()
+	event, err := events.New(events.TypeCoinsTransferred, events.CoinsTransferred{
+		SenderID:   uuid.New(),
+		ReceiverID: uuid.New(),
+		Amount:     10,
+	})
+	require.NoError(t, err)
+	return event
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	event := newTestEvent(t)
+
+	var received events.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, event.ID.String(), r.Header.Get("X-Event-ID"))
+		assert.Equal(t, events.TypeCoinsTransferred, r.Header.Get("X-Event-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhookPublisher(server.URL, server.Client())
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	assert.Equal(t, event.ID, received.ID)
+	assert.JSONEq(t, string(event.Payload), string(received.Payload))
+}
+
+func TestWebhookPublisherErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	publisher := NewWebhookPublisher(server.URL, server.Client())
+	assert.Error(t, publisher.Publish(context.Background(), newTestEvent(t)))
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+
+	first, second := newTestEvent(t), newTestEvent(t)
+	require.NoError(t, publisher.Publish(context.Background(), first))
+	require.NoError(t, publisher.Publish(context.Background(), second))
+	require.NoError(t, publisher.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []uuid.UUID
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e events.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []uuid.UUID{first.ID, second.ID}, ids)
+}
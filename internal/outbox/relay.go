@@ -0,0 +1,107 @@
+// Package outbox доставляет события из outbox внешним получателям.
+//
+// Доставка at-least-once: событие помечается опубликованным только после
+// успешной публикации, поэтому после сбоя оно может прийти повторно.
+// Получатели отбрасывают дубликаты по events.Event.ID.
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/logging"
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"go.uber.org/zap"
+)
+
+type Storage interface {
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]storage.OutboxEvent, error)
+	MarkOutboxEventsPublished(ctx context.Context, ids []int64) error
+}
+
+// Publisher точка расширения для доставки событий: лог, файл, webhook
+// или брокер сообщений
+type Publisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+type Config struct {
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease время, на которое событие закрепляется за relay. Если публикация
+	// не удалась, событие будет повторено после истечения аренды.
+	Lease time.Duration
+}
+
+type Relay struct {
+	storage   Storage
+	publisher Publisher
+	config    Config
+	log       *zap.Logger
+}
+
+func NewRelay(storage Storage, publisher Publisher, config Config, zapLogger *zap.Logger) *Relay {
+	return &Relay{
+		storage:   storage,
+		publisher: publisher,
+		config:    config,
+		log:       zapLogger,
+	}
+}
+
+// Run публикует события, пока не отменен ctx
+func (r *Relay) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		published, err := r.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.logger(ctx).Error("outbox relay Flush error:", zap.Error(err))
+		}
+		// полная пачка значит, что в outbox, скорее всего, есть еще события
+		if err == nil && published == r.config.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.config.PollInterval)
+		}
+	}
+}
+
+// Flush публикует одну пачку событий по порядку. На первой ошибке пачка
+// прерывается, чтобы не обгонять неопубликованное событие следующими.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	claimed, err := r.storage.ClaimOutboxEvents(ctx, r.config.BatchSize, r.config.Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(claimed))
+	var publishErr error
+	for _, e := range claimed {
+		if publishErr = r.publisher.Publish(ctx, e.Event); publishErr != nil {
+			metrics.OutboxPublishErrors.WithLabelValues(e.Event.Type).Inc()
+			r.logger(ctx).Warn("outbox publish error:", zap.String("event_id", e.Event.ID.String()),
+				zap.String("event_type", e.Event.Type), zap.Error(publishErr))
+			break
+		}
+		metrics.OutboxPublished.WithLabelValues(e.Event.Type).Inc()
+		ids = append(ids, e.ID)
+	}
+
+	if err := r.storage.MarkOutboxEventsPublished(ctx, ids); err != nil {
+		return 0, err
+	}
+	return len(ids), publishErr
+}
+
+func (r *Relay) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, r.log)
+}
@@ -0,0 +1,125 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingPublisher запоминает события и падает на событии с номером failAt
+type recordingPublisher struct {
+	mu        sync.Mutex
+	published []events.Event
+	calls     int
+	failAt    int
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event events.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	if p.calls == p.failAt {
+		return errors.New("broker is down")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestRelayFlush(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	aliceID, err := s.NewUser(ctx, "alice", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+	bobID, err := s.NewUser(ctx, "bob", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+	require.NoError(t, s.SendCoinsTransaction(ctx, aliceID, bobID, 10, time.Time{}))
+
+	publisher := &recordingPublisher{}
+	relay := NewRelay(s, publisher, Config{BatchSize: 2, Lease: time.Minute}, nil)
+
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	require.Len(t, publisher.published, 3)
+	assert.Equal(t, events.TypeUserRegistered, publisher.published[0].Type)
+	assert.Equal(t, events.TypeUserRegistered, publisher.published[1].Type)
+	assert.Equal(t, events.TypeCoinsTransferred, publisher.published[2].Type)
+}
+
+func TestRelayRetry(t *testing.T) {
+	ctx := context.Background()
+	s := memory.New()
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, err := s.NewUser(ctx, name, "hash", &storage.Onboarding{Bonus: 1000})
+		require.NoError(t, err)
+	}
+
+	// второе событие не публикуется, третье не должно его обогнать
+	publisher := &recordingPublisher{failAt: 2}
+	relay := NewRelay(s, publisher, Config{BatchSize: 10, Lease: 20 * time.Millisecond}, nil)
+
+	published, err := relay.Flush(ctx)
+	assert.Error(t, err)
+	assert.Equal(t, 1, published)
+	require.Len(t, publisher.published, 1)
+
+	// до истечения аренды события не выдаются
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	time.Sleep(50 * time.Millisecond)
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	var usernames []string
+	for _, e := range publisher.published {
+		usernames = append(usernames, string(e.Payload))
+	}
+	require.Len(t, usernames, 3)
+	assert.Contains(t, usernames[0], "alice")
+	assert.Contains(t, usernames[1], "bob")
+	assert.Contains(t, usernames[2], "carol")
+}
+
+func TestRelayRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := memory.New()
+	_, err := s.NewUser(ctx, "alice", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+
+	publisher := &recordingPublisher{}
+	relay := NewRelay(s, publisher, Config{BatchSize: 10, PollInterval: 10 * time.Millisecond, Lease: time.Minute}, nil)
+
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		publisher.mu.Lock()
+		defer publisher.mu.Unlock()
+		return len(publisher.published) == 1
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
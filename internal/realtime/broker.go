@@ -0,0 +1,84 @@
+// Package realtime доставляет уведомления открытым потокам пользователей.
+//
+// Notifier адресует уведомление пользователям и отправляет его через
+// Transport. Transport доставляет сообщение во все инстансы сервиса, включая
+// текущий: Broker для одного инстанса или PostgresTransport через
+// LISTEN/NOTIFY для нескольких. Broker раздает сообщения локальным подписчикам.
+package realtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/google/uuid"
+)
+
+// Message событие для конкретного пользователя
+type Message struct {
+	UserID uuid.UUID    `json:"userId"`
+	Event  events.Event `json:"event"`
+}
+
+type Transport interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+// Broker in-process pub/sub. Подписчик, который не успевает читать,
+// теряет события, но не тормозит отправителей.
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan events.Event]struct{}
+	buffer      int
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{
+		subscribers: make(map[uuid.UUID]map[chan events.Event]struct{}),
+		buffer:      buffer,
+	}
+}
+
+// Subscribe возвращает канал событий пользователя и функцию отписки.
+// После отписки канал закрывается.
+func (b *Broker) Subscribe(userID uuid.UUID) (<-chan events.Event, func()) {
+	ch := make(chan events.Event, b.buffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan events.Event]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+	metrics.RealtimeSubscribers.Inc()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			close(ch)
+			b.mu.Unlock()
+			metrics.RealtimeSubscribers.Dec()
+		})
+	}
+}
+
+// Publish раздает сообщение подписчикам этого инстанса
+func (b *Broker) Publish(_ context.Context, message Message) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subscribers[message.UserID] {
+		select {
+		case ch <- message.Event:
+		default:
+			metrics.RealtimeDropped.Inc()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker(1)
+	aliceID, bobID := uuid.New(), uuid.New()
+
+	// у пользователя может быть несколько открытых потоков
+	first, unsubscribeFirst := broker.Subscribe(aliceID)
+	second, unsubscribeSecond := broker.Subscribe(aliceID)
+	bob, unsubscribeBob := broker.Subscribe(bobID)
+	defer unsubscribeBob()
+
+	event, err := events.New(events.TypeBalanceChanged, events.BalanceChanged{UserID: aliceID, Balance: 900, Delta: -100})
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(ctx, Message{UserID: aliceID, Event: event}))
+
+	assert.Equal(t, event, <-first)
+	assert.Equal(t, event, <-second)
+	assert.Empty(t, bob)
+
+	// медленный подписчик теряет события, отправитель не блокируется
+	require.NoError(t, broker.Publish(ctx, Message{UserID: aliceID, Event: event}))
+	require.NoError(t, broker.Publish(ctx, Message{UserID: aliceID, Event: event}))
+	assert.Len(t, first, 1)
+
+	unsubscribeFirst()
+	unsubscribeFirst()
+	<-first
+	_, ok := <-first
+	assert.False(t, ok)
+
+	unsubscribeSecond()
+	require.NoError(t, broker.Publish(ctx, Message{UserID: aliceID, Event: event}))
+	assert.NotContains(t, broker.subscribers, aliceID)
+}
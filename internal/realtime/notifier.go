@@ -0,0 +1,71 @@
+package realtime
+
+import (
+	"context"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/logging"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Notifier реализует service.Notifier и отправляет уведомление тем
+// пользователям, которых оно касается
+type Notifier struct {
+	transport Transport
+	log       *zap.Logger
+}
+
+func NewNotifier(transport Transport, zapLogger *zap.Logger) *Notifier {
+	return &Notifier{transport: transport, log: zapLogger}
+}
+
+func (n *Notifier) Notify(ctx context.Context, eventType string, payload any) {
+	recipients := recipients(payload)
+	if len(recipients) == 0 {
+		return
+	}
+	event, err := events.New(eventType, payload)
+	if err != nil {
+		n.logger(ctx).Error("realtime Notify events.New error:", zap.Error(err))
+		return
+	}
+	for _, userID := range recipients {
+		if err := n.transport.Publish(ctx, Message{UserID: userID, Event: event}); err != nil {
+			n.logger(ctx).Error("realtime Notify Publish error:", zap.Error(err))
+		}
+	}
+}
+
+// recipients пользователи, которым показывается уведомление
+func recipients(payload any) []uuid.UUID {
+	switch p := payload.(type) {
+	case events.TransferReceived:
+		return []uuid.UUID{p.ReceiverID}
+	case events.PurchaseMade:
+		return []uuid.UUID{p.UserID}
+	case events.GiftReceived:
+		return []uuid.UUID{p.RecipientID}
+	case events.ItemReceived:
+		return []uuid.UUID{p.ReceiverID}
+	case events.ContributionReceived:
+		return []uuid.UUID{p.ReceiverID}
+	case events.TeamCoinsReceived:
+		return []uuid.UUID{p.ReceiverID}
+	case events.BalanceLow:
+		return []uuid.UUID{p.UserID}
+	case events.BalanceChanged:
+		return []uuid.UUID{p.UserID}
+	case events.OrderStatusChanged:
+		return []uuid.UUID{p.UserID}
+	case events.UserWelcome:
+		return []uuid.UUID{p.UserID}
+	case events.BonusVested:
+		return []uuid.UUID{p.UserID}
+	}
+	return nil
+}
+
+func (n *Notifier) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, n.log)
+}
@@ -0,0 +1,46 @@
+package realtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	ctx := context.Background()
+	broker := NewBroker(10)
+	notifier := NewNotifier(broker, nil)
+	aliceID, bobID := uuid.New(), uuid.New()
+
+	alice, unsubscribeAlice := broker.Subscribe(aliceID)
+	defer unsubscribeAlice()
+	bob, unsubscribeBob := broker.Subscribe(bobID)
+	defer unsubscribeBob()
+
+	// перевод видит только получатель
+	notifier.Notify(ctx, events.TypeTransferReceived, events.TransferReceived{
+		SenderID: aliceID, Sender: "alice", ReceiverID: bobID, Receiver: "bob", Amount: 10,
+	})
+	notifier.Notify(ctx, events.TypeBalanceChanged, events.BalanceChanged{UserID: aliceID, Balance: 990, Delta: -10})
+	notifier.Notify(ctx, events.TypePurchaseMade, events.PurchaseMade{UserID: aliceID, Item: "cup", Quantity: 1, Total: 20})
+	notifier.Notify(ctx, events.TypeBalanceLow, events.BalanceLow{UserID: bobID, Balance: 10, Threshold: 100})
+	notifier.Notify(ctx, events.TypeOrderStatusChanged, events.OrderStatusChanged{OrderID: 1, UserID: bobID, Status: "shipped"})
+	// события без адресата не рассылаются
+	notifier.Notify(ctx, events.TypeUserRegistered, events.UserRegistered{UserID: aliceID, Username: "alice"})
+
+	require.Len(t, alice, 2)
+	assert.Equal(t, events.TypeBalanceChanged, (<-alice).Type)
+	assert.Equal(t, events.TypePurchaseMade, (<-alice).Type)
+
+	require.Len(t, bob, 3)
+	transfer := <-bob
+	assert.Equal(t, events.TypeTransferReceived, transfer.Type)
+	assert.JSONEq(t, `{"senderId":"`+aliceID.String()+`","sender":"alice","receiverId":"`+bobID.String()+
+		`","receiver":"bob","amount":10}`, string(transfer.Payload))
+	assert.Equal(t, events.TypeBalanceLow, (<-bob).Type)
+	assert.Equal(t, events.TypeOrderStatusChanged, (<-bob).Type)
+}
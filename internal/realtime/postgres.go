@@ -0,0 +1,88 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/logging" //nolint:gci
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Channel канал LISTEN/NOTIFY для realtime сообщений
+const Channel = "avito_shop_realtime"
+
+// reconnectDelay пауза перед повторным LISTEN после потери соединения
+const reconnectDelay = time.Second
+
+// PostgresTransport рассылает сообщения всем инстансам через NOTIFY.
+// Каждый инстанс слушает канал в Run и отдает сообщения своему Broker,
+// поэтому сообщение, отправленное этим же инстансом, тоже приходит через Postgres.
+type PostgresTransport struct {
+	pool   *pgxpool.Pool
+	broker *Broker
+	log    *zap.Logger
+}
+
+func NewPostgresTransport(pool *pgxpool.Pool, broker *Broker, zapLogger *zap.Logger) *PostgresTransport {
+	return &PostgresTransport{pool: pool, broker: broker, log: zapLogger}
+}
+
+func (t *PostgresTransport) Publish(ctx context.Context, message Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = t.pool.Exec(ctx, "SELECT pg_notify($1, $2)", Channel, string(data))
+	return err
+}
+
+// Run слушает канал, пока не отменен ctx. Сообщения, отправленные, пока
+// соединение было потеряно, не восстанавливаются: клиенты перечитывают
+// состояние через /api/info после переподключения потока.
+func (t *PostgresTransport) Run(ctx context.Context) {
+	for {
+		err := t.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		t.logger(ctx).Error("realtime listen error:", zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (t *PostgresTransport) listen(ctx context.Context) error {
+	pooled, err := t.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// соединение после LISTEN нельзя возвращать в пул для обычных запросов
+	conn := pooled.Hijack()
+	defer conn.Close(context.WithoutCancel(ctx))
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{Channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var message Message
+		if err := json.Unmarshal([]byte(notification.Payload), &message); err != nil {
+			t.logger(ctx).Error("realtime notification json.Unmarshal error:", zap.Error(err))
+			continue
+		}
+		_ = t.broker.Publish(ctx, message)
+	}
+}
+
+func (t *PostgresTransport) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, t.log)
+}
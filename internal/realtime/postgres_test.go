@@ -0,0 +1,56 @@
+package realtime
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostgresTransport имитирует два инстанса на одной базе: сообщение,
+// отправленное первым, получает подписчик второго
+func TestPostgresTransport(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN is not set")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newInstance := func() (*Broker, *PostgresTransport) {
+		pool, err := storage.NewPostgresPool(ctx, storage.ConnectionParams{DbDriver: storage.DBPostgres, ConnectionString: dsn})
+		require.NoError(t, err)
+		t.Cleanup(pool.Close)
+		broker := NewBroker(10)
+		transport := NewPostgresTransport(pool, broker, nil)
+		go transport.Run(ctx)
+		return broker, transport
+	}
+	_, sender := newInstance()
+	receiver, _ := newInstance()
+
+	userID := uuid.New()
+	stream, unsubscribe := receiver.Subscribe(userID)
+	defer unsubscribe()
+
+	event, err := events.New(events.TypeBalanceChanged, events.BalanceChanged{UserID: userID, Balance: 900, Delta: -100})
+	require.NoError(t, err)
+
+	// LISTEN выполняется в фоне, поэтому отправка повторяется, пока сообщение не дойдет
+	require.Eventually(t, func() bool {
+		require.NoError(t, sender.Publish(ctx, Message{UserID: userID, Event: event}))
+		select {
+		case received := <-stream:
+			assert.Equal(t, event.ID, received.ID)
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
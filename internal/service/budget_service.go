@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// периоды восполнения бюджета на благодарности, границы считаются в UTC
+const (
+	BudgetPeriodMonth = "month"
+	BudgetPeriodWeek  = "week"
+)
+
+// SetBudget назначает сотруднику бюджет на благодарности на каждый период.
+// Бюджет сразу восполняется до нового лимита, 0 отключает бюджет.
+func (s *Service) SetBudget(ctx context.Context, username string, limit int) (_ *Budget, err error) {
+	ctx, span := tracing.Start(ctx, "Service.SetBudget")
+	defer func() { tracing.End(span, err) }()
+
+	if limit < 0 {
+		return nil, apperr.ErrValidation.WithDetail("limit must not be negative")
+	}
+	user, err := s.Storage.FindUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		s.logger(ctx).Error("SetBudget FindUser error:", zap.Error(err))
+		return nil, err
+	}
+
+	start, _, err := s.budgetPeriod(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err = s.Storage.SetBudget(ctx, user.EmployeeId, limit, start); err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		s.logger(ctx).Error("SetBudget Storage.SetBudget error:", zap.Error(err))
+		return nil, err
+	}
+	return s.Budget(ctx, user.EmployeeId)
+}
+
+// Budget бюджет на благодарности сотрудника в текущем периоде
+func (s *Service) Budget(ctx context.Context, userID uuid.UUID) (_ *Budget, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Budget")
+	defer func() { tracing.End(span, err) }()
+
+	start, end, err := s.budgetPeriod(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	budget, err := s.Storage.GetBudget(ctx, userID, start)
+	if err != nil {
+		s.logger(ctx).Error("Budget GetBudget error:", zap.Error(err))
+		return nil, err
+	}
+	return &Budget{Limit: budget.Limit, Remaining: budget.Remaining, ResetsAt: end}, nil
+}
+
+// budgetPeriod начало и конец периода бюджета компании запроса, в который попадает now
+func (s *Service) budgetPeriod(ctx context.Context, now time.Time) (time.Time, time.Time, error) {
+	period, err := s.tenantBudgetPeriod(ctx)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start, end := periodBounds(period, now)
+	return start, end, nil
+}
+
+// periodBounds начало и конец периода бюджета period, в который попадает now
+func periodBounds(period string, now time.Time) (time.Time, time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == BudgetPeriodWeek {
+		// неделя начинается в понедельник
+		start := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	}
+	start := day.AddDate(0, 0, 1-day.Day())
+	return start, start.AddDate(0, 1, 0)
+}
+
+// tenantBudgetPeriod период бюджета компании запроса, настройки компании читаются из хранилища
+func (s *Service) tenantBudgetPeriod(ctx context.Context) (string, error) {
+	tenantID := storage.TenantID(ctx)
+	if tenantID == storage.DefaultTenantID {
+		return s.BudgetPeriod, nil
+	}
+	tenant, err := s.Storage.GetTenant(ctx, tenantID)
+	if err != nil {
+		s.logger(ctx).Error("tenantBudgetPeriod GetTenant error:", zap.Error(err))
+		return "", err
+	}
+	return s.budgetPeriodSetting(ctx, tenant.BudgetPeriod), nil
+}
+
+// budgetPeriodSetting период бюджета компании запроса по ее настройке tenantPeriod. Компания по умолчанию
+// и компании без своего периода используют BudgetPeriod сервиса.
+func (s *Service) budgetPeriodSetting(ctx context.Context, tenantPeriod string) string {
+	if storage.TenantID(ctx) == storage.DefaultTenantID || tenantPeriod == "" {
+		return s.BudgetPeriod
+	}
+	return tenantPeriod
+}
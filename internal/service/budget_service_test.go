@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetBudget(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	start, end := periodBounds(svc.BudgetPeriod, time.Now())
+
+	mockStorage.On("FindUser", mock.Anything, "alice").Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "nobody").Return((*storage.Employee)(nil), sql.ErrNoRows)
+	mockStorage.On("SetBudget", mock.Anything, userID, 300, start).Return(nil).Once()
+	mockStorage.On("GetBudget", mock.Anything, userID, start).Return(&storage.Budget{Limit: 300, Remaining: 300}, nil)
+
+	budget, err := svc.SetBudget(ctx, "alice", 300)
+	assert.NoError(t, err)
+	assert.Equal(t, &Budget{Limit: 300, Remaining: 300, ResetsAt: end}, budget)
+
+	_, err = svc.SetBudget(ctx, "alice", -1)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.SetBudget(ctx, "nobody", 300)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	// среда, 19 марта 2025, в UTC+3
+	now := time.Date(2025, 3, 19, 1, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	start, end := periodBounds("", now)
+	assert.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), end)
+
+	// неделя с понедельника, 01:30 по Москве - еще вторник по UTC
+	start, end = periodBounds(BudgetPeriodWeek, now)
+	assert.Equal(t, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 3, 24, 0, 0, 0, 0, time.UTC), end)
+
+	start, _ = periodBounds(BudgetPeriodWeek, time.Date(2025, 3, 23, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), start)
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// LedgerLimit сколько последних записей журнала инвентаря отдается пользователю
+const LedgerLimit = 100
+
+// SendItems передает товар из своего инвентаря коллеге. Вариант товара
+// выбирается среди тех, что есть в инвентаре, как при покупке.
+func (s *Service) SendItems(ctx context.Context, userID uuid.UUID, toUsername string, merchName string,
+	choice VariantChoice, quantity int) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.SendItems")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 {
+		return apperr.ErrValidation.WithDetail("quantity must be positive")
+	}
+	receiver, err := s.Storage.FindUser(ctx, toUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.logger(ctx).Error("SendItems FindUser error:", zap.Error(err))
+		return err
+	}
+	if receiver.EmployeeId == userID {
+		return apperr.ErrValidation.WithDetail("receiver must be another employee")
+	}
+
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return err
+	}
+	// инвентарь читается с primary: товар могли купить только что
+	inventory, err := s.Storage.GetInventories(storage.WithPrimary(ctx), userID)
+	if err != nil {
+		s.logger(ctx).Error("SendItems GetInventories error:", zap.Error(err))
+		return err
+	}
+	owned := ownedVariants(merch, inventory)
+	if len(merch.Variants) > 0 && len(owned) == 0 {
+		return ErrNotEnoughItems
+	}
+	variant, err := chooseVariant(owned, choice)
+	if err != nil {
+		return err
+	}
+
+	transfer := storage.ItemTransfer{MerchID: merch.MerchID, Name: merch.Name, Quantity: quantity}
+	if variant != nil {
+		transfer.VariantID, transfer.SKU = variant.ID, variant.SKU
+	}
+	err = s.Storage.TransferItemsTransaction(ctx, userID, receiver.EmployeeId, transfer)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotEnoughItems) {
+			return ErrNotEnoughItems
+		}
+		s.logger(ctx).Error("SendItems TransferItemsTransaction error:", zap.Error(err))
+		return err
+	}
+
+	s.notifyItems(ctx, userID, receiver, transfer)
+	return nil
+}
+
+// InventoryLedger последние движения товаров в инвентаре пользователя, новые первыми
+func (s *Service) InventoryLedger(ctx context.Context, userID uuid.UUID) (_ []storage.LedgerEntry, err error) {
+	ctx, span := tracing.Start(ctx, "Service.InventoryLedger")
+	defer func() { tracing.End(span, err) }()
+
+	entries, err := s.Storage.GetInventoryLedger(ctx, userID, LedgerLimit)
+	if err != nil {
+		s.logger(ctx).Error("InventoryLedger GetInventoryLedger error:", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ownedVariants варианты товара, которые есть в инвентаре
+func ownedVariants(merch *storage.MerchItem, inventory []storage.InventoryItem) []storage.MerchVariant {
+	var owned []storage.MerchVariant
+	for _, v := range merch.Variants {
+		for _, item := range inventory {
+			if item.Name == merch.Name && item.SKU == v.SKU {
+				owned = append(owned, v)
+				break
+			}
+		}
+	}
+	return owned
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSendItems(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	bobID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: bobID, Name: "bob"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "alice").Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "nobody").Return((*storage.Employee)(nil), sql.ErrNoRows)
+	mockStorage.On("GetUser4UserID", mock.Anything, userID).Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "hoody").
+		Return(&storage.MerchItem{MerchID: 6, Name: "hoody", Price: 300, Variants: hoodyVariants}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetInventories", mock.Anything, userID).Return([]storage.InventoryItem{
+		{Name: "cup", Quantity: 1},
+		{Name: "hoody", SKU: "HOODY-M-RED", Size: "M", Color: "red", Quantity: 1},
+	}, nil)
+	// размер M неоднозначен в каталоге, но в инвентаре только красная
+	mockStorage.On("TransferItemsTransaction", mock.Anything, userID, bobID, storage.ItemTransfer{
+		MerchID: 6, Name: "hoody", VariantID: 2, SKU: "HOODY-M-RED", Quantity: 1,
+	}).Return(nil).Once()
+	mockStorage.On("TransferItemsTransaction", mock.Anything, userID, bobID, storage.ItemTransfer{
+		MerchID: 2, Name: "cup", Quantity: 3,
+	}).Return(storage.ErrNotEnoughItems).Once()
+	notifier.On("Notify", mock.Anything, events.TypeItemReceived, events.ItemReceived{
+		SenderID: userID, Sender: "alice", ReceiverID: bobID, Receiver: "bob", Item: "hoody", SKU: "HOODY-M-RED", Quantity: 1,
+	}).Once()
+
+	err := svc.SendItems(ctx, userID, "bob", "hoody", VariantChoice{Size: "M"}, 1)
+	assert.NoError(t, err)
+
+	err = svc.SendItems(ctx, userID, "bob", "cup", VariantChoice{}, 3)
+	assert.ErrorIs(t, err, ErrNotEnoughItems)
+	// варианта нет в инвентаре
+	err = svc.SendItems(ctx, userID, "bob", "hoody", VariantChoice{Size: "XL"}, 1)
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+	err = svc.SendItems(ctx, userID, "nobody", "cup", VariantChoice{}, 1)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	err = svc.SendItems(ctx, userID, "alice", "cup", VariantChoice{}, 1)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	err = svc.SendItems(ctx, userID, "bob", "cup", VariantChoice{}, 0)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
@@ -1,10 +1,23 @@
 package service
 
+import "time"
+
+// Inventory SKU, Size и Color заполнены для товаров с вариантами
 type Inventory struct {
 	Type     string `json:"type"`
+	SKU      string `json:"sku"`
+	Size     string `json:"size"`
+	Color    string `json:"color"`
 	Quantity int    `json:"quantity"`
 }
 
+// VariantChoice выбор варианта товара при покупке: по SKU или по размеру и цвету
+type VariantChoice struct {
+	SKU   string
+	Size  string
+	Color string
+}
+
 type Received struct {
 	FromUser string `json:"fromUser"`
 	Amount   int    `json:"amount"`
@@ -20,13 +33,39 @@ type CoinHistory struct {
 	Sent     []Sent     `json:"sent"`
 }
 
+// WishlistGoal товар из вишлиста и прогресс накопления на него: Saved - сколько монет
+// цели уже есть на балансе, Progress - то же в процентах
+type WishlistGoal struct {
+	ID          int64     `json:"id"`
+	Item        string    `json:"item"`
+	Price       int       `json:"price"`
+	Goal        int       `json:"goal"`
+	Contributed int       `json:"contributed"`
+	Saved       int       `json:"saved"`
+	Progress    int       `json:"progress"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Budget бюджет на благодарности коллегам: тратится только на переводы и
+// восполняется до Limit в ResetsAt
+type Budget struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	ResetsAt  time.Time `json:"resetsAt"`
+}
+
+// FullInfo Budget nil, если бюджет сотруднику не назначен
 type FullInfo struct {
-	Coins       int         `json:"coins"`
-	Inventory   []Inventory `json:"inventory"`
-	CoinHistory CoinHistory `json:"coinHistory"`
+	Coins       int            `json:"coins"`
+	Budget      *Budget        `json:"budget"`
+	Inventory   []Inventory    `json:"inventory"`
+	CoinHistory CoinHistory    `json:"coinHistory"`
+	Wishlist    []WishlistGoal `json:"wishlist"`
 }
 
+// UserData Tenant короткое имя компании, пусто - компания по умолчанию
 type UserData struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Tenant   string `json:"tenant"`
 }
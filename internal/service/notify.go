@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Notifier точка расширения для уведомлений, например webhook.Service.
+// Операция к моменту вызова уже выполнена, поэтому Notify не возвращает ошибку.
+type Notifier interface {
+	Notify(ctx context.Context, eventType string, payload any)
+}
+
+// Notifiers отправляет уведомление каждому получателю по очереди
+type Notifiers []Notifier
+
+func (n Notifiers) Notify(ctx context.Context, eventType string, payload any) {
+	for _, notifier := range n {
+		notifier.Notify(ctx, eventType, payload)
+	}
+}
+
+// notifyTransfer fromBudget - часть перевода из бюджета на благодарности, баланс отправителя она не меняет
+func (s *Service) notifyTransfer(ctx context.Context, senderID uuid.UUID, receiver *storage.Employee, amount int,
+	fromBudget int) {
+	if s.Notifier == nil {
+		return
+	}
+	sender, err := s.Storage.GetUser4UserID(ctx, senderID)
+	if err != nil {
+		s.logger(ctx).Error("notifyTransfer GetUser4UserID error:", zap.Error(err))
+		return
+	}
+	s.Notifier.Notify(ctx, events.TypeTransferReceived, events.TransferReceived{
+		SenderID:   senderID,
+		Sender:     sender.Name,
+		ReceiverID: receiver.EmployeeId,
+		Receiver:   receiver.Name,
+		Amount:     amount,
+	})
+	if amount > fromBudget {
+		s.notifyBalance(ctx, senderID, fromBudget-amount)
+	}
+	s.notifyBalance(ctx, receiver.EmployeeId, amount)
+}
+
+// notifyItems уведомляет получателя о переданном товаре
+func (s *Service) notifyItems(ctx context.Context, senderID uuid.UUID, receiver *storage.Employee, transfer storage.ItemTransfer) {
+	if s.Notifier == nil {
+		return
+	}
+	sender, err := s.Storage.GetUser4UserID(ctx, senderID)
+	if err != nil {
+		s.logger(ctx).Error("notifyItems GetUser4UserID error:", zap.Error(err))
+		return
+	}
+	s.Notifier.Notify(ctx, events.TypeItemReceived, events.ItemReceived{
+		SenderID:   senderID,
+		Sender:     sender.Name,
+		ReceiverID: receiver.EmployeeId,
+		Receiver:   receiver.Name,
+		Item:       transfer.Name,
+		SKU:        transfer.SKU,
+		Quantity:   transfer.Quantity,
+	})
+}
+
+// notifyContribution уведомляет получателя о взносе с итогом взносов на товар
+func (s *Service) notifyContribution(ctx context.Context, senderID uuid.UUID, receiver *storage.Employee,
+	contribution storage.Contribution) {
+	if s.Notifier == nil {
+		return
+	}
+	sender, err := s.Storage.GetUser4UserID(ctx, senderID)
+	if err != nil {
+		s.logger(ctx).Error("notifyContribution GetUser4UserID error:", zap.Error(err))
+		return
+	}
+	wishlist, err := s.Storage.GetWishlist(storage.WithPrimary(ctx), receiver.EmployeeId)
+	if err != nil {
+		s.logger(ctx).Error("notifyContribution GetWishlist error:", zap.Error(err))
+		return
+	}
+	payload := events.ContributionReceived{
+		SenderID:   senderID,
+		Sender:     sender.Name,
+		ReceiverID: receiver.EmployeeId,
+		Receiver:   receiver.Name,
+		Item:       contribution.Item,
+		Amount:     contribution.Amount,
+	}
+	for _, w := range wishlist {
+		if w.ItemID == contribution.ItemID {
+			payload.Contributed, payload.Goal = w.Contributed, w.Goal
+		}
+	}
+	s.Notifier.Notify(ctx, events.TypeContributionReceived, payload)
+	s.notifyBalance(ctx, senderID, -contribution.Amount)
+	s.notifyBalance(ctx, receiver.EmployeeId, contribution.Amount)
+}
+
+// notifyPurchase total - списанная сумма с учетом скидок. Командная покупка
+// не меняет личный баланс, поэтому balance.changed для нее не отправляется.
+func (s *Service) notifyPurchase(ctx context.Context, userID uuid.UUID, item string, quantity int, total int,
+	team string) {
+	if s.Notifier == nil {
+		return
+	}
+	s.Notifier.Notify(ctx, events.TypePurchaseMade, events.PurchaseMade{
+		UserID:   userID,
+		Item:     item,
+		Quantity: quantity,
+		Total:    total,
+		Team:     team,
+	})
+	if team == "" {
+		s.notifyBalance(ctx, userID, -total)
+	}
+}
+
+// notifyTeamCoins уведомляет участника о монетах из пула команды
+func (s *Service) notifyTeamCoins(ctx context.Context, managerID uuid.UUID, team *storage.Team,
+	receiver *storage.TeamMember, amount int) {
+	if s.Notifier == nil {
+		return
+	}
+	payload := events.TeamCoinsReceived{
+		TeamID:     team.ID,
+		Team:       team.Name,
+		ManagerID:  managerID,
+		ReceiverID: receiver.EmployeeID,
+		Receiver:   receiver.Username,
+		Amount:     amount,
+	}
+	if manager := team.Member(managerID); manager != nil {
+		payload.Manager = manager.Username
+	}
+	s.Notifier.Notify(ctx, events.TypeTeamCoinsReceived, payload)
+	s.notifyBalance(ctx, receiver.EmployeeID, amount)
+}
+
+// notifyGift уведомляет получателя о подарке, покупатель получает обычное уведомление о покупке
+func (s *Service) notifyGift(ctx context.Context, senderID uuid.UUID, recipient *storage.Employee, merch storage.MerchInfo) {
+	if s.Notifier == nil {
+		return
+	}
+	sender, err := s.Storage.GetUser4UserID(ctx, senderID)
+	if err != nil {
+		s.logger(ctx).Error("notifyGift GetUser4UserID error:", zap.Error(err))
+		return
+	}
+	s.Notifier.Notify(ctx, events.TypeGiftReceived, events.GiftReceived{
+		SenderID:    senderID,
+		Sender:      sender.Name,
+		RecipientID: recipient.EmployeeId,
+		Recipient:   recipient.Name,
+		Item:        merch.Name,
+		SKU:         merch.SKU,
+		Quantity:    merch.Amount,
+		Message:     merch.GiftMessage,
+	})
+}
+
+// notifyBalance сообщает новый баланс. balance.low отправляется только при
+// переходе через порог, а не при каждой операции ниже порога.
+func (s *Service) notifyBalance(ctx context.Context, userID uuid.UUID, delta int) {
+	balance, err := s.Storage.GetBalance(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("notifyBalance GetBalance error:", zap.Error(err))
+		return
+	}
+	s.Notifier.Notify(ctx, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID:  userID,
+		Balance: balance,
+		Delta:   delta,
+	})
+	if s.LowBalanceThreshold > 0 && balance < s.LowBalanceThreshold && balance-delta >= s.LowBalanceThreshold {
+		s.Notifier.Notify(ctx, events.TypeBalanceLow, events.BalanceLow{
+			UserID:    userID,
+			Balance:   balance,
+			Threshold: s.LowBalanceThreshold,
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, eventType string, payload any) {
+	m.Called(ctx, eventType, payload)
+}
+
+func TestSendCoins_Notify(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, LowBalanceThreshold: 100, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	toUserID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: toUserID, Name: "bob"}, nil)
+	mockStorage.On("GetBudget", mock.Anything, userID, mock.Anything).Return(&storage.Budget{}, nil)
+	mockStorage.On("SendCoinsTransaction", mock.Anything, userID, toUserID, 50, mock.Anything).Return(nil)
+	mockStorage.On("GetUser4UserID", mock.Anything, userID).Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	// баланс опустился со 120 до 70 и пересек порог
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(70, nil)
+	mockStorage.On("GetBalance", mock.Anything, toUserID).Return(1050, nil)
+	notifier.On("Notify", mock.Anything, events.TypeTransferReceived, events.TransferReceived{
+		SenderID: userID, Sender: "alice", ReceiverID: toUserID, Receiver: "bob", Amount: 50,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 70, Delta: -50,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceLow, events.BalanceLow{
+		UserID: userID, Balance: 70, Threshold: 100,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: toUserID, Balance: 1050, Delta: 50,
+	}).Once()
+
+	err := svc.SendCoins(ctx, userID, "bob", 50)
+	assert.NoError(t, err)
+	notifier.AssertExpectations(t)
+}
+
+func TestSendCoins_NotifyBudget(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	toUserID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: toUserID, Name: "bob"}, nil)
+	mockStorage.On("GetUser4UserID", mock.Anything, userID).Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("GetBudget", mock.Anything, userID, mock.Anything).Return(&storage.Budget{Limit: 100, Remaining: 30}, nil)
+	mockStorage.On("SendCoinsTransaction", mock.Anything, userID, toUserID, mock.Anything, mock.Anything).Return(nil)
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(980, nil)
+	mockStorage.On("GetBalance", mock.Anything, toUserID).Return(1050, nil)
+	notifier.On("Notify", mock.Anything, events.TypeTransferReceived, mock.Anything)
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: toUserID, Balance: 1050, Delta: 20,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: toUserID, Balance: 1050, Delta: 50,
+	}).Once()
+	// из 50 монет 30 ушли из бюджета, баланс отправителя уменьшился на 20
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 980, Delta: -20,
+	}).Once()
+
+	// перевод целиком из бюджета не меняет баланс отправителя
+	assert.NoError(t, svc.SendCoins(ctx, userID, "bob", 20))
+	assert.NoError(t, svc.SendCoins(ctx, userID, "bob", 50))
+	notifier.AssertExpectations(t)
+}
+
+func TestPurchaseMerch_Notify(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, LowBalanceThreshold: 100, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	// цена для покупки читается с primary
+	mockStorage.On("GetMerchItems", mock.MatchedBy(storage.IsPrimary), "cup").
+		Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(nil)
+	// баланс уже был ниже порога, повторного balance.low нет
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(40, nil)
+	notifier.On("Notify", mock.Anything, events.TypePurchaseMade, events.PurchaseMade{
+		UserID: userID, Item: "cup", Quantity: 2, Total: 40,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 40, Delta: -40,
+	}).Once()
+
+	err := svc.PurchaseMerch(ctx, userID, "cup", VariantChoice{}, 2, "")
+	assert.NoError(t, err)
+	notifier.AssertExpectations(t)
+	notifier.AssertNotCalled(t, "Notify", mock.Anything, events.TypeBalanceLow, mock.Anything)
+}
+
+func TestGiftMerch_Notify(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	bobID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: bobID, Name: "bob"}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(nil)
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(980, nil)
+	mockStorage.On("GetUser4UserID", mock.Anything, userID).Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	// даритель получает обычную покупку, получатель - подарок
+	notifier.On("Notify", mock.Anything, events.TypePurchaseMade, events.PurchaseMade{
+		UserID: userID, Item: "cup", Quantity: 1, Total: 20,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 980, Delta: -20,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeGiftReceived, events.GiftReceived{
+		SenderID: userID, Sender: "alice", RecipientID: bobID, Recipient: "bob", Item: "cup", Quantity: 1, Message: "ура",
+	}).Once()
+
+	err := svc.GiftMerch(ctx, userID, "bob", "cup", VariantChoice{}, "", "ура")
+	assert.NoError(t, err)
+	notifier.AssertExpectations(t)
+}
+
+func TestPurchaseMerch_NotEnoughCoinsNoNotify(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, LowBalanceThreshold: 100, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(storage.ErrNotEnoughCoins)
+
+	err := svc.PurchaseMerch(ctx, userID, "cup", VariantChoice{}, 2, "")
+	assert.ErrorIs(t, err, ErrNotEnoughCoins)
+	notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestNotifiers(t *testing.T) {
+	first, second := new(MockNotifier), new(MockNotifier)
+	payload := events.BalanceLow{UserID: uuid.New(), Balance: 10, Threshold: 100}
+	first.On("Notify", mock.Anything, events.TypeBalanceLow, payload).Once()
+	second.On("Notify", mock.Anything, events.TypeBalanceLow, payload).Once()
+
+	Notifiers{first, second}.Notify(context.Background(), events.TypeBalanceLow, payload)
+	first.AssertExpectations(t)
+	second.AssertExpectations(t)
+}
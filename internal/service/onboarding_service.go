@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// vestBatchSize сколько отложенных бонусов выдается за один проход
+const vestBatchSize = 100
+
+// onboarding бонус нового сотрудника с ролью role по правилам компании: учетная запись
+// с ролью admin получает AdminSignupBonus, если он задан, бонус откладывается на
+// BonusVestingDays дней. Роль сохраняется вместе с учетной записью, поэтому бонус
+// зависит от нее, а не от совпадения имени со списком администраторов.
+func (s *Service) onboarding(tenant *storage.Tenant, username string, role string, now time.Time) storage.Onboarding {
+	onboarding := storage.Onboarding{
+		Username: username,
+		Role:     role,
+		Bonus:    tenant.SignupBonus,
+		VestsAt:  now.UTC().AddDate(0, 0, tenant.BonusVestingDays),
+	}
+	if role == storage.RoleAdmin && tenant.AdminSignupBonus != nil {
+		onboarding.Bonus = *tenant.AdminSignupBonus
+	}
+	return onboarding
+}
+
+// notifyWelcome приветствует нового сотрудника и сообщает, когда придет бонус
+func (s *Service) notifyWelcome(ctx context.Context, userID uuid.UUID, onboarding storage.Onboarding) {
+	if s.Notifier == nil {
+		return
+	}
+	welcome := events.UserWelcome{
+		UserID:   userID,
+		Username: onboarding.Username,
+		Role:     onboarding.Role,
+		Bonus:    onboarding.Bonus,
+	}
+	if onboarding.VestedAt == nil && onboarding.Bonus > 0 {
+		welcome.VestsAt = &onboarding.VestsAt
+	}
+	s.Notifier.Notify(ctx, events.TypeUserWelcome, welcome)
+}
+
+// VestSignupBonuses выдает наступившие отложенные бонусы за регистрацию во всех компаниях
+// и возвращает их число
+func (s *Service) VestSignupBonuses(ctx context.Context) (int, error) {
+	vested, err := s.Storage.VestSignupBonuses(ctx, time.Now(), vestBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, o := range vested {
+		if s.Notifier == nil || o.Bonus == 0 {
+			continue
+		}
+		// уведомления и webhooks принадлежат компании сотрудника
+		tenantCtx := storage.WithTenant(ctx, o.TenantID)
+		s.Notifier.Notify(tenantCtx, events.TypeBonusVested, events.BonusVested{UserID: o.EmployeeID, Amount: o.Bonus})
+		s.notifyBalance(tenantCtx, o.EmployeeID, o.Bonus)
+	}
+	return len(vested), nil
+}
+
+// RunVesting выдает отложенные бонусы раз в interval, пока не отменен ctx
+func (s *Service) RunVesting(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		vested, err := s.VestSignupBonuses(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger(ctx).Error("RunVesting VestSignupBonuses error:", zap.Error(err))
+		}
+		// полная пачка значит, что наступивших бонусов, скорее всего, больше
+		if err == nil && vested == vestBatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(interval)
+		}
+	}
+}
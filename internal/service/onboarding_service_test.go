@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOnboardingPolicy(t *testing.T) {
+	svc := Service{Admins: []string{"root"}, log: newTestLogger()}
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	adminBonus := 5000
+	acme := &storage.Tenant{ID: 2, SignupBonus: 300, AdminSignupBonus: &adminBonus, BonusVestingDays: 14,
+		Admins: []string{"Boss"}}
+
+	o := svc.onboarding(acme, "alice", storage.RoleEmployee, now)
+	assert.Equal(t, storage.Onboarding{Username: "alice", Role: storage.RoleEmployee, Bonus: 300,
+		VestsAt: now.AddDate(0, 0, 14)}, o)
+
+	o = svc.onboarding(acme, "boss", storage.RoleAdmin, now)
+	assert.Equal(t, storage.RoleAdmin, o.Role)
+	assert.Equal(t, 5000, o.Bonus)
+
+	// бонус администратора дает роль учетной записи, а не имя из списка
+	o = svc.onboarding(acme, "boss", storage.RoleEmployee, now)
+	assert.Equal(t, storage.RoleEmployee, o.Role)
+	assert.Equal(t, 300, o.Bonus)
+
+	// без отдельного бонуса администратор получает обычный
+	def := &storage.Tenant{ID: storage.DefaultTenantID, SignupBonus: DefaultSignupBonus}
+	o = svc.onboarding(def, "root", storage.RoleAdmin, now)
+	assert.Equal(t, storage.RoleAdmin, o.Role)
+	assert.Equal(t, DefaultSignupBonus, o.Bonus)
+	assert.Equal(t, now, o.VestsAt)
+
+	// администраторы из окружения действуют только в компании по умолчанию
+	assert.True(t, svc.isAdmin(acme, "boss"))
+	assert.False(t, svc.isAdmin(acme, "root"))
+	assert.True(t, svc.isAdmin(def, "root"))
+}
+
+func TestLoginUser_Welcome(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	acme := &storage.Tenant{ID: 7, Slug: "acme", SignupBonus: 300, BonusVestingDays: 30}
+	mockStorage.On("GetTenantBySlug", mock.Anything, "acme").Return(acme, nil)
+	mockStorage.On("GetUserAuthData", mock.Anything, "carol").Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
+	mockStorage.On("NewUser", mock.Anything, "carol", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool {
+		return o.Bonus == 300 && o.VestsAt.After(time.Now().AddDate(0, 0, 29))
+	})).Return(userID, nil)
+	// бонус отложен, поэтому в приветствии есть дата выдачи
+	notifier.On("Notify", mock.MatchedBy(func(ctx context.Context) bool {
+		return storage.TenantID(ctx) == 7
+	}), events.TypeUserWelcome, mock.MatchedBy(func(welcome events.UserWelcome) bool {
+		return welcome.UserID == userID && welcome.Username == "carol" && welcome.Bonus == 300 &&
+			welcome.VestsAt != nil
+	})).Once()
+
+	_, err := svc.LoginUser(ctx, UserData{Username: "carol", Password: "password", Tenant: "acme"})
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestVestSignupBonuses(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	aliceID := uuid.New()
+	bobID := uuid.New()
+
+	mockStorage.On("VestSignupBonuses", mock.Anything, mock.Anything, vestBatchSize).Return([]storage.Onboarding{
+		{TenantID: 2, EmployeeID: aliceID, Bonus: 300},
+		// нулевой бонус отмечается выданным без уведомлений
+		{TenantID: 2, EmployeeID: bobID},
+	}, nil).Once()
+	mockStorage.On("GetBalance", mock.Anything, aliceID).Return(300, nil)
+	inAcme := mock.MatchedBy(func(ctx context.Context) bool { return storage.TenantID(ctx) == 2 })
+	notifier.On("Notify", inAcme, events.TypeBonusVested, events.BonusVested{UserID: aliceID, Amount: 300}).Once()
+	notifier.On("Notify", inAcme, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: aliceID, Balance: 300, Delta: 300,
+	}).Once()
+
+	vested, err := svc.VestSignupBonuses(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, vested)
+
+	mockStorage.On("VestSignupBonuses", mock.Anything, mock.Anything, vestBatchSize).
+		Return(nil, errors.New("db error")).Once()
+	_, err = svc.VestSignupBonuses(ctx)
+	assert.Error(t, err)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// OrdersLimit максимальный размер очереди заказов в ответе администратору
+const OrdersLimit = 100
+
+var (
+	ErrOrderNotFound = apperr.New(apperr.CodeOrderNotFound, "order not found")
+	ErrOrderStatus   = apperr.New(apperr.CodeOrderStatus, "operation is not allowed in current order status")
+)
+
+// orderTransitions допустимые переходы: новый статус -> статусы, из которых в него можно перейти
+var orderTransitions = map[string][]string{
+	storage.OrderApproved:  {storage.OrderPlaced},
+	storage.OrderShipped:   {storage.OrderApproved},
+	storage.OrderDelivered: {storage.OrderShipped},
+	storage.OrderCancelled: {storage.OrderPlaced, storage.OrderApproved},
+}
+
+// addressEditable статусы, в которых пользователь может менять адрес доставки
+var addressEditable = []string{storage.OrderPlaced, storage.OrderApproved}
+
+// OrderStatuses все статусы заказа в порядке жизненного цикла
+var OrderStatuses = []string{
+	storage.OrderPlaced,
+	storage.OrderApproved,
+	storage.OrderShipped,
+	storage.OrderDelivered,
+	storage.OrderCancelled,
+}
+
+// Orders история заказов пользователя
+func (s *Service) Orders(ctx context.Context, userID uuid.UUID) (_ []storage.Order, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Orders")
+	defer func() { tracing.End(span, err) }()
+
+	orders, err := s.Storage.GetOrders(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("Orders GetOrders error:", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
+
+// ListOrders очередь заказов для администратора, пустой status - все статусы
+func (s *Service) ListOrders(ctx context.Context, status string) (_ []storage.Order, err error) {
+	ctx, span := tracing.Start(ctx, "Service.ListOrders")
+	defer func() { tracing.End(span, err) }()
+
+	if status != "" && !slices.Contains(OrderStatuses, status) {
+		return nil, apperr.ErrValidation.WithDetail("unknown order status " + status)
+	}
+	orders, err := s.Storage.ListOrders(ctx, status, OrdersLimit)
+	if err != nil {
+		s.logger(ctx).Error("ListOrders Storage.ListOrders error:", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
+
+// SetShippingAddress меняет адрес доставки, пока заказ не отправлен
+func (s *Service) SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.SetShippingAddress")
+	defer func() { tracing.End(span, err) }()
+
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return apperr.ErrValidation.WithDetail("address must not be empty")
+	}
+	err = s.Storage.SetShippingAddress(ctx, userID, orderID, address, addressEditable)
+	if err != nil {
+		return s.orderError(ctx, "SetShippingAddress", err)
+	}
+	return nil
+}
+
+// CancelOrder отмена заказа пользователем, доступна до подтверждения.
+// Стоимость заказа возвращается на баланс покупателя, подарок может отменить
+// и получатель, и даритель. Заказ, товар из которого уже передан, не отменяется.
+func (s *Service) CancelOrder(ctx context.Context, userID uuid.UUID, orderID int64) (_ *storage.Order, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CancelOrder")
+	defer func() { tracing.End(span, err) }()
+
+	return s.updateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orderID,
+		UserID:  userID,
+		From:    []string{storage.OrderPlaced},
+		To:      storage.OrderCancelled,
+	})
+}
+
+// AdvanceOrder переводит заказ в следующий статус по решению администратора.
+// Отправить заказ можно только с адресом доставки.
+func (s *Service) AdvanceOrder(ctx context.Context, orderID int64, status string) (_ *storage.Order, err error) {
+	ctx, span := tracing.Start(ctx, "Service.AdvanceOrder")
+	defer func() { tracing.End(span, err) }()
+
+	from, ok := orderTransitions[status]
+	if !ok {
+		return nil, apperr.ErrValidation.WithDetail("unknown target order status " + status)
+	}
+	if status == storage.OrderShipped {
+		order, err := s.Storage.GetOrder(ctx, orderID)
+		if err != nil {
+			return nil, s.orderError(ctx, "AdvanceOrder", err)
+		}
+		if order.ShippingAddress == "" {
+			return nil, ErrOrderStatus.WithDetail("order has no shipping address")
+		}
+	}
+	return s.updateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orderID,
+		From:    from,
+		To:      status,
+	})
+}
+
+func (s *Service) updateOrderStatus(ctx context.Context, update storage.OrderStatusUpdate) (*storage.Order, error) {
+	order, err := s.Storage.UpdateOrderStatus(ctx, update)
+	if err != nil {
+		return nil, s.orderError(ctx, "updateOrderStatus", err)
+	}
+	s.notifyOrder(ctx, order)
+	return order, nil
+}
+
+func (s *Service) orderError(ctx context.Context, operation string, err error) error {
+	switch {
+	case errors.Is(err, storage.ErrOrderNotFound):
+		return ErrOrderNotFound
+	case errors.Is(err, storage.ErrOrderStatus):
+		return ErrOrderStatus
+	case errors.Is(err, storage.ErrNotEnoughItems):
+		return ErrOrderStatus.WithDetail("order items were already given away")
+	}
+	s.logger(ctx).Error(operation+" error:", zap.Error(err))
+	return err
+}
+
+func (s *Service) notifyOrder(ctx context.Context, order *storage.Order) {
+	if s.Notifier == nil {
+		return
+	}
+	refund := 0
+	if order.Status == storage.OrderCancelled {
+		refund = order.Total
+	}
+	s.Notifier.Notify(ctx, events.TypeOrderStatusChanged, events.OrderStatusChanged{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Status:  order.Status,
+		Refund:  refund,
+	})
+	if refund > 0 {
+		// монеты возвращаются тому, кто заплатил, у подарка - дарителю
+		s.notifyBalance(ctx, order.Payer(), refund)
+	}
+}
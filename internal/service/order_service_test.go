@@ -0,0 +1,115 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCancelOrder(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	cancelled := &storage.Order{ID: 3, UserID: userID, Item: "cup", Quantity: 2, Total: 40, Status: storage.OrderCancelled}
+
+	// пользователь может отменить только свой неподтвержденный заказ
+	mockStorage.On("UpdateOrderStatus", mock.Anything, storage.OrderStatusUpdate{
+		OrderID: 3, UserID: userID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	}).Return(cancelled, nil)
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(1000, nil)
+	notifier.On("Notify", mock.Anything, events.TypeOrderStatusChanged, events.OrderStatusChanged{
+		OrderID: 3, UserID: userID, Status: storage.OrderCancelled, Refund: 40,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 1000, Delta: 40,
+	}).Once()
+
+	order, err := svc.CancelOrder(ctx, userID, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, cancelled, order)
+	notifier.AssertExpectations(t)
+}
+
+func TestCancelOrder_Errors(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("UpdateOrderStatus", mock.Anything, mock.MatchedBy(func(u storage.OrderStatusUpdate) bool {
+		return u.OrderID == 1
+	})).Return(nil, storage.ErrOrderNotFound)
+	mockStorage.On("UpdateOrderStatus", mock.Anything, mock.MatchedBy(func(u storage.OrderStatusUpdate) bool {
+		return u.OrderID == 2
+	})).Return(nil, storage.ErrOrderStatus)
+	mockStorage.On("UpdateOrderStatus", mock.Anything, mock.MatchedBy(func(u storage.OrderStatusUpdate) bool {
+		return u.OrderID == 3
+	})).Return(nil, storage.ErrNotEnoughItems)
+
+	_, err := svc.CancelOrder(ctx, userID, 1)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = svc.CancelOrder(ctx, userID, 2)
+	assert.ErrorIs(t, err, ErrOrderStatus)
+	// товар из заказа уже передан коллеге
+	_, err = svc.CancelOrder(ctx, userID, 3)
+	assert.ErrorIs(t, err, ErrOrderStatus)
+}
+
+func TestAdvanceOrder(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("UpdateOrderStatus", mock.Anything, storage.OrderStatusUpdate{
+		OrderID: 5, From: []string{storage.OrderPlaced}, To: storage.OrderApproved,
+	}).Return(&storage.Order{ID: 5, UserID: userID, Status: storage.OrderApproved}, nil)
+
+	order, err := svc.AdvanceOrder(ctx, 5, storage.OrderApproved)
+	assert.NoError(t, err)
+	assert.Equal(t, storage.OrderApproved, order.Status)
+
+	// вернуть заказ в начальный статус нельзя
+	_, err = svc.AdvanceOrder(ctx, 5, storage.OrderPlaced)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
+
+func TestAdvanceOrder_ShipWithoutAddress(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+
+	mockStorage.On("GetOrder", mock.Anything, int64(5)).Return(&storage.Order{ID: 5, Status: storage.OrderApproved}, nil)
+
+	_, err := svc.AdvanceOrder(ctx, 5, storage.OrderShipped)
+	assert.ErrorIs(t, err, ErrOrderStatus)
+	mockStorage.AssertNotCalled(t, "UpdateOrderStatus", mock.Anything, mock.Anything)
+}
+
+func TestSetShippingAddress(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("SetShippingAddress", mock.Anything, userID, int64(5), "Москва",
+		[]string{storage.OrderPlaced, storage.OrderApproved}).Return(nil)
+
+	assert.NoError(t, svc.SetShippingAddress(ctx, userID, 5, "  Москва "))
+	assert.ErrorIs(t, svc.SetShippingAddress(ctx, userID, 5, " "), apperr.ErrValidation)
+}
+
+func TestListOrders_UnknownStatus(t *testing.T) {
+	svc := Service{Storage: new(MockStorage), log: newTestLogger()}
+
+	_, err := svc.ListOrders(context.Background(), "lost")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// SetMerchPrice меняет цену товара с текущего момента. Уже оформленные
+// заказы сохраняют цену на момент покупки.
+func (s *Service) SetMerchPrice(ctx context.Context, merchName string, price int) (_ *storage.MerchPrice, err error) {
+	ctx, span := tracing.Start(ctx, "Service.SetMerchPrice")
+	defer func() { tracing.End(span, err) }()
+
+	if price < 1 {
+		return nil, apperr.ErrValidation.WithDetail("price must be positive")
+	}
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return nil, err
+	}
+
+	merch.Price = price
+	at := time.Now().UTC()
+	if err := s.Storage.SetMerchPrice(ctx, *merch, at); err != nil {
+		s.logger(ctx).Error("SetMerchPrice Storage.SetMerchPrice error:", zap.Error(err))
+		return nil, err
+	}
+	return &storage.MerchPrice{ItemID: merch.MerchID, Price: price, EffectiveFrom: at}, nil
+}
+
+func (s *Service) PriceHistory(ctx context.Context, merchName string) (_ []storage.MerchPrice, err error) {
+	ctx, span := tracing.Start(ctx, "Service.PriceHistory")
+	defer func() { tracing.End(span, err) }()
+
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return nil, err
+	}
+	prices, err := s.Storage.GetPriceHistory(ctx, merch.MerchID)
+	if err != nil {
+		s.logger(ctx).Error("PriceHistory Storage.GetPriceHistory error:", zap.Error(err))
+		return nil, err
+	}
+	return prices, nil
+}
+
+// SalesReport продажи за период [from, to) по ценам на момент покупки
+func (s *Service) SalesReport(ctx context.Context, from, to time.Time) (_ []storage.SalesReportLine, err error) {
+	ctx, span := tracing.Start(ctx, "Service.SalesReport")
+	defer func() { tracing.End(span, err) }()
+
+	if !to.After(from) {
+		return nil, apperr.ErrValidation.WithDetail("to must be after from")
+	}
+	report, err := s.Storage.GetSalesReport(ctx, from, to)
+	if err != nil {
+		s.logger(ctx).Error("SalesReport Storage.GetSalesReport error:", zap.Error(err))
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *Service) merchItem(ctx context.Context, merchName string) (*storage.MerchItem, error) {
+	merch, err := s.Storage.GetMerchItems(ctx, merchName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMerchNotFound
+		}
+		s.logger(ctx).Error("merchItem GetMerchItems error:", zap.Error(err))
+		return nil, err
+	}
+	return merch, nil
+}
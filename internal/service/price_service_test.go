@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSetMerchPrice(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 3, Name: "cup", Price: 20}, nil)
+	mockStorage.On("SetMerchPrice", mock.Anything, storage.MerchItem{MerchID: 3, Name: "cup", Price: 25}, mock.Anything).Return(nil)
+
+	price, err := svc.SetMerchPrice(ctx, "cup", 25)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, price.ItemID)
+	assert.Equal(t, 25, price.Price)
+	assert.Nil(t, price.EffectiveTo)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestSetMerchPrice_Rejected(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "unknown").Return((*storage.MerchItem)(nil), sql.ErrNoRows)
+
+	_, err := svc.SetMerchPrice(ctx, "cup", 0)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	_, err = svc.SetMerchPrice(ctx, "unknown", 25)
+	assert.ErrorIs(t, err, ErrMerchNotFound)
+
+	// цена в хранилище не меняется
+	mockStorage.AssertNotCalled(t, "SetMerchPrice", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSalesReport(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	lines := []storage.SalesReportLine{{Item: "cup", UnitPrice: 20, Orders: 1, Quantity: 2, Gross: 40, Total: 40}}
+	mockStorage.On("GetSalesReport", mock.Anything, from, to).Return(lines, nil)
+
+	report, err := svc.SalesReport(ctx, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, lines, report)
+
+	_, err = svc.SalesReport(ctx, to, from)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+}
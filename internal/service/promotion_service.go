@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrPromoInvalid      = apperr.New(apperr.CodePromoInvalid, "promo code is invalid or expired")
+	ErrPromoExhausted    = apperr.New(apperr.CodePromoExhausted, "promo code redemption limit reached")
+	ErrPromotionNotFound = apperr.New(apperr.CodePromoNotFound, "promotion not found")
+	ErrPromotionExists   = apperr.New(apperr.CodePromoExists, "promo code already exists")
+)
+
+var promoCodeRe = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+
+// NormalizePromoCode промокоды не зависят от регистра и пробелов по краям
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CreatePromotion заводит распродажу (без кода) или промокод. Товар задается
+// по имени в promotion.Item, пустое имя - скидка на весь каталог.
+func (s *Service) CreatePromotion(ctx context.Context, promotion storage.Promotion) (_ *storage.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CreatePromotion")
+	defer func() { tracing.End(span, err) }()
+
+	promotion.Code = NormalizePromoCode(promotion.Code)
+	if err := validatePromotion(promotion); err != nil {
+		return nil, err
+	}
+	promotion.ItemID = nil
+	if promotion.Item != "" {
+		merch, err := s.Storage.GetMerchItems(ctx, promotion.Item)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrMerchNotFound
+			}
+			s.logger(ctx).Error("CreatePromotion GetMerchItems error:", zap.Error(err))
+			return nil, err
+		}
+		promotion.ItemID = &merch.MerchID
+	}
+
+	if err := s.Storage.CreatePromotion(ctx, &promotion); err != nil {
+		if errors.Is(err, storage.ErrPromotionCodeTaken) {
+			return nil, ErrPromotionExists
+		}
+		s.logger(ctx).Error("CreatePromotion Storage.CreatePromotion error:", zap.Error(err))
+		return nil, err
+	}
+	return &promotion, nil
+}
+
+func validatePromotion(p storage.Promotion) error {
+	switch {
+	case p.Kind != storage.DiscountPercent && p.Kind != storage.DiscountFixed:
+		return apperr.ErrValidation.WithDetail("kind must be percent or fixed")
+	case p.Value < 1 || p.Kind == storage.DiscountPercent && p.Value > 100:
+		return apperr.ErrValidation.WithDetail("value must be positive, percent at most 100")
+	case !p.EndsAt.After(p.StartsAt):
+		return apperr.ErrValidation.WithDetail("endsAt must be after startsAt")
+	case p.MaxRedemptions < 0 || p.PerUserLimit < 0:
+		return apperr.ErrValidation.WithDetail("redemption limits must not be negative")
+	case p.Code != "" && !promoCodeRe.MatchString(p.Code):
+		return apperr.ErrValidation.WithDetail("code must be 3-32 letters, digits, _ or -")
+	case p.Code == "" && (p.MaxRedemptions > 0 || p.PerUserLimit > 0):
+		return apperr.ErrValidation.WithDetail("redemption limits require a code")
+	}
+	return nil
+}
+
+func (s *Service) ListPromotions(ctx context.Context) (_ []storage.Promotion, err error) {
+	ctx, span := tracing.Start(ctx, "Service.ListPromotions")
+	defer func() { tracing.End(span, err) }()
+
+	promotions, err := s.Storage.ListPromotions(ctx)
+	if err != nil {
+		s.logger(ctx).Error("ListPromotions Storage.ListPromotions error:", zap.Error(err))
+		return nil, err
+	}
+	return promotions, nil
+}
+
+// EndPromotion досрочно завершает скидку, история погашений сохраняется
+func (s *Service) EndPromotion(ctx context.Context, promotionID int64) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.EndPromotion")
+	defer func() { tracing.End(span, err) }()
+
+	err = s.Storage.EndPromotion(ctx, promotionID, time.Now())
+	if err != nil {
+		if errors.Is(err, storage.ErrPromotionNotFound) {
+			return ErrPromotionNotFound
+		}
+		s.logger(ctx).Error("EndPromotion Storage.EndPromotion error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// checkout считает скидку покупки: сначала лучшая из действующих распродаж,
+// затем промокод на оставшуюся сумму. Лимиты промокода окончательно
+// проверяются при погашении в транзакции покупки.
+func (s *Service) checkout(ctx context.Context, merch *storage.MerchItem, quantity int, promoCode string) (*storage.MerchInfo, error) {
+	now := time.Now()
+	info := &storage.MerchInfo{
+		MerchID: merch.MerchID,
+		Name:    merch.Name,
+		Price:   merch.Price,
+		Amount:  quantity,
+	}
+	subtotal := merch.Price * quantity
+
+	sales, err := s.Storage.GetActiveSales(ctx, merch.MerchID, now)
+	if err != nil {
+		s.logger(ctx).Error("checkout GetActiveSales error:", zap.Error(err))
+		return nil, err
+	}
+	for _, sale := range sales {
+		info.Discount = max(info.Discount, sale.Discount(subtotal))
+	}
+
+	promoCode = NormalizePromoCode(promoCode)
+	if promoCode == "" {
+		return info, nil
+	}
+	promotion, err := s.Storage.GetPromotionByCode(ctx, promoCode)
+	if err != nil {
+		if errors.Is(err, storage.ErrPromotionNotFound) {
+			return nil, ErrPromoInvalid
+		}
+		s.logger(ctx).Error("checkout GetPromotionByCode error:", zap.Error(err))
+		return nil, err
+	}
+	if !promotion.Active(merch.MerchID, now) {
+		return nil, ErrPromoInvalid
+	}
+	if promotion.MaxRedemptions > 0 && promotion.Redemptions >= promotion.MaxRedemptions {
+		return nil, ErrPromoExhausted
+	}
+	info.Discount += promotion.Discount(subtotal - info.Discount)
+	info.PromotionID = promotion.ID
+	return info, nil
+}
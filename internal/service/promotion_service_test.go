@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPurchaseMerch_SaleAndPromo(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	itemID := 1
+	now := time.Now()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "hoody").Return(&storage.MerchItem{MerchID: itemID, Name: "hoody", Price: 200}, nil)
+	// из двух распродаж применяется лучшая
+	mockStorage.On("GetActiveSales", mock.Anything, itemID, mock.Anything).Return([]storage.Promotion{
+		{ID: 1, Kind: storage.DiscountFixed, Value: 30},
+		{ID: 2, Kind: storage.DiscountPercent, Value: 20, ItemID: &itemID},
+	}, nil)
+	mockStorage.On("GetPromotionByCode", mock.Anything, "BLACKFRIDAY").Return(&storage.Promotion{
+		ID: 3, Code: "BLACKFRIDAY", Kind: storage.DiscountFixed, Value: 50,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour),
+	}, nil)
+	// 400 - 20% распродажи - 50 по промокоду
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, storage.MerchInfo{
+		MerchID: itemID, Name: "hoody", Price: 200, Amount: 2, Discount: 130, PromotionID: 3,
+	}).Return(nil)
+
+	err := svc.PurchaseMerch(ctx, userID, "hoody", VariantChoice{}, 2, " blackfriday ")
+	assert.NoError(t, err)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestPurchaseMerch_PromoRejected(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	itemID, otherItemID := 1, 2
+	now := time.Now()
+
+	cases := []struct {
+		name      string
+		promotion *storage.Promotion
+		lookupErr error
+		purchase  error
+		want      error
+	}{
+		{name: "unknown", lookupErr: storage.ErrPromotionNotFound, want: ErrPromoInvalid},
+		{name: "expired", promotion: &storage.Promotion{ID: 1, Kind: storage.DiscountFixed, Value: 10,
+			StartsAt: now.Add(-2 * time.Hour), EndsAt: now.Add(-time.Hour)}, want: ErrPromoInvalid},
+		{name: "other item", promotion: &storage.Promotion{ID: 1, Kind: storage.DiscountFixed, Value: 10, ItemID: &otherItemID,
+			StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}, want: ErrPromoInvalid},
+		{name: "single use redeemed", promotion: &storage.Promotion{ID: 1, Kind: storage.DiscountFixed, Value: 10,
+			MaxRedemptions: 1, Redemptions: 1, StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}, want: ErrPromoExhausted},
+		// лимит на пользователя проверяется при погашении
+		{name: "per user limit", promotion: &storage.Promotion{ID: 1, Kind: storage.DiscountFixed, Value: 10, PerUserLimit: 1,
+			StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}, purchase: storage.ErrPromoExhausted, want: ErrPromoExhausted},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			svc := Service{Storage: mockStorage, log: newTestLogger()}
+
+			mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: itemID, Name: "cup", Price: 20}, nil)
+			mockStorage.On("GetActiveSales", mock.Anything, itemID, mock.Anything).Return([]storage.Promotion(nil), nil)
+			if tc.lookupErr != nil {
+				mockStorage.On("GetPromotionByCode", mock.Anything, "CODE").Return(nil, tc.lookupErr)
+			} else {
+				mockStorage.On("GetPromotionByCode", mock.Anything, "CODE").Return(tc.promotion, nil)
+			}
+			mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(tc.purchase)
+
+			err := svc.PurchaseMerch(ctx, userID, "cup", VariantChoice{}, 1, "code")
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestCreatePromotion(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	now := time.Now()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "hoody").Return(&storage.MerchItem{MerchID: 6, Name: "hoody", Price: 300}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "yacht").Return((*storage.MerchItem)(nil), sql.ErrNoRows)
+	mockStorage.On("CreatePromotion", mock.Anything, mock.MatchedBy(func(p *storage.Promotion) bool {
+		return p.Code == "SWAG-10"
+	})).Return(nil)
+	mockStorage.On("CreatePromotion", mock.Anything, mock.MatchedBy(func(p *storage.Promotion) bool {
+		return p.Code == "TAKEN"
+	})).Return(storage.ErrPromotionCodeTaken)
+
+	promotion, err := svc.CreatePromotion(ctx, storage.Promotion{Code: "swag-10", Kind: storage.DiscountPercent, Value: 10,
+		Item: "hoody", StartsAt: now, EndsAt: now.Add(time.Hour), MaxRedemptions: 100, PerUserLimit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "SWAG-10", promotion.Code)
+	assert.Equal(t, 6, *promotion.ItemID)
+
+	_, err = svc.CreatePromotion(ctx, storage.Promotion{Code: "TAKEN", Kind: storage.DiscountFixed, Value: 10,
+		StartsAt: now, EndsAt: now.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrPromotionExists)
+
+	_, err = svc.CreatePromotion(ctx, storage.Promotion{Kind: storage.DiscountFixed, Value: 10, Item: "yacht",
+		StartsAt: now, EndsAt: now.Add(time.Hour)})
+	assert.ErrorIs(t, err, ErrMerchNotFound)
+
+	invalid := []storage.Promotion{
+		{Kind: "gift", Value: 10, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{Kind: storage.DiscountPercent, Value: 101, StartsAt: now, EndsAt: now.Add(time.Hour)},
+		{Kind: storage.DiscountFixed, Value: 10, StartsAt: now, EndsAt: now},
+		{Kind: storage.DiscountFixed, Value: 10, StartsAt: now, EndsAt: now.Add(time.Hour), Code: "a b"},
+		// у распродажи нет лимитов погашений
+		{Kind: storage.DiscountFixed, Value: 10, StartsAt: now, EndsAt: now.Add(time.Hour), PerUserLimit: 1},
+	}
+	for _, p := range invalid {
+		_, err = svc.CreatePromotion(ctx, p)
+		assert.ErrorIs(t, err, apperr.ErrValidation)
+	}
+}
+
+func TestPromotionDiscount(t *testing.T) {
+	percent := storage.Promotion{Kind: storage.DiscountPercent, Value: 15}
+	fixed := storage.Promotion{Kind: storage.DiscountFixed, Value: 50}
+
+	assert.Equal(t, 30, percent.Discount(200))
+	assert.Equal(t, 1, percent.Discount(10))
+	assert.Equal(t, 50, fixed.Discount(200))
+	// скидка не больше суммы покупки
+	assert.Equal(t, 20, fixed.Discount(20))
+}
@@ -2,38 +2,103 @@ package service
 
 import (
 	"context"
-	"errors"
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/logging"
 	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"time"
 )
 
 type StorageInterface interface {
 	GetUser4UserID(ctx context.Context, userID uuid.UUID) (*storage.Employee, error)
 	GetUserAuthData(ctx context.Context, username string) (*storage.AuthData, error)
-	NewUser(ctx context.Context, username string, passwordHash string) (uuid.UUID, error)
+	NewUser(ctx context.Context, username string, passwordHash string, onboarding *storage.Onboarding) (uuid.UUID, error)
 	GetBalance(ctx context.Context, userID uuid.UUID) (int, error)
 	GetInventories(ctx context.Context, userID uuid.UUID) ([]storage.InventoryItem, error)
 	GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]storage.SenderInfo, error)
 	GetSendedCoins(ctx context.Context, userID uuid.UUID) ([]storage.SenderInfo, error)
-	SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int) error
+	GetWalletInfo(ctx context.Context, userID uuid.UUID) (*storage.WalletInfo, error)
+	SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int, period time.Time) error
 	PurchaseMerchTransaction(ctx context.Context, userID uuid.UUID, merch storage.MerchInfo) error
 	FindUser(ctx context.Context, username string) (*storage.Employee, error)
 	GetMerchItems(ctx context.Context, merchName string) (*storage.MerchItem, error)
+	GetOrders(ctx context.Context, userID uuid.UUID) ([]storage.Order, error)
+	ListOrders(ctx context.Context, status string, limit int) ([]storage.Order, error)
+	GetOrder(ctx context.Context, orderID int64) (*storage.Order, error)
+	SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string, from []string) error
+	UpdateOrderStatus(ctx context.Context, update storage.OrderStatusUpdate) (*storage.Order, error)
+	CreatePromotion(ctx context.Context, promotion *storage.Promotion) error
+	ListPromotions(ctx context.Context) ([]storage.Promotion, error)
+	GetPromotionByCode(ctx context.Context, code string) (*storage.Promotion, error)
+	GetActiveSales(ctx context.Context, itemID int, at time.Time) ([]storage.Promotion, error)
+	EndPromotion(ctx context.Context, promotionID int64, at time.Time) error
+	SetMerchPrice(ctx context.Context, item storage.MerchItem, at time.Time) error
+	GetPriceHistory(ctx context.Context, itemID int) ([]storage.MerchPrice, error)
+	GetSalesReport(ctx context.Context, from, to time.Time) ([]storage.SalesReportLine, error)
+	CreateVariant(ctx context.Context, variant *storage.MerchVariant) error
+	UpdateVariant(ctx context.Context, variant storage.MerchVariant) error
+	TransferItemsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, transfer storage.ItemTransfer) error
+	GetInventoryLedger(ctx context.Context, userID uuid.UUID, limit int) ([]storage.LedgerEntry, error)
+	SaveWish(ctx context.Context, wish *storage.WishlistItem) error
+	GetWishlist(ctx context.Context, userID uuid.UUID) ([]storage.WishlistItem, error)
+	DeleteWish(ctx context.Context, userID uuid.UUID, itemID int) error
+	ContributeTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, contribution storage.Contribution) error
+	CreateTeam(ctx context.Context, team *storage.Team) error
+	GetTeam(ctx context.Context, teamID int64) (*storage.Team, error)
+	GetTeams(ctx context.Context, userID uuid.UUID) ([]storage.Team, error)
+	SetTeamMember(ctx context.Context, teamID int64, userID uuid.UUID, role string) error
+	RemoveTeamMember(ctx context.Context, teamID int64, userID uuid.UUID) error
+	DepositTeamCoins(ctx context.Context, teamID int64, actorID uuid.UUID, amount int) error
+	DistributeTeamCoins(ctx context.Context, teamID int64, managerID uuid.UUID, receiverID uuid.UUID, amount int) error
+	GetTeamHistory(ctx context.Context, teamID int64, limit int) ([]storage.TeamEntry, error)
+	SetBudget(ctx context.Context, userID uuid.UUID, limit int, period time.Time) error
+	GetBudget(ctx context.Context, userID uuid.UUID, period time.Time) (*storage.Budget, error)
+	CreateTenant(ctx context.Context, tenant *storage.Tenant, catalog []storage.MerchItem) error
+	GetTenant(ctx context.Context, tenantID int64) (*storage.Tenant, error)
+	GetTenantBySlug(ctx context.Context, slug string) (*storage.Tenant, error)
+	ListTenants(ctx context.Context) ([]storage.Tenant, error)
+	UpdateTenant(ctx context.Context, tenant storage.Tenant, admins []string) ([]uuid.UUID, error)
+	VestSignupBonuses(ctx context.Context, now time.Time, limit int) ([]storage.Onboarding, error)
 }
 
 var (
-	ErrGenerateJWT          = errors.New("token generate error")
-	ErrGeneratePasswordHash = errors.New("password hash generate error")
-	ErrInvelidPassword      = errors.New("invalid password")
-	ErrUserNotFound         = errors.New("user not found")
-	ErrMerchNotFound        = errors.New("merch not found")
-	ErrNotEnoughCoins       = errors.New("not enough coins on balance")
+	ErrGenerateJWT          = apperr.New(apperr.CodeInternal, "token generate error")
+	ErrGeneratePasswordHash = apperr.New(apperr.CodeInternal, "password hash generate error")
+	ErrInvelidPassword      = apperr.New(apperr.CodeInvalidPassword, "invalid password")
+	ErrUserNotFound         = apperr.New(apperr.CodeUserNotFound, "user not found")
+	ErrMerchNotFound        = apperr.New(apperr.CodeMerchNotFound, "merch not found")
+	ErrNotEnoughCoins       = apperr.New(apperr.CodeNotEnoughCoins, "not enough coins on balance")
+	ErrVariantNotFound      = apperr.New(apperr.CodeVariantNotFound, "merch variant not found")
+	ErrVariantRequired      = apperr.New(apperr.CodeVariantRequired, "merch has variants, choose sku or size and color")
+	ErrVariantExists        = apperr.New(apperr.CodeVariantExists, "merch variant already exists")
+	ErrOutOfStock           = apperr.New(apperr.CodeOutOfStock, "merch variant is out of stock")
+	ErrNotEnoughItems       = apperr.New(apperr.CodeNotEnoughItems, "not enough items in inventory")
+	ErrWishNotFound         = apperr.New(apperr.CodeWishNotFound, "item is not in the wishlist")
+	ErrTeamNotFound         = apperr.New(apperr.CodeTeamNotFound, "team not found")
+	ErrTeamExists           = apperr.New(apperr.CodeTeamExists, "team with this name already exists")
+	ErrNotTeamMember        = apperr.New(apperr.CodeNotTeamMember, "employee is not a team member")
+	ErrTenantNotFound       = apperr.New(apperr.CodeTenantNotFound, "tenant not found")
+	ErrTenantExists         = apperr.New(apperr.CodeTenantExists, "tenant with this slug already exists")
+	ErrUserExists           = apperr.New(apperr.CodeUserExists, "user with this username already exists")
+	// ErrAdminSignup имя из списка администраторов нельзя занять входом с любым паролем
+	ErrAdminSignup = apperr.ErrForbidden.WithDetail("admin accounts are created by an administrator")
 )
 
 type Service struct {
 	Storage StorageInterface
-	log     *zap.Logger
+	// Notifier получает уведомления о завершенных операциях, nil - уведомления выключены
+	Notifier Notifier
+	// LowBalanceThreshold порог уведомления balance.low, 0 - не уведомлять
+	LowBalanceThreshold int
+	// BudgetPeriod период восполнения бюджета на благодарности: BudgetPeriodMonth (по умолчанию) или BudgetPeriodWeek.
+	// Компании, кроме компании по умолчанию, могут задать свой период в настройках.
+	BudgetPeriod string
+	// Admins администраторы компании по умолчанию. Их учетные записи не создаются входом,
+	// только ProvisionAdmins или администратором через CreateUser.
+	Admins []string
+	log    *zap.Logger
 }
 
 func New(storage StorageInterface, zapLogger *zap.Logger) *Service {
@@ -42,3 +107,7 @@ func New(storage StorageInterface, zapLogger *zap.Logger) *Service {
 		log:     zapLogger,
 	}
 }
+
+func (s *Service) logger(ctx context.Context) *zap.Logger {
+	return tracing.Logger(ctx, logging.FromContext(ctx, s.log))
+}
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/logging"
 	"github.com/Vic07Region/avito-shop/internal/storage"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 type MockStorage struct {
@@ -27,8 +33,9 @@ func (m *MockStorage) GetUserAuthData(ctx context.Context, username string) (*st
 	return args.Get(0).(*storage.AuthData), args.Error(1)
 }
 
-func (m *MockStorage) NewUser(ctx context.Context, username string, passwordHash string) (uuid.UUID, error) {
-	args := m.Called(ctx, username, passwordHash)
+func (m *MockStorage) NewUser(ctx context.Context, username string, passwordHash string,
+	onboarding *storage.Onboarding) (uuid.UUID, error) {
+	args := m.Called(ctx, username, passwordHash, onboarding)
 	if args.Get(0) == nil {
 		return uuid.Nil, args.Error(1)
 	}
@@ -55,8 +62,17 @@ func (m *MockStorage) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([
 	return args.Get(0).([]storage.SenderInfo), args.Error(1)
 }
 
-func (m *MockStorage) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int) error {
-	args := m.Called(ctx, senderID, receiverID, amount)
+func (m *MockStorage) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*storage.WalletInfo, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.WalletInfo), args.Error(1)
+}
+
+func (m *MockStorage) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int,
+	period time.Time) error {
+	args := m.Called(ctx, senderID, receiverID, amount, period)
 	return args.Error(0)
 }
 
@@ -70,8 +86,236 @@ func (m *MockStorage) GetMerchItems(ctx context.Context, merchName string) (*sto
 	return args.Get(0).(*storage.MerchItem), args.Error(1)
 }
 
+func (m *MockStorage) GetOrders(ctx context.Context, userID uuid.UUID) ([]storage.Order, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]storage.Order), args.Error(1)
+}
+
+func (m *MockStorage) ListOrders(ctx context.Context, status string, limit int) ([]storage.Order, error) {
+	args := m.Called(ctx, status, limit)
+	return args.Get(0).([]storage.Order), args.Error(1)
+}
+
+func (m *MockStorage) GetOrder(ctx context.Context, orderID int64) (*storage.Order, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Order), args.Error(1)
+}
+
+func (m *MockStorage) SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string, from []string) error {
+	args := m.Called(ctx, userID, orderID, address, from)
+	return args.Error(0)
+}
+
+func (m *MockStorage) CreatePromotion(ctx context.Context, promotion *storage.Promotion) error {
+	args := m.Called(ctx, promotion)
+	return args.Error(0)
+}
+
+func (m *MockStorage) ListPromotions(ctx context.Context) ([]storage.Promotion, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storage.Promotion), args.Error(1)
+}
+
+func (m *MockStorage) GetPromotionByCode(ctx context.Context, code string) (*storage.Promotion, error) {
+	args := m.Called(ctx, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Promotion), args.Error(1)
+}
+
+func (m *MockStorage) GetActiveSales(ctx context.Context, itemID int, at time.Time) ([]storage.Promotion, error) {
+	args := m.Called(ctx, itemID, at)
+	return args.Get(0).([]storage.Promotion), args.Error(1)
+}
+
+func (m *MockStorage) EndPromotion(ctx context.Context, promotionID int64, at time.Time) error {
+	args := m.Called(ctx, promotionID, at)
+	return args.Error(0)
+}
+
+func (m *MockStorage) SetMerchPrice(ctx context.Context, item storage.MerchItem, at time.Time) error {
+	args := m.Called(ctx, item, at)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetPriceHistory(ctx context.Context, itemID int) ([]storage.MerchPrice, error) {
+	args := m.Called(ctx, itemID)
+	return args.Get(0).([]storage.MerchPrice), args.Error(1)
+}
+
+func (m *MockStorage) GetSalesReport(ctx context.Context, from, to time.Time) ([]storage.SalesReportLine, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).([]storage.SalesReportLine), args.Error(1)
+}
+
+func (m *MockStorage) CreateVariant(ctx context.Context, variant *storage.MerchVariant) error {
+	args := m.Called(ctx, variant)
+	return args.Error(0)
+}
+
+func (m *MockStorage) UpdateVariant(ctx context.Context, variant storage.MerchVariant) error {
+	args := m.Called(ctx, variant)
+	return args.Error(0)
+}
+
+func (m *MockStorage) TransferItemsTransaction(ctx context.Context, senderID, receiverID uuid.UUID,
+	transfer storage.ItemTransfer,
+) error {
+	args := m.Called(ctx, senderID, receiverID, transfer)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetInventoryLedger(ctx context.Context, userID uuid.UUID, limit int) ([]storage.LedgerEntry, error) {
+	args := m.Called(ctx, userID, limit)
+	return args.Get(0).([]storage.LedgerEntry), args.Error(1)
+}
+
+func (m *MockStorage) SaveWish(ctx context.Context, wish *storage.WishlistItem) error {
+	args := m.Called(ctx, wish)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetWishlist(ctx context.Context, userID uuid.UUID) ([]storage.WishlistItem, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]storage.WishlistItem), args.Error(1)
+}
+
+func (m *MockStorage) DeleteWish(ctx context.Context, userID uuid.UUID, itemID int) error {
+	args := m.Called(ctx, userID, itemID)
+	return args.Error(0)
+}
+
+func (m *MockStorage) ContributeTransaction(ctx context.Context, senderID, receiverID uuid.UUID,
+	contribution storage.Contribution,
+) error {
+	args := m.Called(ctx, senderID, receiverID, contribution)
+	return args.Error(0)
+}
+
+func (m *MockStorage) CreateTeam(ctx context.Context, team *storage.Team) error {
+	args := m.Called(ctx, team)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetTeam(ctx context.Context, teamID int64) (*storage.Team, error) {
+	args := m.Called(ctx, teamID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Team), args.Error(1)
+}
+
+func (m *MockStorage) GetTeams(ctx context.Context, userID uuid.UUID) ([]storage.Team, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]storage.Team), args.Error(1)
+}
+
+func (m *MockStorage) SetTeamMember(ctx context.Context, teamID int64, userID uuid.UUID, role string) error {
+	args := m.Called(ctx, teamID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockStorage) RemoveTeamMember(ctx context.Context, teamID int64, userID uuid.UUID) error {
+	args := m.Called(ctx, teamID, userID)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DepositTeamCoins(ctx context.Context, teamID int64, actorID uuid.UUID, amount int) error {
+	args := m.Called(ctx, teamID, actorID, amount)
+	return args.Error(0)
+}
+
+func (m *MockStorage) DistributeTeamCoins(ctx context.Context, teamID int64, managerID, receiverID uuid.UUID,
+	amount int,
+) error {
+	args := m.Called(ctx, teamID, managerID, receiverID, amount)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetTeamHistory(ctx context.Context, teamID int64, limit int) ([]storage.TeamEntry, error) {
+	args := m.Called(ctx, teamID, limit)
+	return args.Get(0).([]storage.TeamEntry), args.Error(1)
+}
+
+func (m *MockStorage) SetBudget(ctx context.Context, userID uuid.UUID, limit int, period time.Time) error {
+	args := m.Called(ctx, userID, limit, period)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetBudget(ctx context.Context, userID uuid.UUID, period time.Time) (*storage.Budget, error) {
+	args := m.Called(ctx, userID, period)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Budget), args.Error(1)
+}
+
+func (m *MockStorage) CreateTenant(ctx context.Context, tenant *storage.Tenant, catalog []storage.MerchItem) error {
+	args := m.Called(ctx, tenant, catalog)
+	return args.Error(0)
+}
+
+func (m *MockStorage) GetTenant(ctx context.Context, tenantID int64) (*storage.Tenant, error) {
+	args := m.Called(ctx, tenantID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Tenant), args.Error(1)
+}
+
+func (m *MockStorage) VestSignupBonuses(ctx context.Context, now time.Time, limit int) ([]storage.Onboarding, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]storage.Onboarding), args.Error(1)
+}
+
+func (m *MockStorage) GetTenantBySlug(ctx context.Context, slug string) (*storage.Tenant, error) {
+	args := m.Called(ctx, slug)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Tenant), args.Error(1)
+}
+
+func (m *MockStorage) ListTenants(ctx context.Context) ([]storage.Tenant, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]storage.Tenant), args.Error(1)
+}
+
+func (m *MockStorage) UpdateTenant(ctx context.Context, tenant storage.Tenant, admins []string) ([]uuid.UUID, error) {
+	args := m.Called(ctx, tenant, admins)
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockStorage) UpdateOrderStatus(ctx context.Context, update storage.OrderStatusUpdate) (*storage.Order, error) {
+	args := m.Called(ctx, update)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*storage.Order), args.Error(1)
+}
+
 // Инициализируем моковый логгер
 func newTestLogger() *zap.Logger {
 	logger, _ := zap.NewDevelopment()
 	return logger
 }
+
+func TestLoggerFromContext(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	ctx := logging.WithLogger(context.Background(), zap.New(core).With(zap.String("request_id", "req-1")))
+	svc := Service{log: newTestLogger()}
+
+	svc.logger(ctx).Error("test message")
+
+	entries := logs.FilterMessage("test message").All()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "req-1", entries[0].ContextMap()["request_id"])
+	}
+}
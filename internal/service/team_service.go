@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// TeamHistoryLimit сколько последних записей истории командного кошелька отдается участнику
+const TeamHistoryLimit = 100
+
+// CreateTeam создает команду с пустым кошельком
+func (s *Service) CreateTeam(ctx context.Context, name string) (_ *storage.Team, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateTeam")
+	defer func() { tracing.End(span, err) }()
+
+	team := storage.Team{Name: strings.TrimSpace(name)}
+	if team.Name == "" {
+		return nil, apperr.ErrValidation.WithDetail("team name must not be empty")
+	}
+	if err = s.Storage.CreateTeam(ctx, &team); err != nil {
+		if errors.Is(err, storage.ErrTeamTaken) {
+			return nil, ErrTeamExists
+		}
+		s.logger(ctx).Error("CreateTeam Storage.CreateTeam error:", zap.Error(err))
+		return nil, err
+	}
+	return &team, nil
+}
+
+// SetTeamMember добавляет сотрудника в команду или меняет его роль, возвращает обновленную команду
+func (s *Service) SetTeamMember(ctx context.Context, teamID int64, username string, role string) (_ *storage.Team,
+	err error) {
+	ctx, span := tracing.Start(ctx, "Service.SetTeamMember")
+	defer func() { tracing.End(span, err) }()
+
+	if role != storage.TeamRoleMember && role != storage.TeamRoleManager {
+		return nil, apperr.ErrValidation.WithDetail("role must be member or manager")
+	}
+	user, err := s.Storage.FindUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		s.logger(ctx).Error("SetTeamMember FindUser error:", zap.Error(err))
+		return nil, err
+	}
+	if _, err = s.team(ctx, teamID); err != nil {
+		return nil, err
+	}
+	if err = s.Storage.SetTeamMember(ctx, teamID, user.EmployeeId, role); err != nil {
+		s.logger(ctx).Error("SetTeamMember Storage.SetTeamMember error:", zap.Error(err))
+		return nil, err
+	}
+	return s.team(ctx, teamID)
+}
+
+func (s *Service) RemoveTeamMember(ctx context.Context, teamID int64, username string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.RemoveTeamMember")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.Storage.FindUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.logger(ctx).Error("RemoveTeamMember FindUser error:", zap.Error(err))
+		return err
+	}
+	if _, err = s.team(ctx, teamID); err != nil {
+		return err
+	}
+	if err = s.Storage.RemoveTeamMember(ctx, teamID, user.EmployeeId); err != nil {
+		if errors.Is(err, storage.ErrNotTeamMember) {
+			return ErrNotTeamMember
+		}
+		s.logger(ctx).Error("RemoveTeamMember Storage.RemoveTeamMember error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DepositTeamCoins переводит монеты с баланса администратора в кошелек команды.
+// Так команда получает бюджет вместо личного бонуса менеджеру.
+func (s *Service) DepositTeamCoins(ctx context.Context, adminID uuid.UUID, teamID int64, amount int) (_ *storage.Team,
+	err error) {
+	ctx, span := tracing.Start(ctx, "Service.DepositTeamCoins")
+	defer func() { tracing.End(span, err) }()
+
+	if amount <= 0 {
+		return nil, apperr.ErrValidation.WithDetail("amount must be positive")
+	}
+	if err = s.Storage.DepositTeamCoins(ctx, teamID, adminID, amount); err != nil {
+		if errors.Is(err, storage.ErrTeamNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		if errors.Is(err, storage.ErrNotEnoughCoins) {
+			return nil, ErrNotEnoughCoins
+		}
+		s.logger(ctx).Error("DepositTeamCoins Storage.DepositTeamCoins error:", zap.Error(err))
+		return nil, err
+	}
+	return s.team(ctx, teamID)
+}
+
+// Teams команды пользователя
+func (s *Service) Teams(ctx context.Context, userID uuid.UUID) (_ []storage.Team, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Teams")
+	defer func() { tracing.End(span, err) }()
+
+	teams, err := s.Storage.GetTeams(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("Teams GetTeams error:", zap.Error(err))
+		return nil, err
+	}
+	return teams, nil
+}
+
+// Team команда, в которой состоит пользователь. Чужая команда неотличима от несуществующей.
+func (s *Service) Team(ctx context.Context, userID uuid.UUID, teamID int64) (_ *storage.Team, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Team")
+	defer func() { tracing.End(span, err) }()
+
+	team, _, err := s.teamMember(ctx, userID, teamID)
+	return team, err
+}
+
+// TeamHistory история кошелька команды для ее участника, новые записи первыми
+func (s *Service) TeamHistory(ctx context.Context, userID uuid.UUID, teamID int64) (_ []storage.TeamEntry, err error) {
+	ctx, span := tracing.Start(ctx, "Service.TeamHistory")
+	defer func() { tracing.End(span, err) }()
+
+	if _, _, err = s.teamMember(ctx, userID, teamID); err != nil {
+		return nil, err
+	}
+	entries, err := s.Storage.GetTeamHistory(ctx, teamID, TeamHistoryLimit)
+	if err != nil {
+		s.logger(ctx).Error("TeamHistory GetTeamHistory error:", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// DistributeTeamCoins менеджер переводит монеты из пула команды на баланс участника
+func (s *Service) DistributeTeamCoins(ctx context.Context, userID uuid.UUID, teamID int64, toUsername string,
+	amount int) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.DistributeTeamCoins")
+	defer func() { tracing.End(span, err) }()
+
+	if amount <= 0 {
+		return apperr.ErrValidation.WithDetail("amount must be positive")
+	}
+	team, err := s.teamManager(ctx, userID, teamID)
+	if err != nil {
+		return err
+	}
+	var receiver *storage.TeamMember
+	for i, m := range team.Members {
+		if strings.EqualFold(m.Username, toUsername) {
+			receiver = &team.Members[i]
+		}
+	}
+	if receiver == nil {
+		return ErrNotTeamMember
+	}
+
+	err = s.Storage.DistributeTeamCoins(ctx, teamID, userID, receiver.EmployeeID, amount)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrTeamNotFound):
+			return ErrTeamNotFound
+		case errors.Is(err, storage.ErrNotEnoughCoins):
+			metrics.InsufficientFunds.WithLabelValues(metrics.OperationDistribute).Inc()
+			return ErrNotEnoughCoins
+		}
+		s.logger(ctx).Error("DistributeTeamCoins Storage.DistributeTeamCoins error:", zap.Error(err))
+		return err
+	}
+
+	s.notifyTeamCoins(ctx, userID, team, receiver, amount)
+	return nil
+}
+
+// BuyForTeam менеджер покупает товар на команду: монеты списываются с кошелька
+// команды, товар и заказ достаются менеджеру
+func (s *Service) BuyForTeam(ctx context.Context, userID uuid.UUID, teamID int64, merchName string,
+	choice VariantChoice, quantity int, promoCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.BuyForTeam")
+	defer func() { tracing.End(span, err) }()
+
+	if quantity <= 0 {
+		return apperr.ErrValidation.WithDetail("quantity must be positive")
+	}
+	team, err := s.teamManager(ctx, userID, teamID)
+	if err != nil {
+		return err
+	}
+	return s.purchase(ctx, userID, merchName, choice, quantity, promoCode, nil, "", team)
+}
+
+func (s *Service) team(ctx context.Context, teamID int64) (*storage.Team, error) {
+	team, err := s.Storage.GetTeam(ctx, teamID)
+	if err != nil {
+		if errors.Is(err, storage.ErrTeamNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		s.logger(ctx).Error("team GetTeam error:", zap.Error(err))
+		return nil, err
+	}
+	return team, nil
+}
+
+// teamMember команда и участие в ней пользователя
+func (s *Service) teamMember(ctx context.Context, userID uuid.UUID, teamID int64) (*storage.Team, *storage.TeamMember,
+	error) {
+	team, err := s.team(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member := team.Member(userID)
+	if member == nil {
+		return nil, nil, ErrTeamNotFound
+	}
+	return team, member, nil
+}
+
+// teamManager команда, которой пользователь управляет
+func (s *Service) teamManager(ctx context.Context, userID uuid.UUID, teamID int64) (*storage.Team, error) {
+	team, member, err := s.teamMember(ctx, userID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != storage.TeamRoleManager {
+		return nil, apperr.ErrForbidden.WithDetail("only team managers can spend the team balance")
+	}
+	return team, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testTeam(managerID, memberID uuid.UUID) *storage.Team {
+	return &storage.Team{ID: 3, Name: "backend", Balance: 500, Members: []storage.TeamMember{
+		{EmployeeID: managerID, Username: "alice", Role: storage.TeamRoleManager},
+		{EmployeeID: memberID, Username: "bob", Role: storage.TeamRoleMember},
+	}}
+}
+
+func TestDistributeTeamCoins(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	managerID := uuid.New()
+	memberID := uuid.New()
+
+	mockStorage.On("GetTeam", mock.Anything, int64(3)).Return(testTeam(managerID, memberID), nil)
+	mockStorage.On("GetTeam", mock.Anything, int64(4)).Return((*storage.Team)(nil), storage.ErrTeamNotFound)
+	mockStorage.On("DistributeTeamCoins", mock.Anything, int64(3), managerID, memberID, 50).Return(nil).Once()
+	mockStorage.On("DistributeTeamCoins", mock.Anything, int64(3), managerID, memberID, 5000).
+		Return(storage.ErrNotEnoughCoins).Once()
+	mockStorage.On("GetBalance", mock.Anything, memberID).Return(1050, nil)
+	notifier.On("Notify", mock.Anything, events.TypeTeamCoinsReceived, events.TeamCoinsReceived{
+		TeamID: 3, Team: "backend", ManagerID: managerID, Manager: "alice", ReceiverID: memberID, Receiver: "bob",
+		Amount: 50,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: memberID, Balance: 1050, Delta: 50,
+	}).Once()
+
+	// имя получателя сравнивается без учета регистра
+	assert.NoError(t, svc.DistributeTeamCoins(ctx, managerID, 3, "Bob", 50))
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, managerID, 3, "bob", 5000), ErrNotEnoughCoins)
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, managerID, 3, "carol", 50), ErrNotTeamMember)
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, managerID, 3, "bob", 0), apperr.ErrValidation)
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, managerID, 4, "bob", 50), ErrTeamNotFound)
+	// участник без роли менеджера не распоряжается пулом, чужак не видит команду
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, memberID, 3, "alice", 50), apperr.ErrForbidden)
+	assert.ErrorIs(t, svc.DistributeTeamCoins(ctx, uuid.New(), 3, "bob", 50), ErrTeamNotFound)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
+
+func TestDepositTeamCoins(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	adminID := uuid.New()
+
+	mockStorage.On("DepositTeamCoins", mock.Anything, int64(3), adminID, 100).Return(nil).Once()
+	mockStorage.On("DepositTeamCoins", mock.Anything, int64(3), adminID, 5000).Return(storage.ErrNotEnoughCoins).Once()
+	mockStorage.On("DepositTeamCoins", mock.Anything, int64(4), adminID, 100).Return(storage.ErrTeamNotFound).Once()
+	mockStorage.On("GetTeam", mock.Anything, int64(3)).Return(testTeam(uuid.New(), uuid.New()), nil)
+
+	team, err := svc.DepositTeamCoins(ctx, adminID, 3, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), team.ID)
+	// пополнение идет с баланса администратора, монеты не создаются
+	_, err = svc.DepositTeamCoins(ctx, adminID, 3, 5000)
+	assert.ErrorIs(t, err, ErrNotEnoughCoins)
+	_, err = svc.DepositTeamCoins(ctx, adminID, 4, 100)
+	assert.ErrorIs(t, err, ErrTeamNotFound)
+	_, err = svc.DepositTeamCoins(ctx, adminID, 3, 0)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestBuyForTeam(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	managerID := uuid.New()
+	memberID := uuid.New()
+
+	mockStorage.On("GetTeam", mock.Anything, int64(3)).Return(testTeam(managerID, memberID), nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, managerID, storage.MerchInfo{
+		MerchID: 2, Name: "cup", Price: 20, Amount: 3, TeamID: 3,
+	}).Return(nil).Once()
+	// личный баланс менеджера не меняется, balance.changed не отправляется
+	notifier.On("Notify", mock.Anything, events.TypePurchaseMade, events.PurchaseMade{
+		UserID: managerID, Item: "cup", Quantity: 3, Total: 60, Team: "backend",
+	}).Once()
+
+	assert.NoError(t, svc.BuyForTeam(ctx, managerID, 3, "cup", VariantChoice{}, 3, ""))
+	assert.ErrorIs(t, svc.BuyForTeam(ctx, memberID, 3, "cup", VariantChoice{}, 1, ""), apperr.ErrForbidden)
+	assert.ErrorIs(t, svc.BuyForTeam(ctx, managerID, 3, "cup", VariantChoice{}, 0, ""), apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
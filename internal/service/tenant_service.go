@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// DefaultSignupBonus бонус новому сотруднику, если компания не задала свой
+const DefaultSignupBonus = 1000
+
+// MaxBonusVestingDays на сколько дней можно отложить бонус за регистрацию
+const MaxBonusVestingDays = 365
+
+// tenantSlug короткое имя компании, с ним сотрудники входят в сервис
+var tenantSlug = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// CreateTenant создает компанию. Пустой catalog - копия каталога компании по умолчанию.
+// Для администраторов компании создаются учетные записи с паролем adminPassword, иначе в новой
+// компании некому было бы войти в /api/admin. Компании создают только администраторы компании по умолчанию.
+func (s *Service) CreateTenant(ctx context.Context, tenant storage.Tenant, catalog []storage.MerchItem,
+	adminPassword string) (_ *storage.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateTenant")
+	defer func() { tracing.End(span, err) }()
+
+	if storage.TenantID(ctx) != storage.DefaultTenantID {
+		return nil, apperr.ErrForbidden
+	}
+	if !tenantSlug.MatchString(tenant.Slug) {
+		return nil, apperr.ErrValidation.WithDetail("slug must be 1-32 lowercase letters, digits or hyphens")
+	}
+	if err = validateTenant(&tenant); err != nil {
+		return nil, err
+	}
+	if len(tenant.Admins) == 0 || adminPassword == "" {
+		return nil, apperr.ErrValidation.WithDetail("new tenant needs admins and adminPassword")
+	}
+	names := make(map[string]bool, len(catalog))
+	for _, item := range catalog {
+		if item.Name == "" || item.Price <= 0 {
+			return nil, apperr.ErrValidation.WithDetail("catalog item must have a name and a positive price")
+		}
+		if names[item.Name] {
+			return nil, apperr.ErrValidation.WithDetail("duplicate catalog item " + item.Name)
+		}
+		names[item.Name] = true
+	}
+	if len(catalog) == 0 {
+		catalog = nil
+	}
+
+	if err = s.Storage.CreateTenant(ctx, &tenant, catalog); err != nil {
+		if errors.Is(err, storage.ErrTenantTaken) {
+			return nil, ErrTenantExists
+		}
+		s.logger(ctx).Error("CreateTenant Storage.CreateTenant error:", zap.Error(err))
+		return nil, err
+	}
+	tenantCtx := storage.WithTenant(ctx, tenant.ID)
+	for _, admin := range tenant.Admins {
+		if _, err = s.CreateUser(tenantCtx, admin, adminPassword); err != nil {
+			return nil, err
+		}
+	}
+	return &tenant, nil
+}
+
+// ListTenants все компании, доступно администраторам компании по умолчанию
+func (s *Service) ListTenants(ctx context.Context) ([]storage.Tenant, error) {
+	if storage.TenantID(ctx) != storage.DefaultTenantID {
+		return nil, apperr.ErrForbidden
+	}
+	tenants, err := s.Storage.ListTenants(ctx)
+	if err != nil {
+		s.logger(ctx).Error("ListTenants Storage.ListTenants error:", zap.Error(err))
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// Tenant настройки компании пользователя
+func (s *Service) Tenant(ctx context.Context) (*storage.Tenant, error) {
+	tenant, err := s.Storage.GetTenant(ctx, storage.TenantID(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrTenantNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		s.logger(ctx).Error("Tenant GetTenant error:", zap.Error(err))
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// UpdateTenant меняет название и настройки компании пользователя. Роли сотрудников
+// следуют за списком администраторов, в компании по умолчанию в него входят и ADMIN_USERNAMES.
+func (s *Service) UpdateTenant(ctx context.Context, tenant storage.Tenant) (_ *storage.Tenant, err error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateTenant")
+	defer func() { tracing.End(span, err) }()
+
+	if err = validateTenant(&tenant); err != nil {
+		return nil, err
+	}
+	tenant.ID = storage.TenantID(ctx)
+	admins := tenant.Admins
+	if tenant.ID == storage.DefaultTenantID {
+		admins = append(slices.Clone(s.Admins), admins...)
+	}
+	if _, err = s.Storage.UpdateTenant(ctx, tenant, admins); err != nil {
+		if errors.Is(err, storage.ErrTenantNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		s.logger(ctx).Error("UpdateTenant Storage.UpdateTenant error:", zap.Error(err))
+		return nil, err
+	}
+	return s.Tenant(ctx)
+}
+
+func validateTenant(tenant *storage.Tenant) error {
+	tenant.Name = strings.TrimSpace(tenant.Name)
+	if tenant.Name == "" {
+		return apperr.ErrValidation.WithDetail("tenant name must not be empty")
+	}
+	if tenant.SignupBonus < 0 {
+		return apperr.ErrValidation.WithDetail("signupBonus must not be negative")
+	}
+	if tenant.AdminSignupBonus != nil && *tenant.AdminSignupBonus < 0 {
+		return apperr.ErrValidation.WithDetail("adminSignupBonus must not be negative")
+	}
+	if tenant.BonusVestingDays < 0 || tenant.BonusVestingDays > MaxBonusVestingDays {
+		return apperr.ErrValidation.WithDetail("bonusVestingDays must be between 0 and 365")
+	}
+	switch tenant.BudgetPeriod {
+	case "", BudgetPeriodMonth, BudgetPeriodWeek:
+	default:
+		return apperr.ErrValidation.WithDetail("budgetPeriod must be month or week")
+	}
+	for _, admin := range tenant.Admins {
+		// администраторы хранятся через запятую
+		if admin == "" || strings.Contains(admin, ",") {
+			return apperr.ErrValidation.WithDetail("invalid admin username")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateTenant(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+
+	admins := []string{"boss"}
+	mockStorage.On("CreateTenant", mock.Anything, &storage.Tenant{Slug: "acme", Name: "Acme", SignupBonus: 500,
+		Admins: admins}, []storage.MerchItem(nil)).Return(nil).Once().
+		Run(func(args mock.Arguments) { args.Get(1).(*storage.Tenant).ID = 2 })
+	mockStorage.On("CreateTenant", mock.Anything, &storage.Tenant{Slug: "taken", Name: "Taken", Admins: admins},
+		[]storage.MerchItem(nil)).Return(storage.ErrTenantTaken).Once()
+	// учетная запись администратора создается в новой компании
+	mockStorage.On("GetTenant", mock.Anything, int64(2)).Return(&storage.Tenant{ID: 2, Admins: admins}, nil).Once()
+	mockStorage.On("NewUser", mock.MatchedBy(func(ctx context.Context) bool { return storage.TenantID(ctx) == 2 }),
+		"boss", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool { return o.Role == storage.RoleAdmin })).
+		Return(uuid.New(), nil).Once()
+
+	tenant, err := svc.CreateTenant(ctx, storage.Tenant{Slug: "acme", Name: " Acme ", SignupBonus: 500, Admins: admins},
+		[]storage.MerchItem{}, "password")
+	assert.NoError(t, err)
+	assert.Equal(t, "Acme", tenant.Name)
+
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "taken", Name: "Taken", Admins: admins}, nil, "password")
+	assert.ErrorIs(t, err, ErrTenantExists)
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "Bad Slug", Name: "Bad", Admins: admins}, nil, "password")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "neg", Name: "Neg", SignupBonus: -1, Admins: admins}, nil,
+		"password")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "cat", Name: "Cat", Admins: admins},
+		[]storage.MerchItem{{Name: "cup"}}, "password")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	// без администраторов в компанию некому войти
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "empty", Name: "Empty"}, nil, "password")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.CreateTenant(ctx, storage.Tenant{Slug: "nopass", Name: "No pass", Admins: admins}, nil, "")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	// компании создают только из компании по умолчанию
+	_, err = svc.CreateTenant(storage.WithTenant(ctx, 2), storage.Tenant{Slug: "sub", Name: "Sub", Admins: admins}, nil,
+		"password")
+	assert.ErrorIs(t, err, apperr.ErrForbidden)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestUpdateTenant(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := storage.WithTenant(context.Background(), 2)
+
+	settings := storage.Tenant{ID: 2, Name: "Acme", SignupBonus: 300, BudgetPeriod: BudgetPeriodWeek, Admins: []string{"boss"}}
+	mockStorage.On("UpdateTenant", mock.Anything, settings, []string{"boss"}).Return([]uuid.UUID(nil), nil).Once()
+	mockStorage.On("GetTenant", mock.Anything, int64(2)).Return(&settings, nil)
+
+	// ID берется из контекста, а не из запроса
+	tenant, err := svc.UpdateTenant(ctx, storage.Tenant{ID: 5, Name: "Acme", SignupBonus: 300,
+		BudgetPeriod: BudgetPeriodWeek, Admins: []string{"boss"}})
+	assert.NoError(t, err)
+	assert.Equal(t, &settings, tenant)
+
+	_, err = svc.UpdateTenant(ctx, storage.Tenant{Name: "Acme", BudgetPeriod: "year"})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	negative := -1
+	_, err = svc.UpdateTenant(ctx, storage.Tenant{Name: "Acme", AdminSignupBonus: &negative})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.UpdateTenant(ctx, storage.Tenant{Name: "Acme", BonusVestingDays: MaxBonusVestingDays + 1})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	// в компании по умолчанию роль admin сохраняют и ADMIN_USERNAMES
+	svc.Admins = []string{"root"}
+	defaults := storage.Tenant{ID: storage.DefaultTenantID, Name: "Default", Admins: []string{"boss"}}
+	mockStorage.On("UpdateTenant", mock.Anything, defaults, []string{"root", "boss"}).Return([]uuid.UUID(nil), nil).Once()
+	mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).Return(&defaults, nil).Once()
+	_, err = svc.UpdateTenant(context.Background(), storage.Tenant{Name: "Default", Admins: []string{"boss"}})
+	assert.NoError(t, err)
+
+	// период бюджета компании берется из ее настроек
+	start, end, err := svc.budgetPeriod(ctx, time.Date(2025, 3, 19, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2025, 3, 24, 0, 0, 0, 0, time.UTC), end)
+	mockStorage.AssertExpectations(t)
+}
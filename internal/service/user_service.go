@@ -3,52 +3,159 @@ package service
 import (
 	"context"
 	"errors" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
 	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/Vic07Region/avito-shop/internal/utils" //nolint:gci
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt" //nolint:gci
 )
 
-func (s *Service) LoginUser(ctx context.Context, userdata UserData) (string, error) {
+func (s *Service) LoginUser(ctx context.Context, userdata UserData) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "Service.LoginUser")
+	defer func() { tracing.End(span, err) }()
+
+	tenantID := storage.DefaultTenantID
+	var tenant *storage.Tenant
+	if userdata.Tenant != "" {
+		tenant, err = s.Storage.GetTenantBySlug(ctx, userdata.Tenant)
+		if err != nil {
+			if errors.Is(err, storage.ErrTenantNotFound) {
+				return "", ErrTenantNotFound
+			}
+			s.logger(ctx).Error("LoginUser GetTenantBySlug error:", zap.Error(err))
+			return "", err
+		}
+		tenantID = tenant.ID
+	}
+	ctx = storage.WithTenant(ctx, tenantID)
+
 	userAuthData, err := s.Storage.GetUserAuthData(ctx, userdata.Username)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
+			if tenant == nil {
+				if tenant, err = s.Storage.GetTenant(ctx, tenantID); err != nil {
+					s.logger(ctx).Error("LoginUser GetTenant error:", zap.Error(err))
+					return "", err
+				}
+			}
+			// иначе первый вошедший с именем администратора получил бы его права
+			if s.isAdmin(tenant, userdata.Username) {
+				return "", ErrAdminSignup
+			}
+
 			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(userdata.Password), bcrypt.DefaultCost)
 			if err != nil {
 				//s.log.Error("LoginUser Generate passowrd hash Error:", zap.Error(err))
 				return "", ErrGeneratePasswordHash
 			}
 
-			userID, err := s.Storage.NewUser(ctx, userdata.Username, string(hashedPassword))
+			// первым входом создаются только сотрудники
+			onboarding := s.onboarding(tenant, userdata.Username, storage.RoleEmployee, time.Now())
+			userID, err := s.Storage.NewUser(ctx, userdata.Username, string(hashedPassword), &onboarding)
 			if err != nil {
-				s.log.Error("LoginUser NewUser Error:", zap.Error(err))
+				s.logger(ctx).Error("LoginUser NewUser Error:", zap.Error(err))
 				return "", err
 			}
+			s.notifyWelcome(ctx, userID, onboarding)
 
-			token, err := utils.GenerateJWT(utils.User{UserID: userID})
+			token, err := utils.GenerateJWT(utils.User{UserID: userID, TenantID: tenantID})
 			if err != nil {
-				s.log.Error("LoginUser NewUser GenerateJWT error:", zap.Error(err))
+				s.logger(ctx).Error("LoginUser NewUser GenerateJWT error:", zap.Error(err))
 				return "", ErrGenerateJWT
 			}
 			return token, nil
 		}
 
-		s.log.Error("LoginUser GetUserAuthData error:", zap.Error(err))
+		s.logger(ctx).Error("LoginUser GetUserAuthData error:", zap.Error(err))
+		metrics.LoginFailures.WithLabelValues(metrics.ReasonInternalError).Inc()
 		return "", err
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(userAuthData.PasswordHash), []byte(userdata.Password))
 	if err != nil {
-		s.log.Error("LoginUser validate error:", zap.Error(err))
+		s.logger(ctx).Error("LoginUser validate error:", zap.Error(err))
+		metrics.LoginFailures.WithLabelValues(metrics.ReasonInvalidPassword).Inc()
 		return "", ErrInvelidPassword
 	}
 
-	token, err := utils.GenerateJWT(utils.User{UserID: userAuthData.UserID})
+	token, err := utils.GenerateJWT(utils.User{UserID: userAuthData.UserID, TenantID: tenantID})
 	if err != nil {
-		s.log.Error("LoginUser GenerateJWT error:", zap.Error(err))
+		s.logger(ctx).Error("LoginUser GenerateJWT error:", zap.Error(err))
 		return "", ErrGenerateJWT
 	}
 
 	return token, nil
 }
+
+// CreateUser создает учетную запись в компании администратора. Сотрудник из списка
+// администраторов компании получает роль admin, остальные - employee.
+func (s *Service) CreateUser(ctx context.Context, username, password string) (_ *storage.Employee, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateUser")
+	defer func() { tracing.End(span, err) }()
+
+	tenant, err := s.Storage.GetTenant(ctx, storage.TenantID(ctx))
+	if err != nil {
+		if errors.Is(err, storage.ErrTenantNotFound) {
+			return nil, ErrTenantNotFound
+		}
+		s.logger(ctx).Error("CreateUser GetTenant error:", zap.Error(err))
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, ErrGeneratePasswordHash
+	}
+
+	role := storage.RoleEmployee
+	if s.isAdmin(tenant, username) {
+		role = storage.RoleAdmin
+	}
+	onboarding := s.onboarding(tenant, username, role, time.Now())
+	userID, err := s.Storage.NewUser(ctx, username, string(hashedPassword), &onboarding)
+	if err != nil {
+		if errors.Is(err, storage.ErrUsernameTaken) {
+			return nil, ErrUserExists
+		}
+		s.logger(ctx).Error("CreateUser NewUser error:", zap.Error(err))
+		return nil, err
+	}
+	s.notifyWelcome(ctx, userID, onboarding)
+	return &storage.Employee{EmployeeId: userID, Name: username, Role: onboarding.Role}, nil
+}
+
+// ProvisionAdmins создает с паролем password учетные записи администраторов компании
+// по умолчанию, которых еще нет. Существующие записи не меняются: роль admin у них
+// появляется только если их создал администратор.
+func (s *Service) ProvisionAdmins(ctx context.Context, password string) error {
+	ctx = storage.WithTenant(ctx, storage.DefaultTenantID)
+	for _, admin := range s.Admins {
+		_, err := s.Storage.GetUserAuthData(ctx, admin)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			s.logger(ctx).Error("ProvisionAdmins GetUserAuthData error:", zap.Error(err))
+			return err
+		}
+		if _, err = s.CreateUser(ctx, admin, password); err != nil {
+			return err
+		}
+		s.logger(ctx).Info("admin account created", zap.String("username", admin))
+	}
+	return nil
+}
+
+// isAdmin входит ли username в список администраторов компании: это администраторы
+// из настроек компании, для компании по умолчанию еще и Admins
+func (s *Service) isAdmin(tenant *storage.Tenant, username string) bool {
+	isUsername := func(admin string) bool { return strings.EqualFold(admin, username) }
+	if tenant.ID == storage.DefaultTenantID && slices.ContainsFunc(s.Admins, isUsername) {
+		return true
+	}
+	return slices.ContainsFunc(tenant.Admins, isUsername)
+}
@@ -7,7 +7,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/Vic07Region/avito-shop/internal/apperr"
 	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/utils"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,10 +30,16 @@ func TestLoginUser(t *testing.T) {
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.DefaultCost)
 	userID := uuid.New()
 
+	defaultTenant := &storage.Tenant{ID: storage.DefaultTenantID, SignupBonus: DefaultSignupBonus}
+
 	t.Run("User not found - new user created", func(t *testing.T) {
-		mockStorage.On("GetUserAuthData", ctx, testUsername).
+		mockStorage.On("GetUserAuthData", mock.Anything, testUsername).
 			Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
-		mockStorage.On("NewUser", ctx, testUsername, mock.Anything).Return(userID, nil)
+		mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).Return(defaultTenant, nil)
+		mockStorage.On("NewUser", mock.Anything, testUsername, mock.Anything,
+			mock.MatchedBy(func(o *storage.Onboarding) bool {
+				return o.Role == storage.RoleEmployee && o.Bonus == DefaultSignupBonus
+			})).Return(userID, nil)
 
 		token, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: testPassword})
 		assert.NoError(t, err)
@@ -41,8 +49,9 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Error generating password hash", func(t *testing.T) {
 		mockStorage.ExpectedCalls = nil
-		mockStorage.On("GetUserAuthData", ctx, testUsername).
+		mockStorage.On("GetUserAuthData", mock.Anything, testUsername).
 			Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
+		mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).Return(defaultTenant, nil)
 		// Ломаем bcrypt, передавая слишком длинный пароль
 		longPassword := string(make([]byte, 100_000))
 		_, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: longPassword})
@@ -52,9 +61,10 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Error creating new user", func(t *testing.T) {
 		mockStorage.ExpectedCalls = nil
-		mockStorage.On("GetUserAuthData", ctx, testUsername).
+		mockStorage.On("GetUserAuthData", mock.Anything, testUsername).
 			Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
-		mockStorage.On("NewUser", ctx, testUsername, mock.Anything).
+		mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).Return(defaultTenant, nil)
+		mockStorage.On("NewUser", mock.Anything, testUsername, mock.Anything, mock.Anything).
 			Return(uuid.UUID{}, errors.New("db error"))
 		_, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: testPassword})
 		log.Println(err)
@@ -63,7 +73,7 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("User found - successful login", func(t *testing.T) {
 		mockStorage.ExpectedCalls = nil
-		mockStorage.On("GetUserAuthData", ctx, testUsername).Return(&storage.AuthData{UserID: userID, PasswordHash: string(hashedPassword)}, nil)
+		mockStorage.On("GetUserAuthData", mock.Anything, testUsername).Return(&storage.AuthData{UserID: userID, PasswordHash: string(hashedPassword)}, nil)
 		token, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: testPassword})
 		assert.NoError(t, err)
 		assert.NotEmpty(t, token)
@@ -72,11 +82,101 @@ func TestLoginUser(t *testing.T) {
 
 	t.Run("Incorrect password", func(t *testing.T) {
 		mockStorage.ExpectedCalls = nil
-		mockStorage.On("GetUserAuthData", ctx, testUsername).Return(&storage.AuthData{UserID: userID, PasswordHash: string(hashedPassword)}, nil)
+		mockStorage.On("GetUserAuthData", mock.Anything, testUsername).Return(&storage.AuthData{UserID: userID, PasswordHash: string(hashedPassword)}, nil)
 
 		_, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: "wrongpassword"})
 		assert.Error(t, err)
 		assert.Equal(t, ErrInvelidPassword, err)
 	})
 
+	t.Run("Tenant login", func(t *testing.T) {
+		mockStorage.ExpectedCalls = nil
+		mockStorage.On("GetTenantBySlug", mock.Anything, "acme").Return(&storage.Tenant{ID: 7, Slug: "acme"}, nil)
+		mockStorage.On("GetUserAuthData", mock.MatchedBy(func(ctx context.Context) bool {
+			return storage.TenantID(ctx) == 7
+		}), testUsername).Return(&storage.AuthData{UserID: userID, PasswordHash: string(hashedPassword)}, nil)
+
+		token, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: testPassword, Tenant: "acme"})
+		assert.NoError(t, err)
+		claims, err := utils.ValidateToken(token)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(7), claims.TenantID)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Unknown tenant", func(t *testing.T) {
+		mockStorage.ExpectedCalls = nil
+		mockStorage.On("GetTenantBySlug", mock.Anything, "nope").Return(nil, storage.ErrTenantNotFound)
+
+		_, err := svc.LoginUser(ctx, UserData{Username: testUsername, Password: testPassword, Tenant: "nope"})
+		assert.ErrorIs(t, err, ErrTenantNotFound)
+	})
+}
+
+func TestLoginUser_AdminSignup(t *testing.T) {
+	t.Setenv("SECRET_KEY", "secret")
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, Admins: []string{"root"}, log: newTestLogger()}
+	ctx := context.Background()
+
+	mockStorage.On("GetUserAuthData", mock.Anything, "ROOT").Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
+	mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).
+		Return(&storage.Tenant{ID: storage.DefaultTenantID, SignupBonus: DefaultSignupBonus}, nil)
+
+	// имя администратора нельзя занять входом с любым паролем
+	_, err := svc.LoginUser(ctx, UserData{Username: "ROOT", Password: "password"})
+	assert.ErrorIs(t, err, apperr.ErrForbidden)
+	mockStorage.AssertNotCalled(t, "NewUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateUser(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, Admins: []string{"root"}, log: newTestLogger()}
+	ctx := context.Background()
+	rootID := uuid.New()
+
+	// администраторы компании по умолчанию - ADMIN_USERNAMES вместе с настройками компании
+	adminBonus := 5000
+	mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).Return(&storage.Tenant{
+		ID: storage.DefaultTenantID, SignupBonus: DefaultSignupBonus, AdminSignupBonus: &adminBonus,
+		Admins: []string{"Boss"},
+	}, nil)
+	// бонус администратора следует из роли создаваемой учетной записи
+	mockStorage.On("NewUser", mock.Anything, "root", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool {
+		return o.Role == storage.RoleAdmin && o.Bonus == adminBonus
+	})).Return(rootID, nil).Once()
+	mockStorage.On("NewUser", mock.Anything, "boss", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool {
+		return o.Role == storage.RoleAdmin
+	})).Return(uuid.New(), nil).Once()
+	mockStorage.On("NewUser", mock.Anything, "bob", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool {
+		return o.Role == storage.RoleEmployee && o.Bonus == DefaultSignupBonus
+	})).Return(uuid.Nil, storage.ErrUsernameTaken).Once()
+
+	user, err := svc.CreateUser(ctx, "root", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, &storage.Employee{EmployeeId: rootID, Name: "root", Role: storage.RoleAdmin}, user)
+	user, err = svc.CreateUser(ctx, "boss", "password")
+	assert.NoError(t, err)
+	assert.Equal(t, storage.RoleAdmin, user.Role)
+	_, err = svc.CreateUser(ctx, "bob", "password")
+	assert.ErrorIs(t, err, ErrUserExists)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestProvisionAdmins(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, Admins: []string{"root", "ops"}, log: newTestLogger()}
+	ctx := context.Background()
+
+	// существующая учетная запись не меняется, недостающая создается
+	mockStorage.On("GetUserAuthData", mock.Anything, "root").Return(&storage.AuthData{UserID: uuid.New()}, nil)
+	mockStorage.On("GetUserAuthData", mock.Anything, "ops").Return((*storage.AuthData)(nil), storage.ErrUserNotFound)
+	mockStorage.On("GetTenant", mock.Anything, storage.DefaultTenantID).
+		Return(&storage.Tenant{ID: storage.DefaultTenantID, SignupBonus: DefaultSignupBonus}, nil)
+	mockStorage.On("NewUser", mock.Anything, "ops", mock.Anything, mock.MatchedBy(func(o *storage.Onboarding) bool {
+		return o.Role == storage.RoleAdmin
+	})).Return(uuid.New(), nil).Once()
+
+	assert.NoError(t, svc.ProvisionAdmins(ctx, "password"))
+	mockStorage.AssertExpectations(t)
 }
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"go.uber.org/zap"
+)
+
+// NormalizeVariant SKU и размер хранятся в верхнем регистре, цвет - в нижнем
+func NormalizeVariant(choice VariantChoice) VariantChoice {
+	return VariantChoice{
+		SKU:   strings.ToUpper(strings.TrimSpace(choice.SKU)),
+		Size:  strings.ToUpper(strings.TrimSpace(choice.Size)),
+		Color: strings.ToLower(strings.TrimSpace(choice.Color)),
+	}
+}
+
+// Merch товар каталога с вариантами и их остатками
+func (s *Service) Merch(ctx context.Context, merchName string) (_ *storage.MerchItem, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Merch")
+	defer func() { tracing.End(span, err) }()
+
+	return s.merchItem(ctx, merchName)
+}
+
+// CreateVariant добавляет товару вариант и возвращает товар со всеми вариантами. Цена 0 - цена товара.
+func (s *Service) CreateVariant(ctx context.Context, merchName string, variant storage.MerchVariant) (_ *storage.MerchItem, err error) {
+	ctx, span := tracing.Start(ctx, "Service.CreateVariant")
+	defer func() { tracing.End(span, err) }()
+
+	choice := NormalizeVariant(VariantChoice{SKU: variant.SKU, Size: variant.Size, Color: variant.Color})
+	variant.SKU, variant.Size, variant.Color = choice.SKU, choice.Size, choice.Color
+	switch {
+	case variant.SKU == "":
+		return nil, apperr.ErrValidation.WithDetail("sku is required")
+	case variant.Size == "" && variant.Color == "":
+		return nil, apperr.ErrValidation.WithDetail("size or color is required")
+	case variant.Price < 0 || variant.Stock < 0:
+		return nil, apperr.ErrValidation.WithDetail("price and stock must not be negative")
+	}
+
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return nil, err
+	}
+	variant.ItemID = merch.MerchID
+	variant.Item = merch.Name
+
+	if err := s.Storage.CreateVariant(ctx, &variant); err != nil {
+		if errors.Is(err, storage.ErrVariantTaken) {
+			return nil, ErrVariantExists
+		}
+		s.logger(ctx).Error("CreateVariant Storage.CreateVariant error:", zap.Error(err))
+		return nil, err
+	}
+	merch.Variants = append(merch.Variants, variant)
+	return merch, nil
+}
+
+// UpdateVariant задает цену и остаток варианта и возвращает товар со всеми
+// вариантами. Остаток заменяется целиком, например после инвентаризации.
+func (s *Service) UpdateVariant(ctx context.Context, merchName string, sku string, price int, stock int) (_ *storage.MerchItem, err error) {
+	ctx, span := tracing.Start(ctx, "Service.UpdateVariant")
+	defer func() { tracing.End(span, err) }()
+
+	if price < 0 || stock < 0 {
+		return nil, apperr.ErrValidation.WithDetail("price and stock must not be negative")
+	}
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return nil, err
+	}
+	variant, err := chooseVariant(merch.Variants, VariantChoice{SKU: sku})
+	if err != nil {
+		return nil, err
+	}
+
+	variant.Price = price
+	variant.Stock = stock
+	if err := s.Storage.UpdateVariant(ctx, *variant); err != nil {
+		if errors.Is(err, storage.ErrVariantNotFound) {
+			return nil, ErrVariantNotFound
+		}
+		s.logger(ctx).Error("UpdateVariant Storage.UpdateVariant error:", zap.Error(err))
+		return nil, err
+	}
+	for i := range merch.Variants {
+		if merch.Variants[i].ID == variant.ID {
+			merch.Variants[i] = *variant
+		}
+	}
+	return merch, nil
+}
+
+// chooseVariant находит выбранный вариант. У товара без вариантов выбирать
+// нечего, результат nil. Размер и цвет могут быть заданы частично, если
+// этого достаточно для однозначного выбора.
+func chooseVariant(variants []storage.MerchVariant, choice VariantChoice) (*storage.MerchVariant, error) {
+	choice = NormalizeVariant(choice)
+	if len(variants) == 0 {
+		if choice != (VariantChoice{}) {
+			return nil, ErrVariantNotFound
+		}
+		return nil, nil
+	}
+	if choice == (VariantChoice{}) {
+		return nil, ErrVariantRequired
+	}
+
+	var found []storage.MerchVariant
+	for _, v := range variants {
+		switch {
+		case choice.SKU != "" && v.SKU != choice.SKU:
+		case choice.Size != "" && v.Size != choice.Size:
+		case choice.Color != "" && v.Color != choice.Color:
+		default:
+			found = append(found, v)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, ErrVariantNotFound
+	case 1:
+		return &found[0], nil
+	default:
+		return nil, ErrVariantRequired
+	}
+}
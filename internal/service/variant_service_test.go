@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var hoodyVariants = []storage.MerchVariant{
+	{ID: 1, ItemID: 6, Item: "hoody", SKU: "HOODY-M-BLACK", Size: "M", Color: "black", Stock: 5},
+	{ID: 2, ItemID: 6, Item: "hoody", SKU: "HOODY-M-RED", Size: "M", Color: "red", Stock: 5},
+	{ID: 3, ItemID: 6, Item: "hoody", SKU: "HOODY-XL-RED", Size: "XL", Color: "red", Price: 350, Stock: 1},
+}
+
+func TestChooseVariant(t *testing.T) {
+	cases := []struct {
+		name   string
+		choice VariantChoice
+		sku    string
+		err    error
+	}{
+		{name: "by sku", choice: VariantChoice{SKU: " hoody-xl-red "}, sku: "HOODY-XL-RED"},
+		{name: "by size and color", choice: VariantChoice{Size: "m", Color: "Red"}, sku: "HOODY-M-RED"},
+		{name: "size is enough", choice: VariantChoice{Size: "XL"}, sku: "HOODY-XL-RED"},
+		{name: "ambiguous", choice: VariantChoice{Size: "M"}, err: ErrVariantRequired},
+		{name: "no choice", err: ErrVariantRequired},
+		{name: "unknown", choice: VariantChoice{Size: "S"}, err: ErrVariantNotFound},
+		{name: "sku mismatch", choice: VariantChoice{SKU: "HOODY-XL-RED", Size: "M"}, err: ErrVariantNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			variant, err := chooseVariant(hoodyVariants, tc.choice)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.sku, variant.SKU)
+		})
+	}
+
+	// у товара без вариантов выбирать нечего
+	variant, err := chooseVariant(nil, VariantChoice{})
+	assert.NoError(t, err)
+	assert.Nil(t, variant)
+	_, err = chooseVariant(nil, VariantChoice{Size: "M"})
+	assert.ErrorIs(t, err, ErrVariantNotFound)
+}
+
+func TestPurchaseMerch_Variant(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	hoody := &storage.MerchItem{MerchID: 6, Name: "hoody", Price: 300, Variants: hoodyVariants}
+	mockStorage.On("GetMerchItems", mock.Anything, "hoody").Return(hoody, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, 6, mock.Anything).Return([]storage.Promotion(nil), nil)
+	// у варианта своя цена
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, storage.MerchInfo{
+		MerchID: 6, Name: "hoody", VariantID: 3, SKU: "HOODY-XL-RED", Price: 350, Amount: 1,
+	}).Return(storage.ErrOutOfStock).Once()
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, storage.MerchInfo{
+		MerchID: 6, Name: "hoody", VariantID: 1, SKU: "HOODY-M-BLACK", Price: 300, Amount: 1,
+	}).Return(nil).Once()
+
+	err := svc.PurchaseMerch(ctx, userID, "hoody", VariantChoice{Size: "XL"}, 1, "")
+	assert.ErrorIs(t, err, ErrOutOfStock)
+
+	err = svc.PurchaseMerch(ctx, userID, "hoody", VariantChoice{Size: "M", Color: "black"}, 1, "")
+	assert.NoError(t, err)
+
+	err = svc.PurchaseMerch(ctx, userID, "hoody", VariantChoice{}, 1, "")
+	assert.ErrorIs(t, err, ErrVariantRequired)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestCreateVariant(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "hoody").
+		Return(&storage.MerchItem{MerchID: 6, Name: "hoody", Price: 300}, nil)
+	mockStorage.On("CreateVariant", mock.Anything, &storage.MerchVariant{
+		ItemID: 6, Item: "hoody", SKU: "HOODY-L", Size: "L", Color: "navy", Stock: 2,
+	}).Return(nil).Once()
+	mockStorage.On("CreateVariant", mock.Anything, mock.Anything).Return(storage.ErrVariantTaken).Once()
+
+	merch, err := svc.CreateVariant(ctx, "hoody", storage.MerchVariant{SKU: " hoody-l", Size: "l", Color: "Navy", Stock: 2})
+	assert.NoError(t, err)
+	assert.Len(t, merch.Variants, 1)
+	assert.Equal(t, "HOODY-L", merch.Variants[0].SKU)
+
+	_, err = svc.CreateVariant(ctx, "hoody", storage.MerchVariant{SKU: "HOODY-L", Size: "L"})
+	assert.ErrorIs(t, err, ErrVariantExists)
+
+	_, err = svc.CreateVariant(ctx, "hoody", storage.MerchVariant{SKU: "HOODY-ANY"})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.CreateVariant(ctx, "hoody", storage.MerchVariant{SKU: "HOODY-L", Size: "L", Stock: -1})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+}
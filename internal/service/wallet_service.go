@@ -4,145 +4,219 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"sync"
-
-	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
-func (s *Service) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*FullInfo, error) {
-	var wg sync.WaitGroup
-	var fullInfo FullInfo
-
-	errChan := make(chan error, 4)
-	wg.Add(4)
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+// GiftMessageMaxLen длина сообщения к подарку в символах
+const GiftMessageMaxLen = 255
 
-	go func() {
-		defer wg.Done()
-		coins, err := s.Storage.GetBalance(ctx, userID)
-		if err != nil {
-			s.log.Error("GetWalletInfo GetBalance error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-		fullInfo.Coins = coins
-	}()
-
-	go func() {
-		defer wg.Done()
-		inventories, err := s.Storage.GetInventories(ctx, userID)
-		if err != nil {
-			s.log.Error("GetWalletInfo GetInventories error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-		var invList []Inventory
-		for _, i := range inventories {
-			invList = append(invList, Inventory{
-				Type:     i.Name,
-				Quantity: i.Quantity,
-			})
-		}
-		fullInfo.Inventory = invList
-	}()
+func (s *Service) GetWalletInfo(ctx context.Context, userID uuid.UUID) (_ *FullInfo, err error) {
+	ctx, span := tracing.Start(ctx, "Service.GetWalletInfo")
+	defer func() { tracing.End(span, err) }()
 
-	go func() {
-		defer wg.Done()
-		sendedCoins, err := s.Storage.GetSendedCoins(ctx, userID)
-		if err != nil {
-			s.log.Error("GetWalletInfo GetSendedCoins error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-		var sendedList []Sent
-		for _, i := range sendedCoins {
-			sendedList = append(sendedList, Sent{
-				ToUser: i.Username,
-				Amount: i.Amount,
-			})
-		}
-		fullInfo.CoinHistory.Sent = sendedList
-	}()
+	walletInfo, err := s.Storage.GetWalletInfo(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("GetWalletInfo Storage.GetWalletInfo error:", zap.Error(err))
+		return nil, err
+	}
 
-	go func() {
-		defer wg.Done()
-		receivedCoins, err := s.Storage.GetReceivedCoins(ctx, userID)
-		if err != nil {
-			s.log.Error("GetWalletInfo GetReceivedCoins error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-		var receivedList []Received
-		for _, i := range receivedCoins {
-			receivedList = append(receivedList, Received{
-				FromUser: i.Username,
-				Amount:   i.Amount,
-			})
-		}
-		fullInfo.CoinHistory.Received = receivedList
-	}()
+	var fullInfo FullInfo
+	fullInfo.Coins = walletInfo.Balance
+	for _, i := range walletInfo.Inventory {
+		fullInfo.Inventory = append(fullInfo.Inventory, Inventory{
+			Type:     i.Name,
+			SKU:      i.SKU,
+			Size:     i.Size,
+			Color:    i.Color,
+			Quantity: i.Quantity,
+		})
+	}
+	for _, i := range walletInfo.Sent {
+		fullInfo.CoinHistory.Sent = append(fullInfo.CoinHistory.Sent, Sent{
+			ToUser: i.Username,
+			Amount: i.Amount,
+		})
+	}
+	for _, i := range walletInfo.Received {
+		fullInfo.CoinHistory.Received = append(fullInfo.CoinHistory.Received, Received{
+			FromUser: i.Username,
+			Amount:   i.Amount,
+		})
+	}
 
-	wg.Wait()
-	close(errChan)
+	fullInfo.Wishlist = wishlistGoals(walletInfo.Wishlist, walletInfo.Balance)
 
-	for err := range errChan {
-		if err != nil {
-			return nil, err
+	// бюджет из того же snapshot, период считается по настройке компании из него же
+	if walletInfo.Budget.Limit > 0 {
+		start, end := periodBounds(s.budgetPeriodSetting(ctx, walletInfo.Budget.TenantPeriod), time.Now())
+		fullInfo.Budget = &Budget{
+			Limit:     walletInfo.Budget.Limit,
+			Remaining: walletInfo.Budget.Remaining(start),
+			ResetsAt:  end,
 		}
 	}
 
 	return &fullInfo, nil
 }
 
-func (s *Service) SendCoins(ctx context.Context, userID uuid.UUID, toUsername string, amount int) error {
+func (s *Service) SendCoins(ctx context.Context, userID uuid.UUID, toUsername string, amount int) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.SendCoins")
+	defer func() { tracing.End(span, err) }()
+
 	user, err := s.Storage.FindUser(ctx, toUsername)
 	if err != nil {
 
 		if err == sql.ErrNoRows {
 			return ErrUserNotFound
 		}
-		s.log.Error("SendCoins FindUser error:", zap.Error(err))
+		s.logger(ctx).Error("SendCoins FindUser error:", zap.Error(err))
 		return err
 	}
 
-	err = s.Storage.SendCoinsTransaction(ctx, userID, user.EmployeeId, amount)
+	// иначе бюджет на благодарности можно было бы перевести себе на баланс
+	if user.EmployeeId == userID {
+		return apperr.ErrValidation.WithDetail("cannot send coins to yourself")
+	}
+
+	// сколько монет уйдет из бюджета, нужно только для уведомления о балансе
+	period, _, err := s.budgetPeriod(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	fromBudget := 0
+	if s.Notifier != nil {
+		budget, err := s.Storage.GetBudget(ctx, userID, period)
+		if err != nil {
+			s.logger(ctx).Error("SendCoins GetBudget error:", zap.Error(err))
+		} else {
+			fromBudget = min(budget.Remaining, amount)
+		}
+	}
+
+	err = s.Storage.SendCoinsTransaction(ctx, userID, user.EmployeeId, amount, period)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotEnoughCoins) {
+			metrics.InsufficientFunds.WithLabelValues(metrics.OperationSendCoins).Inc()
 			return ErrNotEnoughCoins
 		}
-		s.log.Error("SendCoins SendCoinsTransaction error:", zap.Error(err))
+		s.logger(ctx).Error("SendCoins SendCoinsTransaction error:", zap.Error(err))
 		return err
 	}
 
+	metrics.CoinsTransferred.Add(float64(amount))
+	s.notifyTransfer(ctx, userID, user, amount, fromBudget)
 	return nil
 }
 
-func (s *Service) PurchaseMerch(ctx context.Context, userID uuid.UUID, merchName string, quantity int) error {
-	merch, err := s.Storage.GetMerchItems(ctx, merchName)
+// PurchaseMerch покупает товар с учетом распродаж и промокода, пустой promoCode - без промокода.
+// Товар с вариантами покупается только с выбором варианта.
+func (s *Service) PurchaseMerch(ctx context.Context, userID uuid.UUID, merchName string, choice VariantChoice,
+	quantity int, promoCode string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.PurchaseMerch")
+	defer func() { tracing.End(span, err) }()
+
+	return s.purchase(ctx, userID, merchName, choice, quantity, promoCode, nil, "", nil)
+}
+
+// GiftMerch покупает товар в подарок коллеге: монеты списываются с покупателя,
+// товар и заказ достаются получателю. Скидки и лимиты промокода считаются по покупателю.
+func (s *Service) GiftMerch(ctx context.Context, userID uuid.UUID, toUsername string, merchName string,
+	choice VariantChoice, promoCode string, message string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.GiftMerch")
+	defer func() { tracing.End(span, err) }()
+
+	message = strings.TrimSpace(message)
+	if utf8.RuneCountInString(message) > GiftMessageMaxLen {
+		return apperr.ErrValidation.WithDetail(fmt.Sprintf("message must be at most %d characters", GiftMessageMaxLen))
+	}
+	recipient, err := s.Storage.FindUser(ctx, toUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.logger(ctx).Error("GiftMerch FindUser error:", zap.Error(err))
+		return err
+	}
+	if recipient.EmployeeId == userID {
+		return apperr.ErrValidation.WithDetail("gift recipient must be another employee")
+	}
+	return s.purchase(ctx, userID, merchName, choice, 1, promoCode, recipient, message, nil)
+}
+
+// purchase общая часть покупки для себя, в подарок и на команду. recipient nil - покупка для себя,
+// team nil - покупка с личного баланса
+func (s *Service) purchase(ctx context.Context, userID uuid.UUID, merchName string, choice VariantChoice,
+	quantity int, promoCode string, recipient *storage.Employee, message string, team *storage.Team) error {
+	// цена и остатки с primary: реплика или кэш могут отставать, например сразу после смены цены
+	merch, err := s.Storage.GetMerchItems(storage.WithPrimary(ctx), merchName)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return ErrMerchNotFound
 		}
-		s.log.Error("PurchaseMerch GetMerchItems error:", zap.Error(err))
+		s.logger(ctx).Error("purchase GetMerchItems error:", zap.Error(err))
 		return err
 	}
 
-	err = s.Storage.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{
-		MerchID: merch.MerchID,
-		Price:   merch.Price,
-		Amount:  quantity,
-	})
+	variant, err := chooseVariant(merch.Variants, choice)
+	if err != nil {
+		return err
+	}
+	if variant != nil {
+		priced := *merch
+		priced.Price = variant.UnitPrice(merch.Price)
+		merch = &priced
+	}
+
+	info, err := s.checkout(ctx, merch, quantity, promoCode)
+	if err != nil {
+		return err
+	}
+	if variant != nil {
+		info.VariantID = variant.ID
+		info.SKU = variant.SKU
+	}
+	if recipient != nil {
+		info.RecipientID = recipient.EmployeeId
+		info.GiftMessage = message
+	}
+	teamName := ""
+	if team != nil {
+		info.TeamID, teamName = team.ID, team.Name
+	}
+
+	err = s.Storage.PurchaseMerchTransaction(ctx, userID, *info)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotEnoughCoins) {
+			metrics.InsufficientFunds.WithLabelValues(metrics.OperationPurchaseMerch).Inc()
 			return ErrNotEnoughCoins
 		}
-		s.log.Error("PurchaseMerch PurchaseMerchTransaction error:", zap.Error(err))
+		if errors.Is(err, storage.ErrPromoInvalid) {
+			return ErrPromoInvalid
+		}
+		if errors.Is(err, storage.ErrPromoExhausted) {
+			return ErrPromoExhausted
+		}
+		if errors.Is(err, storage.ErrOutOfStock) {
+			return ErrOutOfStock
+		}
+		s.logger(ctx).Error("purchase PurchaseMerchTransaction error:", zap.Error(err))
 		return err
 	}
 
+	metrics.Purchases.WithLabelValues(merchName).Add(float64(quantity))
+	s.notifyPurchase(ctx, userID, merch.Name, quantity, info.Price*quantity-info.Discount, teamName)
+	if recipient != nil {
+		s.notifyGift(ctx, userID, recipient, *info)
+	}
 	return nil
 }
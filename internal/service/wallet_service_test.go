@@ -3,12 +3,18 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/storage"
 )
 
 func TestGetWalletInfo_Success(t *testing.T) {
@@ -20,16 +26,72 @@ func TestGetWalletInfo_Success(t *testing.T) {
 
 	ctx := context.Background()
 	userID := uuid.New()
-
-	mockStorage.On("GetBalance", mock.Anything, userID).Return(1000, nil)
-	mockStorage.On("GetInventories", mock.Anything, userID).Return([]storage.InventoryItem{}, nil)
-	mockStorage.On("GetSendedCoins", mock.Anything, userID).Return([]storage.SenderInfo{}, nil)
-	mockStorage.On("GetReceivedCoins", mock.Anything, userID).Return([]storage.SenderInfo{}, nil)
+	start, _ := periodBounds(svc.BudgetPeriod, time.Now())
+
+	mockStorage.On("GetWalletInfo", mock.Anything, userID).Return(&storage.WalletInfo{
+		Balance:   870,
+		Inventory: []storage.InventoryItem{{Name: "cup", Quantity: 1}},
+		Received:  []storage.SenderInfo{{Username: "alice", Amount: 20}},
+		Sent:      []storage.SenderInfo{{Username: "bob", Amount: 130}},
+		Wishlist: []storage.WishlistItem{
+			{ID: 1, ItemID: 5, Item: "powerbank", Price: 200, Goal: 1000, Contributed: 50},
+			{ID: 2, ItemID: 3, Item: "book", Price: 50, Goal: 40},
+		},
+		Budget: storage.WalletBudget{Limit: 200, Budget: 150, Period: start},
+	}, nil)
 
 	info, err := svc.GetWalletInfo(ctx, userID)
 	assert.NoError(t, err)
 	assert.NotNil(t, info)
-	assert.Equal(t, 1000, info.Coins)
+	assert.Equal(t, 870, info.Coins)
+	assert.Equal(t, []Inventory{{Type: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, []Received{{FromUser: "alice", Amount: 20}}, info.CoinHistory.Received)
+	assert.Equal(t, []Sent{{ToUser: "bob", Amount: 130}}, info.CoinHistory.Sent)
+	if assert.NotNil(t, info.Budget) {
+		assert.Equal(t, 200, info.Budget.Limit)
+		assert.Equal(t, 150, info.Budget.Remaining)
+		assert.Equal(t, 1, info.Budget.ResetsAt.Day())
+	}
+	// баланс засчитывается в каждую цель, но не больше ее
+	assert.Equal(t, []WishlistGoal{
+		{ID: 1, Item: "powerbank", Price: 200, Goal: 1000, Contributed: 50, Saved: 870, Progress: 87},
+		{ID: 2, Item: "book", Price: 50, Goal: 40, Saved: 40, Progress: 100},
+	}, info.Wishlist)
+	// бюджет берется из snapshot кошелька, без отдельных запросов
+	mockStorage.AssertNotCalled(t, "GetBudget", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetWalletInfo_BudgetPeriod(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, BudgetPeriod: BudgetPeriodMonth}
+	ctx := storage.WithTenant(context.Background(), 2)
+	userID := uuid.New()
+	weekStart, weekEnd := periodBounds(BudgetPeriodWeek, time.Now())
+
+	// остаток прошлой недели: в компании с недельным периодом бюджет уже восполнен
+	mockStorage.On("GetWalletInfo", mock.Anything, userID).Return(&storage.WalletInfo{
+		Balance: 100,
+		Budget: storage.WalletBudget{Limit: 200, Budget: 50, Period: weekStart.AddDate(0, 0, -7),
+			TenantPeriod: BudgetPeriodWeek},
+	}, nil)
+
+	info, err := svc.GetWalletInfo(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, &Budget{Limit: 200, Remaining: 200, ResetsAt: weekEnd}, info.Budget)
+	mockStorage.AssertNotCalled(t, "GetTenant", mock.Anything, mock.Anything)
+}
+
+func TestGetWalletInfo_Error(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("GetWalletInfo", mock.Anything, userID).Return(nil, sql.ErrNoRows)
+
+	info, err := svc.GetWalletInfo(ctx, userID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.Nil(t, info)
 }
 
 func TestSendCoins_Success(t *testing.T) {
@@ -41,13 +103,44 @@ func TestSendCoins_Success(t *testing.T) {
 	toUsername := "test_user"
 	amount := 500
 
-	mockStorage.On("FindUser", ctx, toUsername).Return(&storage.Employee{EmployeeId: toUserID}, nil)
-	mockStorage.On("SendCoinsTransaction", ctx, userID, toUserID, amount).Return(nil)
+	mockStorage.On("FindUser", mock.Anything, toUsername).Return(&storage.Employee{EmployeeId: toUserID}, nil)
+	mockStorage.On("SendCoinsTransaction", mock.Anything, userID, toUserID, amount, mock.Anything).Return(nil)
 
 	err := svc.SendCoins(ctx, userID, toUsername, amount)
 	assert.NoError(t, err)
 }
 
+func TestSendCoins_Yourself(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "alice").Return(&storage.Employee{EmployeeId: userID}, nil)
+
+	err := svc.SendCoins(ctx, userID, "alice", 50)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertNotCalled(t, "SendCoinsTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
+func TestSendCoins_TenantError(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := storage.WithTenant(context.Background(), 2)
+	userID := uuid.New()
+	dbErr := errors.New("connection reset")
+
+	// без периода бюджета компании перевод не выполняется
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: uuid.New()}, nil)
+	mockStorage.On("GetTenant", mock.Anything, int64(2)).Return((*storage.Tenant)(nil), dbErr)
+
+	err := svc.SendCoins(ctx, userID, "bob", 50)
+	assert.ErrorIs(t, err, dbErr)
+	mockStorage.AssertNotCalled(t, "SendCoinsTransaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything)
+}
+
 func TestSendCoins_UserNotFound(t *testing.T) {
 	mockStorage := new(MockStorage)
 	svc := Service{Storage: mockStorage}
@@ -55,7 +148,7 @@ func TestSendCoins_UserNotFound(t *testing.T) {
 	userID := uuid.New()
 	toUsername := "unknown_user"
 
-	mockStorage.On("FindUser", ctx, toUsername).Return(&storage.Employee{}, sql.ErrNoRows)
+	mockStorage.On("FindUser", mock.Anything, toUsername).Return(&storage.Employee{}, sql.ErrNoRows)
 
 	err := svc.SendCoins(ctx, userID, toUsername, 500)
 	assert.ErrorIs(t, err, ErrUserNotFound)
@@ -71,10 +164,11 @@ func TestPurchaseMerch_Success(t *testing.T) {
 	merchName := "Cool T-Shirt"
 	quantity := 2
 
-	mockStorage.On("GetMerchItems", ctx, merchName).Return(&storage.MerchItem{MerchID: 1, Price: 200}, nil)
-	mockStorage.On("PurchaseMerchTransaction", ctx, userID, mock.Anything).Return(nil)
+	mockStorage.On("GetMerchItems", mock.Anything, merchName).Return(&storage.MerchItem{MerchID: 1, Price: 200}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(nil)
 
-	err := svc.PurchaseMerch(ctx, userID, merchName, quantity)
+	err := svc.PurchaseMerch(ctx, userID, merchName, VariantChoice{}, quantity, "")
 	assert.NoError(t, err)
 }
 
@@ -87,9 +181,64 @@ func TestPurchaseMerch_NotEnoughCoins(t *testing.T) {
 	merchName := "Expensive Item"
 	quantity := 5
 
-	mockStorage.On("GetMerchItems", ctx, merchName).Return(&storage.MerchItem{MerchID: 2, Price: 1000}, nil)
-	mockStorage.On("PurchaseMerchTransaction", ctx, userID, mock.Anything).Return(storage.ErrNotEnoughCoins)
+	mockStorage.On("GetMerchItems", mock.Anything, merchName).Return(&storage.MerchItem{MerchID: 2, Price: 1000}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(storage.ErrNotEnoughCoins)
 
-	err := svc.PurchaseMerch(ctx, userID, merchName, quantity)
+	err := svc.PurchaseMerch(ctx, userID, merchName, VariantChoice{}, quantity, "")
 	assert.ErrorIs(t, err, ErrNotEnoughCoins)
 }
+
+func TestGiftMerch(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	bobID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: bobID, Name: "bob"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "alice").Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "nobody").Return((*storage.Employee)(nil), sql.ErrNoRows)
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	// в подарок всегда одна штука, сообщение без пробелов по краям
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, storage.MerchInfo{
+		MerchID: 2, Name: "cup", Price: 20, Amount: 1, RecipientID: bobID, GiftMessage: "Спасибо за помощь",
+	}).Return(nil).Once()
+
+	err := svc.GiftMerch(ctx, userID, "bob", "cup", VariantChoice{}, "", "  Спасибо за помощь ")
+	assert.NoError(t, err)
+
+	err = svc.GiftMerch(ctx, userID, "nobody", "cup", VariantChoice{}, "", "")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	err = svc.GiftMerch(ctx, userID, "alice", "cup", VariantChoice{}, "", "")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	err = svc.GiftMerch(ctx, userID, "bob", "cup", VariantChoice{}, "", strings.Repeat("я", GiftMessageMaxLen+1))
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestWalletMetrics(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage,
+		log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	toUserID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "receiver").Return(&storage.Employee{EmployeeId: toUserID}, nil)
+	mockStorage.On("SendCoinsTransaction", mock.Anything, userID, toUserID, 30, mock.Anything).Return(nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "metrics-cup").Return(&storage.MerchItem{MerchID: 3, Price: 20}, nil)
+	mockStorage.On("GetActiveSales", mock.Anything, mock.Anything, mock.Anything).Return([]storage.Promotion(nil), nil)
+	mockStorage.On("PurchaseMerchTransaction", mock.Anything, userID, mock.Anything).Return(storage.ErrNotEnoughCoins)
+
+	transferred := testutil.ToFloat64(metrics.CoinsTransferred)
+	rejected := testutil.ToFloat64(metrics.InsufficientFunds.WithLabelValues(metrics.OperationPurchaseMerch))
+
+	assert.NoError(t, svc.SendCoins(ctx, userID, "receiver", 30))
+	assert.ErrorIs(t, svc.PurchaseMerch(ctx, userID, "metrics-cup", VariantChoice{}, 1, ""), ErrNotEnoughCoins)
+
+	assert.Equal(t, transferred+30, testutil.ToFloat64(metrics.CoinsTransferred))
+	assert.Equal(t, rejected+1, testutil.ToFloat64(metrics.InsufficientFunds.WithLabelValues(metrics.OperationPurchaseMerch)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.Purchases.WithLabelValues("metrics-cup")))
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// AddToWishlist добавляет товар в вишлист или меняет цель накопления.
+// Цель 0 - текущая цена товара.
+func (s *Service) AddToWishlist(ctx context.Context, userID uuid.UUID, merchName string, goal int) (_ *WishlistGoal,
+	err error) {
+	ctx, span := tracing.Start(ctx, "Service.AddToWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	if goal < 0 {
+		return nil, apperr.ErrValidation.WithDetail("goal must not be negative")
+	}
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return nil, err
+	}
+	if goal == 0 {
+		goal = merch.Price
+	}
+
+	wish := storage.WishlistItem{EmployeeID: userID, ItemID: merch.MerchID, Item: merch.Name, Price: merch.Price, Goal: goal}
+	if err = s.Storage.SaveWish(ctx, &wish); err != nil {
+		s.logger(ctx).Error("AddToWishlist SaveWish error:", zap.Error(err))
+		return nil, err
+	}
+	balance, err := s.Storage.GetBalance(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("AddToWishlist GetBalance error:", zap.Error(err))
+		return nil, err
+	}
+	goals := wishlistGoals([]storage.WishlistItem{wish}, balance)
+	return &goals[0], nil
+}
+
+func (s *Service) RemoveFromWishlist(ctx context.Context, userID uuid.UUID, merchName string) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.RemoveFromWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return err
+	}
+	err = s.Storage.DeleteWish(ctx, userID, merch.MerchID)
+	if err != nil {
+		if errors.Is(err, storage.ErrWishNotFound) {
+			return ErrWishNotFound
+		}
+		s.logger(ctx).Error("RemoveFromWishlist DeleteWish error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Wishlist вишлист пользователя с прогрессом по текущему балансу
+func (s *Service) Wishlist(ctx context.Context, userID uuid.UUID) (_ []WishlistGoal, err error) {
+	ctx, span := tracing.Start(ctx, "Service.Wishlist")
+	defer func() { tracing.End(span, err) }()
+
+	wishlist, err := s.Storage.GetWishlist(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("Wishlist GetWishlist error:", zap.Error(err))
+		return nil, err
+	}
+	balance, err := s.Storage.GetBalance(ctx, userID)
+	if err != nil {
+		s.logger(ctx).Error("Wishlist GetBalance error:", zap.Error(err))
+		return nil, err
+	}
+	return wishlistGoals(wishlist, balance), nil
+}
+
+// ColleagueWishlist вишлист коллеги без прогресса: баланс виден только владельцу
+func (s *Service) ColleagueWishlist(ctx context.Context, username string) (_ []storage.WishlistItem, err error) {
+	ctx, span := tracing.Start(ctx, "Service.ColleagueWishlist")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := s.Storage.FindUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		s.logger(ctx).Error("ColleagueWishlist FindUser error:", zap.Error(err))
+		return nil, err
+	}
+	wishlist, err := s.Storage.GetWishlist(ctx, user.EmployeeId)
+	if err != nil {
+		s.logger(ctx).Error("ColleagueWishlist GetWishlist error:", zap.Error(err))
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+// Contribute переводит монеты коллеге на товар из его вишлиста. Это обычный перевод:
+// монеты попадают на баланс получателя и в историю переводов обоих.
+func (s *Service) Contribute(ctx context.Context, userID uuid.UUID, toUsername string, merchName string,
+	amount int) (err error) {
+	ctx, span := tracing.Start(ctx, "Service.Contribute")
+	defer func() { tracing.End(span, err) }()
+
+	if amount <= 0 {
+		return apperr.ErrValidation.WithDetail("amount must be positive")
+	}
+	receiver, err := s.Storage.FindUser(ctx, toUsername)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.logger(ctx).Error("Contribute FindUser error:", zap.Error(err))
+		return err
+	}
+	if receiver.EmployeeId == userID {
+		return apperr.ErrValidation.WithDetail("contribution receiver must be another employee")
+	}
+	merch, err := s.merchItem(ctx, merchName)
+	if err != nil {
+		return err
+	}
+
+	contribution := storage.Contribution{ItemID: merch.MerchID, Item: merch.Name, Amount: amount}
+	err = s.Storage.ContributeTransaction(ctx, userID, receiver.EmployeeId, contribution)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrWishNotFound):
+			return ErrWishNotFound
+		case errors.Is(err, storage.ErrNotEnoughCoins):
+			metrics.InsufficientFunds.WithLabelValues(metrics.OperationContribute).Inc()
+			return ErrNotEnoughCoins
+		}
+		s.logger(ctx).Error("Contribute ContributeTransaction error:", zap.Error(err))
+		return err
+	}
+
+	metrics.CoinsTransferred.Add(float64(amount))
+	s.notifyContribution(ctx, userID, receiver, contribution)
+	return nil
+}
+
+// wishlistGoals считает прогресс по балансу: монеты баланса засчитываются в каждую цель
+func wishlistGoals(wishlist []storage.WishlistItem, balance int) []WishlistGoal {
+	goals := make([]WishlistGoal, 0, len(wishlist))
+	for _, w := range wishlist {
+		saved := min(max(balance, 0), w.Goal)
+		goals = append(goals, WishlistGoal{
+			ID:          w.ID,
+			Item:        w.Item,
+			Price:       w.Price,
+			Goal:        w.Goal,
+			Contributed: w.Contributed,
+			Saved:       saved,
+			Progress:    saved * 100 / w.Goal,
+			CreatedAt:   w.CreatedAt,
+		})
+	}
+	return goals
+}
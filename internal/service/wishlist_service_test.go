@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAddToWishlist(t *testing.T) {
+	mockStorage := new(MockStorage)
+	svc := Service{Storage: mockStorage, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockStorage.On("GetMerchItems", mock.Anything, "powerbank").
+		Return(&storage.MerchItem{MerchID: 5, Name: "powerbank", Price: 200}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "car").Return((*storage.MerchItem)(nil), sql.ErrNoRows)
+	// без цели копим на цену товара
+	mockStorage.On("SaveWish", mock.Anything, &storage.WishlistItem{
+		EmployeeID: userID, ItemID: 5, Item: "powerbank", Price: 200, Goal: 200,
+	}).Run(func(args mock.Arguments) {
+		wish := args.Get(1).(*storage.WishlistItem)
+		wish.ID, wish.Contributed = 3, 20
+	}).Return(nil).Once()
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(150, nil)
+
+	wish, err := svc.AddToWishlist(ctx, userID, "powerbank", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &WishlistGoal{ID: 3, Item: "powerbank", Price: 200, Goal: 200, Contributed: 20, Saved: 150,
+		Progress: 75}, wish)
+
+	_, err = svc.AddToWishlist(ctx, userID, "powerbank", -1)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = svc.AddToWishlist(ctx, userID, "car", 100)
+	assert.ErrorIs(t, err, ErrMerchNotFound)
+	mockStorage.AssertExpectations(t)
+}
+
+func TestContribute(t *testing.T) {
+	mockStorage := new(MockStorage)
+	notifier := new(MockNotifier)
+	svc := Service{Storage: mockStorage, Notifier: notifier, log: newTestLogger()}
+	ctx := context.Background()
+	userID := uuid.New()
+	bobID := uuid.New()
+
+	mockStorage.On("FindUser", mock.Anything, "bob").Return(&storage.Employee{EmployeeId: bobID, Name: "bob"}, nil)
+	mockStorage.On("FindUser", mock.Anything, "alice").Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("GetUser4UserID", mock.Anything, userID).Return(&storage.Employee{EmployeeId: userID, Name: "alice"}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "powerbank").
+		Return(&storage.MerchItem{MerchID: 5, Name: "powerbank", Price: 200}, nil)
+	mockStorage.On("GetMerchItems", mock.Anything, "cup").Return(&storage.MerchItem{MerchID: 2, Name: "cup", Price: 20}, nil)
+	mockStorage.On("ContributeTransaction", mock.Anything, userID, bobID, storage.Contribution{
+		ItemID: 5, Item: "powerbank", Amount: 30,
+	}).Return(nil).Once()
+	mockStorage.On("ContributeTransaction", mock.Anything, userID, bobID, storage.Contribution{
+		ItemID: 5, Item: "powerbank", Amount: 5000,
+	}).Return(storage.ErrNotEnoughCoins).Once()
+	mockStorage.On("ContributeTransaction", mock.Anything, userID, bobID, storage.Contribution{
+		ItemID: 2, Item: "cup", Amount: 10,
+	}).Return(storage.ErrWishNotFound).Once()
+	mockStorage.On("GetWishlist", mock.Anything, bobID).Return([]storage.WishlistItem{
+		{ID: 1, EmployeeID: bobID, ItemID: 5, Item: "powerbank", Price: 200, Goal: 300, Contributed: 80},
+	}, nil)
+	mockStorage.On("GetBalance", mock.Anything, userID).Return(970, nil)
+	mockStorage.On("GetBalance", mock.Anything, bobID).Return(1030, nil)
+	// получатель видит итог взносов на товар
+	notifier.On("Notify", mock.Anything, events.TypeContributionReceived, events.ContributionReceived{
+		SenderID: userID, Sender: "alice", ReceiverID: bobID, Receiver: "bob", Item: "powerbank", Amount: 30,
+		Contributed: 80, Goal: 300,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: userID, Balance: 970, Delta: -30,
+	}).Once()
+	notifier.On("Notify", mock.Anything, events.TypeBalanceChanged, events.BalanceChanged{
+		UserID: bobID, Balance: 1030, Delta: 30,
+	}).Once()
+
+	assert.NoError(t, svc.Contribute(ctx, userID, "bob", "powerbank", 30))
+	assert.ErrorIs(t, svc.Contribute(ctx, userID, "bob", "powerbank", 5000), ErrNotEnoughCoins)
+	assert.ErrorIs(t, svc.Contribute(ctx, userID, "bob", "cup", 10), ErrWishNotFound)
+	assert.ErrorIs(t, svc.Contribute(ctx, userID, "alice", "powerbank", 10), apperr.ErrValidation)
+	assert.ErrorIs(t, svc.Contribute(ctx, userID, "bob", "powerbank", 0), apperr.ErrValidation)
+	mockStorage.AssertExpectations(t)
+	notifier.AssertExpectations(t)
+}
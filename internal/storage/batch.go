@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// execAtomic выполняет запросы атомарно: либо все, либо ни одного.
+//
+// Для postgres запросы уходят одним pgx.Batch за один round trip и
+// выполняются в неявной транзакции. Уровня read committed достаточно:
+// балансы меняются относительными UPDATE под блокировкой строки, а
+// отрицательный баланс запрещает CHECK.
+//
+// Для остальных драйверов (sqlite, sqlmock в тестах) запросы
+// выполняются последовательно в одной sql.Tx.
+func (q *Queries) execAtomic(ctx context.Context, operation string, statements ...sq.Sqlizer) error {
+	if q.pool != nil {
+		return q.execBatch(ctx, operation, statements)
+	}
+	return q.execTx(ctx, statements)
+}
+
+func (q *Queries) execBatch(ctx context.Context, operation string, statements []sq.Sqlizer) (err error) {
+	batch := &pgx.Batch{}
+	queries := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		query, args, buildErr := statement.ToSql()
+		if buildErr != nil {
+			return fmt.Errorf("failed to build query: %w", buildErr)
+		}
+		batch.Queue(query, args...)
+		queries = append(queries, query)
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "db.BATCH "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", q.dbSystem()),
+			attribute.String("db.statement", strings.Join(queries, ";\n")),
+			attribute.Int("db.batch.size", len(queries)),
+		),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	results := q.pool.SendBatch(ctx, batch)
+	for range queries {
+		if _, err = results.Exec(); err != nil {
+			_ = results.Close()
+			return err
+		}
+	}
+	return results.Close()
+}
+
+func (q *Queries) execTx(ctx context.Context, statements []sq.Sqlizer) (err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execStatements выполняет запросы по очереди в уже открытой транзакции
+func (q *Queries) execStatements(ctx context.Context, tx *sql.Tx, statements []sq.Sqlizer) error {
+	for _, statement := range statements {
+		query, args, err := statement.ToSql()
+		if err != nil {
+			return fmt.Errorf("failed to build query: %w", err)
+		}
+		if _, err = q.traced(tx).ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rollbackIgnoreDone(tx *sql.Tx) error {
+	if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return err
+	}
+	return nil
+}
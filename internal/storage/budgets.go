@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SetBudget назначает сотруднику бюджет на благодарности и восполняет его
+// до нового лимита в текущем периоде. Лимит 0 отключает бюджет.
+func (q *Queries) SetBudget(ctx context.Context, userID uuid.UUID, limit int, period time.Time) error {
+	result, err := q.builder().Update("wallets").
+		Set("budget_limit", limit).
+		Set("budget", limit).
+		Set("budget_period", period).
+		Where(sq.Eq{"employee_id": userID}).
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("SetBudget ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrUserNotFound)
+}
+
+// GetBudget бюджет сотрудника в периоде, который начался в period
+func (q *Queries) GetBudget(ctx context.Context, userID uuid.UUID, period time.Time) (*Budget, error) {
+	var budget Budget
+	err := q.builder().Select("budget_limit").
+		Column(q.currentBudget(period)).
+		From("wallets").
+		Where(sq.Eq{"employee_id": userID}).
+		RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&budget.Limit, &budget.Remaining)
+	if err != nil {
+		q.logger(ctx).Error("GetBudget QueryRowContext error:", zap.Error(err))
+		return nil, err
+	}
+	return &budget, nil
+}
@@ -0,0 +1,251 @@
+// Package cache read-through кэш поверх service.StorageInterface.
+//
+// Кэшируются каталог мерча, пользователь по id и информация о кошельке,
+// ключи включают компанию запроса. Товар, прочитанный с primary (storage.WithPrimary), не кэшируется.
+// SendCoinsTransaction, PurchaseMerchTransaction, TransferItemsTransaction, ContributeTransaction,
+// DepositTeamCoins, DistributeTeamCoins, UpdateOrderStatus и VestSignupBonuses сбрасывают кошельки участников, включая получателя
+// подарка, SetBudget - кошелек сотрудника, SaveWish и DeleteWish - кошелек владельца вишлиста, смена цены,
+// вариантов и их остатков - товар каталога, UpdateTenant - сотрудников, чья роль изменилась. Новый период
+// бюджета компании попадает в закэшированные кошельки через TTL.Wallet. Остальные методы идут в хранилище напрямую.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/logging" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/metrics"
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Cache хранилище для закэшированных значений. Значения сериализуются,
+// поэтому вместо LRU можно подключить общий кэш (redis, memcached) для
+// нескольких инстансов сервиса.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+}
+
+// TTL время жизни записей по видам данных
+type TTL struct {
+	Merch  time.Duration
+	User   time.Duration
+	Wallet time.Duration
+}
+
+// виды данных, используются в ключах и в метриках
+const (
+	kindMerch  = "merch"
+	kindUser   = "user"
+	kindWallet = "wallet"
+)
+
+type Storage struct {
+	service.StorageInterface
+	cache Cache
+	ttl   TTL
+	log   *zap.Logger
+}
+
+func New(next service.StorageInterface, cache Cache, ttl TTL, log *zap.Logger) *Storage {
+	return &Storage{
+		StorageInterface: next,
+		cache:            cache,
+		ttl:              ttl,
+		log:              log,
+	}
+}
+
+// GetMerchItems при чтении с primary идет мимо кэша: так читает покупка, цена и
+// остатки берутся актуальные
+func (s *Storage) GetMerchItems(ctx context.Context, merchName string) (*storage.MerchItem, error) {
+	if storage.IsPrimary(ctx) {
+		return s.StorageInterface.GetMerchItems(ctx, merchName)
+	}
+	return readThrough(ctx, s, kindMerch, merchName, s.ttl.Merch, func() (*storage.MerchItem, error) {
+		return s.StorageInterface.GetMerchItems(ctx, merchName)
+	})
+}
+
+func (s *Storage) GetUser4UserID(ctx context.Context, userID uuid.UUID) (*storage.Employee, error) {
+	return readThrough(ctx, s, kindUser, userID.String(), s.ttl.User, func() (*storage.Employee, error) {
+		return s.StorageInterface.GetUser4UserID(ctx, userID)
+	})
+}
+
+func (s *Storage) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*storage.WalletInfo, error) {
+	return readThrough(ctx, s, kindWallet, userID.String(), s.ttl.Wallet, func() (*storage.WalletInfo, error) {
+		return s.StorageInterface.GetWalletInfo(ctx, userID)
+	})
+}
+
+// SendCoinsTransaction сбрасывает кошельки в любом случае: при ошибке
+// коммита неизвестно, применилась ли транзакция
+func (s *Storage) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int,
+	period time.Time) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, senderID.String()), key(ctx, kindWallet, receiverID.String()))
+	return s.StorageInterface.SendCoinsTransaction(ctx, senderID, receiverID, amount, period)
+}
+
+// TransferItemsTransaction сбрасывает кошельки обоих, инвентарь входит в информацию о кошельке
+func (s *Storage) TransferItemsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	transfer storage.ItemTransfer) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, senderID.String()), key(ctx, kindWallet, receiverID.String()))
+	return s.StorageInterface.TransferItemsTransaction(ctx, senderID, receiverID, transfer)
+}
+
+// ContributeTransaction взнос на вишлист - перевод монет, сбрасывает кошельки обоих
+func (s *Storage) ContributeTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	contribution storage.Contribution) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, senderID.String()), key(ctx, kindWallet, receiverID.String()))
+	return s.StorageInterface.ContributeTransaction(ctx, senderID, receiverID, contribution)
+}
+
+// SetBudget назначает бюджет на благодарности, сбрасывает кошелек сотрудника: бюджет входит в WalletInfo
+func (s *Storage) SetBudget(ctx context.Context, userID uuid.UUID, limit int, period time.Time) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, userID.String()))
+	return s.StorageInterface.SetBudget(ctx, userID, limit, period)
+}
+
+// SaveWish добавляет товар в вишлист, сбрасывает кошелек владельца: вишлист входит в WalletInfo
+func (s *Storage) SaveWish(ctx context.Context, wish *storage.WishlistItem) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, wish.EmployeeID.String()))
+	return s.StorageInterface.SaveWish(ctx, wish)
+}
+
+// DeleteWish удаляет товар из вишлиста, сбрасывает кошелек владельца
+func (s *Storage) DeleteWish(ctx context.Context, userID uuid.UUID, itemID int) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, userID.String()))
+	return s.StorageInterface.DeleteWish(ctx, userID, itemID)
+}
+
+// DepositTeamCoins пополняет пул команды с баланса actorID, сбрасывает его кошелек
+func (s *Storage) DepositTeamCoins(ctx context.Context, teamID int64, actorID uuid.UUID, amount int) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, actorID.String()))
+	return s.StorageInterface.DepositTeamCoins(ctx, teamID, actorID, amount)
+}
+
+// DistributeTeamCoins пополняет баланс участника из пула команды, сбрасывает его кошелек
+func (s *Storage) DistributeTeamCoins(ctx context.Context, teamID int64, managerID uuid.UUID, receiverID uuid.UUID,
+	amount int) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, receiverID.String()))
+	return s.StorageInterface.DistributeTeamCoins(ctx, teamID, managerID, receiverID, amount)
+}
+
+func (s *Storage) PurchaseMerchTransaction(ctx context.Context, userID uuid.UUID, merch storage.MerchInfo) error {
+	defer s.cache.Delete(ctx, key(ctx, kindWallet, userID.String()))
+	if merch.RecipientID != uuid.Nil {
+		// подарок попадает в инвентарь получателя
+		defer s.cache.Delete(ctx, key(ctx, kindWallet, merch.RecipientID.String()))
+	}
+	if merch.VariantID != 0 {
+		defer s.cache.Delete(ctx, key(ctx, kindMerch, merch.Name))
+	}
+	return s.StorageInterface.PurchaseMerchTransaction(ctx, userID, merch)
+}
+
+// UpdateTenant сбрасывает сотрудников, чья роль изменилась, иначе AdminMiddleware видел бы старую роль до TTL
+func (s *Storage) UpdateTenant(ctx context.Context, tenant storage.Tenant, admins []string) ([]uuid.UUID, error) {
+	changed, err := s.StorageInterface.UpdateTenant(ctx, tenant, admins)
+	// сотрудники принадлежат tenant.ID, а не компании запроса
+	tenantCtx := storage.WithTenant(ctx, tenant.ID)
+	keys := make([]string, 0, len(changed))
+	for _, id := range changed {
+		keys = append(keys, key(tenantCtx, kindUser, id.String()))
+	}
+	if len(keys) > 0 {
+		s.cache.Delete(ctx, keys...)
+	}
+	return changed, err
+}
+
+// UpdateOrderStatus сбрасывает кошельки владельца и покупателя заказа: отмена
+// убирает товар из инвентаря и возвращает монеты тому, кто заплатил
+func (s *Storage) UpdateOrderStatus(ctx context.Context, update storage.OrderStatusUpdate) (*storage.Order, error) {
+	order, err := s.StorageInterface.UpdateOrderStatus(ctx, update)
+	if order == nil {
+		if update.UserID != uuid.Nil {
+			s.cache.Delete(ctx, key(ctx, kindWallet, update.UserID.String()))
+		}
+		return order, err
+	}
+
+	keys := []string{key(ctx, kindWallet, order.UserID.String())}
+	if order.Gift != nil {
+		keys = append(keys, key(ctx, kindWallet, order.Gift.FromID.String()))
+	}
+	if order.SKU != "" {
+		// отмена возвращает товар на остаток варианта
+		keys = append(keys, key(ctx, kindMerch, order.Item))
+	}
+	s.cache.Delete(ctx, keys...)
+	return order, err
+}
+
+// VestSignupBonuses выдает бонусы сотрудникам разных компаний, ключ кошелька
+// строится по компании каждого сотрудника
+func (s *Storage) VestSignupBonuses(ctx context.Context, now time.Time, limit int) ([]storage.Onboarding, error) {
+	vested, err := s.StorageInterface.VestSignupBonuses(ctx, now, limit)
+	keys := make([]string, 0, len(vested))
+	for _, o := range vested {
+		keys = append(keys, key(storage.WithTenant(ctx, o.TenantID), kindWallet, o.EmployeeID.String()))
+	}
+	if len(keys) > 0 {
+		s.cache.Delete(ctx, keys...)
+	}
+	return vested, err
+}
+
+func (s *Storage) SetMerchPrice(ctx context.Context, item storage.MerchItem, at time.Time) error {
+	defer s.cache.Delete(ctx, key(ctx, kindMerch, item.Name))
+	return s.StorageInterface.SetMerchPrice(ctx, item, at)
+}
+
+func (s *Storage) CreateVariant(ctx context.Context, variant *storage.MerchVariant) error {
+	defer s.cache.Delete(ctx, key(ctx, kindMerch, variant.Item))
+	return s.StorageInterface.CreateVariant(ctx, variant)
+}
+
+func (s *Storage) UpdateVariant(ctx context.Context, variant storage.MerchVariant) error {
+	defer s.cache.Delete(ctx, key(ctx, kindMerch, variant.Item))
+	return s.StorageInterface.UpdateVariant(ctx, variant)
+}
+
+func key(ctx context.Context, kind string, id string) string {
+	return fmt.Sprintf("%s:%d:%s", kind, storage.TenantID(ctx), id)
+}
+
+// readThrough отдает значение из кэша или загружает его через load.
+// Ошибки не кэшируются, ошибки сериализации только логируются.
+func readThrough[T any](ctx context.Context, s *Storage, kind string, id string, ttl time.Duration,
+	load func() (*T, error)) (*T, error) {
+	k := key(ctx, kind, id)
+	if data, ok := s.cache.Get(ctx, k); ok {
+		var value T
+		err := json.Unmarshal(data, &value)
+		if err == nil {
+			metrics.CacheRequests.WithLabelValues(kind, metrics.CacheHit).Inc()
+			return &value, nil
+		}
+		logging.FromContext(ctx, s.log).Warn("cache json.Unmarshal error:", zap.String("key", k), zap.Error(err))
+	}
+	metrics.CacheRequests.WithLabelValues(kind, metrics.CacheMiss).Inc()
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		logging.FromContext(ctx, s.log).Warn("cache json.Marshal error:", zap.String("key", k), zap.Error(err))
+		return value, nil
+	}
+	s.cache.Set(ctx, k, data, ttl)
+	return value, nil
+}
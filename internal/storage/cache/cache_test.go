@@ -0,0 +1,194 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/cache"
+	"github.com/Vic07Region/avito-shop/internal/storage/memory"
+	"github.com/Vic07Region/avito-shop/internal/storage/storagetest"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTTL = cache.TTL{Merch: time.Minute, User: time.Minute, Wallet: time.Minute}
+
+// countingStorage считает обращения к хранилищу за кэшем
+type countingStorage struct {
+	service.StorageInterface
+	merchCalls  int
+	userCalls   int
+	walletCalls int
+}
+
+func (s *countingStorage) GetMerchItems(ctx context.Context, merchName string) (*storage.MerchItem, error) {
+	s.merchCalls++
+	return s.StorageInterface.GetMerchItems(ctx, merchName)
+}
+
+func (s *countingStorage) GetUser4UserID(ctx context.Context, userID uuid.UUID) (*storage.Employee, error) {
+	s.userCalls++
+	return s.StorageInterface.GetUser4UserID(ctx, userID)
+}
+
+func (s *countingStorage) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*storage.WalletInfo, error) {
+	s.walletCalls++
+	return s.StorageInterface.GetWalletInfo(ctx, userID)
+}
+
+// TestConformance кэш не должен менять наблюдаемое поведение хранилища
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(_ *testing.T) service.StorageInterface {
+		return cache.New(memory.New(), cache.NewLRU(100), testTTL, nil)
+	})
+}
+
+func TestReadThrough(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{StorageInterface: memory.New()}
+	s := cache.New(next, cache.NewLRU(100), testTTL, nil)
+
+	userID, err := s.NewUser(ctx, "alice", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+
+	for range 3 {
+		item, err := s.GetMerchItems(ctx, "cup")
+		require.NoError(t, err)
+		assert.Equal(t, 20, item.Price)
+
+		user, err := s.GetUser4UserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", user.Name)
+	}
+	assert.Equal(t, 1, next.merchCalls)
+	assert.Equal(t, 1, next.userCalls)
+
+	// ошибки не кэшируются
+	for range 2 {
+		_, err := s.GetMerchItems(ctx, "no-such-merch")
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 3, next.merchCalls)
+
+	// чтение с primary (цена при покупке) идет мимо кэша
+	_, err = s.GetMerchItems(storage.WithPrimary(ctx), "cup")
+	require.NoError(t, err)
+	assert.Equal(t, 4, next.merchCalls)
+}
+
+func TestWalletInvalidation(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{StorageInterface: memory.New()}
+	s := cache.New(next, cache.NewLRU(100), testTTL, nil)
+
+	aliceID, err := s.NewUser(ctx, "alice", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+	bobID, err := s.NewUser(ctx, "bob", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+
+	for _, userID := range []uuid.UUID{aliceID, bobID, aliceID, bobID} {
+		_, err := s.GetWalletInfo(ctx, userID)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, next.walletCalls)
+
+	require.NoError(t, s.SendCoinsTransaction(ctx, aliceID, bobID, 100, time.Time{}))
+
+	info, err := s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, 900, info.Balance)
+	info, err = s.GetWalletInfo(ctx, bobID)
+	require.NoError(t, err)
+	assert.Equal(t, 1100, info.Balance)
+	assert.Equal(t, 4, next.walletCalls)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, aliceID, storage.MerchInfo{MerchID: cup.MerchID, Price: cup.Price, Amount: 1}))
+
+	info, err = s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, 880, info.Balance)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, 5, next.walletCalls)
+
+	// передача товара сбрасывает кошельки обоих
+	require.NoError(t, s.TransferItemsTransaction(ctx, aliceID, bobID,
+		storage.ItemTransfer{MerchID: cup.MerchID, Name: "cup", Quantity: 1}))
+	info, err = s.GetWalletInfo(ctx, bobID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 1}}, info.Inventory)
+	require.NoError(t, s.TransferItemsTransaction(ctx, bobID, aliceID,
+		storage.ItemTransfer{MerchID: cup.MerchID, Name: "cup", Quantity: 1}))
+	info, err = s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 1}}, info.Inventory)
+	assert.Equal(t, 7, next.walletCalls)
+
+	// отмена администратором сбрасывает кошелек владельца заказа
+	orders, err := s.GetOrders(ctx, aliceID)
+	require.NoError(t, err)
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orders[0].ID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+
+	info, err = s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, 900, info.Balance)
+	assert.Empty(t, info.Inventory)
+	assert.Equal(t, 8, next.walletCalls)
+}
+
+func TestTeamDepositInvalidation(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{StorageInterface: memory.New()}
+	s := cache.New(next, cache.NewLRU(100), testTTL, nil)
+
+	adminID, err := s.NewUser(ctx, "admin", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+	team := storage.Team{Name: "backend"}
+	require.NoError(t, s.CreateTeam(ctx, &team))
+
+	info, err := s.GetWalletInfo(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, info.Balance)
+
+	// взнос в пул команды списывается с баланса, кошелек перечитывается
+	require.NoError(t, s.DepositTeamCoins(ctx, team.ID, adminID, 300))
+	info, err = s.GetWalletInfo(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, 700, info.Balance)
+	assert.Equal(t, 2, next.walletCalls)
+}
+
+func TestWishlistInvalidation(t *testing.T) {
+	ctx := context.Background()
+	next := &countingStorage{StorageInterface: memory.New()}
+	s := cache.New(next, cache.NewLRU(100), testTTL, nil)
+
+	aliceID, err := s.NewUser(ctx, "alice", "hash", &storage.Onboarding{Bonus: 1000})
+	require.NoError(t, err)
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+
+	info, err := s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Empty(t, info.Wishlist)
+
+	require.NoError(t, s.SaveWish(ctx, &storage.WishlistItem{EmployeeID: aliceID, ItemID: cup.MerchID, Goal: 20}))
+	info, err = s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	require.Len(t, info.Wishlist, 1)
+	assert.Equal(t, "cup", info.Wishlist[0].Item)
+
+	require.NoError(t, s.DeleteWish(ctx, aliceID, cup.MerchID))
+	info, err = s.GetWalletInfo(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Empty(t, info.Wishlist)
+	assert.Equal(t, 3, next.walletCalls)
+}
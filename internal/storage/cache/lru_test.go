@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(2)
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	// обращение к "a" делает самой старой запись "b"
+	_, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+	c.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, ok = c.Get(ctx, "b")
+	assert.False(t, ok)
+	value, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUExpiration(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	c := NewLRU(10)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("1"), time.Second)
+	_, ok := c.Get(ctx, "a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Second)
+	_, ok = c.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestLRUDelete(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRU(10)
+
+	c.Set(ctx, "a", []byte("1"), time.Minute)
+	c.Set(ctx, "b", []byte("2"), time.Minute)
+	c.Delete(ctx, "a", "unknown")
+
+	_, ok := c.Get(ctx, "a")
+	assert.False(t, ok)
+	_, ok = c.Get(ctx, "b")
+	assert.True(t, ok)
+}
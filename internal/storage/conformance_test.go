@@ -0,0 +1,40 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newPostgres подключается к реальной базе с примененными migrations/init.sql,
+// например: TEST_DB_DSN="user=postgres password=... dbname=shop sslmode=disable" go test ./internal/storage
+func newPostgres(tb testing.TB) *storage.Queries {
+	tb.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		tb.Skip("TEST_DB_DSN is not set")
+	}
+
+	pool, err := storage.NewPostgresPool(context.Background(), storage.ConnectionParams{
+		DbDriver:         storage.DBPostgres,
+		ConnectionString: dsn,
+		MaxOpenConns:     10,
+	})
+	require.NoError(tb, err)
+	tb.Cleanup(pool.Close)
+
+	return storage.NewPostgres(pool, zap.NewNop())
+}
+
+func TestConformance(t *testing.T) {
+	queries := newPostgres(t)
+	storagetest.Run(t, func(_ *testing.T) service.StorageInterface {
+		return queries
+	})
+}
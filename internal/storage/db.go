@@ -1,26 +1,62 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 	"time"
 )
 
 const (
 	DBPostgres = "postgres"
+	DBSQLite   = "sqlite"
+	DBMemory   = "memory"
 )
 
 var (
-	ErrUserNotFound   = errors.New("user not found")
-	ErrNotEnoughCoins = errors.New("not enough coins on balance")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrNotEnoughCoins   = errors.New("not enough coins on balance")
+	ErrUsernameTaken    = errors.New("username already taken")
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrOrderNotFound    = errors.New("order not found")
+	// ErrOrderStatus заказ в статусе, из которого операция недоступна
+	ErrOrderStatus = errors.New("operation is not allowed in current order status")
+	// ErrPromoInvalid промокод не найден, еще не начал или уже закончил действовать, или не подходит к товару
+	ErrPromoInvalid = errors.New("promo code is invalid")
+	// ErrPromoExhausted промокод погашен максимальное число раз, всего или пользователем
+	ErrPromoExhausted     = errors.New("promo code redemption limit reached")
+	ErrPromotionNotFound  = errors.New("promotion not found")
+	ErrPromotionCodeTaken = errors.New("promotion code already exists")
+	ErrVariantNotFound    = errors.New("merch variant not found")
+	// ErrVariantTaken SKU или сочетание размера и цвета уже заняты
+	ErrVariantTaken = errors.New("merch variant already exists")
+	ErrOutOfStock   = errors.New("merch variant is out of stock")
+	// ErrNotEnoughItems в инвентаре меньше товара, чем нужно передать или вернуть при отмене
+	ErrNotEnoughItems = errors.New("not enough items in inventory")
+	// ErrWishNotFound товара нет в вишлисте сотрудника
+	ErrWishNotFound = errors.New("wishlist item not found")
+	ErrTeamNotFound = errors.New("team not found")
+	ErrTeamTaken    = errors.New("team name already taken")
+	// ErrNotTeamMember сотрудник не состоит в команде
+	ErrNotTeamMember  = errors.New("employee is not a team member")
+	ErrTenantNotFound = errors.New("tenant not found")
+	// ErrTenantTaken короткое имя компании уже занято
+	ErrTenantTaken = errors.New("tenant slug already taken")
 )
 
 type Queries struct {
 	db  *sql.DB
 	log *zap.Logger
+	// pool задан только для postgres, через него идут batch запросы
+	pool   *pgxpool.Pool
+	driver string
+	// replicas реплики для чтения, см. UseReplicas
+	replicas *replicaSet
 }
 
 type ConnectionParams struct {
@@ -32,7 +68,12 @@ type ConnectionParams struct {
 }
 
 func NewDBConection(params ConnectionParams) (*sql.DB, error) {
-	db, err := sql.Open(params.DbDriver, params.ConnectionString)
+	driverName := params.DbDriver
+	if driverName == DBPostgres {
+		// postgres работает через pgx, lib/pq больше не используется
+		driverName = "pgx"
+	}
+	db, err := sql.Open(driverName, params.ConnectionString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
@@ -40,15 +81,63 @@ func NewDBConection(params ConnectionParams) (*sql.DB, error) {
 	db.SetMaxIdleConns(params.MsxIdleConns)
 	db.SetConnMaxLifetime(params.MaxLifeTime)
 	if err = db.Ping(); err != nil {
-		err := db.Close()
-		if err != nil {
-			return nil, err
-		}
+		_ = db.Close()
 		return nil, fmt.Errorf("failed to ping database: %v", err)
 	}
 	return db, nil
 }
 
+// NewPostgresPool создает pgxpool для postgres.
+// MsxIdleConns соответствует минимальному числу соединений в пуле.
+func NewPostgresPool(ctx context.Context, params ConnectionParams) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(params.ConnectionString)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %v", err)
+	}
+	if params.MaxOpenConns > 0 {
+		config.MaxConns = int32(params.MaxOpenConns)
+	}
+	if params.MsxIdleConns > 0 {
+		config.MinConns = min(int32(params.MsxIdleConns), config.MaxConns)
+	}
+	if params.MaxLifeTime > 0 {
+		config.MaxConnLifetime = params.MaxLifeTime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %v", err)
+	}
+	if err = pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %v", err)
+	}
+	return pool, nil
+}
+
+// NewPostgres создает Queries поверх pgxpool: обычные запросы идут через
+// database/sql обертку над тем же пулом, транзакции перевода и покупки -
+// через pgx.Batch
+func NewPostgres(pool *pgxpool.Pool, zapLogger *zap.Logger) *Queries {
+	return &Queries{
+		db:     stdlib.OpenDBFromPool(pool),
+		log:    zapLogger,
+		pool:   pool,
+		driver: DBPostgres,
+	}
+}
+
+// DB возвращает database/sql обертку соединения, например для метрик пула
+func (q *Queries) DB() *sql.DB {
+	return q.db
+}
+
 func New(db *sql.DB, zapLogger *zap.Logger) *Queries {
-	return &Queries{db, zapLogger}
+	return NewWithDriver(db, DBPostgres, zapLogger)
+}
+
+// NewWithDriver создает Queries для указанного драйвера (DBPostgres или DBSQLite),
+// от драйвера зависят плейсхолдеры и разбор ошибок ограничений
+func NewWithDriver(db *sql.DB, driver string, zapLogger *zap.Logger) *Queries {
+	return &Queries{db: db, log: zapLogger, driver: driver}
 }
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/jackc/pgx/v5/pgconn"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// коды ошибок postgres
+const (
+	pgCheckViolation  = "23514"
+	pgUniqueViolation = "23505"
+)
+
+func (q *Queries) isSQLite() bool {
+	return q.driver == DBSQLite
+}
+
+func (q *Queries) placeholders() sq.PlaceholderFormat {
+	if q.isSQLite() {
+		return sq.Question
+	}
+	return sq.Dollar
+}
+
+// builder возвращает squirrel builder с плейсхолдерами текущего драйвера
+func (q *Queries) builder() sq.StatementBuilderType {
+	return sq.StatementBuilder.PlaceholderFormat(q.placeholders())
+}
+
+// usernameEq регистронезависимое сравнение username
+func (q *Queries) usernameEq(username string) sq.Sqlizer {
+	if q.isSQLite() {
+		return sq.Expr("username = ? COLLATE NOCASE", username)
+	}
+	return sq.Expr("username ILIKE ?", username)
+}
+
+// insertedPurchaseID id покупки, вставленной раньше в той же сессии postgres. В отличие
+// от lastval, currval последовательности purchases не сдвигают вставки в другие таблицы.
+func insertedPurchaseID() sq.Sqlizer {
+	return sq.Expr("currval(pg_get_serial_sequence('purchases', 'purchase_id'))")
+}
+
+func (q *Queries) dbSystem() string {
+	if q.isSQLite() {
+		return "sqlite"
+	}
+	return "postgresql"
+}
+
+// isCheckViolation нарушение CHECK, для wallets это отрицательный баланс
+func isCheckViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgCheckViolation
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_CHECK
+	}
+	return false
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgUniqueViolation
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// sprintfSkipLocked подставляет FOR UPDATE SKIP LOCKED в подзапрос выборки.
+// В sqlite запись сериализуется на уровне базы, блокировка строк не нужна.
+func sprintfSkipLocked(query string, skipLocked bool) string {
+	if skipLocked {
+		return fmt.Sprintf(query, " FOR UPDATE SKIP LOCKED")
+	}
+	return fmt.Sprintf(query, "")
+}
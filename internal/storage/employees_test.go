@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -21,11 +22,11 @@ func TestGetUser4UserID(t *testing.T) {
 	userID := uuid.New()
 	createdAt := time.Now() // Генерируем текущее время
 
-	rows := sqlmock.NewRows([]string{"employee_id", "username", "email", "created_at"}).
-		AddRow(userID, "testuser", "test@example.com", createdAt) // Используем time.Time
+	rows := sqlmock.NewRows([]string{"employee_id", "username", "email", "role", "created_at"}).
+		AddRow(userID, "testuser", "test@example.com", RoleAdmin, createdAt) // Используем time.Time
 
-	mock.ExpectQuery(`SELECT employee_id, username, email, created_at FROM employees WHERE employee_id = \$1`).
-		WithArgs(userID).
+	mock.ExpectQuery(`SELECT employee_id, username, email, role, created_at FROM employees WHERE employee_id = \$1 AND tenant_id = \$2`).
+		WithArgs(userID, DefaultTenantID).
 		WillReturnRows(rows)
 
 	user, err := queries.GetUser4UserID(ctx, userID)
@@ -34,6 +35,7 @@ func TestGetUser4UserID(t *testing.T) {
 	assert.Equal(t, userID, user.EmployeeId)
 	assert.Equal(t, "testuser", user.Name)
 	assert.Equal(t, "test@example.com", *user.Email)
+	assert.Equal(t, RoleAdmin, user.Role)
 	assert.WithinDuration(t, createdAt, user.CreatedAt, time.Second) // Проверяем с учетом возможных миллисекундных отклонений
 }
 
@@ -50,8 +52,8 @@ func TestGetUserAuthData(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"employee_id", "password_hash"}).
 		AddRow(userID, passwordHash)
 
-	mock.ExpectQuery(`SELECT employee_id, password_hash FROM employees WHERE username ILIKE \$1`).
-		WithArgs(username).
+	mock.ExpectQuery(`SELECT employee_id, password_hash FROM employees WHERE username ILIKE \$1 AND tenant_id = \$2`).
+		WithArgs(username, DefaultTenantID).
 		WillReturnRows(rows)
 
 	data, err := queries.GetUserAuthData(ctx, username)
@@ -73,20 +75,36 @@ func TestNewUser(t *testing.T) {
 
 	mock.ExpectBegin()
 
-	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO employees (username,password_hash) VALUES ($1,$2) RETURNING employee_id`)).
-		WithArgs(username, passwordHash).
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO employees (tenant_id,username,password_hash,role) VALUES ($1,$2,$3,$4) `+
+		`RETURNING employee_id`)).
+		WithArgs(int64(7), username, passwordHash, RoleEmployee).
 		WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(newUserID))
 
-	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO wallets (employee_id,balance) VALUES ($1,$2)`)).
-		WithArgs(newUserID, 1000).
+	// наступивший бонус сразу попадает на баланс
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO onboarding (tenant_id,username,employee_id,role,bonus,vests_at,`+
+		`vested_at,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT (tenant_id, username) DO NOTHING `+
+		`RETURNING onboarding_id`)).
+		WithArgs(int64(7), username, newUserID, RoleEmployee, 500, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"onboarding_id"}).AddRow(int64(3)))
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO wallets (employee_id,tenant_id,balance) VALUES ($1,$2,$3)`)).
+		WithArgs(newUserID, int64(7), 500).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO outbox (tenant_id,event_id,event_type,payload,created_at) VALUES ($1,$2,$3,$4,$5)`)).
+		WithArgs(int64(7), sqlmock.AnyArg(), events.TypeUserRegistered, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectCommit()
 
-	userID, err := queries.NewUser(ctx, username, passwordHash)
+	onboarding := &Onboarding{Role: RoleEmployee, Bonus: 500, VestsAt: time.Now()}
+	userID, err := queries.NewUser(WithTenant(ctx, 7), username, passwordHash, onboarding)
 	require.NoError(t, err, "Ошибка при создании пользователя")
 	require.NotEqual(t, uuid.Nil, userID, "userID не должен быть nil")
 	assert.Equal(t, newUserID, userID)
+	assert.Equal(t, int64(3), onboarding.ID)
+	assert.Equal(t, newUserID, onboarding.EmployeeID)
+	assert.NotNil(t, onboarding.VestedAt)
 
 	require.NoError(t, mock.ExpectationsWereMet(), "Не все ожидания были выполнены")
 }
@@ -104,8 +122,8 @@ func TestFindUser(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"employee_id", "username", "created_at"}).
 		AddRow(userID, username, createdAt)
 
-	mock.ExpectQuery(`SELECT employee_id, username, created_at FROM employees WHERE username ILIKE \$1`).
-		WithArgs(username).
+	mock.ExpectQuery(`SELECT employee_id, username, created_at FROM employees WHERE username ILIKE \$1 AND tenant_id = \$2`).
+		WithArgs(username, DefaultTenantID).
 		WillReturnRows(rows)
 
 	user, err := queries.FindUser(ctx, username)
@@ -5,50 +5,57 @@ import (
 	"database/sql" //nolint:gci
 	"errors"
 	sq "github.com/Masterminds/squirrel"
+	"github.com/Vic07Region/avito-shop/internal/events"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 func (q *Queries) GetUser4UserID(ctx context.Context, userID uuid.UUID) (*Employee, error) {
-	sqlquery := sq.Select("employee_id", "username", "email", "created_at").
-		PlaceholderFormat(sq.Dollar).From("employees").Where(sq.Eq{"employee_id": userID})
+	sqlquery := q.builder().Select("employee_id", "username", "email", "role", "created_at").
+		From("employees").Where(sq.Eq{"employee_id": userID}).Where(tenantEq(ctx, ""))
 	var user Employee
-	err := sqlquery.RunWith(q.db).QueryRowContext(ctx).Scan(&user.EmployeeId, &user.Name, &user.Email, &user.CreatedAt)
+	err := sqlquery.RunWith(q.traced(q.db)).QueryRowContext(ctx).
+		Scan(&user.EmployeeId, &user.Name, &user.Email, &user.Role, &user.CreatedAt)
 	if err != nil {
-		q.log.Error("GetUser4UserID QueryRowContext scan error:", zap.Error(err))
+		q.logger(ctx).Error("GetUser4UserID QueryRowContext scan error:", zap.Error(err))
 		return nil, err
 	}
 	return &user, nil
 }
 
 func (q *Queries) GetUserAuthData(ctx context.Context, username string) (*AuthData, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	sqlquery := sqlBuilder.Select("employee_id", "password_hash").
 		From("employees").
-		Where(sq.Expr("username ILIKE ?", username))
+		Where(q.usernameEq(username)).
+		Where(tenantEq(ctx, ""))
 
 	var data AuthData
 
-	err := sqlquery.RunWith(q.db).QueryRowContext(ctx).Scan(&data.UserID, &data.PasswordHash)
+	err := sqlquery.RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&data.UserID, &data.PasswordHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
 		}
-		q.log.Error("GetUserAuthData QueryRowContext scan error:", zap.Error(err))
+		q.logger(ctx).Error("GetUserAuthData QueryRowContext scan error:", zap.Error(err))
 		return nil, err
 	}
 	return &data, nil
 }
 
-func (q *Queries) NewUser(ctx context.Context, username string, passwordHash string) (uuid.UUID, error) {
+// NewUser создает сотрудника с ролью и бонусом onboarding: ID, EmployeeID, Username и VestedAt
+// заполняются здесь, пустая роль - RoleEmployee. Бонус, срок которого уже наступил, сразу попадает на баланс. Если по этому
+// имени бонус уже выдавался, Bonus обнуляется и запись не создается.
+func (q *Queries) NewUser(ctx context.Context, username string, passwordHash string, onboarding *Onboarding) (
+	uuid.UUID, error) {
 	txOptions := sql.TxOptions{
 		Isolation: sql.LevelDefault,
 		ReadOnly:  false,
 	}
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	tx, err := q.db.BeginTx(ctx, &txOptions)
 	if err != nil {
-		q.log.Error("NewUser BeginTX error:", zap.Error(err))
+		q.logger(ctx).Error("NewUser BeginTX error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
@@ -58,43 +65,70 @@ func (q *Queries) NewUser(ctx context.Context, username string, passwordHash str
 		}
 	}()
 
+	tenant := TenantID(ctx)
+	if onboarding.Role == "" {
+		onboarding.Role = RoleEmployee
+	}
 	UserQuery := sqlBuilder.Insert("employees").
-		Columns("username", "password_hash").
-		Values(username, passwordHash).
+		Columns("tenant_id", "username", "password_hash", "role").
+		Values(tenant, username, passwordHash, onboarding.Role).
 		Suffix("RETURNING employee_id")
 
 	var userID uuid.UUID
 
-	err = UserQuery.RunWith(tx).QueryRowContext(ctx).Scan(&userID)
+	err = UserQuery.RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&userID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return uuid.Nil, ErrUsernameTaken
+		}
+		q.logger(ctx).Error("NewUser UserQuery error:", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	balance, err := q.onboard(ctx, tx, tenant, userID, username, onboarding)
 	if err != nil {
-		q.log.Error("NewUser UserQuery error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
 	WalletQuery := sqlBuilder.Insert("wallets").
-		Columns("employee_id", "balance").
-		Values(userID, 1000)
+		Columns("employee_id", "tenant_id", "balance").
+		Values(userID, tenant, balance)
 
-	result, err := WalletQuery.RunWith(tx).ExecContext(ctx)
+	result, err := WalletQuery.RunWith(q.traced(tx)).ExecContext(ctx)
 	if err != nil {
-		q.log.Error("NewUser WalletQuery error:", zap.Error(err))
+		q.logger(ctx).Error("NewUser WalletQuery error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
 	affected, err := result.RowsAffected()
 	if err != nil {
-		q.log.Error("NewUser RowsAffected error:", zap.Error(err))
+		q.logger(ctx).Error("NewUser RowsAffected error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
 	if affected < 1 {
-		q.log.Error("NewUser affected rows:", zap.Int64("affected", affected))
+		q.logger(ctx).Error("NewUser affected rows:", zap.Int64("affected", affected))
+		return uuid.Nil, err
+	}
+
+	event, err := events.New(events.TypeUserRegistered, events.UserRegistered{
+		UserID:   userID,
+		Username: username,
+	})
+	if err != nil {
+		q.logger(ctx).Error("NewUser events.New error:", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	_, err = q.outboxInsert(tenant, event).RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("NewUser OutboxQuery error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		q.log.Error("NewUser Commit error:", zap.Error(err))
+		q.logger(ctx).Error("NewUser Commit error:", zap.Error(err))
 		return uuid.Nil, err
 	}
 
@@ -102,17 +136,18 @@ func (q *Queries) NewUser(ctx context.Context, username string, passwordHash str
 }
 
 func (q *Queries) FindUser(ctx context.Context, username string) (*Employee, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	sqlquery := sqlBuilder.Select("employee_id", "username", "created_at").
 		From("employees").
-		Where(sq.Expr("username ILIKE ?", username))
+		Where(q.usernameEq(username)).
+		Where(tenantEq(ctx, ""))
 
 	var user Employee
-	err := sqlquery.RunWith(q.db).
+	err := sqlquery.RunWith(q.traced(q.db)).
 		QueryRowContext(ctx).
 		Scan(&user.EmployeeId, &user.Name, &user.CreatedAt)
 	if err != nil {
-		q.log.Error("FindUser QueryRowContext scan error:", zap.Error(err))
+		q.logger(ctx).Error("FindUser QueryRowContext scan error:", zap.Error(err))
 		return nil, err
 	}
 	return &user, nil
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// inventoryUpsert прибавляет quantity к строке инвентаря, недостающая строка создается
+const inventoryUpsert = "ON CONFLICT (employee_id, item_id, COALESCE(variant_id, 0)) " +
+	"DO UPDATE SET quantity = inventory.quantity + excluded.quantity"
+
+var ledgerColumns = []string{"tenant_id", "employee_id", "item_id", "variant_id", "kind", "quantity", "purchase_id",
+	"counterparty_id", "created_at"}
+
+func (q *Queries) inventoryAdd(tenant int64, userID uuid.UUID, itemID int, variantID *int,
+	quantity int) sq.InsertBuilder {
+	return q.builder().Insert("inventory").
+		Columns("employee_id", "tenant_id", "item_id", "variant_id", "quantity").
+		Values(userID, tenant, itemID, variantID, quantity).
+		Suffix(inventoryUpsert)
+}
+
+// takeItems списывает quantity из инвентаря пользователя. Условный UPDATE блокирует
+// строку инвентаря, количество не уходит в минус.
+func (q *Queries) takeItems(ctx context.Context, tx *sql.Tx, userID uuid.UUID, itemID int, variantID *int,
+	quantity int) error {
+	variant := 0
+	if variantID != nil {
+		variant = *variantID
+	}
+	result, err := q.builder().Update("inventory").
+		Set("quantity", sq.Expr("quantity - ?", quantity)).
+		Where(sq.Eq{"employee_id": userID, "item_id": itemID}).
+		Where("COALESCE(variant_id, 0) = ?", variant).
+		Where(sq.GtOrEq{"quantity": quantity}).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrNotEnoughItems)
+}
+
+// TransferItemsTransaction передает товар из инвентаря отправителя получателю
+// и записывает передачу в журнал обоих
+func (q *Queries) TransferItemsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	transfer ItemTransfer) error {
+	var variantID *int
+	if transfer.VariantID != 0 {
+		variantID = &transfer.VariantID
+	}
+	now := time.Now().UTC()
+	tenant := TenantID(ctx)
+
+	ledgerQuery := q.builder().Insert("inventory_ledger").
+		Columns(ledgerColumns...).
+		Values(tenant, senderID, transfer.MerchID, variantID, LedgerGifted, -transfer.Quantity, nil, receiverID, now).
+		Values(tenant, receiverID, transfer.MerchID, variantID, LedgerReceived, transfer.Quantity, nil, senderID, now)
+
+	event, err := events.New(events.TypeItemsTransferred, events.ItemsTransferred{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		ItemID:     transfer.MerchID,
+		Item:       transfer.Name,
+		SKU:        transfer.SKU,
+		Quantity:   transfer.Quantity,
+	})
+	if err != nil {
+		q.logger(ctx).Error("TransferItems events.New error:", zap.Error(err))
+		return err
+	}
+
+	err = q.transferTx(ctx, senderID, receiverID, transfer.MerchID, variantID, transfer.Quantity,
+		[]sq.Sqlizer{ledgerQuery, q.outboxInsert(tenant, event)})
+	if err != nil {
+		if errors.Is(err, ErrNotEnoughItems) {
+			return err
+		}
+		q.logger(ctx).Error("TransferItems error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// transferTx переносит товар между инвентарями и выполняет запросы журнала в одной транзакции.
+// Строки инвентаря блокируются в одном порядке, чтобы встречные передачи не ловили deadlock.
+func (q *Queries) transferTx(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, itemID int,
+	variantID *int, quantity int, statements []sq.Sqlizer) (err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	receiverQuery := q.inventoryAdd(TenantID(ctx), receiverID, itemID, variantID, quantity)
+	if receiverID.String() < senderID.String() {
+		if err = q.execStatements(ctx, tx, []sq.Sqlizer{receiverQuery}); err != nil {
+			return err
+		}
+	} else {
+		statements = append([]sq.Sqlizer{receiverQuery}, statements...)
+	}
+	if err = q.takeItems(ctx, tx, senderID, itemID, variantID, quantity); err != nil {
+		return err
+	}
+
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetInventoryLedger журнал инвентаря пользователя, новые записи первыми
+func (q *Queries) GetInventoryLedger(ctx context.Context, userID uuid.UUID, limit int) ([]LedgerEntry, error) {
+	sqlQuery := q.builder().Select("entry_id", "kind", "name", "COALESCE(sku, '')", "COALESCE(size, '')",
+		"COALESCE(color, '')", "quantity", "COALESCE(purchase_id, 0)", "COALESCE(username, '')",
+		"inventory_ledger.created_at").
+		From("inventory_ledger").
+		InnerJoin("merch_items using(item_id)").
+		LeftJoin("merch_variants ON merch_variants.variant_id = inventory_ledger.variant_id").
+		LeftJoin("employees ON employees.employee_id = inventory_ledger.counterparty_id").
+		Where(sq.Eq{"inventory_ledger.employee_id": userID}).
+		OrderBy("entry_id DESC").
+		Limit(uint64(limit))
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("GetInventoryLedger QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []LedgerEntry
+	for rows.Next() {
+		var e LedgerEntry
+		err := rows.Scan(&e.ID, &e.Kind, &e.Item, &e.SKU, &e.Size, &e.Color, &e.Quantity, &e.OrderID,
+			&e.Counterparty, &e.CreatedAt)
+		if err != nil {
+			q.logger(ctx).Error("GetInventoryLedger rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetInventoryLedger rows error:", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
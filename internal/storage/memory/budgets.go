@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/google/uuid"
+)
+
+// budget бюджет на благодарности, period - начало периода, к которому относится remaining
+type budget struct {
+	limit     int
+	remaining int
+	period    time.Time
+}
+
+func (s *Storage) SetBudget(ctx context.Context, userID uuid.UUID, limit int, period time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	if _, ok := d.wallets[userID]; !ok {
+		return storage.ErrUserNotFound
+	}
+	d.budgets[userID] = &budget{limit: limit, remaining: limit, period: period}
+	return nil
+}
+
+func (s *Storage) GetBudget(ctx context.Context, userID uuid.UUID, period time.Time) (*storage.Budget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	if _, ok := d.wallets[userID]; !ok {
+		return nil, sql.ErrNoRows
+	}
+	result := storage.Budget{Remaining: d.budgetRemaining(userID, period)}
+	if b, ok := d.budgets[userID]; ok {
+		result.Limit = b.limit
+	}
+	return &result, nil
+}
+
+// budgetRemaining остаток бюджета в периоде, который начался в period. С началом
+// нового периода бюджет снова равен лимиту, как в postgres. Вызывается под блокировкой.
+func (d *tenant) budgetRemaining(userID uuid.UUID, period time.Time) int {
+	b, ok := d.budgets[userID]
+	if !ok {
+		return 0
+	}
+	if b.period.Before(period) {
+		return b.limit
+	}
+	return b.remaining
+}
+
+// walletBudget бюджет сотрудника для WalletInfo, вызывается под блокировкой
+func (d *tenant) walletBudget(userID uuid.UUID) storage.WalletBudget {
+	result := storage.WalletBudget{TenantPeriod: d.settings.BudgetPeriod}
+	if b, ok := d.budgets[userID]; ok {
+		result.Limit, result.Budget, result.Period = b.limit, b.remaining, b.period
+	}
+	return result
+}
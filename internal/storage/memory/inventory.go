@@ -0,0 +1,108 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+)
+
+// holding строка инвентаря, variantID 0 - товар без вариантов
+type holding struct {
+	employeeID uuid.UUID
+	itemID     int
+	variantID  int
+}
+
+// ledgerEntry запись журнала инвентаря, purchaseID 0 - передача без заказа
+type ledgerEntry struct {
+	holding
+	kind           string
+	quantity       int
+	purchaseID     int64
+	counterpartyID uuid.UUID
+	createdAt      time.Time
+}
+
+func (s *Storage) TransferItemsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	transfer storage.ItemTransfer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	if _, ok := d.employees[receiverID]; !ok {
+		return sql.ErrNoRows
+	}
+	from := holding{senderID, transfer.MerchID, transfer.VariantID}
+	if d.holdings[from] < transfer.Quantity {
+		return storage.ErrNotEnoughItems
+	}
+	event, err := events.New(events.TypeItemsTransferred, events.ItemsTransferred{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		ItemID:     transfer.MerchID,
+		Item:       transfer.Name,
+		SKU:        transfer.SKU,
+		Quantity:   transfer.Quantity,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	to := holding{receiverID, transfer.MerchID, transfer.VariantID}
+	d.addItems(from, storage.LedgerGifted, -transfer.Quantity, 0, receiverID, now)
+	d.addItems(to, storage.LedgerReceived, transfer.Quantity, 0, senderID, now)
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+func (s *Storage) GetInventoryLedger(ctx context.Context, userID uuid.UUID, limit int) ([]storage.LedgerEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var entries []storage.LedgerEntry
+	for i := len(d.ledger) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := d.ledger[i]
+		if e.employeeID != userID {
+			continue
+		}
+		entry := storage.LedgerEntry{
+			ID:        int64(i + 1),
+			Kind:      e.kind,
+			Item:      d.merch[e.itemID].Name,
+			Quantity:  e.quantity,
+			OrderID:   e.purchaseID,
+			CreatedAt: e.createdAt,
+		}
+		if v := d.variant(e.variantID); v != nil {
+			entry.SKU, entry.Size, entry.Color = v.SKU, v.Size, v.Color
+		}
+		if counterparty, ok := d.employees[e.counterpartyID]; ok {
+			entry.Counterparty = counterparty.Name
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// addItems меняет количество товара в инвентаре и пишет запись в журнал,
+// вызывается под блокировкой записи после проверки остатка
+func (d *tenant) addItems(h holding, kind string, quantity int, purchaseID int64, counterpartyID uuid.UUID,
+	at time.Time) {
+	d.holdings[h] += quantity
+	d.ledger = append(d.ledger, ledgerEntry{
+		holding:        h,
+		kind:           kind,
+		quantity:       quantity,
+		purchaseID:     purchaseID,
+		counterpartyID: counterpartyID,
+		createdAt:      at,
+	})
+}
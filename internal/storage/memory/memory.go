@@ -0,0 +1,556 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+)
+
+// defaultSignupBonus бонус компании по умолчанию, как DEFAULT в схеме
+const defaultSignupBonus = 1000
+
+// DefaultCatalog совпадает с начальными данными migrations/init.sql
+var DefaultCatalog = []storage.MerchItem{
+	{Name: "t-shirt", Price: 80},
+	{Name: "cup", Price: 20},
+	{Name: "book", Price: 50},
+	{Name: "pen", Price: 10},
+	{Name: "powerbank", Price: 200},
+	{Name: "hoody", Price: 300},
+	{Name: "umbrella", Price: 200},
+	{Name: "socks", Price: 10},
+	{Name: "wallet", Price: 50},
+	{Name: "pink-hoody", Price: 500},
+}
+
+type employee struct {
+	storage.Employee
+	passwordHash string
+}
+
+// purchase id заказа совпадает с позицией покупки в purchases плюс один.
+// buyerID покупатель подарка, uuid.Nil - покупка для себя. teamID команда,
+// оплатившая покупку, 0 - личная покупка.
+type purchase struct {
+	employeeID      uuid.UUID
+	buyerID         uuid.UUID
+	teamID          int64
+	giftMessage     string
+	itemID          int
+	variantID       int
+	quantity        int
+	unitPrice       int
+	total           int
+	discount        int
+	promotionID     int64
+	status          string
+	shippingAddress string
+	createdAt       time.Time
+	updatedAt       time.Time
+}
+
+type transaction struct {
+	senderID   uuid.UUID
+	receiverID uuid.UUID
+	amount     int
+	createdAt  time.Time
+}
+
+type outboxRecord struct {
+	storage.OutboxEvent
+	lockedUntil time.Time
+	published   bool
+}
+
+// Storage хранит данные в памяти процесса и повторяет семантику
+// postgres реализации: проверку баланса, уникальность username и
+// регистронезависимый поиск как у ILIKE.
+type Storage struct {
+	mu sync.RWMutex
+	// tenants id компании совпадает с позицией в tenants плюс один
+	tenants    []*tenant
+	outbox     []*outboxRecord
+	deliveries []*storage.WebhookDelivery
+}
+
+// tenant данные одной компании, компании не видят данные друг друга
+type tenant struct {
+	settings     storage.Tenant
+	employees    map[uuid.UUID]*employee
+	wallets      map[uuid.UUID]int
+	merch        map[int]storage.MerchItem
+	variants     []storage.MerchVariant
+	prices       []storage.MerchPrice
+	purchases    []purchase
+	holdings     map[holding]int
+	ledger       []ledgerEntry
+	transactions []transaction
+	webhooks     []storage.Webhook
+	promotions   []storage.Promotion
+	// wishlist без Item и Price, они берутся из каталога при чтении
+	wishlist []storage.WishlistItem
+	wishSeq  int64
+	// teams id команды совпадает с позицией в teams плюс один, у участников нет Username
+	teams       []storage.Team
+	teamEntries []teamEntry
+	// budgets бюджеты на благодарности, сотрудника без бюджета нет в map
+	budgets map[uuid.UUID]*budget
+	// onboarding бонусы за регистрацию по имени сотрудника в нижнем регистре
+	onboarding    map[string]*storage.Onboarding
+	onboardingSeq int64
+}
+
+func New() *Storage {
+	return NewWithCatalog(DefaultCatalog)
+}
+
+func NewWithCatalog(catalog []storage.MerchItem) *Storage {
+	s := &Storage{}
+	s.tenants = append(s.tenants, newTenant(storage.Tenant{
+		ID:          storage.DefaultTenantID,
+		Slug:        "default",
+		Name:        "Default",
+		SignupBonus: defaultSignupBonus,
+		CreatedAt:   time.Now().UTC(),
+	}, catalog))
+	return s
+}
+
+func newTenant(settings storage.Tenant, catalog []storage.MerchItem) *tenant {
+	d := &tenant{
+		settings:  settings,
+		employees: make(map[uuid.UUID]*employee),
+		wallets:   make(map[uuid.UUID]int),
+		merch:     make(map[int]storage.MerchItem),
+		holdings:  make(map[holding]int),
+		budgets:   make(map[uuid.UUID]*budget),
+
+		onboarding: make(map[string]*storage.Onboarding),
+	}
+	for i, item := range catalog {
+		item.MerchID = i + 1
+		d.merch[item.MerchID] = item
+		d.prices = append(d.prices, storage.MerchPrice{ItemID: item.MerchID, Price: item.Price,
+			EffectiveFrom: settings.CreatedAt})
+	}
+	return d
+}
+
+// data данные компании запроса. Для неизвестной компании возвращаются
+// пустые данные, как пустой результат запроса с чужим tenant_id.
+func (s *Storage) data(ctx context.Context) *tenant {
+	id := storage.TenantID(ctx)
+	if id < 1 || id > int64(len(s.tenants)) {
+		return newTenant(storage.Tenant{ID: id}, nil)
+	}
+	return s.tenants[id-1]
+}
+
+func (s *Storage) GetUser4UserID(ctx context.Context, userID uuid.UUID) (*storage.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	e, ok := d.employees[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	user := e.Employee
+	return &user, nil
+}
+
+func (s *Storage) GetUserAuthData(ctx context.Context, username string) (*storage.AuthData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	e := d.findByUsername(username)
+	if e == nil {
+		return nil, storage.ErrUserNotFound
+	}
+	return &storage.AuthData{UserID: e.EmployeeId, PasswordHash: e.passwordHash}, nil
+}
+
+func (s *Storage) NewUser(ctx context.Context, username string, passwordHash string, onboarding *storage.Onboarding) (
+	uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	for _, e := range d.employees {
+		if e.Name == username {
+			return uuid.Nil, storage.ErrUsernameTaken
+		}
+	}
+
+	userID := uuid.New()
+	event, err := events.New(events.TypeUserRegistered, events.UserRegistered{
+		UserID:   userID,
+		Username: username,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if onboarding.Role == "" {
+		onboarding.Role = storage.RoleEmployee
+	}
+	d.employees[userID] = &employee{
+		Employee: storage.Employee{
+			EmployeeId: userID,
+			Name:       username,
+			Role:       onboarding.Role,
+			CreatedAt:  time.Now(),
+		},
+		passwordHash: passwordHash,
+	}
+	d.wallets[userID] = d.onboard(userID, username, onboarding)
+	s.appendEvent(ctx, event)
+	return userID, nil
+}
+
+func (s *Storage) FindUser(ctx context.Context, username string) (*storage.Employee, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	e := d.findByUsername(username)
+	if e == nil {
+		return nil, sql.ErrNoRows
+	}
+	user := e.Employee
+	user.Email = nil
+	return &user, nil
+}
+
+func (s *Storage) GetBalance(ctx context.Context, userID uuid.UUID) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	balance, ok := d.wallets[userID]
+	if !ok {
+		return 0, sql.ErrNoRows
+	}
+	return balance, nil
+}
+
+func (s *Storage) GetInventories(ctx context.Context, userID uuid.UUID) ([]storage.InventoryItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	return d.inventory(userID), nil
+}
+
+func (d *tenant) inventory(userID uuid.UUID) []storage.InventoryItem {
+	var inventoryList []storage.InventoryItem
+	for h, quantity := range d.holdings {
+		if h.employeeID != userID || quantity == 0 {
+			continue
+		}
+		item := storage.InventoryItem{Name: d.merch[h.itemID].Name, Quantity: quantity}
+		if v := d.variant(h.variantID); v != nil {
+			item.SKU, item.Size, item.Color = v.SKU, v.Size, v.Color
+		}
+		inventoryList = append(inventoryList, item)
+	}
+	sort.Slice(inventoryList, func(i, j int) bool {
+		if inventoryList[i].Name != inventoryList[j].Name {
+			return inventoryList[i].Name < inventoryList[j].Name
+		}
+		return inventoryList[i].SKU < inventoryList[j].SKU
+	})
+	return inventoryList
+}
+
+func (s *Storage) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]storage.SenderInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	return d.coinHistory(func(t transaction) (uuid.UUID, bool) {
+		return t.senderID, t.receiverID == userID
+	}), nil
+}
+
+func (s *Storage) GetSendedCoins(ctx context.Context, userID uuid.UUID) ([]storage.SenderInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	return d.coinHistory(func(t transaction) (uuid.UUID, bool) {
+		return t.receiverID, t.senderID == userID
+	}), nil
+}
+
+// GetWalletInfo собирает все под одной блокировкой, данные согласованы между собой
+func (s *Storage) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*storage.WalletInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	balance, ok := d.wallets[userID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &storage.WalletInfo{
+		Balance:   balance,
+		Inventory: d.inventory(userID),
+		Received: d.coinHistory(func(t transaction) (uuid.UUID, bool) {
+			return t.senderID, t.receiverID == userID
+		}),
+		Sent: d.coinHistory(func(t transaction) (uuid.UUID, bool) {
+			return t.receiverID, t.senderID == userID
+		}),
+		Wishlist: d.wishes(userID),
+		Budget:   d.walletBudget(userID),
+	}, nil
+}
+
+func (s *Storage) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int,
+	period time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	senderBalance, ok := d.wallets[senderID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := d.wallets[receiverID]; !ok {
+		return sql.ErrNoRows
+	}
+	remaining := d.budgetRemaining(senderID, period)
+	fromBudget := min(remaining, amount)
+	if senderBalance-(amount-fromBudget) < 0 {
+		return storage.ErrNotEnoughCoins
+	}
+	event, err := events.New(events.TypeCoinsTransferred, events.CoinsTransferred{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Amount:     amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	if b, ok := d.budgets[senderID]; ok {
+		b.remaining, b.period = remaining-fromBudget, period
+	}
+	d.wallets[senderID] -= amount - fromBudget
+	d.wallets[receiverID] += amount
+	d.transactions = append(d.transactions, transaction{
+		senderID:   senderID,
+		receiverID: receiverID,
+		amount:     amount,
+		createdAt:  time.Now(),
+	})
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+func (s *Storage) PurchaseMerchTransaction(ctx context.Context, userID uuid.UUID, merch storage.MerchInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	balance, ok := d.wallets[userID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	var team *storage.Team
+	var teamID *int64
+	if merch.TeamID != 0 {
+		if team = d.team(merch.TeamID); team == nil {
+			return sql.ErrNoRows
+		}
+		balance, teamID = team.Balance, &merch.TeamID
+	}
+	if _, ok := d.merch[merch.MerchID]; !ok {
+		return sql.ErrNoRows
+	}
+	ownerID, buyerID := userID, uuid.Nil
+	var recipientID *uuid.UUID
+	if merch.RecipientID != uuid.Nil {
+		if _, ok := d.employees[merch.RecipientID]; !ok {
+			return sql.ErrNoRows
+		}
+		ownerID, buyerID = merch.RecipientID, userID
+		recipientID = &merch.RecipientID
+	}
+
+	now := time.Now().UTC()
+	var promotion *storage.Promotion
+	if merch.PromotionID != 0 {
+		var err error
+		if promotion, err = d.redeemable(userID, merch.PromotionID, now); err != nil {
+			return err
+		}
+	}
+	variant := d.variant(merch.VariantID)
+	if merch.VariantID != 0 && (variant == nil || variant.Stock < merch.Amount) {
+		return storage.ErrOutOfStock
+	}
+
+	total := merch.Price*merch.Amount - merch.Discount
+	if balance-total < 0 {
+		return storage.ErrNotEnoughCoins
+	}
+	event, err := events.New(events.TypeMerchPurchased, events.MerchPurchased{
+		UserID:      userID,
+		ItemID:      merch.MerchID,
+		Item:        merch.Name,
+		SKU:         merch.SKU,
+		Quantity:    merch.Amount,
+		Price:       merch.Price,
+		Discount:    merch.Discount,
+		Total:       total,
+		RecipientID: recipientID,
+		Message:     merch.GiftMessage,
+		TeamID:      teamID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if promotion != nil {
+		promotion.Redemptions++
+	}
+	if variant != nil {
+		variant.Stock -= merch.Amount
+	}
+	orderID := int64(len(d.purchases) + 1)
+	if team != nil {
+		team.Balance -= total
+		d.addTeamEntry(team.ID, storage.TeamPurchase, -total, userID, orderID, now)
+	} else {
+		d.wallets[userID] -= total
+	}
+	ledgerKind := storage.LedgerAcquired
+	if buyerID != uuid.Nil {
+		ledgerKind = storage.LedgerReceived
+	}
+	d.addItems(holding{ownerID, merch.MerchID, merch.VariantID}, ledgerKind, merch.Amount, orderID, buyerID, now)
+	d.purchases = append(d.purchases, purchase{
+		employeeID:  ownerID,
+		buyerID:     buyerID,
+		teamID:      merch.TeamID,
+		giftMessage: merch.GiftMessage,
+		itemID:      merch.MerchID,
+		variantID:   merch.VariantID,
+		quantity:    merch.Amount,
+		unitPrice:   merch.Price,
+		total:       total,
+		discount:    merch.Discount,
+		promotionID: merch.PromotionID,
+		status:      storage.OrderPlaced,
+		createdAt:   now,
+		updatedAt:   now,
+	})
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+func (s *Storage) GetMerchItems(ctx context.Context, merchName string) (*storage.MerchItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	for _, item := range d.merch {
+		if item.Name == merchName {
+			item.Variants = d.itemVariants(item.MerchID)
+			return &item, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (s *Storage) ClaimOutboxEvents(_ context.Context, limit int, lease time.Duration) ([]storage.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var outboxEvents []storage.OutboxEvent
+	for _, r := range s.outbox {
+		if len(outboxEvents) >= limit {
+			break
+		}
+		if r.published || r.lockedUntil.After(now) {
+			continue
+		}
+		r.lockedUntil = now.Add(lease)
+		outboxEvents = append(outboxEvents, r.OutboxEvent)
+	}
+	return outboxEvents, nil
+}
+
+func (s *Storage) MarkOutboxEventsPublished(_ context.Context, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range ids {
+		// id записи совпадает с ее позицией в outbox
+		if id >= 1 && int(id) <= len(s.outbox) {
+			s.outbox[id-1].published = true
+		}
+	}
+	return nil
+}
+
+// appendEvent добавляет событие в outbox, вызывается под блокировкой записи
+func (s *Storage) appendEvent(ctx context.Context, event events.Event) {
+	event.TenantID = storage.TenantID(ctx)
+	s.outbox = append(s.outbox, &outboxRecord{
+		OutboxEvent: storage.OutboxEvent{ID: int64(len(s.outbox) + 1), Event: event},
+	})
+}
+
+// findByUsername повторяет "username ILIKE ?" без поддержки шаблонов
+func (d *tenant) findByUsername(username string) *employee {
+	for _, e := range d.employees {
+		if strings.EqualFold(e.Name, username) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (d *tenant) coinHistory(match func(t transaction) (uuid.UUID, bool)) []storage.SenderInfo {
+	amounts := make(map[string]int)
+	for _, t := range d.transactions {
+		counterpartyID, ok := match(t)
+		if !ok {
+			continue
+		}
+		if e, ok := d.employees[counterpartyID]; ok {
+			amounts[e.Name] += t.amount
+		}
+	}
+
+	var senderInfoList []storage.SenderInfo
+	for username, amount := range amounts {
+		senderInfoList = append(senderInfoList, storage.SenderInfo{Username: username, Amount: amount})
+	}
+	sort.Slice(senderInfoList, func(i, j int) bool { return senderInfoList[i].Username < senderInfoList[j].Username })
+	return senderInfoList
+}
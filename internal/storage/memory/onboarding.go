@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/google/uuid"
+)
+
+// onboard повторяет storage.Queries: бонус выдается один раз на имя, наступивший
+// бонус сразу попадает на баланс. Возвращает начальный баланс.
+func (d *tenant) onboard(userID uuid.UUID, username string, onboarding *storage.Onboarding) int {
+	now := time.Now().UTC()
+	onboarding.TenantID = d.settings.ID
+	onboarding.EmployeeID = userID
+	onboarding.Username = strings.ToLower(username)
+	onboarding.VestsAt = onboarding.VestsAt.UTC()
+	onboarding.CreatedAt = now
+	onboarding.VestedAt = nil
+	if _, ok := d.onboarding[onboarding.Username]; ok {
+		onboarding.ID = 0
+		onboarding.Bonus = 0
+		return 0
+	}
+
+	d.onboardingSeq++
+	onboarding.ID = d.onboardingSeq
+	balance := 0
+	if !onboarding.VestsAt.After(now) {
+		onboarding.VestedAt = &now
+		balance = onboarding.Bonus
+	}
+	record := *onboarding
+	d.onboarding[onboarding.Username] = &record
+	return balance
+}
+
+func (s *Storage) VestSignupBonuses(_ context.Context, now time.Time, limit int) ([]storage.Onboarding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now = now.UTC()
+	var due []*storage.Onboarding
+	for _, d := range s.tenants {
+		for _, o := range d.onboarding {
+			if o.VestedAt == nil && !o.VestsAt.After(now) {
+				due = append(due, o)
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].VestsAt.Before(due[j].VestsAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var vested []storage.Onboarding
+	for _, o := range due {
+		vestedAt := now
+		o.VestedAt = &vestedAt
+		d := s.tenants[o.TenantID-1]
+		if _, ok := d.wallets[o.EmployeeID]; ok {
+			d.wallets[o.EmployeeID] += o.Bonus
+		}
+		vested = append(vested, *o)
+	}
+	return vested, nil
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+)
+
+func (s *Storage) GetOrders(ctx context.Context, userID uuid.UUID) ([]storage.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var orders []storage.Order
+	for i := len(d.purchases) - 1; i >= 0; i-- {
+		if d.purchases[i].employeeID == userID || d.purchases[i].buyerID == userID {
+			orders = append(orders, d.order(i))
+		}
+	}
+	return orders, nil
+}
+
+func (s *Storage) ListOrders(ctx context.Context, status string, limit int) ([]storage.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var orders []storage.Order
+	for i := range d.purchases {
+		if len(orders) >= limit {
+			break
+		}
+		if status == "" || d.purchases[i].status == status {
+			orders = append(orders, d.order(i))
+		}
+	}
+	return orders, nil
+}
+
+func (s *Storage) GetOrder(ctx context.Context, orderID int64) (*storage.Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	if orderID < 1 || int(orderID) > len(d.purchases) {
+		return nil, storage.ErrOrderNotFound
+	}
+	order := d.order(int(orderID) - 1)
+	return &order, nil
+}
+
+func (s *Storage) SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string, from []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	p, err := d.findOrder(orderID, userID, from)
+	if err != nil {
+		return err
+	}
+	p.shippingAddress = address
+	p.updatedAt = time.Now().UTC()
+	return nil
+}
+
+func (s *Storage) UpdateOrderStatus(ctx context.Context, update storage.OrderStatusUpdate) (*storage.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	p, err := d.findOrder(update.OrderID, update.UserID, update.From)
+	if err != nil {
+		return nil, err
+	}
+	refund := 0
+	owned := holding{p.employeeID, p.itemID, p.variantID}
+	if update.To == storage.OrderCancelled {
+		refund = p.total
+		if d.holdings[owned] < p.quantity {
+			return nil, storage.ErrNotEnoughItems
+		}
+	}
+	event, err := events.New(events.TypeOrderStatusChanged, events.OrderStatusChanged{
+		OrderID: update.OrderID,
+		UserID:  p.employeeID,
+		Status:  update.To,
+		Refund:  refund,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.status = update.To
+	p.updatedAt = time.Now().UTC()
+	if t := d.team(p.teamID); t != nil {
+		t.Balance += refund
+	} else {
+		d.wallets[p.payer()] += refund
+	}
+	if update.To == storage.OrderCancelled {
+		if p.teamID != 0 {
+			d.addTeamEntry(p.teamID, storage.TeamRefund, refund, p.employeeID, update.OrderID, p.updatedAt)
+		}
+		d.addItems(owned, storage.LedgerReturned, -p.quantity, update.OrderID, uuid.Nil, p.updatedAt)
+		if v := d.variant(p.variantID); v != nil {
+			v.Stock += p.quantity
+		}
+		if p.promotionID != 0 && d.promotions[p.promotionID-1].Redemptions > 0 {
+			d.promotions[p.promotionID-1].Redemptions--
+		}
+	}
+	s.appendEvent(ctx, event)
+
+	order := d.order(int(update.OrderID) - 1)
+	return &order, nil
+}
+
+// findOrder повторяет условный UPDATE: заказ видят владелец и покупатель подарка,
+// чужой заказ неотличим от несуществующего
+func (d *tenant) findOrder(orderID int64, userID uuid.UUID, from []string) (*purchase, error) {
+	if orderID < 1 || int(orderID) > len(d.purchases) {
+		return nil, storage.ErrOrderNotFound
+	}
+	p := &d.purchases[orderID-1]
+	if userID != uuid.Nil && p.employeeID != userID && p.buyerID != userID {
+		return nil, storage.ErrOrderNotFound
+	}
+	if !slices.Contains(from, p.status) {
+		return nil, storage.ErrOrderStatus
+	}
+	return p, nil
+}
+
+func (d *tenant) order(i int) storage.Order {
+	p := d.purchases[i]
+	order := storage.Order{
+		ID:              int64(i + 1),
+		UserID:          p.employeeID,
+		Item:            d.merch[p.itemID].Name,
+		Quantity:        p.quantity,
+		UnitPrice:       p.unitPrice,
+		Total:           p.total,
+		Discount:        p.discount,
+		PromoCode:       d.promoCode(p.promotionID),
+		Status:          p.status,
+		ShippingAddress: p.shippingAddress,
+		CreatedAt:       p.createdAt,
+		UpdatedAt:       p.updatedAt,
+	}
+	if v := d.variant(p.variantID); v != nil {
+		order.SKU, order.Size, order.Color = v.SKU, v.Size, v.Color
+	}
+	if t := d.team(p.teamID); t != nil {
+		order.TeamID, order.Team = t.ID, t.Name
+	}
+	if p.buyerID != uuid.Nil {
+		order.Gift = &storage.Gift{
+			FromID:  p.buyerID,
+			From:    d.employees[p.buyerID].Name,
+			To:      d.employees[p.employeeID].Name,
+			Message: p.giftMessage,
+		}
+	}
+	return order
+}
+
+// payer покупатель, оплативший заказ
+func (p purchase) payer() uuid.UUID {
+	if p.buyerID != uuid.Nil {
+		return p.buyerID
+	}
+	return p.employeeID
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+)
+
+func (s *Storage) SetMerchPrice(ctx context.Context, item storage.MerchItem, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	merch, ok := d.merch[item.MerchID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	at = at.UTC()
+	for i := range d.prices {
+		if d.prices[i].ItemID == item.MerchID && d.prices[i].EffectiveTo == nil {
+			d.prices[i].EffectiveTo = &at
+		}
+	}
+	d.prices = append(d.prices, storage.MerchPrice{ItemID: item.MerchID, Price: item.Price, EffectiveFrom: at})
+	merch.Price = item.Price
+	d.merch[item.MerchID] = merch
+	return nil
+}
+
+func (s *Storage) GetPriceHistory(ctx context.Context, itemID int) ([]storage.MerchPrice, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	// записи добавляются в порядке effective_from
+	var prices []storage.MerchPrice
+	for i := len(d.prices) - 1; i >= 0; i-- {
+		if d.prices[i].ItemID == itemID {
+			prices = append(prices, d.prices[i])
+		}
+	}
+	return prices, nil
+}
+
+func (s *Storage) GetSalesReport(ctx context.Context, from, to time.Time) ([]storage.SalesReportLine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	type lineKey struct {
+		itemID    int
+		unitPrice int
+	}
+	lines := make(map[lineKey]*storage.SalesReportLine)
+	for _, p := range d.purchases {
+		if p.status == storage.OrderCancelled || p.createdAt.Before(from) || !p.createdAt.Before(to) {
+			continue
+		}
+		k := lineKey{itemID: p.itemID, unitPrice: p.unitPrice}
+		l, ok := lines[k]
+		if !ok {
+			l = &storage.SalesReportLine{Item: d.merch[p.itemID].Name, UnitPrice: p.unitPrice}
+			lines[k] = l
+		}
+		l.Orders++
+		l.Quantity += p.quantity
+		l.Gross += p.unitPrice * p.quantity
+		l.Discount += p.discount
+		l.Total += p.total
+	}
+
+	var report []storage.SalesReportLine
+	for _, l := range lines {
+		report = append(report, *l)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Item != report[j].Item {
+			return report[i].Item < report[j].Item
+		}
+		return report[i].UnitPrice < report[j].UnitPrice
+	})
+	return report, nil
+}
@@ -0,0 +1,124 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/google/uuid"
+)
+
+func (s *Storage) CreatePromotion(ctx context.Context, promotion *storage.Promotion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	for _, p := range d.promotions {
+		if promotion.Code != "" && p.Code == promotion.Code {
+			return storage.ErrPromotionCodeTaken
+		}
+	}
+	promotion.ID = int64(len(d.promotions) + 1)
+	promotion.StartsAt = promotion.StartsAt.UTC()
+	promotion.EndsAt = promotion.EndsAt.UTC()
+	promotion.CreatedAt = time.Now().UTC()
+	promotion.Redemptions = 0
+	promotion.Item = ""
+	if promotion.ItemID != nil {
+		promotion.Item = d.merch[*promotion.ItemID].Name
+	}
+	d.promotions = append(d.promotions, *promotion)
+	return nil
+}
+
+func (s *Storage) ListPromotions(ctx context.Context) ([]storage.Promotion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var promotions []storage.Promotion
+	for i := len(d.promotions) - 1; i >= 0; i-- {
+		promotions = append(promotions, d.promotions[i])
+	}
+	return promotions, nil
+}
+
+func (s *Storage) GetPromotionByCode(ctx context.Context, code string) (*storage.Promotion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	for _, p := range d.promotions {
+		if p.Code != "" && p.Code == code {
+			return &p, nil
+		}
+	}
+	return nil, storage.ErrPromotionNotFound
+}
+
+func (s *Storage) GetActiveSales(ctx context.Context, itemID int, at time.Time) ([]storage.Promotion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var sales []storage.Promotion
+	for _, p := range d.promotions {
+		if p.Code == "" && p.Active(itemID, at) {
+			sales = append(sales, p)
+		}
+	}
+	return sales, nil
+}
+
+func (s *Storage) EndPromotion(ctx context.Context, promotionID int64, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	if promotionID < 1 || int(promotionID) > len(d.promotions) {
+		return storage.ErrPromotionNotFound
+	}
+	p := &d.promotions[promotionID-1]
+	if p.EndsAt.After(at) {
+		p.EndsAt = at.UTC()
+	}
+	return nil
+}
+
+// redeemable проверяет, что пользователь может погасить промокод в момент at
+func (d *tenant) redeemable(userID uuid.UUID, promotionID int64, at time.Time) (*storage.Promotion, error) {
+	if promotionID < 1 || int(promotionID) > len(d.promotions) {
+		return nil, storage.ErrPromoInvalid
+	}
+	p := &d.promotions[promotionID-1]
+	if at.Before(p.StartsAt) || !at.Before(p.EndsAt) {
+		return nil, storage.ErrPromoInvalid
+	}
+	if p.MaxRedemptions > 0 && p.Redemptions >= p.MaxRedemptions {
+		return nil, storage.ErrPromoExhausted
+	}
+	if p.PerUserLimit > 0 {
+		used := 0
+		for _, purchase := range d.purchases {
+			if purchase.promotionID == promotionID && purchase.payer() == userID && purchase.status != storage.OrderCancelled {
+				used++
+			}
+		}
+		if used >= p.PerUserLimit {
+			return nil, storage.ErrPromoExhausted
+		}
+	}
+	return p, nil
+}
+
+func (d *tenant) promoCode(promotionID int64) string {
+	if promotionID == 0 {
+		return ""
+	}
+	return d.promotions[promotionID-1].Code
+}
@@ -0,0 +1,238 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+)
+
+// teamEntry запись истории командного кошелька, purchaseID 0 - операция без заказа
+type teamEntry struct {
+	teamID     int64
+	kind       string
+	amount     int
+	employeeID uuid.UUID
+	purchaseID int64
+	createdAt  time.Time
+}
+
+func (s *Storage) CreateTeam(ctx context.Context, team *storage.Team) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	for _, t := range d.teams {
+		if t.Name == team.Name {
+			return storage.ErrTeamTaken
+		}
+	}
+	team.ID, team.Balance, team.CreatedAt = int64(len(d.teams)+1), 0, time.Now().UTC()
+	d.teams = append(d.teams, storage.Team{ID: team.ID, Name: team.Name, CreatedAt: team.CreatedAt})
+	return nil
+}
+
+func (s *Storage) GetTeam(ctx context.Context, teamID int64) (*storage.Team, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	t := d.team(teamID)
+	if t == nil {
+		return nil, storage.ErrTeamNotFound
+	}
+	team := d.teamView(*t)
+	return &team, nil
+}
+
+func (s *Storage) GetTeams(ctx context.Context, userID uuid.UUID) ([]storage.Team, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var teams []storage.Team
+	for _, t := range d.teams {
+		if t.Member(userID) != nil {
+			teams = append(teams, d.teamView(t))
+		}
+	}
+	return teams, nil
+}
+
+func (s *Storage) SetTeamMember(ctx context.Context, teamID int64, userID uuid.UUID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	t := d.team(teamID)
+	if t == nil {
+		return storage.ErrTeamNotFound
+	}
+	if _, ok := d.employees[userID]; !ok {
+		return sql.ErrNoRows
+	}
+	if m := t.Member(userID); m != nil {
+		m.Role = role
+		return nil
+	}
+	t.Members = append(t.Members, storage.TeamMember{EmployeeID: userID, Role: role})
+	return nil
+}
+
+func (s *Storage) RemoveTeamMember(ctx context.Context, teamID int64, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	t := d.team(teamID)
+	if t == nil {
+		return storage.ErrNotTeamMember
+	}
+	for i, m := range t.Members {
+		if m.EmployeeID == userID {
+			t.Members = append(t.Members[:i], t.Members[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrNotTeamMember
+}
+
+func (s *Storage) DepositTeamCoins(ctx context.Context, teamID int64, actorID uuid.UUID, amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	t := d.team(teamID)
+	if t == nil {
+		return storage.ErrTeamNotFound
+	}
+	if d.wallets[actorID]-amount < 0 {
+		return storage.ErrNotEnoughCoins
+	}
+	event, err := events.New(events.TypeTeamCoinsDeposited, events.TeamCoinsDeposited{
+		TeamID:  teamID,
+		ActorID: actorID,
+		Amount:  amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	d.wallets[actorID] -= amount
+	t.Balance += amount
+	d.addTeamEntry(teamID, storage.TeamDeposit, amount, actorID, 0, time.Now().UTC())
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+func (s *Storage) DistributeTeamCoins(ctx context.Context, teamID int64, managerID uuid.UUID, receiverID uuid.UUID,
+	amount int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	t := d.team(teamID)
+	if t == nil {
+		return storage.ErrTeamNotFound
+	}
+	if _, ok := d.wallets[receiverID]; !ok {
+		return sql.ErrNoRows
+	}
+	if t.Balance-amount < 0 {
+		return storage.ErrNotEnoughCoins
+	}
+	event, err := events.New(events.TypeTeamCoinsDistributed, events.TeamCoinsDistributed{
+		TeamID:     teamID,
+		ManagerID:  managerID,
+		ReceiverID: receiverID,
+		Amount:     amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	t.Balance -= amount
+	d.wallets[receiverID] += amount
+	d.addTeamEntry(teamID, storage.TeamDistribution, -amount, receiverID, 0, time.Now().UTC())
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+func (s *Storage) GetTeamHistory(ctx context.Context, teamID int64, limit int) ([]storage.TeamEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var entries []storage.TeamEntry
+	for i := len(d.teamEntries) - 1; i >= 0 && len(entries) < limit; i-- {
+		e := d.teamEntries[i]
+		if e.teamID != teamID {
+			continue
+		}
+		entry := storage.TeamEntry{
+			ID:        int64(i + 1),
+			Kind:      e.kind,
+			Amount:    e.amount,
+			OrderID:   e.purchaseID,
+			CreatedAt: e.createdAt,
+		}
+		if employee, ok := d.employees[e.employeeID]; ok {
+			entry.Employee = employee.Name
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// team команда по id, nil - команды нет. Вызывается под блокировкой.
+func (d *tenant) team(teamID int64) *storage.Team {
+	if teamID < 1 || int(teamID) > len(d.teams) {
+		return nil
+	}
+	return &d.teams[teamID-1]
+}
+
+// teamView копия команды с именами участников, менеджеры первыми как в postgres
+func (d *tenant) teamView(t storage.Team) storage.Team {
+	members := make([]storage.TeamMember, 0, len(t.Members))
+	for _, m := range t.Members {
+		m.Username = d.employees[m.EmployeeID].Name
+		members = append(members, m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return members[i].Role < members[j].Role
+		}
+		return members[i].Username < members[j].Username
+	})
+	t.Members = members
+	if len(members) == 0 {
+		t.Members = nil
+	}
+	return t
+}
+
+// addTeamEntry пишет запись в историю командного кошелька, вызывается под блокировкой записи
+func (d *tenant) addTeamEntry(teamID int64, kind string, amount int, employeeID uuid.UUID, purchaseID int64,
+	at time.Time) {
+	d.teamEntries = append(d.teamEntries, teamEntry{
+		teamID:     teamID,
+		kind:       kind,
+		amount:     amount,
+		employeeID: employeeID,
+		purchaseID: purchaseID,
+		createdAt:  at,
+	})
+}
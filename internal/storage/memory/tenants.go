@@ -0,0 +1,114 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/google/uuid"
+)
+
+func (s *Storage) CreateTenant(_ context.Context, settings *storage.Tenant, catalog []storage.MerchItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tenants {
+		if t.settings.Slug == settings.Slug {
+			return storage.ErrTenantTaken
+		}
+	}
+	if catalog == nil {
+		// копия текущего каталога компании по умолчанию, как INSERT ... SELECT
+		def := s.tenants[storage.DefaultTenantID-1]
+		for _, item := range def.merch {
+			catalog = append(catalog, item)
+		}
+		sort.Slice(catalog, func(i, j int) bool { return catalog[i].MerchID < catalog[j].MerchID })
+	}
+
+	settings.ID = int64(len(s.tenants) + 1)
+	settings.CreatedAt = time.Now().UTC()
+	settings.Admins = append([]string(nil), settings.Admins...)
+	settings.AdminSignupBonus = copyBonus(settings.AdminSignupBonus)
+	s.tenants = append(s.tenants, newTenant(*settings, catalog))
+	return nil
+}
+
+func (s *Storage) GetTenant(_ context.Context, tenantID int64) (*storage.Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if tenantID < 1 || tenantID > int64(len(s.tenants)) {
+		return nil, storage.ErrTenantNotFound
+	}
+	return s.tenants[tenantID-1].copySettings(), nil
+}
+
+func (s *Storage) GetTenantBySlug(_ context.Context, slug string) (*storage.Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.tenants {
+		if t.settings.Slug == slug {
+			return t.copySettings(), nil
+		}
+	}
+	return nil, storage.ErrTenantNotFound
+}
+
+func (s *Storage) ListTenants(_ context.Context) ([]storage.Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tenants []storage.Tenant
+	for _, t := range s.tenants {
+		tenants = append(tenants, *t.copySettings())
+	}
+	return tenants, nil
+}
+
+func (s *Storage) UpdateTenant(_ context.Context, settings storage.Tenant, admins []string) ([]uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if settings.ID < 1 || settings.ID > int64(len(s.tenants)) {
+		return nil, storage.ErrTenantNotFound
+	}
+	t := s.tenants[settings.ID-1]
+	t.settings.Name = settings.Name
+	t.settings.SignupBonus = settings.SignupBonus
+	t.settings.AdminSignupBonus = copyBonus(settings.AdminSignupBonus)
+	t.settings.BonusVestingDays = settings.BonusVestingDays
+	t.settings.BudgetPeriod = settings.BudgetPeriod
+	t.settings.Admins = append([]string(nil), settings.Admins...)
+	var changed []uuid.UUID
+	for id, e := range t.employees {
+		role := storage.RoleEmployee
+		if slices.ContainsFunc(admins, func(admin string) bool { return strings.EqualFold(admin, e.Name) }) {
+			role = storage.RoleAdmin
+		}
+		if e.Role != role {
+			e.Role = role
+			changed = append(changed, id)
+		}
+	}
+	return changed, nil
+}
+
+func (d *tenant) copySettings() *storage.Tenant {
+	settings := d.settings
+	settings.Admins = append([]string(nil), settings.Admins...)
+	settings.AdminSignupBonus = copyBonus(settings.AdminSignupBonus)
+	return &settings
+}
+
+func copyBonus(bonus *int) *int {
+	if bonus == nil {
+		return nil
+	}
+	value := *bonus
+	return &value
+}
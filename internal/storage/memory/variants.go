@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+)
+
+func (s *Storage) CreateVariant(ctx context.Context, variant *storage.MerchVariant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	if _, ok := d.merch[variant.ItemID]; !ok {
+		return sql.ErrNoRows
+	}
+	for _, v := range d.variants {
+		if v.SKU == variant.SKU || v.ItemID == variant.ItemID && v.Size == variant.Size && v.Color == variant.Color {
+			return storage.ErrVariantTaken
+		}
+	}
+	variant.ID = len(d.variants) + 1
+	variant.Item = d.merch[variant.ItemID].Name
+	d.variants = append(d.variants, *variant)
+	return nil
+}
+
+func (s *Storage) UpdateVariant(ctx context.Context, variant storage.MerchVariant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	for i := range d.variants {
+		if d.variants[i].ItemID == variant.ItemID && d.variants[i].SKU == variant.SKU {
+			d.variants[i].Price = variant.Price
+			d.variants[i].Stock = variant.Stock
+			return nil
+		}
+	}
+	return storage.ErrVariantNotFound
+}
+
+// variant id варианта совпадает с его позицией в variants плюс один, 0 - без варианта
+func (d *tenant) variant(id int) *storage.MerchVariant {
+	if id < 1 || id > len(d.variants) {
+		return nil
+	}
+	return &d.variants[id-1]
+}
+
+func (d *tenant) itemVariants(itemID int) []storage.MerchVariant {
+	var variants []storage.MerchVariant
+	for _, v := range d.variants {
+		if v.ItemID == itemID {
+			variants = append(variants, v)
+		}
+	}
+	return variants
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/storage" //nolint:gci
+	"github.com/google/uuid"
+)
+
+func (s *Storage) CreateWebhook(ctx context.Context, webhook storage.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	webhook.EventTypes = append([]string(nil), webhook.EventTypes...)
+	d.webhooks = append(d.webhooks, webhook)
+	return nil
+}
+
+func (s *Storage) ListWebhooks(ctx context.Context) ([]storage.Webhook, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := s.data(ctx)
+
+	var webhooks []storage.Webhook
+	for _, w := range d.webhooks {
+		w.EventTypes = append([]string(nil), w.EventTypes...)
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет webhook и его доставки, как ON DELETE CASCADE
+func (s *Storage) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.data(ctx)
+	for i, w := range t.webhooks {
+		if w.ID != webhookID {
+			continue
+		}
+		t.webhooks = append(t.webhooks[:i], t.webhooks[i+1:]...)
+		deliveries := s.deliveries[:0]
+		for _, d := range s.deliveries {
+			if d.WebhookID != webhookID {
+				deliveries = append(deliveries, d)
+			}
+		}
+		s.deliveries = deliveries
+		return nil
+	}
+	return storage.ErrWebhookNotFound
+}
+
+func (s *Storage) CreateWebhookDeliveries(ctx context.Context, deliveries []storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range deliveries {
+		d.TenantID = storage.TenantID(ctx)
+		s.deliveries = append(s.deliveries, &d)
+	}
+	return nil
+}
+
+func (s *Storage) ClaimWebhookDeliveries(_ context.Context, limit int, lease time.Duration) ([]storage.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []*storage.WebhookDelivery
+	for _, d := range s.deliveries {
+		if d.Status == storage.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	var deliveries []storage.WebhookDelivery
+	for _, d := range due {
+		d.NextAttemptAt = now.Add(lease)
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) UpdateWebhookDelivery(_ context.Context, delivery storage.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDelivery(delivery.ID)
+	if d == nil {
+		return storage.ErrDeliveryNotFound
+	}
+	d.Status = delivery.Status
+	d.Attempts = delivery.Attempts
+	d.NextAttemptAt = delivery.NextAttemptAt
+	d.LastError = delivery.LastError
+	d.LastStatusCode = delivery.LastStatusCode
+	d.DeliveredAt = delivery.DeliveredAt
+	return nil
+}
+
+func (s *Storage) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]storage.WebhookDelivery, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var deliveries []storage.WebhookDelivery
+	// новые доставки в конце среза, обходим с конца
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		d := s.deliveries[i]
+		if d.TenantID != storage.TenantID(ctx) || d.WebhookID != webhookID || (status != "" && d.Status != status) {
+			continue
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, nil
+}
+
+func (s *Storage) RedeliverWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.findDelivery(deliveryID)
+	if d == nil || d.TenantID != storage.TenantID(ctx) {
+		return storage.ErrDeliveryNotFound
+	}
+	d.Status = storage.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now().UTC()
+	return nil
+}
+
+func (s *Storage) findDelivery(deliveryID uuid.UUID) *storage.WebhookDelivery {
+	for _, d := range s.deliveries {
+		if d.ID == deliveryID {
+			return d
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+)
+
+func (s *Storage) SaveWish(ctx context.Context, wish *storage.WishlistItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	if _, ok := d.employees[wish.EmployeeID]; !ok {
+		return sql.ErrNoRows
+	}
+	if _, ok := d.merch[wish.ItemID]; !ok {
+		return sql.ErrNoRows
+	}
+	if w := d.wish(wish.EmployeeID, wish.ItemID); w != nil {
+		w.Goal = wish.Goal
+		wish.ID, wish.Contributed, wish.CreatedAt = w.ID, w.Contributed, w.CreatedAt
+		return nil
+	}
+	d.wishSeq++
+	wish.ID, wish.Contributed, wish.CreatedAt = d.wishSeq, 0, time.Now().UTC()
+	d.wishlist = append(d.wishlist, storage.WishlistItem{
+		ID:         wish.ID,
+		EmployeeID: wish.EmployeeID,
+		ItemID:     wish.ItemID,
+		Goal:       wish.Goal,
+		CreatedAt:  wish.CreatedAt,
+	})
+	return nil
+}
+
+func (s *Storage) GetWishlist(ctx context.Context, userID uuid.UUID) ([]storage.WishlistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.data(ctx).wishes(userID), nil
+}
+
+// wishes возвращает вишлист сотрудника с названием и ценой товара, вызывается под блокировкой
+func (d *tenant) wishes(userID uuid.UUID) []storage.WishlistItem {
+	var wishlist []storage.WishlistItem
+	for _, w := range d.wishlist {
+		if w.EmployeeID != userID {
+			continue
+		}
+		item := d.merch[w.ItemID]
+		w.Item, w.Price = item.Name, item.Price
+		wishlist = append(wishlist, w)
+	}
+	return wishlist
+}
+
+func (s *Storage) DeleteWish(ctx context.Context, userID uuid.UUID, itemID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	for i, w := range d.wishlist {
+		if w.EmployeeID == userID && w.ItemID == itemID {
+			d.wishlist = append(d.wishlist[:i], d.wishlist[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrWishNotFound
+}
+
+func (s *Storage) ContributeTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	contribution storage.Contribution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d := s.data(ctx)
+
+	w := d.wish(receiverID, contribution.ItemID)
+	if w == nil {
+		return storage.ErrWishNotFound
+	}
+	senderBalance, ok := d.wallets[senderID]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if senderBalance-contribution.Amount < 0 {
+		return storage.ErrNotEnoughCoins
+	}
+	event, err := events.New(events.TypeWishlistContributed, events.WishlistContributed{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		ItemID:     contribution.ItemID,
+		Item:       contribution.Item,
+		Amount:     contribution.Amount,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Contributed += contribution.Amount
+	d.wallets[senderID] -= contribution.Amount
+	d.wallets[receiverID] += contribution.Amount
+	d.transactions = append(d.transactions, transaction{
+		senderID:   senderID,
+		receiverID: receiverID,
+		amount:     contribution.Amount,
+		createdAt:  time.Now(),
+	})
+	s.appendEvent(ctx, event)
+	return nil
+}
+
+// wish строка вишлиста сотрудника, nil - товара в вишлисте нет
+func (d *tenant) wish(userID uuid.UUID, itemID int) *storage.WishlistItem {
+	for i := range d.wishlist {
+		if d.wishlist[i].EmployeeID == userID && d.wishlist[i].ItemID == itemID {
+			return &d.wishlist[i]
+		}
+	}
+	return nil
+}
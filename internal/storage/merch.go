@@ -9,22 +9,115 @@ import (
 )
 
 func (q *Queries) GetMerchItems(ctx context.Context, merchName string) (*MerchItem, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 
 	sqlQuery := sqlBuilder.Select("item_id", "price").
 		From("merch_items").
-		Where(sq.Eq{"name": merchName})
+		Where(sq.Eq{"name": merchName}).
+		Where(tenantEq(ctx, ""))
 
 	var merchItem MerchItem
-	err := sqlQuery.RunWith(q.db).QueryRowContext(ctx).Scan(&merchItem.MerchID, &merchItem.Price)
+	err := sqlQuery.RunWith(q.reader(ctx)).QueryRowContext(ctx).Scan(&merchItem.MerchID, &merchItem.Price)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, err
 		}
-		q.log.Error("GetMerchItems QueryRowContext scan error:", zap.Error(err))
+		q.logger(ctx).Error("GetMerchItems QueryRowContext scan error:", zap.Error(err))
 		return nil, err
 	}
 	merchItem.Name = merchName
 
+	merchItem.Variants, err = q.getVariants(ctx, merchItem.MerchID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range merchItem.Variants {
+		merchItem.Variants[i].Item = merchName
+	}
+
 	return &merchItem, nil
 }
+
+// CreateVariant добавляет вариант товара, ID заполняется в variant
+func (q *Queries) CreateVariant(ctx context.Context, variant *MerchVariant) error {
+	sqlQuery := q.builder().Insert("merch_variants").
+		Columns("tenant_id", "item_id", "sku", "size", "color", "price", "stock").
+		Values(TenantID(ctx), variant.ItemID, variant.SKU, variant.Size, variant.Color, variantPrice(variant.Price), variant.Stock).
+		Suffix("RETURNING variant_id")
+
+	err := sqlQuery.RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&variant.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrVariantTaken
+		}
+		q.logger(ctx).Error("CreateVariant QueryRowContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// UpdateVariant задает цену и остаток варианта variant.SKU товара variant.ItemID
+func (q *Queries) UpdateVariant(ctx context.Context, variant MerchVariant) error {
+	result, err := q.builder().Update("merch_variants").
+		Set("price", variantPrice(variant.Price)).
+		Set("stock", variant.Stock).
+		Where(sq.Eq{"item_id": variant.ItemID, "sku": variant.SKU}).
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("UpdateVariant ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrVariantNotFound)
+}
+
+// reserveStock списывает quantity с остатка варианта в транзакции покупки.
+// Условный UPDATE блокирует строку варианта, остаток не уходит в минус.
+func (q *Queries) reserveStock(ctx context.Context, tx *sql.Tx, variantID int, quantity int) error {
+	result, err := q.builder().Update("merch_variants").
+		Set("stock", sq.Expr("stock - ?", quantity)).
+		Where(sq.Eq{"variant_id": variantID}).
+		Where(sq.GtOrEq{"stock": quantity}).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	return checkAffected(result, ErrOutOfStock)
+}
+
+func (q *Queries) getVariants(ctx context.Context, itemID int) ([]MerchVariant, error) {
+	sqlQuery := q.builder().Select("variant_id", "item_id", "sku", "size", "color", "COALESCE(price, 0)", "stock").
+		From("merch_variants").
+		Where(sq.Eq{"item_id": itemID}).
+		OrderBy("variant_id")
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("getVariants QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var variants []MerchVariant
+	for rows.Next() {
+		var v MerchVariant
+		if err := rows.Scan(&v.ID, &v.ItemID, &v.SKU, &v.Size, &v.Color, &v.Price, &v.Stock); err != nil {
+			q.logger(ctx).Error("getVariants rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("getVariants rows error:", zap.Error(err))
+		return nil, err
+	}
+	return variants, nil
+}
+
+// variantPrice цена варианта для записи в базу, NULL - цена товара
+func variantPrice(price int) *int {
+	if price == 0 {
+		return nil
+	}
+	return &price
+}
@@ -20,20 +20,29 @@ func TestGetMerchItems(t *testing.T) {
 	merchPrice := 80
 
 	t.Run("Success", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT item_id, price FROM merch_items WHERE name = \$1`).
-			WithArgs(merchName).
+		mock.ExpectQuery(`SELECT item_id, price FROM merch_items WHERE name = \$1 AND tenant_id = \$2`).
+			WithArgs(merchName, DefaultTenantID).
 			WillReturnRows(sqlmock.NewRows([]string{"item_id", "price"}).AddRow(merchID, merchPrice))
+		mock.ExpectQuery(`SELECT variant_id, item_id, sku, size, color, COALESCE\(price, 0\), stock FROM merch_variants WHERE item_id = \$1 ORDER BY variant_id`).
+			WithArgs(merchID).
+			WillReturnRows(sqlmock.NewRows([]string{"variant_id", "item_id", "sku", "size", "color", "price", "stock"}).
+				AddRow(1, merchID, "TSHIRT-M", "M", "", 0, 5).
+				AddRow(2, merchID, "TSHIRT-L", "L", "", 90, 0))
 
 		merchItem, err := queries.GetMerchItems(ctx, merchName)
 		assert.NoError(t, err)
 		assert.NotNil(t, merchItem)
 		assert.Equal(t, merchID, merchItem.MerchID)
 		assert.Equal(t, merchPrice, merchItem.Price)
+		assert.Equal(t, []MerchVariant{
+			{ID: 1, ItemID: merchID, Item: merchName, SKU: "TSHIRT-M", Size: "M", Stock: 5},
+			{ID: 2, ItemID: merchID, Item: merchName, SKU: "TSHIRT-L", Size: "L", Price: 90},
+		}, merchItem.Variants)
 	})
 
 	t.Run("NoRows", func(t *testing.T) {
-		mock.ExpectQuery(`SELECT item_id, price FROM merch_items WHERE name = \$1`).
-			WithArgs(fakeMerchName).
+		mock.ExpectQuery(`SELECT item_id, price FROM merch_items WHERE name = \$1 AND tenant_id = \$2`).
+			WithArgs(fakeMerchName, DefaultTenantID).
 			WillReturnError(sql.ErrNoRows)
 
 		merchItem, err := queries.GetMerchItems(ctx, fakeMerchName)
@@ -44,3 +53,25 @@ func TestGetMerchItems(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestReserveStock(t *testing.T) {
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	reserve := `UPDATE merch_variants SET stock = stock - \$1 WHERE variant_id = \$2 AND stock >= \$3`
+
+	mock.ExpectBegin()
+	mock.ExpectExec(reserve).WithArgs(2, 7, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(reserve).WithArgs(5, 7, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	assert.NoError(t, queries.reserveStock(ctx, tx, 7, 2))
+	// остатка не хватает - строка не обновляется
+	assert.ErrorIs(t, queries.reserveStock(ctx, tx, 7, 5), ErrOutOfStock)
+	assert.NoError(t, tx.Rollback())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
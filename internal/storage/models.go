@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"github.com/Vic07Region/avito-shop/internal/events"
 	"github.com/google/uuid"
 	"time"
 )
@@ -10,7 +11,9 @@ type Employee struct {
 	Name       string    `json:"name"`
 	Email      *string   `json:"email"`
 	Password   string    `json:"password"`
-	CreatedAt  time.Time `json:"created_at"`
+	// Role роль из записи сотрудника, права администратора дает только RoleAdmin
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Wallet struct {
@@ -18,29 +21,401 @@ type Wallet struct {
 	Balance    int       `json:"balance"`
 }
 
+// InventoryItem количество товара у пользователя, по вариантам отдельно.
+// SKU, Size и Color пустые для товаров без вариантов.
 type InventoryItem struct {
 	Name     string `json:"name"`
+	SKU      string `json:"sku"`
+	Size     string `json:"size"`
+	Color    string `json:"color"`
 	Quantity int    `json:"quantity"`
 }
 
+// виды записей журнала инвентаря
+const (
+	LedgerAcquired = "acquired"
+	LedgerReceived = "received"
+	LedgerGifted   = "gifted"
+	LedgerReturned = "returned"
+)
+
+// LedgerEntry движение товара в инвентаре, Quantity отрицательное при списании.
+// OrderID заполнен у покупок и отмен, Counterparty - у подарков и передач.
+type LedgerEntry struct {
+	ID           int64     `json:"id"`
+	Kind         string    `json:"kind"`
+	Item         string    `json:"item"`
+	SKU          string    `json:"sku"`
+	Size         string    `json:"size"`
+	Color        string    `json:"color"`
+	Quantity     int       `json:"quantity"`
+	OrderID      int64     `json:"orderId"`
+	Counterparty string    `json:"counterparty"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// WishlistItem товар в вишлисте сотрудника. Goal - цель накопления в монетах,
+// Contributed - сколько монет на этот товар перевели коллеги. Price - текущая цена каталога.
+type WishlistItem struct {
+	ID          int64     `json:"id"`
+	EmployeeID  uuid.UUID `json:"employeeId"`
+	ItemID      int       `json:"itemId"`
+	Item        string    `json:"item"`
+	Price       int       `json:"price"`
+	Goal        int       `json:"goal"`
+	Contributed int       `json:"contributed"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Contribution взнос коллеги монетами на товар из вишлиста получателя
+type Contribution struct {
+	ItemID int    `json:"itemId"`
+	Item   string `json:"item"`
+	Amount int    `json:"amount"`
+}
+
+// роли участника команды
+const (
+	TeamRoleMember  = "member"
+	TeamRoleManager = "manager"
+)
+
+// Team командный кошелек. Balance - общий пул команды, из него менеджеры
+// переводят монеты участникам и покупают товар на команду.
+type Team struct {
+	ID        int64        `json:"id"`
+	Name      string       `json:"name"`
+	Balance   int          `json:"balance"`
+	Members   []TeamMember `json:"members"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type TeamMember struct {
+	EmployeeID uuid.UUID `json:"employeeId"`
+	Username   string    `json:"username"`
+	Role       string    `json:"role"`
+}
+
+// Member участник команды, nil - сотрудник не состоит в команде
+func (t Team) Member(userID uuid.UUID) *TeamMember {
+	for i := range t.Members {
+		if t.Members[i].EmployeeID == userID {
+			return &t.Members[i]
+		}
+	}
+	return nil
+}
+
+// виды записей истории командного кошелька
+const (
+	TeamDeposit      = "deposit"
+	TeamDistribution = "distribution"
+	TeamPurchase     = "purchase"
+	TeamRefund       = "refund"
+)
+
+// TeamEntry движение монет командного кошелька, Amount отрицательное при списании.
+// Employee - участник, получивший монеты или купивший товар, у пополнения - кто пополнил.
+// OrderID заполнен у покупок и возвратов.
+type TeamEntry struct {
+	ID        int64     `json:"id"`
+	Kind      string    `json:"kind"`
+	Amount    int       `json:"amount"`
+	Employee  string    `json:"employee"`
+	OrderID   int64     `json:"orderId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// ItemTransfer передача товара из инвентаря другому сотруднику
+type ItemTransfer struct {
+	MerchID int    `json:"merchID"`
+	Name    string `json:"name"`
+	// VariantID вариант товара, 0 - товар без вариантов
+	VariantID int    `json:"variantID"`
+	SKU       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+}
+
 type SenderInfo struct {
 	Username string `json:"username"`
 	Amount   int    `json:"amount"`
 }
 
-type MerchInfo struct {
-	MerchID int `json:"merchID"`
-	Price   int `json:"price"`
-	Amount  int `json:"amount"`
+// Budget бюджет на благодарности коллегам. Remaining - остаток в текущем периоде,
+// в начале следующего периода он снова равен Limit.
+type Budget struct {
+	Limit     int `json:"limit"`
+	Remaining int `json:"remaining"`
 }
 
-type MerchItem struct {
+// WalletBudget бюджет в кошельке как он хранится: Budget - остаток в периоде, начавшемся в Period,
+// TenantPeriod - период бюджета из настроек компании, пустой - период сервиса.
+type WalletBudget struct {
+	Limit        int       `json:"limit"`
+	Budget       int       `json:"budget"`
+	Period       time.Time `json:"period"`
+	TenantPeriod string    `json:"tenantPeriod"`
+}
+
+// Remaining остаток в периоде, который начался в period. С началом нового периода
+// бюджет снова равен лимиту, как в GetBudget.
+func (b WalletBudget) Remaining(period time.Time) int {
+	if b.Period.Before(period) {
+		return b.Limit
+	}
+	return b.Budget
+}
+
+// WalletInfo баланс, инвентарь, история переводов, вишлист и бюджет на один момент времени
+type WalletInfo struct {
+	Balance   int             `json:"balance"`
+	Inventory []InventoryItem `json:"inventory"`
+	Received  []SenderInfo    `json:"received"`
+	Sent      []SenderInfo    `json:"sent"`
+	// Wishlist вишлист владельца кошелька в порядке добавления
+	Wishlist []WishlistItem `json:"wishlist"`
+	Budget   WalletBudget   `json:"budget"`
+}
+
+type MerchInfo struct {
 	MerchID int    `json:"merchID"`
 	Name    string `json:"name"`
 	Price   int    `json:"price"`
+	Amount  int    `json:"amount"`
+	// Discount скидка со стоимости Price*Amount
+	Discount int `json:"discount"`
+	// PromotionID промокод, который погашается вместе с покупкой, 0 - без промокода
+	PromotionID int64 `json:"promotionID"`
+	// VariantID вариант, с остатка которого списывается Amount, 0 - товар без вариантов
+	VariantID int    `json:"variantID"`
+	SKU       string `json:"sku"`
+	// RecipientID получатель подарка, uuid.Nil - покупка для себя.
+	// Платит покупатель, товар попадает в инвентарь получателя.
+	RecipientID uuid.UUID `json:"recipientID"`
+	GiftMessage string    `json:"giftMessage"`
+	// TeamID команда, с кошелька которой списываются монеты, 0 - личная покупка.
+	// Товар попадает в инвентарь покупателя.
+	TeamID int64 `json:"teamID"`
+}
+
+type MerchItem struct {
+	MerchID  int            `json:"merchID"`
+	Name     string         `json:"name"`
+	Price    int            `json:"price"`
+	Variants []MerchVariant `json:"variants"`
+}
+
+// MerchVariant размер и цвет товара со своим остатком
+type MerchVariant struct {
+	ID     int `json:"id"`
+	ItemID int `json:"itemID"`
+	// Item название товара, по нему сбрасывается кэш каталога
+	Item  string `json:"item"`
+	SKU   string `json:"sku"`
+	Size  string `json:"size"`
+	Color string `json:"color"`
+	// Price цена варианта, 0 - цена товара
+	Price int `json:"price"`
+	Stock int `json:"stock"`
+}
+
+// UnitPrice цена варианта с учетом цены товара itemPrice
+func (v MerchVariant) UnitPrice(itemPrice int) int {
+	if v.Price > 0 {
+		return v.Price
+	}
+	return itemPrice
+}
+
+// MerchPrice цена товара в период [EffectiveFrom, EffectiveTo), у текущей цены EffectiveTo nil
+type MerchPrice struct {
+	ItemID        int        `json:"itemID"`
+	Price         int        `json:"price"`
+	EffectiveFrom time.Time  `json:"effectiveFrom"`
+	EffectiveTo   *time.Time `json:"effectiveTo"`
+}
+
+// SalesReportLine продажи товара по одной цене. Gross = UnitPrice * Quantity,
+// Total = Gross - Discount - фактически списанные монеты
+type SalesReportLine struct {
+	Item      string `json:"item"`
+	UnitPrice int    `json:"unitPrice"`
+	Orders    int    `json:"orders"`
+	Quantity  int    `json:"quantity"`
+	Gross     int    `json:"gross"`
+	Discount  int    `json:"discount"`
+	Total     int    `json:"total"`
 }
 
 type AuthData struct {
 	UserID       uuid.UUID `json:"userID"`
 	PasswordHash string    `json:"passwordHash"`
 }
+
+// OutboxEvent событие из outbox, ID порядковый номер записи
+type OutboxEvent struct {
+	ID    int64        `json:"id"`
+	Event events.Event `json:"event"`
+}
+
+// статусы доставки webhook
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead попытки исчерпаны, доставку можно повторить только вручную
+	DeliveryDead = "dead"
+)
+
+type Webhook struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret"`
+	EventTypes []string  `json:"eventTypes"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID uuid.UUID `json:"id"`
+	// TenantID компания webhook, воркер доставляет события всех компаний
+	TenantID       int64      `json:"-"`
+	WebhookID      uuid.UUID  `json:"webhookId"`
+	EventID        uuid.UUID  `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Payload        []byte     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastError      string     `json:"lastError"`
+	LastStatusCode int        `json:"lastStatusCode"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+}
+
+// статусы заказа
+const (
+	OrderPlaced    = "placed"
+	OrderApproved  = "approved"
+	OrderShipped   = "shipped"
+	OrderDelivered = "delivered"
+	// OrderCancelled стоимость заказа возвращена на баланс
+	OrderCancelled = "cancelled"
+)
+
+// Order покупка мерча как заказ на доставку, ID совпадает с purchase_id
+type Order struct {
+	ID              int64     `json:"id"`
+	UserID          uuid.UUID `json:"userId"`
+	Item            string    `json:"item"`
+	SKU             string    `json:"sku"`
+	Size            string    `json:"size"`
+	Color           string    `json:"color"`
+	Quantity        int       `json:"quantity"`
+	UnitPrice       int       `json:"unitPrice"`
+	Total           int       `json:"total"`
+	Discount        int       `json:"discount"`
+	PromoCode       string    `json:"promoCode"`
+	Status          string    `json:"status"`
+	ShippingAddress string    `json:"shippingAddress"`
+	// Gift заполнен у заказа-подарка, UserID - получатель
+	Gift *Gift `json:"gift"`
+	// TeamID и Team заполнены у командной покупки, монеты при отмене возвращаются команде
+	TeamID    int64     `json:"teamId"`
+	Team      string    `json:"team"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Gift даритель оплатил заказ получателя. Заказ-подарок видят и ведут оба.
+type Gift struct {
+	FromID  uuid.UUID `json:"fromId"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+}
+
+// Payer пользователь, оплативший заказ, ему возвращаются монеты при отмене
+func (o Order) Payer() uuid.UUID {
+	if o.Gift != nil {
+		return o.Gift.FromID
+	}
+	return o.UserID
+}
+
+// VisibleTo заказ принадлежит пользователю или оплачен им в подарок
+func (o Order) VisibleTo(userID uuid.UUID) bool {
+	return o.UserID == userID || o.Payer() == userID
+}
+
+// виды скидок
+const (
+	DiscountPercent = "percent"
+	DiscountFixed   = "fixed"
+)
+
+// Promotion скидка на товар или, без ItemID, на весь каталог. Без Code -
+// распродажа, применяется автоматически в период [StartsAt, EndsAt).
+type Promotion struct {
+	ID       int64     `json:"id"`
+	Code     string    `json:"code"`
+	Kind     string    `json:"kind"`
+	Value    int       `json:"value"`
+	ItemID   *int      `json:"itemID"`
+	Item     string    `json:"item"`
+	StartsAt time.Time `json:"startsAt"`
+	EndsAt   time.Time `json:"endsAt"`
+	// MaxRedemptions и PerUserLimit: 0 - без ограничения
+	MaxRedemptions int       `json:"maxRedemptions"`
+	PerUserLimit   int       `json:"perUserLimit"`
+	Redemptions    int       `json:"redemptions"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// Active действует ли скидка на товар itemID в момент at
+func (p Promotion) Active(itemID int, at time.Time) bool {
+	return !at.Before(p.StartsAt) && at.Before(p.EndsAt) && (p.ItemID == nil || *p.ItemID == itemID)
+}
+
+// Discount скидка с суммы total, не больше самой суммы
+func (p Promotion) Discount(total int) int {
+	discount := p.Value
+	if p.Kind == DiscountPercent {
+		discount = total * p.Value / 100
+	}
+	return min(discount, total)
+}
+
+// Tenant компания на общем развертывании: свои сотрудники, каталог и настройки.
+// BudgetPeriod пустой - период бюджета сервиса, Admins - администраторы компании.
+// AdminSignupBonus бонус новому администратору, nil - как SignupBonus, бонус попадает
+// на баланс через BonusVestingDays дней после регистрации.
+type Tenant struct {
+	ID               int64     `json:"id"`
+	Slug             string    `json:"slug"`
+	Name             string    `json:"name"`
+	SignupBonus      int       `json:"signupBonus"`
+	AdminSignupBonus *int      `json:"adminSignupBonus,omitempty"`
+	BonusVestingDays int       `json:"bonusVestingDays"`
+	BudgetPeriod     string    `json:"budgetPeriod"`
+	Admins           []string  `json:"admins"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// роли сотрудника при регистрации, от роли зависит бонус
+const (
+	RoleEmployee = "employee"
+	RoleAdmin    = "admin"
+)
+
+// Onboarding бонус за регистрацию сотрудника. Бонус выдается один раз на имя в компании,
+// VestedAt nil - бонус ждет VestsAt и еще не на балансе.
+type Onboarding struct {
+	ID         int64      `json:"id"`
+	TenantID   int64      `json:"-"`
+	EmployeeID uuid.UUID  `json:"employeeId"`
+	Username   string     `json:"username"`
+	Role       string     `json:"role"`
+	Bonus      int        `json:"bonus"`
+	VestsAt    time.Time  `json:"vestsAt"`
+	VestedAt   *time.Time `json:"vestedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// vestOnboardingQuery отмечает наступившие бонусы выданными. Как и при выборке outbox,
+// SKIP LOCKED не дает двум инстансам выдать один бонус дважды.
+const vestOnboardingQuery = `UPDATE onboarding SET vested_at = ?
+WHERE onboarding_id IN (
+	SELECT onboarding_id FROM onboarding
+	WHERE vested_at IS NULL AND vests_at <= ?
+	ORDER BY vests_at LIMIT ?%s
+)
+RETURNING onboarding_id, tenant_id, employee_id, username, role, bonus, vests_at, created_at`
+
+// onboard сохраняет запись о бонусе нового сотрудника и возвращает, сколько монет
+// положить на баланс сразу. Имя хранится в нижнем регистре, как при входе.
+func (q *Queries) onboard(ctx context.Context, tx *sql.Tx, tenant int64, userID uuid.UUID, username string,
+	onboarding *Onboarding) (int, error) {
+	now := time.Now().UTC()
+	onboarding.TenantID = tenant
+	onboarding.EmployeeID = userID
+	onboarding.Username = strings.ToLower(username)
+	onboarding.VestsAt = onboarding.VestsAt.UTC()
+	onboarding.CreatedAt = now
+	onboarding.VestedAt = nil
+	var vestedAt *time.Time
+	if !onboarding.VestsAt.After(now) {
+		vestedAt = &now
+	}
+
+	err := q.builder().Insert("onboarding").
+		Columns("tenant_id", "username", "employee_id", "role", "bonus", "vests_at", "vested_at", "created_at").
+		Values(tenant, onboarding.Username, userID, onboarding.Role, onboarding.Bonus, onboarding.VestsAt, vestedAt, now).
+		Suffix("ON CONFLICT (tenant_id, username) DO NOTHING RETURNING onboarding_id").
+		RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&onboarding.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// бонус по этому имени уже выдавался
+			onboarding.ID = 0
+			onboarding.Bonus = 0
+			return 0, nil
+		}
+		q.logger(ctx).Error("NewUser OnboardingQuery error:", zap.Error(err))
+		return 0, err
+	}
+	if vestedAt == nil {
+		return 0, nil
+	}
+	onboarding.VestedAt = vestedAt
+	return onboarding.Bonus, nil
+}
+
+// VestSignupBonuses кладет на баланс до limit бонусов за регистрацию, срок которых наступил
+// к now, и возвращает их. Работает по всем компаниям.
+func (q *Queries) VestSignupBonuses(ctx context.Context, now time.Time, limit int) (_ []Onboarding, err error) {
+	query, err := q.placeholders().ReplacePlaceholders(sprintfSkipLocked(vestOnboardingQuery, !q.isSQLite()))
+	if err != nil {
+		q.logger(ctx).Error("VestSignupBonuses ReplacePlaceholders error:", zap.Error(err))
+		return nil, err
+	}
+
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		q.logger(ctx).Error("VestSignupBonuses BeginTx error:", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	now = now.UTC()
+	rows, err := q.traced(tx).QueryContext(ctx, query, now, now, limit)
+	if err != nil {
+		q.logger(ctx).Error("VestSignupBonuses QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	var vested []Onboarding
+	for rows.Next() {
+		o := Onboarding{VestedAt: &now}
+		if err = rows.Scan(&o.ID, &o.TenantID, &o.EmployeeID, &o.Username, &o.Role, &o.Bonus, &o.VestsAt,
+			&o.CreatedAt); err != nil {
+			rows.Close()
+			q.logger(ctx).Error("VestSignupBonuses rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		o.VestsAt = o.VestsAt.UTC()
+		o.CreatedAt = o.CreatedAt.UTC()
+		vested = append(vested, o)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("VestSignupBonuses rows error:", zap.Error(err))
+		return nil, err
+	}
+
+	var statements []sq.Sqlizer
+	for _, o := range vested {
+		if o.Bonus == 0 {
+			continue
+		}
+		// кошелька может не быть, если сотрудник удален: бонус все равно считается выданным
+		statements = append(statements, q.builder().Update("wallets").
+			Set("balance", sq.Expr("balance + ?", o.Bonus)).
+			Where(sq.Eq{"employee_id": o.EmployeeID}))
+	}
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		q.logger(ctx).Error("VestSignupBonuses WalletQuery error:", zap.Error(err))
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		q.logger(ctx).Error("VestSignupBonuses Commit error:", zap.Error(err))
+		return nil, err
+	}
+	return vested, nil
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVestSignupBonuses(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	now := time.Date(2025, 3, 15, 9, 0, 0, 0, time.UTC)
+	vestsAt := now.Add(-time.Hour)
+	aliceID := uuid.New()
+	bobID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE onboarding SET vested_at = $1`)+`(?s).*`+
+		regexp.QuoteMeta(`ORDER BY vests_at LIMIT $3 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(now, now, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"onboarding_id", "tenant_id", "employee_id", "username", "role",
+			"bonus", "vests_at", "created_at"}).
+			AddRow(1, 2, aliceID, "alice", RoleEmployee, 300, vestsAt, vestsAt).
+			AddRow(2, 2, bobID, "bob", RoleEmployee, 0, vestsAt, vestsAt))
+	// нулевой бонус баланс не меняет
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE wallets SET balance = balance + $1 WHERE employee_id = $2`)).
+		WithArgs(300, aliceID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	vested, err := queries.VestSignupBonuses(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, vested, 2)
+	assert.Equal(t, Onboarding{ID: 1, TenantID: 2, EmployeeID: aliceID, Username: "alice", Role: RoleEmployee,
+		Bonus: 300, VestsAt: vestsAt, VestedAt: &now, CreatedAt: vestsAt}, vested[0])
+	assert.Equal(t, bobID, vested[1].EmployeeID)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
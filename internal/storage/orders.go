@@ -0,0 +1,303 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// OrderStatusUpdate переход заказа в статус To из одного из статусов From.
+// UserID ограничивает операцию заказами пользователя и оплаченными им подарками,
+// uuid.Nil - любой заказ.
+type OrderStatusUpdate struct {
+	OrderID int64
+	UserID  uuid.UUID
+	From    []string
+	To      string
+}
+
+func (q *Queries) ordersQuery() sq.SelectBuilder {
+	return q.builder().Select("purchase_id", "purchases.employee_id", "merch_items.name", "COALESCE(sku, '')",
+		"COALESCE(size, '')", "COALESCE(color, '')", "quantity", "unit_price", "total", "discount",
+		"COALESCE(code, '')", "status", "shipping_address", "purchases.buyer_id", "COALESCE(buyers.username, '')",
+		"recipients.username", "gift_message", "COALESCE(purchases.team_id, 0)", "COALESCE(teams.name, '')",
+		"purchase_date", "updated_at").
+		From("purchases").
+		InnerJoin("merch_items using(item_id)").
+		LeftJoin("merch_variants ON merch_variants.variant_id = purchases.variant_id").
+		LeftJoin("promotions using(promotion_id)").
+		InnerJoin("employees recipients ON recipients.employee_id = purchases.employee_id").
+		LeftJoin("employees buyers ON buyers.employee_id = purchases.buyer_id").
+		LeftJoin("teams ON teams.team_id = purchases.team_id")
+}
+
+// orderOf заказы пользователя и подарки, которые он оплатил
+func orderOf(userID uuid.UUID) sq.Sqlizer {
+	return sq.Or{sq.Eq{"employee_id": userID}, sq.Eq{"buyer_id": userID}}
+}
+
+// GetOrders заказы пользователя и оплаченные им подарки, новые первыми
+func (q *Queries) GetOrders(ctx context.Context, userID uuid.UUID) ([]Order, error) {
+	sqlQuery := q.ordersQuery().
+		Where(sq.Or{sq.Eq{"purchases.employee_id": userID}, sq.Eq{"purchases.buyer_id": userID}}).
+		OrderBy("purchase_id DESC")
+	return q.queryOrders(ctx, "GetOrders", sqlQuery.RunWith(q.traced(q.db)))
+}
+
+// ListOrders очередь заказов для обработки, старые первыми. Пустой status - все статусы.
+func (q *Queries) ListOrders(ctx context.Context, status string, limit int) ([]Order, error) {
+	sqlQuery := q.ordersQuery().
+		Where(tenantEq(ctx, "purchases")).
+		OrderBy("purchase_id").
+		Limit(uint64(limit))
+	if status != "" {
+		sqlQuery = sqlQuery.Where(sq.Eq{"status": status})
+	}
+	return q.queryOrders(ctx, "ListOrders", sqlQuery.RunWith(q.traced(q.db)))
+}
+
+func (q *Queries) GetOrder(ctx context.Context, orderID int64) (*Order, error) {
+	return q.getOrder(ctx, q.traced(q.db), orderID)
+}
+
+// SetShippingAddress меняет адрес доставки заказа пользователя, пока заказ в одном из статусов from
+func (q *Queries) SetShippingAddress(ctx context.Context, userID uuid.UUID, orderID int64, address string, from []string) error {
+	sqlQuery := q.builder().Update("purchases").
+		Set("shipping_address", address).
+		Set("updated_at", time.Now().UTC()).
+		Where(sq.Eq{"purchase_id": orderID, "status": from}).
+		Where(orderOf(userID))
+	result, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("SetShippingAddress ExecContext error:", zap.Error(err))
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		q.logger(ctx).Error("SetShippingAddress RowsAffected error:", zap.Error(err))
+		return err
+	}
+	if affected == 0 {
+		return q.orderError(ctx, q.traced(q.db), orderID, userID)
+	}
+	return nil
+}
+
+// UpdateOrderStatus переводит заказ в новый статус и пишет событие в outbox.
+// При отмене стоимость заказа возвращается на баланс в той же транзакции, у командной
+// покупки - в кошелек команды, а товар списывается из инвентаря владельца. Если
+// владелец уже передал товар, отмена возвращает ErrNotEnoughItems.
+func (q *Queries) UpdateOrderStatus(ctx context.Context, update OrderStatusUpdate) (_ *Order, err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus BeginTx error:", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	now := time.Now().UTC()
+	sqlQuery := q.builder().Update("purchases").
+		Set("status", update.To).
+		Set("updated_at", now).
+		Where(sq.Eq{"purchase_id": update.OrderID, "status": update.From}).
+		Where(tenantEq(ctx, ""))
+	if update.UserID != uuid.Nil {
+		sqlQuery = sqlQuery.Where(orderOf(update.UserID))
+	}
+	// UPDATE блокирует строку заказа до конца транзакции, поэтому
+	// параллельная отмена не вернет монеты второй раз
+	result, err := sqlQuery.RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus ExecContext error:", zap.Error(err))
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus RowsAffected error:", zap.Error(err))
+		return nil, err
+	}
+	if affected == 0 {
+		return nil, q.orderError(ctx, q.traced(tx), update.OrderID, update.UserID)
+	}
+
+	order, err := q.getOrder(ctx, q.traced(tx), update.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	refund := 0
+	if update.To == OrderCancelled {
+		refund = order.Total
+		if err = q.refund(ctx, tx, order, now); err != nil {
+			q.logger(ctx).Error("UpdateOrderStatus refund error:", zap.Error(err))
+			return nil, err
+		}
+		if err = q.returnItems(ctx, tx, order.ID, now); err != nil {
+			if errors.Is(err, ErrNotEnoughItems) {
+				return nil, err
+			}
+			q.logger(ctx).Error("UpdateOrderStatus returnItems error:", zap.Error(err))
+			return nil, err
+		}
+		if order.SKU != "" {
+			// товар возвращается на остаток варианта
+			_, err = q.builder().Update("merch_variants").
+				Set("stock", sq.Expr("stock + ?", order.Quantity)).
+				Where(sq.Expr("variant_id = (SELECT variant_id FROM purchases WHERE purchase_id = ?)", order.ID)).
+				RunWith(q.traced(tx)).ExecContext(ctx)
+			if err != nil {
+				q.logger(ctx).Error("UpdateOrderStatus restock error:", zap.Error(err))
+				return nil, err
+			}
+		}
+		if order.PromoCode != "" {
+			// погашение промокода возвращается, его снова можно использовать
+			_, err = q.builder().Update("promotions").
+				Set("redemptions", sq.Expr("redemptions - 1")).
+				Where(sq.Expr("promotion_id = (SELECT promotion_id FROM purchases WHERE purchase_id = ?)", order.ID)).
+				Where(sq.Gt{"redemptions": 0}).
+				RunWith(q.traced(tx)).ExecContext(ctx)
+			if err != nil {
+				q.logger(ctx).Error("UpdateOrderStatus promo redemption error:", zap.Error(err))
+				return nil, err
+			}
+		}
+	}
+
+	event, err := events.New(events.TypeOrderStatusChanged, events.OrderStatusChanged{
+		OrderID: order.ID,
+		UserID:  order.UserID,
+		Status:  order.Status,
+		Refund:  refund,
+	})
+	if err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus events.New error:", zap.Error(err))
+		return nil, err
+	}
+	if _, err = q.outboxInsert(TenantID(ctx), event).RunWith(q.traced(tx)).ExecContext(ctx); err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus outbox error:", zap.Error(err))
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		q.logger(ctx).Error("UpdateOrderStatus Commit error:", zap.Error(err))
+		return nil, err
+	}
+	return order, nil
+}
+
+// refund возвращает стоимость отмененного заказа тому, кто платил
+func (q *Queries) refund(ctx context.Context, tx *sql.Tx, order *Order, at time.Time) error {
+	if order.TeamID == 0 {
+		_, err := q.builder().Update("wallets").
+			Set("balance", sq.Expr("balance + ?", order.Total)).
+			Where(sq.Eq{"employee_id": order.Payer()}).
+			RunWith(q.traced(tx)).ExecContext(ctx)
+		return err
+	}
+	_, err := q.builder().Update("teams").
+		Set("balance", sq.Expr("balance + ?", order.Total)).
+		Where(sq.Eq{"team_id": order.TeamID}).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = q.teamEntry(TenantID(ctx), order.TeamID, TeamRefund, order.Total, order.UserID, order.ID, at).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	return err
+}
+
+// returnItems списывает товар отмененного заказа из инвентаря владельца и пишет это в журнал
+func (q *Queries) returnItems(ctx context.Context, tx *sql.Tx, orderID int64, at time.Time) error {
+	var ownerID uuid.UUID
+	var itemID, quantity int
+	var variantID *int
+	err := q.builder().Select("employee_id", "item_id", "variant_id", "quantity").
+		From("purchases").
+		Where(sq.Eq{"purchase_id": orderID}).
+		RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&ownerID, &itemID, &variantID, &quantity)
+	if err != nil {
+		return err
+	}
+	if err = q.takeItems(ctx, tx, ownerID, itemID, variantID, quantity); err != nil {
+		return err
+	}
+	_, err = q.builder().Insert("inventory_ledger").
+		Columns(ledgerColumns...).
+		Values(TenantID(ctx), ownerID, itemID, variantID, LedgerReturned, -quantity, orderID, nil, at).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	return err
+}
+
+func (q *Queries) getOrder(ctx context.Context, runner sq.BaseRunner, orderID int64) (*Order, error) {
+	orders, err := q.queryOrders(ctx, "GetOrder", q.ordersQuery().
+		Where(sq.Eq{"purchase_id": orderID}).
+		Where(tenantEq(ctx, "purchases")).
+		RunWith(runner))
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 {
+		return nil, ErrOrderNotFound
+	}
+	return &orders[0], nil
+}
+
+// orderError объясняет, почему условный UPDATE заказа не затронул строк
+func (q *Queries) orderError(ctx context.Context, runner sq.BaseRunner, orderID int64, userID uuid.UUID) error {
+	order, err := q.getOrder(ctx, runner, orderID)
+	if err != nil {
+		return err
+	}
+	// чужой заказ неотличим от несуществующего
+	if userID != uuid.Nil && !order.VisibleTo(userID) {
+		return ErrOrderNotFound
+	}
+	return ErrOrderStatus
+}
+
+func (q *Queries) queryOrders(ctx context.Context, operation string, sqlQuery sq.SelectBuilder) ([]Order, error) {
+	rows, err := sqlQuery.QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error(operation+" QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		var buyerID uuid.NullUUID
+		var gift Gift
+		err := rows.Scan(&o.ID, &o.UserID, &o.Item, &o.SKU, &o.Size, &o.Color, &o.Quantity, &o.UnitPrice, &o.Total, &o.Discount, &o.PromoCode, &o.Status,
+			&o.ShippingAddress, &buyerID, &gift.From, &gift.To, &gift.Message, &o.TeamID, &o.Team, &o.CreatedAt,
+			&o.UpdatedAt)
+		if err != nil {
+			q.logger(ctx).Error(operation+" rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		if buyerID.Valid {
+			gift.FromID = buyerID.UUID
+			o.Gift = &gift
+		}
+		o.CreatedAt = o.CreatedAt.UTC()
+		o.UpdatedAt = o.UpdatedAt.UTC()
+		orders = append(orders, o)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error(operation+" rows error:", zap.Error(err))
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var orderColumns = []string{"purchase_id", "employee_id", "name", "sku", "size", "color", "quantity", "unit_price", "total", "discount", "code",
+	"status", "shipping_address", "buyer_id", "buyer", "recipient", "gift_message", "team_id", "team", "purchase_date", "updated_at"}
+
+func TestUpdateOrderStatus_Cancel(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1, updated_at = \$2 WHERE purchase_id = \$3 AND status IN \(\$4\) AND tenant_id = \$5 AND \(employee_id = \$6 OR buyer_id = \$7\)`).
+		WithArgs(OrderCancelled, sqlmock.AnyArg(), int64(7), OrderPlaced, DefaultTenantID, userID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT purchase_id, .* FROM purchases INNER JOIN merch_items using\(item_id\) LEFT JOIN merch_variants ON merch_variants.variant_id = purchases.variant_id LEFT JOIN promotions using\(promotion_id\) INNER JOIN employees recipients ON recipients.employee_id = purchases.employee_id LEFT JOIN employees buyers ON buyers.employee_id = purchases.buyer_id LEFT JOIN teams ON teams.team_id = purchases.team_id WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(7), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(7, userID, "cup", "", "", "", 2, 20, 40, 0, "", OrderCancelled, "", nil, "", "alice", "", 0, "", now, now))
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+		WithArgs(40, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// товар списывается из инвентаря владельца
+	mock.ExpectQuery(`SELECT employee_id, item_id, variant_id, quantity FROM purchases WHERE purchase_id = \$1`).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "item_id", "variant_id", "quantity"}).AddRow(userID, 2, nil, 2))
+	mock.ExpectExec(`UPDATE inventory SET quantity = quantity - \$1 WHERE employee_id = \$2 AND item_id = \$3 AND COALESCE\(variant_id, 0\) = \$4 AND quantity >= \$5`).
+		WithArgs(2, userID, 2, 0, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory_ledger \(tenant_id,employee_id,item_id,variant_id,kind,quantity,purchase_id,counterparty_id,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9\)`).
+		WithArgs(DefaultTenantID, userID, 2, nil, LedgerReturned, -2, int64(7), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeOrderStatusChanged,
+			`{"orderId":7,"userId":"`+userID.String()+`","status":"cancelled","refund":40}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 7, UserID: userID, From: []string{OrderPlaced}, To: OrderCancelled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Order{ID: 7, UserID: userID, Item: "cup", Quantity: 2, UnitPrice: 20, Total: 40, Status: OrderCancelled,
+		CreatedAt: now, UpdatedAt: now}, order)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderStatus_CancelGift(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	recipientID := uuid.New()
+	buyerID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1, updated_at = \$2 WHERE purchase_id = \$3 AND status IN \(\$4\) AND tenant_id = \$5 AND \(employee_id = \$6 OR buyer_id = \$7\)`).
+		WithArgs(OrderCancelled, sqlmock.AnyArg(), int64(9), OrderPlaced, DefaultTenantID, recipientID, recipientID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT purchase_id, .* WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(9), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(9, recipientID, "cup", "", "", "", 1, 20, 20, 0, "", OrderCancelled, "", buyerID, "bob", "alice", "С днём рождения!", 0, "", now, now))
+	// подарок отменил получатель, но деньги возвращаются тому, кто платил
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+		WithArgs(20, buyerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT employee_id, item_id, variant_id, quantity FROM purchases`).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "item_id", "variant_id", "quantity"}).AddRow(recipientID, 2, nil, 1))
+	mock.ExpectExec(`UPDATE inventory SET quantity = quantity - \$1`).
+		WithArgs(1, recipientID, 2, 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory_ledger`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 9, UserID: recipientID, From: []string{OrderPlaced}, To: OrderCancelled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &Gift{FromID: buyerID, From: "bob", To: "alice", Message: "С днём рождения!"}, order.Gift)
+	assert.Equal(t, buyerID, order.Payer())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderStatus_CancelRestock(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT purchase_id, .* WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(8), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(8, userID, "hoody", "HOODY-M", "M", "", 1, 300, 300, 0, "", OrderCancelled, "", nil, "", "alice", "", 0, "", now, now))
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+		WithArgs(300, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT employee_id, item_id, variant_id, quantity FROM purchases`).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "item_id", "variant_id", "quantity"}).AddRow(userID, 6, 1, 1))
+	mock.ExpectExec(`UPDATE inventory SET quantity = quantity - \$1`).
+		WithArgs(1, userID, 6, 1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory_ledger`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// заказ варианта возвращает товар на остаток
+	mock.ExpectExec(`UPDATE merch_variants SET stock = stock \+ \$1 WHERE variant_id = \(SELECT variant_id FROM purchases WHERE purchase_id = \$2\)`).
+		WithArgs(1, int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 8, UserID: userID, From: []string{OrderPlaced}, To: OrderCancelled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "HOODY-M", order.SKU)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderStatus_CancelPromo(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT purchase_id, .* WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(10), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(10, userID, "cup", "", "", "", 1, 20, 15, 5, "SPRING", OrderCancelled, "", nil, "", "alice", "", 0, "", now, now))
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+		WithArgs(15, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT employee_id, item_id, variant_id, quantity FROM purchases`).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "item_id", "variant_id", "quantity"}).AddRow(userID, 2, nil, 1))
+	mock.ExpectExec(`UPDATE inventory SET quantity = quantity - \$1`).
+		WithArgs(1, userID, 2, 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory_ledger`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// погашение промокода возвращается в той же транзакции
+	mock.ExpectExec(`UPDATE promotions SET redemptions = redemptions - 1 WHERE promotion_id = \(SELECT promotion_id FROM purchases WHERE purchase_id = \$1\) AND redemptions > \$2`).
+		WithArgs(int64(10), 0).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	order, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 10, UserID: userID, From: []string{OrderPlaced}, To: OrderCancelled,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "SPRING", order.PromoCode)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderStatus_CancelItemsGiven(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT purchase_id, .* WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(7), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(7, userID, "cup", "", "", "", 2, 20, 40, 0, "", OrderCancelled, "", nil, "", "alice", "", 0, "", now, now))
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT employee_id, item_id, variant_id, quantity FROM purchases`).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id", "item_id", "variant_id", "quantity"}).AddRow(userID, 2, nil, 2))
+	// товар уже передан другому, в инвентаре его меньше, чем в заказе
+	mock.ExpectExec(`UPDATE inventory SET quantity = quantity - \$1`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 7, UserID: userID, From: []string{OrderPlaced}, To: OrderCancelled,
+	})
+	assert.ErrorIs(t, err, ErrNotEnoughItems)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateOrderStatus_WrongStatus(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE purchases SET status = \$1, updated_at = \$2 WHERE purchase_id = \$3 AND status IN \(\$4\) AND tenant_id = \$5`).
+		WithArgs(OrderShipped, sqlmock.AnyArg(), int64(7), OrderApproved, DefaultTenantID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT purchase_id, .* WHERE purchase_id = \$1 AND purchases.tenant_id = \$2`).
+		WithArgs(int64(7), DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows(orderColumns).
+			AddRow(7, userID, "cup", "", "", "", 2, 20, 40, 0, "", OrderPlaced, "", nil, "", "alice", "", 0, "", now, now))
+	mock.ExpectRollback()
+
+	_, err := queries.UpdateOrderStatus(ctx, OrderStatusUpdate{
+		OrderID: 7, From: []string{OrderApproved}, To: OrderShipped,
+	})
+	assert.ErrorIs(t, err, ErrOrderStatus)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
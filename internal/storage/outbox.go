@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"go.uber.org/zap"
+)
+
+// claimOutboxQuery берет неопубликованные события в аренду на время обработки.
+// Аренда не дает двум relay публиковать одно событие одновременно, а после
+// падения relay события снова станут доступны по истечении аренды.
+const claimOutboxQuery = `UPDATE outbox SET locked_until = ?, attempts = attempts + 1
+WHERE outbox_id IN (
+	SELECT outbox_id FROM outbox
+	WHERE published_at IS NULL AND (locked_until IS NULL OR locked_until < ?)
+	ORDER BY outbox_id LIMIT ?%s
+)
+RETURNING outbox_id, tenant_id, event_id, event_type, payload, created_at`
+
+func (q *Queries) outboxInsert(tenant int64, event events.Event) sq.InsertBuilder {
+	return q.builder().Insert("outbox").
+		Columns("tenant_id", "event_id", "event_type", "payload", "created_at").
+		Values(tenant, event.ID, event.Type, string(event.Payload), event.OccurredAt)
+}
+
+// ClaimOutboxEvents возвращает до limit неопубликованных событий в порядке записи
+// и блокирует их для других relay на время lease
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]OutboxEvent, error) {
+	query, err := q.placeholders().ReplacePlaceholders(sprintfSkipLocked(claimOutboxQuery, !q.isSQLite()))
+	if err != nil {
+		q.logger(ctx).Error("ClaimOutboxEvents ReplacePlaceholders error:", zap.Error(err))
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	rows, err := q.traced(q.db).QueryContext(ctx, query, now.Add(lease), now, limit)
+	if err != nil {
+		q.logger(ctx).Error("ClaimOutboxEvents QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var outboxEvents []OutboxEvent
+	for rows.Next() {
+		var e OutboxEvent
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Event.TenantID, &e.Event.ID, &e.Event.Type, &payload, &e.Event.OccurredAt); err != nil {
+			q.logger(ctx).Error("ClaimOutboxEvents rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		e.Event.Payload = []byte(payload)
+		e.Event.OccurredAt = e.Event.OccurredAt.UTC()
+		outboxEvents = append(outboxEvents, e)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("ClaimOutboxEvents rows error:", zap.Error(err))
+		return nil, err
+	}
+
+	// RETURNING не гарантирует порядок
+	sort.Slice(outboxEvents, func(i, j int) bool { return outboxEvents[i].ID < outboxEvents[j].ID })
+	return outboxEvents, nil
+}
+
+func (q *Queries) MarkOutboxEventsPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	sqlQuery := q.builder().Update("outbox").
+		Set("published_at", time.Now().UTC()).
+		Where(sq.Eq{"outbox_id": ids})
+	if _, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx); err != nil {
+		q.logger(ctx).Error("MarkOutboxEventsPublished ExecContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"go.uber.org/zap"
+)
+
+// SetMerchPrice делает item.Price ценой товара item.MerchID с момента at:
+// закрывает текущую запись истории цен и открывает новую.
+func (q *Queries) SetMerchPrice(ctx context.Context, item MerchItem, at time.Time) error {
+	at = at.UTC()
+	// UPDATE merch_items первым блокирует строку товара, поэтому параллельные
+	// смены цены одного товара выполняются по очереди и не оставляют двух текущих цен
+	merchQuery := q.builder().Update("merch_items").
+		Set("price", item.Price).
+		Where(sq.Eq{"item_id": item.MerchID})
+
+	closeQuery := q.builder().Update("merch_prices").
+		Set("effective_to", at).
+		Where(sq.Eq{"item_id": item.MerchID, "effective_to": nil})
+
+	openQuery := q.builder().Insert("merch_prices").
+		Columns("tenant_id", "item_id", "price", "effective_from").
+		Values(TenantID(ctx), item.MerchID, item.Price, at)
+
+	if err := q.execAtomic(ctx, "SetMerchPrice", merchQuery, closeQuery, openQuery); err != nil {
+		q.logger(ctx).Error("SetMerchPrice execAtomic error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetPriceHistory история цен товара, текущая цена первой
+func (q *Queries) GetPriceHistory(ctx context.Context, itemID int) ([]MerchPrice, error) {
+	sqlQuery := q.builder().Select("item_id", "price", "effective_from", "effective_to").
+		From("merch_prices").
+		Where(sq.Eq{"item_id": itemID}).
+		OrderBy("effective_from DESC", "price_id DESC")
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("GetPriceHistory QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var prices []MerchPrice
+	for rows.Next() {
+		var p MerchPrice
+		if err := rows.Scan(&p.ItemID, &p.Price, &p.EffectiveFrom, &p.EffectiveTo); err != nil {
+			q.logger(ctx).Error("GetPriceHistory rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		p.EffectiveFrom = p.EffectiveFrom.UTC()
+		if p.EffectiveTo != nil {
+			effectiveTo := p.EffectiveTo.UTC()
+			p.EffectiveTo = &effectiveTo
+		}
+		prices = append(prices, p)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetPriceHistory rows error:", zap.Error(err))
+		return nil, err
+	}
+	return prices, nil
+}
+
+// GetSalesReport продажи за период [from, to) по товарам и ценам покупки.
+// Отмененные заказы не учитываются: их стоимость возвращена на баланс.
+func (q *Queries) GetSalesReport(ctx context.Context, from, to time.Time) ([]SalesReportLine, error) {
+	sqlQuery := q.builder().Select("name", "unit_price", "COUNT(*)", "SUM(quantity)",
+		"SUM(unit_price * quantity)", "SUM(discount)", "SUM(total)").
+		From("purchases").
+		InnerJoin("merch_items using(item_id)").
+		Where(sq.NotEq{"status": OrderCancelled}).
+		Where(tenantEq(ctx, "purchases")).
+		Where(sq.GtOrEq{"purchase_date": from.UTC()}).
+		Where(sq.Lt{"purchase_date": to.UTC()}).
+		GroupBy("name", "unit_price").
+		OrderBy("name", "unit_price")
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("GetSalesReport QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var report []SalesReportLine
+	for rows.Next() {
+		var l SalesReportLine
+		err := rows.Scan(&l.Item, &l.UnitPrice, &l.Orders, &l.Quantity, &l.Gross, &l.Discount, &l.Total)
+		if err != nil {
+			q.logger(ctx).Error("GetSalesReport rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		report = append(report, l)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetSalesReport rows error:", zap.Error(err))
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMerchPrice(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// сначала строка товара, она сериализует параллельные смены цены
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE merch_items SET price = \$1 WHERE item_id = \$2`).
+		WithArgs(25, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE merch_prices SET effective_to = \$1 WHERE effective_to IS NULL AND item_id = \$2`).
+		WithArgs(at, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO merch_prices \(tenant_id,item_id,price,effective_from\) VALUES \(\$1,\$2,\$3,\$4\)`).
+		WithArgs(DefaultTenantID, 3, 25, at).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := queries.SetMerchPrice(ctx, MerchItem{MerchID: 3, Name: "cup", Price: 25}, at)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSalesReport(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	mock.ExpectQuery(`SELECT name, unit_price, COUNT\(\*\), SUM\(quantity\), SUM\(unit_price \* quantity\), SUM\(discount\), SUM\(total\) FROM purchases INNER JOIN merch_items using\(item_id\) WHERE status <> \$1 AND purchases.tenant_id = \$2 AND purchase_date >= \$3 AND purchase_date < \$4 GROUP BY name, unit_price ORDER BY name, unit_price`).
+		WithArgs(OrderCancelled, DefaultTenantID, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "unit_price", "count", "quantity", "gross", "discount", "total"}).
+			AddRow("cup", 20, 2, 3, 60, 5, 55).
+			AddRow("cup", 25, 1, 1, 25, 0, 25))
+
+	report, err := queries.GetSalesReport(ctx, from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []SalesReportLine{
+		{Item: "cup", UnitPrice: 20, Orders: 2, Quantity: 3, Gross: 60, Discount: 5, Total: 55},
+		{Item: "cup", UnitPrice: 25, Orders: 1, Quantity: 1, Gross: 25, Discount: 0, Total: 25},
+	}, report)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
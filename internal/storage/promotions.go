@@ -0,0 +1,184 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func (q *Queries) promotionsQuery() sq.SelectBuilder {
+	return q.builder().Select("promotion_id", "COALESCE(code, '')", "kind", "value", "promotions.item_id",
+		"COALESCE(name, '')", "starts_at", "ends_at", "max_redemptions", "per_user_limit", "redemptions",
+		"created_at").
+		From("promotions").
+		LeftJoin("merch_items ON merch_items.item_id = promotions.item_id")
+}
+
+// CreatePromotion сохраняет скидку, ID и время создания заполняются в promotion
+func (q *Queries) CreatePromotion(ctx context.Context, promotion *Promotion) error {
+	var code *string
+	if promotion.Code != "" {
+		code = &promotion.Code
+	}
+	promotion.CreatedAt = time.Now().UTC()
+	sqlQuery := q.builder().Insert("promotions").
+		Columns("tenant_id", "code", "kind", "value", "item_id", "starts_at", "ends_at", "max_redemptions",
+			"per_user_limit", "created_at").
+		Values(TenantID(ctx), code, promotion.Kind, promotion.Value, promotion.ItemID, promotion.StartsAt.UTC(),
+			promotion.EndsAt.UTC(), promotion.MaxRedemptions, promotion.PerUserLimit, promotion.CreatedAt).
+		Suffix("RETURNING promotion_id")
+
+	err := sqlQuery.RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&promotion.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrPromotionCodeTaken
+		}
+		q.logger(ctx).Error("CreatePromotion QueryRowContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListPromotions все скидки, новые первыми
+func (q *Queries) ListPromotions(ctx context.Context) ([]Promotion, error) {
+	return q.queryPromotions(ctx, "ListPromotions", q.promotionsQuery().
+		Where(tenantEq(ctx, "promotions")).
+		OrderBy("promotion_id DESC").
+		RunWith(q.traced(q.db)))
+}
+
+// GetPromotionByCode ищет промокод без учета периода действия
+func (q *Queries) GetPromotionByCode(ctx context.Context, code string) (*Promotion, error) {
+	promotions, err := q.queryPromotions(ctx, "GetPromotionByCode", q.promotionsQuery().
+		Where(sq.Eq{"code": code}).
+		Where(tenantEq(ctx, "promotions")).
+		RunWith(q.traced(q.db)))
+	if err != nil {
+		return nil, err
+	}
+	if len(promotions) == 0 {
+		return nil, ErrPromotionNotFound
+	}
+	return &promotions[0], nil
+}
+
+// GetActiveSales распродажи, которые действуют на товар itemID в момент at
+func (q *Queries) GetActiveSales(ctx context.Context, itemID int, at time.Time) ([]Promotion, error) {
+	at = at.UTC()
+	return q.queryPromotions(ctx, "GetActiveSales", q.promotionsQuery().
+		Where(sq.Eq{"code": nil}).
+		Where(tenantEq(ctx, "promotions")).
+		Where(sq.Or{sq.Eq{"promotions.item_id": nil}, sq.Eq{"promotions.item_id": itemID}}).
+		Where(sq.LtOrEq{"starts_at": at}).
+		Where(sq.Gt{"ends_at": at}).
+		OrderBy("promotion_id").
+		RunWith(q.traced(q.db)))
+}
+
+// EndPromotion завершает скидку в момент at. Уже завершенная скидка не меняется.
+func (q *Queries) EndPromotion(ctx context.Context, promotionID int64, at time.Time) error {
+	at = at.UTC()
+	result, err := q.builder().Update("promotions").
+		Set("ends_at", sq.Expr("CASE WHEN ends_at > ? THEN ? ELSE ends_at END", at, at)).
+		Where(sq.Eq{"promotion_id": promotionID}).
+		Where(tenantEq(ctx, "")).
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("EndPromotion ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrPromotionNotFound)
+}
+
+// redeemPromo погашает промокод в транзакции покупки. UPDATE промокода блокирует
+// его строку до конца транзакции, поэтому лимиты не превышаются при параллельных покупках.
+func (q *Queries) redeemPromo(ctx context.Context, tx *sql.Tx, userID uuid.UUID, promotionID int64, at time.Time) error {
+	var perUserLimit int
+	err := q.builder().Update("promotions").
+		Set("redemptions", sq.Expr("redemptions + 1")).
+		Where(sq.Eq{"promotion_id": promotionID}).
+		Where(sq.LtOrEq{"starts_at": at}).
+		Where(sq.Gt{"ends_at": at}).
+		Where(sq.Or{sq.Eq{"max_redemptions": 0}, sq.Expr("redemptions < max_redemptions")}).
+		Suffix("RETURNING per_user_limit").
+		RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&perUserLimit)
+	if errors.Is(err, sql.ErrNoRows) {
+		return q.promoError(ctx, tx, promotionID, at)
+	}
+	if err != nil {
+		return err
+	}
+
+	if perUserLimit > 0 {
+		var used int
+		err = q.builder().Select("COUNT(*)").
+			From("purchases").
+			Where(sq.Eq{"promotion_id": promotionID}).
+			// лимит на пользователя считается по покупателю, в том числе для подарков
+			Where("COALESCE(buyer_id, employee_id) = ?", userID).
+			// отмененный заказ возвращает и использование промокода
+			Where(sq.NotEq{"status": OrderCancelled}).
+			RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if used >= perUserLimit {
+			return ErrPromoExhausted
+		}
+	}
+	return nil
+}
+
+// promoError объясняет, почему промокод не удалось погасить
+func (q *Queries) promoError(ctx context.Context, tx *sql.Tx, promotionID int64, at time.Time) error {
+	promotions, err := q.queryPromotions(ctx, "promoError", q.promotionsQuery().
+		Where(sq.Eq{"promotion_id": promotionID}).
+		RunWith(q.traced(tx)))
+	if err != nil {
+		return err
+	}
+	if len(promotions) == 0 || at.Before(promotions[0].StartsAt) || !at.Before(promotions[0].EndsAt) {
+		return ErrPromoInvalid
+	}
+	return ErrPromoExhausted
+}
+
+func (q *Queries) queryPromotions(ctx context.Context, operation string, sqlQuery sq.SelectBuilder) ([]Promotion, error) {
+	rows, err := sqlQuery.QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error(operation+" QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var promotions []Promotion
+	for rows.Next() {
+		var p Promotion
+		var itemID sql.NullInt64
+		err := rows.Scan(&p.ID, &p.Code, &p.Kind, &p.Value, &itemID, &p.Item, &p.StartsAt, &p.EndsAt,
+			&p.MaxRedemptions, &p.PerUserLimit, &p.Redemptions, &p.CreatedAt)
+		if err != nil {
+			q.logger(ctx).Error(operation+" rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		if itemID.Valid {
+			id := int(itemID.Int64)
+			p.ItemID = &id
+		}
+		p.StartsAt = p.StartsAt.UTC()
+		p.EndsAt = p.EndsAt.UTC()
+		p.CreatedAt = p.CreatedAt.UTC()
+		promotions = append(promotions, p)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error(operation+" rows error:", zap.Error(err))
+		return nil, err
+	}
+	return promotions, nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+var promotionColumns = []string{"promotion_id", "code", "kind", "value", "item_id", "name", "starts_at", "ends_at",
+	"max_redemptions", "per_user_limit", "redemptions", "created_at"}
+
+func TestPurchaseMerchTransaction_Promo(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE promotions SET redemptions = redemptions \+ 1 WHERE promotion_id = \$1 AND starts_at <= \$2 AND ends_at > \$3 AND \(max_redemptions = \$4 OR redemptions < max_redemptions\) RETURNING per_user_limit`).
+		WithArgs(int64(9), sqlmock.AnyArg(), sqlmock.AnyArg(), 0).
+		WillReturnRows(sqlmock.NewRows([]string{"per_user_limit"}).AddRow(1))
+	// отмененные заказы не расходуют лимит
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM purchases WHERE promotion_id = \$1 AND COALESCE\(buyer_id, employee_id\) = \$2 `+
+		`AND status <> \$3`).
+		WithArgs(int64(9), userID, OrderCancelled).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(`UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`).
+		WithArgs(30, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO purchases`).
+		WithArgs(DefaultTenantID, userID, nil, nil, "", 3, nil, 2, 20, 30, 10, int64(9), OrderPlaced, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"purchase_id"}).AddRow(5))
+	mock.ExpectExec(`INSERT INTO inventory_ledger`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeMerchPurchased,
+			`{"userId":"`+userID.String()+`","itemId":3,"item":"cup","quantity":2,"price":20,"discount":10,"total":30}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := queries.PurchaseMerchTransaction(ctx, userID, MerchInfo{MerchID: 3, Name: "cup", Price: 20, Amount: 2,
+		Discount: 10, PromotionID: 9})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseMerchTransaction_PromoExhausted(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	now := time.Now().UTC()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE promotions SET redemptions = redemptions \+ 1`).
+		WillReturnRows(sqlmock.NewRows([]string{"per_user_limit"}))
+	mock.ExpectQuery(`SELECT promotion_id, .* FROM promotions LEFT JOIN merch_items ON merch_items.item_id = promotions.item_id WHERE promotion_id = \$1`).
+		WithArgs(int64(9)).
+		WillReturnRows(sqlmock.NewRows(promotionColumns).
+			AddRow(9, "ONCE", DiscountFixed, 10, nil, "", now.Add(-time.Hour), now.Add(time.Hour), 1, 0, 1, now))
+	mock.ExpectRollback()
+
+	err := queries.PurchaseMerchTransaction(ctx, userID, MerchInfo{MerchID: 3, Name: "cup", Price: 20, Amount: 2,
+		Discount: 10, PromotionID: 9})
+	assert.ErrorIs(t, err, ErrPromoExhausted)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/metrics" //nolint:gci
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"go.uber.org/zap"
+)
+
+// Replica реплика postgres только для чтения
+type Replica struct {
+	Name string
+	DB   *sql.DB
+}
+
+func NewReplica(name string, pool *pgxpool.Pool) Replica {
+	return Replica{Name: name, DB: stdlib.OpenDBFromPool(pool)}
+}
+
+type ReplicaConfig struct {
+	// MaxLag реплика с большим отставанием не используется до следующей проверки
+	MaxLag        time.Duration
+	CheckInterval time.Duration
+}
+
+type replicaState struct {
+	Replica
+	available atomic.Bool
+}
+
+type replicaSet struct {
+	replicas []*replicaState
+	config   ReplicaConfig
+	next     atomic.Uint64
+}
+
+// отставание реплики в секундах; если все полученные WAL применены,
+// реплика актуальна, даже когда на primary давно не было записи
+const replicaLagQuery = `SELECT CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0) END::float8`
+
+type primaryKey struct{}
+
+// WithPrimary направляет все чтения в контексте на primary,
+// например когда нужно прочитать только что записанные данные
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// IsPrimary запрошено ли в контексте чтение с primary. Такие чтения идут и мимо кэша.
+func IsPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// UseReplicas подключает реплики для чтения и запускает проверку их отставания.
+// Пока первая проверка не прошла, чтения идут на primary.
+// Возвращает функцию остановки проверки.
+func (q *Queries) UseReplicas(replicas []Replica, config ReplicaConfig) (stop func()) {
+	set := &replicaSet{config: config}
+	for _, r := range replicas {
+		set.replicas = append(set.replicas, &replicaState{Replica: r})
+	}
+	q.replicas = set
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(config.CheckInterval)
+		defer ticker.Stop()
+		for {
+			q.checkReplicas(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+func (q *Queries) checkReplicas(ctx context.Context) {
+	for _, r := range q.replicas.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, q.replicas.config.CheckInterval)
+		var lag float64
+		err := r.DB.QueryRowContext(checkCtx, replicaLagQuery).Scan(&lag)
+		cancel()
+
+		available := err == nil && lag <= q.replicas.config.MaxLag.Seconds()
+		if err != nil {
+			q.logger(ctx).Warn("replica lag check error:", zap.String("replica", r.Name), zap.Error(err))
+		} else {
+			metrics.ReplicaLag.WithLabelValues(r.Name).Set(lag)
+		}
+		if r.available.Swap(available) != available {
+			q.logger(ctx).Info("replica availability changed", zap.String("replica", r.Name),
+				zap.Bool("available", available), zap.Float64("lag_seconds", lag))
+		}
+		if available {
+			metrics.ReplicaAvailable.WithLabelValues(r.Name).Set(1)
+		} else {
+			metrics.ReplicaAvailable.WithLabelValues(r.Name).Set(0)
+		}
+	}
+}
+
+// reader выбирает соединение для запроса, который допускает отставание:
+// доступную реплику по кругу или primary, если реплик нет или все отстают
+func (q *Queries) reader(ctx context.Context) tracedRunner {
+	if q.replicas == nil || IsPrimary(ctx) {
+		return q.traced(q.db)
+	}
+	n := uint64(len(q.replicas.replicas))
+	start := q.replicas.next.Add(1)
+	for i := range n {
+		r := q.replicas.replicas[(start+i)%n]
+		if r.available.Load() {
+			return tracedRunner{StdSqlCtx: r.DB, system: q.dbSystem(), instance: r.Name}
+		}
+	}
+	return q.traced(q.db)
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReplica(t *testing.T, queries *Queries, maxLag time.Duration) sqlmock.Sqlmock {
+	replicaDB, replicaMock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = replicaDB.Close() })
+
+	queries.replicas = &replicaSet{
+		replicas: []*replicaState{{Replica: Replica{Name: "replica-1", DB: replicaDB}}},
+		config:   ReplicaConfig{MaxLag: maxLag, CheckInterval: time.Second},
+	}
+	return replicaMock
+}
+
+func expectLag(mock sqlmock.Sqlmock, lag float64) {
+	mock.ExpectQuery(`SELECT CASE WHEN pg_last_wal_receive_lsn\(\) = pg_last_wal_replay_lsn\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"lag"}).AddRow(lag))
+}
+
+func TestReplicaRouting(t *testing.T) {
+	ctx := context.Background()
+	db, primaryMock, queries := setupMockDB(t)
+	defer db.Close()
+	replicaMock := setupReplica(t, queries, 5*time.Second)
+
+	userID := uuid.New()
+	inventoryQuery := `SELECT name, .* quantity FROM inventory`
+	inventoryColumns := []string{"name", "sku", "size", "color", "quantity"}
+
+	// до первой проверки реплика не используется
+	primaryMock.ExpectQuery(inventoryQuery).WithArgs(userID, 0).
+		WillReturnRows(sqlmock.NewRows(inventoryColumns))
+	_, err := queries.GetInventories(ctx, userID)
+	require.NoError(t, err)
+
+	expectLag(replicaMock, 0.5)
+	queries.checkReplicas(ctx)
+
+	replicaMock.ExpectQuery(inventoryQuery).WithArgs(userID, 0).
+		WillReturnRows(sqlmock.NewRows(inventoryColumns).AddRow("cup", "", "", "", 1))
+	inventories, err := queries.GetInventories(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []InventoryItem{{Name: "cup", Quantity: 1}}, inventories)
+
+	// баланс всегда читается с primary
+	primaryMock.ExpectQuery(`SELECT balance FROM wallets`).WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(980))
+	_, err = queries.GetBalance(ctx, userID)
+	require.NoError(t, err)
+
+	// явный запрос на чтение с primary
+	primaryMock.ExpectQuery(inventoryQuery).WithArgs(userID, 0).
+		WillReturnRows(sqlmock.NewRows(inventoryColumns))
+	_, err = queries.GetInventories(WithPrimary(ctx), userID)
+	require.NoError(t, err)
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestReplicaLagFallback(t *testing.T) {
+	ctx := context.Background()
+	db, primaryMock, queries := setupMockDB(t)
+	defer db.Close()
+	replicaMock := setupReplica(t, queries, 5*time.Second)
+
+	expectLag(replicaMock, 0)
+	queries.checkReplicas(ctx)
+	assert.True(t, queries.replicas.replicas[0].available.Load())
+
+	// отставание больше MaxLag
+	expectLag(replicaMock, 30)
+	queries.checkReplicas(ctx)
+	assert.False(t, queries.replicas.replicas[0].available.Load())
+
+	primaryMock.ExpectQuery(`SELECT item_id, price FROM merch_items`).WithArgs("cup", DefaultTenantID).
+		WillReturnRows(sqlmock.NewRows([]string{"item_id", "price"}).AddRow(2, 20))
+	primaryMock.ExpectQuery(`SELECT variant_id, .* FROM merch_variants`).WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"variant_id", "item_id", "sku", "size", "color", "price", "stock"}))
+	_, err := queries.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+
+	// ошибка проверки тоже исключает реплику
+	expectLag(replicaMock, 0)
+	queries.checkReplicas(ctx)
+	replicaMock.ExpectQuery(`SELECT CASE`).WillReturnError(errors.New("connection refused"))
+	queries.checkReplicas(ctx)
+	assert.False(t, queries.replicas.replicas[0].available.Load())
+
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
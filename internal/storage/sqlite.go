@@ -0,0 +1,32 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	_ "embed" //nolint:revive
+	"fmt"
+	"net/url"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// SQLiteConnectionString строка подключения к файлу базы sqlite.
+// busy_timeout и _txlock=immediate нужны, чтобы конкурентные
+// транзакции ждали блокировку, а не падали с SQLITE_BUSY.
+func SQLiteConnectionString(path string) string {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Set("_txlock", "immediate")
+	return "file:" + path + "?" + params.Encode()
+}
+
+// MigrateSQLite создает схему и начальный каталог мерча, если их еще нет
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("failed to apply sqlite schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+-- Схема для sqlite, эквивалентна migrations/init.sql.
+-- Применяется при старте сервиса, поэтому все объекты создаются через IF NOT EXISTS.
+
+-- компания со своими сотрудниками, каталогом и настройками. signup_bonus - монеты новому сотруднику,
+-- admin_signup_bonus - новому администратору компании, NULL - как signup_bonus, bonus_vesting_days -
+-- через сколько дней после регистрации бонус попадает на баланс, budget_period - период бюджета на благодарности, пустой - BUDGET_PERIOD сервиса, admins -
+-- администраторы компании через запятую. tenant_id в остальных таблицах - компания строки,
+-- username, sku, код промокода и название команды уникальны в пределах компании
+CREATE TABLE IF NOT EXISTS tenants (
+    tenant_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    slug VARCHAR(64) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    signup_bonus INTEGER NOT NULL DEFAULT 1000 CHECK (signup_bonus >= 0),
+    admin_signup_bonus INTEGER CHECK (admin_signup_bonus >= 0),
+    bonus_vesting_days INTEGER NOT NULL DEFAULT 0 CHECK (bonus_vesting_days >= 0),
+    budget_period VARCHAR(16) NOT NULL DEFAULT '',
+    admins TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- uuid v4 генерируется выражением, т.к. в sqlite нет uuid_generate_v4()
+CREATE TABLE IF NOT EXISTS employees (
+    employee_id TEXT PRIMARY KEY NOT NULL DEFAULT (
+        lower(hex(randomblob(4))) || '-' || lower(hex(randomblob(2))) || '-4' ||
+        substr(lower(hex(randomblob(2))), 2) || '-' ||
+        substr('89ab', abs(random()) % 4 + 1, 1) || substr(lower(hex(randomblob(2))), 2) || '-' ||
+        lower(hex(randomblob(6)))
+    ),
+    tenant_id INTEGER NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'employee' CHECK (role IN ('employee', 'admin')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, username),
+    UNIQUE (tenant_id, email),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS wallets (
+    employee_id TEXT PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    balance INTEGER NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    -- бюджет на благодарности: тратится только на переводы коллегам, в начале
+    -- периода восполняется до budget_limit. budget_period - начало периода, к которому относится budget
+    budget INTEGER NOT NULL DEFAULT 0 CHECK (budget >= 0),
+    budget_limit INTEGER NOT NULL DEFAULT 0 CHECK (budget_limit >= 0),
+    budget_period TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- бонус за регистрацию. Выдается один раз на имя сотрудника в компании, запись не удаляется
+-- вместе с сотрудником, поэтому заново созданная учетная запись бонус не получает.
+-- vested_at NULL - бонус ждет vests_at и еще не на балансе
+CREATE TABLE IF NOT EXISTS onboarding (
+    onboarding_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    employee_id TEXT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    bonus INTEGER NOT NULL CHECK (bonus >= 0),
+    vests_at TIMESTAMP NOT NULL,
+    vested_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (tenant_id, username),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS merch_items (
+    item_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS merch_variants (
+    variant_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    size VARCHAR(16) NOT NULL DEFAULT '',
+    color VARCHAR(32) NOT NULL DEFAULT '',
+    price INTEGER CHECK (price > 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    UNIQUE (tenant_id, sku),
+    UNIQUE (item_id, size, color),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS merch_prices (
+    price_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    price INTEGER NOT NULL CHECK (price > 0),
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP,
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS promotions (
+    promotion_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    code VARCHAR(32),
+    kind VARCHAR(16) NOT NULL,
+    value INTEGER NOT NULL CHECK (value > 0),
+    item_id INTEGER,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    per_user_limit INTEGER NOT NULL DEFAULT 0,
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, code),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS teams (
+    team_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    balance INTEGER NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_members (
+    team_id INTEGER NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    employee_id TEXT NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member',
+    PRIMARY KEY (team_id, employee_id),
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS purchases (
+    purchase_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    employee_id TEXT NOT NULL,
+    buyer_id TEXT,
+    team_id INTEGER,
+    gift_message VARCHAR(255) NOT NULL DEFAULT '',
+    item_id INTEGER NOT NULL,
+    quantity INTEGER NOT NULL,
+    unit_price INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    discount INTEGER NOT NULL DEFAULT 0,
+    promotion_id INTEGER,
+    variant_id INTEGER,
+    status VARCHAR(16) NOT NULL DEFAULT 'placed',
+    shipping_address TEXT NOT NULL DEFAULT '',
+    purchase_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (buyer_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(promotion_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    transaction_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    sender_id TEXT NOT NULL,
+    receiver_id TEXT NOT NULL,
+    amount INTEGER NOT NULL,
+    transaction_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (sender_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (receiver_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS inventory (
+    employee_id TEXT NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    variant_id INTEGER,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS inventory_ledger (
+    entry_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    employee_id TEXT NOT NULL,
+    item_id INTEGER NOT NULL,
+    variant_id INTEGER,
+    kind VARCHAR(16) NOT NULL,
+    quantity INTEGER NOT NULL,
+    purchase_id INTEGER,
+    counterparty_id TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(purchase_id),
+    FOREIGN KEY (counterparty_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS wishlist (
+    wish_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    employee_id TEXT NOT NULL,
+    item_id INTEGER NOT NULL,
+    goal INTEGER NOT NULL CHECK (goal > 0),
+    contributed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (employee_id, item_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS team_transactions (
+    entry_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    amount INTEGER NOT NULL,
+    employee_id TEXT,
+    purchase_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(purchase_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS outbox (
+    outbox_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id INTEGER NOT NULL,
+    event_id TEXT UNIQUE NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    published_at TIMESTAMP,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id TEXT PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id TEXT PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    webhook_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_employees_email ON employees (email);
+CREATE INDEX IF NOT EXISTS idx_employees_username ON employees (tenant_id, username COLLATE NOCASE);
+CREATE INDEX IF NOT EXISTS idx_merch_items_name ON merch_items (tenant_id, name);
+CREATE INDEX IF NOT EXISTS idx_purchases_employee_id ON purchases (employee_id);
+CREATE INDEX IF NOT EXISTS idx_purchases_buyer_id ON purchases (buyer_id) WHERE buyer_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_purchases_status ON purchases (status, purchase_id);
+CREATE INDEX IF NOT EXISTS idx_purchases_purchase_date ON purchases (purchase_date);
+CREATE INDEX IF NOT EXISTS idx_purchases_promotion_id ON purchases (promotion_id, employee_id);
+CREATE INDEX IF NOT EXISTS idx_merch_variants_item_id ON merch_variants (item_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_merch_prices_current ON merch_prices (item_id) WHERE effective_to IS NULL;
+CREATE INDEX IF NOT EXISTS idx_merch_prices_item_id ON merch_prices (item_id, effective_from);
+CREATE INDEX IF NOT EXISTS idx_promotions_sales ON promotions (item_id, ends_at) WHERE code IS NULL;
+CREATE INDEX IF NOT EXISTS idx_transactions_sender_id ON transactions (sender_id);
+CREATE INDEX IF NOT EXISTS idx_transactions_receiver_id ON transactions (receiver_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_inventory_holding ON inventory (employee_id, item_id, COALESCE(variant_id, 0));
+CREATE INDEX IF NOT EXISTS idx_inventory_ledger_employee_id ON inventory_ledger (employee_id, entry_id);
+CREATE INDEX IF NOT EXISTS idx_team_members_employee_id ON team_members (employee_id);
+CREATE INDEX IF NOT EXISTS idx_team_transactions_team_id ON team_transactions (team_id, entry_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox (outbox_id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_onboarding_vesting ON onboarding (vests_at) WHERE vested_at IS NULL;
+
+-- INIT default tenant and merch data
+INSERT INTO tenants (slug, name)
+SELECT 'default', 'default'
+WHERE NOT EXISTS (SELECT 1 FROM tenants);
+
+INSERT INTO merch_items (tenant_id, name, price)
+SELECT 1, column1, column2 FROM (
+    VALUES ('t-shirt', 80), ('cup', 20), ('book', 50), ('pen', 10), ('powerbank', 200),
+        ('hoody', 300), ('umbrella', 200), ('socks', 10), ('wallet', 50), ('pink-hoody', 500)
+)
+WHERE NOT EXISTS (SELECT 1 FROM merch_items);
+
+INSERT INTO merch_prices (tenant_id, item_id, price, effective_from)
+SELECT tenant_id, item_id, price, CURRENT_TIMESTAMP FROM merch_items
+WHERE NOT EXISTS (SELECT 1 FROM merch_prices);
@@ -0,0 +1,35 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/storagetest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newSQLite(tb testing.TB) *storage.Queries {
+	tb.Helper()
+	db, err := storage.NewDBConection(storage.ConnectionParams{
+		DbDriver:         storage.DBSQLite,
+		ConnectionString: storage.SQLiteConnectionString(filepath.Join(tb.TempDir(), "shop.db")),
+	})
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = db.Close() })
+
+	require.NoError(tb, storage.MigrateSQLite(context.Background(), db))
+	// повторное применение схемы не должно падать
+	require.NoError(tb, storage.MigrateSQLite(context.Background(), db))
+
+	return storage.NewWithDriver(db, storage.DBSQLite, zap.NewNop())
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) service.StorageInterface {
+		return newSQLite(t)
+	})
+}
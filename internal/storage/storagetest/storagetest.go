@@ -0,0 +1,1472 @@
+// Package storagetest общий набор тестов, который должна проходить
+// любая реализация service.StorageInterface.
+package storagetest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signupBonus = 1000
+
+// Run прогоняет набор на хранилище, которое возвращает newStorage.
+// Имена пользователей генерируются случайно, поэтому набор можно
+// запускать на непустой базе.
+func Run(t *testing.T, newStorage func(t *testing.T) service.StorageInterface) {
+	t.Helper()
+
+	t.Run("NewUser", func(t *testing.T) { testNewUser(t, newStorage(t)) })
+	t.Run("UsernameLookup", func(t *testing.T) { testUsernameLookup(t, newStorage(t)) })
+	t.Run("UnknownUser", func(t *testing.T) { testUnknownUser(t, newStorage(t)) })
+	t.Run("SendCoins", func(t *testing.T) { testSendCoins(t, newStorage(t)) })
+	t.Run("SendCoinsNotEnough", func(t *testing.T) { testSendCoinsNotEnough(t, newStorage(t)) })
+	t.Run("SendCoinsConcurrent", func(t *testing.T) { testSendCoinsConcurrent(t, newStorage(t)) })
+	t.Run("Budgets", func(t *testing.T) { testBudgets(t, newStorage(t)) })
+	t.Run("MerchItems", func(t *testing.T) { testMerchItems(t, newStorage(t)) })
+	t.Run("PurchaseMerch", func(t *testing.T) { testPurchaseMerch(t, newStorage(t)) })
+	t.Run("PurchaseMerchNotEnough", func(t *testing.T) { testPurchaseMerchNotEnough(t, newStorage(t)) })
+	t.Run("WalletInfo", func(t *testing.T) { testWalletInfo(t, newStorage(t)) })
+	t.Run("Orders", func(t *testing.T) { testOrders(t, newStorage(t)) })
+	t.Run("Gifts", func(t *testing.T) { testGifts(t, newStorage(t)) })
+	t.Run("InventoryTransfer", func(t *testing.T) { testInventoryTransfer(t, newStorage(t)) })
+	t.Run("PurchaseAfterTransfer", func(t *testing.T) { testPurchaseAfterTransfer(t, newStorage(t)) })
+	t.Run("Wishlist", func(t *testing.T) { testWishlist(t, newStorage(t)) })
+	t.Run("Teams", func(t *testing.T) { testTeams(t, newStorage(t)) })
+	t.Run("Promotions", func(t *testing.T) { testPromotions(t, newStorage(t)) })
+	t.Run("Prices", func(t *testing.T) { testPrices(t, newStorage(t)) })
+	t.Run("Variants", func(t *testing.T) { testVariants(t, newStorage(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, newStorage(t)) })
+	t.Run("Webhooks", func(t *testing.T) { testWebhooks(t, newStorage(t)) })
+	t.Run("Tenants", func(t *testing.T) { testTenants(t, newStorage(t)) })
+	t.Run("Onboarding", func(t *testing.T) { testOnboarding(t, newStorage(t)) })
+}
+
+func randomUsername() string {
+	return "u" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+}
+
+// signupOnboarding бонус, сразу попадающий на баланс
+func signupOnboarding(bonus int) *storage.Onboarding {
+	return &storage.Onboarding{Role: storage.RoleEmployee, Bonus: bonus, VestsAt: time.Now()}
+}
+
+func newUser(t *testing.T, s service.StorageInterface) (uuid.UUID, string) {
+	t.Helper()
+	username := randomUsername()
+	userID, err := s.NewUser(context.Background(), username, "hash-"+username, signupOnboarding(signupBonus))
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, userID)
+	return userID, username
+}
+
+func testNewUser(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, username := newUser(t, s)
+
+	balance, err := s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	user, err := s.GetUser4UserID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, userID, user.EmployeeId)
+	assert.Equal(t, username, user.Name)
+	assert.Equal(t, storage.RoleEmployee, user.Role)
+
+	_, err = s.NewUser(ctx, username, "other-hash", signupOnboarding(signupBonus))
+	assert.ErrorIs(t, err, storage.ErrUsernameTaken)
+
+	// роль сохраняется в записи сотрудника
+	adminID, err := s.NewUser(ctx, randomUsername(), "hash", &storage.Onboarding{Role: storage.RoleAdmin})
+	require.NoError(t, err)
+	admin, err := s.GetUser4UserID(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.RoleAdmin, admin.Role)
+}
+
+func testUsernameLookup(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, username := newUser(t, s)
+
+	authData, err := s.GetUserAuthData(ctx, strings.ToUpper(username))
+	require.NoError(t, err)
+	assert.Equal(t, userID, authData.UserID)
+	assert.Equal(t, "hash-"+username, authData.PasswordHash)
+
+	user, err := s.FindUser(ctx, strings.ToUpper(username))
+	require.NoError(t, err)
+	assert.Equal(t, userID, user.EmployeeId)
+}
+
+func testUnknownUser(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+
+	_, err := s.GetUserAuthData(ctx, randomUsername())
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	_, err = s.FindUser(ctx, randomUsername())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = s.GetUser4UserID(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testSendCoins(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	senderID, senderName := newUser(t, s)
+	receiverID, receiverName := newUser(t, s)
+
+	require.NoError(t, s.SendCoinsTransaction(ctx, senderID, receiverID, 100, time.Time{}))
+	require.NoError(t, s.SendCoinsTransaction(ctx, senderID, receiverID, 50, time.Time{}))
+
+	balance, err := s.GetBalance(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-150, balance)
+
+	balance, err = s.GetBalance(ctx, receiverID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus+150, balance)
+
+	sent, err := s.GetSendedCoins(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.SenderInfo{{Username: receiverName, Amount: 150}}, sent)
+
+	received, err := s.GetReceivedCoins(ctx, receiverID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.SenderInfo{{Username: senderName, Amount: 150}}, received)
+
+	received, err = s.GetReceivedCoins(ctx, senderID)
+	require.NoError(t, err)
+	assert.Empty(t, received)
+}
+
+func testSendCoinsNotEnough(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	senderID, _ := newUser(t, s)
+	receiverID, _ := newUser(t, s)
+
+	err := s.SendCoinsTransaction(ctx, senderID, receiverID, signupBonus+1, time.Time{})
+	assert.ErrorIs(t, err, storage.ErrNotEnoughCoins)
+
+	balance, err := s.GetBalance(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	balance, err = s.GetBalance(ctx, receiverID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	sent, err := s.GetSendedCoins(ctx, senderID)
+	require.NoError(t, err)
+	assert.Empty(t, sent)
+}
+
+func testSendCoinsConcurrent(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	senderID, _ := newUser(t, s)
+	receiverID, _ := newUser(t, s)
+
+	const workers = 20
+	const amount = 100
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// serializable транзакции могут откатываться, считаем только успешные
+			if err := s.SendCoinsTransaction(ctx, senderID, receiverID, amount, time.Time{}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	senderBalance, err := s.GetBalance(ctx, senderID)
+	require.NoError(t, err)
+	receiverBalance, err := s.GetBalance(ctx, receiverID)
+	require.NoError(t, err)
+
+	assert.GreaterOrEqual(t, senderBalance, 0)
+	assert.LessOrEqual(t, succeeded, signupBonus/amount)
+	assert.Equal(t, signupBonus-succeeded*amount, senderBalance)
+	assert.Equal(t, 2*signupBonus, senderBalance+receiverBalance)
+}
+
+func testBudgets(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	managerID, _ := newUser(t, s)
+	receiverID, _ := newUser(t, s)
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := march.AddDate(0, 1, 0)
+
+	budget, err := s.GetBudget(ctx, managerID, march)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Budget{}, *budget)
+	info, err := s.GetWalletInfo(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.WalletBudget{}, info.Budget)
+	assert.ErrorIs(t, s.SetBudget(ctx, uuid.New(), 100, march), storage.ErrUserNotFound)
+	require.NoError(t, s.SetBudget(ctx, managerID, 100, march))
+	info, err = s.GetWalletInfo(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.WalletBudget{Limit: 100, Budget: 100, Period: march}, info.Budget)
+
+	// перевод сначала тратит бюджет, баланс не меняется
+	require.NoError(t, s.SendCoinsTransaction(ctx, managerID, receiverID, 60, march))
+	budget, err = s.GetBudget(ctx, managerID, march)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Budget{Limit: 100, Remaining: 40}, *budget)
+	balance, err := s.GetBalance(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	// не хватает бюджета - остаток списывается с баланса
+	require.NoError(t, s.SendCoinsTransaction(ctx, managerID, receiverID, 50, march))
+	budget, err = s.GetBudget(ctx, managerID, march)
+	require.NoError(t, err)
+	assert.Equal(t, 0, budget.Remaining)
+	balance, err = s.GetBalance(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-10, balance)
+	balance, err = s.GetBalance(ctx, receiverID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus+110, balance)
+
+	// в новом периоде бюджет снова полный, нехватка не трогает ни бюджет, ни баланс
+	budget, err = s.GetBudget(ctx, managerID, april)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Budget{Limit: 100, Remaining: 100}, *budget)
+	err = s.SendCoinsTransaction(ctx, managerID, receiverID, signupBonus+100, april)
+	assert.ErrorIs(t, err, storage.ErrNotEnoughCoins)
+	require.NoError(t, s.SendCoinsTransaction(ctx, managerID, receiverID, 30, april))
+	budget, err = s.GetBudget(ctx, managerID, april)
+	require.NoError(t, err)
+	assert.Equal(t, storage.Budget{Limit: 100, Remaining: 70}, *budget)
+	// информация о кошельке считает остаток так же, как GetBudget
+	info, err = s.GetWalletInfo(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, 70, info.Budget.Remaining(april))
+	assert.Equal(t, 100, info.Budget.Remaining(april.AddDate(0, 1, 0)))
+	balance, err = s.GetBalance(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-10, balance)
+
+	// бюджет не тратится на покупки
+	pinkHoody, err := s.GetMerchItems(ctx, "pink-hoody")
+	require.NoError(t, err)
+	err = s.PurchaseMerchTransaction(ctx, managerID, storage.MerchInfo{
+		MerchID: pinkHoody.MerchID, Price: pinkHoody.Price, Amount: 2,
+	})
+	assert.ErrorIs(t, err, storage.ErrNotEnoughCoins)
+}
+
+func testMerchItems(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+
+	item, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	assert.Equal(t, "cup", item.Name)
+	assert.Equal(t, 20, item.Price)
+	assert.NotZero(t, item.MerchID)
+
+	_, err = s.GetMerchItems(ctx, "no-such-merch")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testPurchaseMerch(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, _ := newUser(t, s)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	book, err := s.GetMerchItems(ctx, "book")
+	require.NoError(t, err)
+
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: cup.MerchID, Price: cup.Price, Amount: 2}))
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: cup.MerchID, Price: cup.Price, Amount: 1}))
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: book.MerchID, Price: book.Price, Amount: 1}))
+
+	balance, err := s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-3*cup.Price-book.Price, balance)
+
+	inventory, err := s.GetInventories(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "book", Quantity: 1}, {Name: "cup", Quantity: 3}}, inventory)
+}
+
+func testPurchaseMerchNotEnough(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, _ := newUser(t, s)
+
+	hoody, err := s.GetMerchItems(ctx, "pink-hoody")
+	require.NoError(t, err)
+
+	err = s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: hoody.MerchID, Price: hoody.Price, Amount: 3})
+	assert.ErrorIs(t, err, storage.ErrNotEnoughCoins)
+
+	balance, err := s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	inventory, err := s.GetInventories(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+}
+
+func testWalletInfo(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, userName := newUser(t, s)
+	friendID, friendName := newUser(t, s)
+
+	info, err := s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, &storage.WalletInfo{Balance: signupBonus}, info)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+
+	require.NoError(t, s.SendCoinsTransaction(ctx, userID, friendID, 100, time.Time{}))
+	require.NoError(t, s.SendCoinsTransaction(ctx, friendID, userID, 30, time.Time{}))
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: cup.MerchID, Price: cup.Price, Amount: 2}))
+
+	info, err = s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, &storage.WalletInfo{
+		Balance:   signupBonus - 100 + 30 - 2*cup.Price,
+		Inventory: []storage.InventoryItem{{Name: "cup", Quantity: 2}},
+		Received:  []storage.SenderInfo{{Username: friendName, Amount: 30}},
+		Sent:      []storage.SenderInfo{{Username: friendName, Amount: 100}},
+	}, info)
+
+	info, err = s.GetWalletInfo(ctx, friendID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus+100-30, info.Balance)
+	assert.Equal(t, []storage.SenderInfo{{Username: userName, Amount: 100}}, info.Received)
+
+	_, err = s.GetWalletInfo(ctx, uuid.New())
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func testOrders(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, _ := newUser(t, s)
+	otherID, _ := newUser(t, s)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	book, err := s.GetMerchItems(ctx, "book")
+	require.NoError(t, err)
+
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: cup.MerchID, Price: cup.Price, Amount: 2}))
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: book.MerchID, Price: book.Price, Amount: 1}))
+
+	// новые заказы первыми
+	orders, err := s.GetOrders(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	bookOrder, cupOrder := orders[0], orders[1]
+	assert.Equal(t, "book", bookOrder.Item)
+	assert.Equal(t, "cup", cupOrder.Item)
+	assert.Equal(t, 2, cupOrder.Quantity)
+	assert.Equal(t, 2*cup.Price, cupOrder.Total)
+	assert.Equal(t, storage.OrderPlaced, cupOrder.Status)
+	assert.Equal(t, userID, cupOrder.UserID)
+	assert.WithinDuration(t, time.Now(), cupOrder.CreatedAt, time.Minute)
+
+	placed, err := s.ListOrders(ctx, storage.OrderPlaced, 1000)
+	require.NoError(t, err)
+	assert.Contains(t, placed, cupOrder)
+
+	// чужой заказ неотличим от несуществующего
+	err = s.SetShippingAddress(ctx, otherID, cupOrder.ID, "Москва", []string{storage.OrderPlaced})
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: cupOrder.ID, UserID: otherID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+	_, err = s.GetOrder(ctx, 1<<40)
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+
+	require.NoError(t, s.SetShippingAddress(ctx, userID, cupOrder.ID, "Москва, Лесная 7", []string{storage.OrderPlaced}))
+	order, err := s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: cupOrder.ID, From: []string{storage.OrderPlaced}, To: storage.OrderApproved,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storage.OrderApproved, order.Status)
+	assert.Equal(t, "Москва, Лесная 7", order.ShippingAddress)
+
+	// переход из неподходящего статуса
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: cupOrder.ID, From: []string{storage.OrderPlaced}, To: storage.OrderApproved,
+	})
+	assert.ErrorIs(t, err, storage.ErrOrderStatus)
+	err = s.SetShippingAddress(ctx, userID, cupOrder.ID, "Казань", []string{storage.OrderPlaced})
+	assert.ErrorIs(t, err, storage.ErrOrderStatus)
+
+	// отмена возвращает монеты и убирает товар из инвентаря
+	order, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: bookOrder.ID, UserID: userID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, storage.OrderCancelled, order.Status)
+
+	balance, err := s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-2*cup.Price, balance)
+
+	info, err := s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 2}}, info.Inventory)
+
+	order, err = s.GetOrder(ctx, bookOrder.ID)
+	require.NoError(t, err)
+	assert.Equal(t, storage.OrderCancelled, order.Status)
+
+	// повторная отмена не возвращает монеты второй раз
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: bookOrder.ID, From: []string{storage.OrderPlaced, storage.OrderApproved}, To: storage.OrderCancelled,
+	})
+	assert.ErrorIs(t, err, storage.ErrOrderStatus)
+	balance, err = s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-2*cup.Price, balance)
+}
+
+func testGifts(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	buyerID, buyer := newUser(t, s)
+	recipientID, recipient := newUser(t, s)
+	otherID, _ := newUser(t, s)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+
+	// получателя нет — покупка не проходит
+	err = s.PurchaseMerchTransaction(ctx, buyerID, storage.MerchInfo{
+		MerchID: cup.MerchID, Price: cup.Price, Amount: 1, RecipientID: uuid.New(),
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, buyerID, storage.MerchInfo{
+		MerchID: cup.MerchID, Price: cup.Price, Amount: 1, RecipientID: recipientID, GiftMessage: "С праздником!",
+	}))
+
+	// платит даритель, товар у получателя
+	balance, err := s.GetBalance(ctx, buyerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-cup.Price, balance)
+	balance, err = s.GetBalance(ctx, recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	info, err := s.GetWalletInfo(ctx, recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 1}}, info.Inventory)
+	info, err = s.GetWalletInfo(ctx, buyerID)
+	require.NoError(t, err)
+	assert.Empty(t, info.Inventory)
+
+	// заказ виден обоим
+	gift := &storage.Gift{FromID: buyerID, From: buyer, To: recipient, Message: "С праздником!"}
+	var orderID int64
+	for _, userID := range []uuid.UUID{buyerID, recipientID} {
+		orders, err := s.GetOrders(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, orders, 1)
+		assert.Equal(t, recipientID, orders[0].UserID)
+		assert.Equal(t, gift, orders[0].Gift)
+		orderID = orders[0].ID
+	}
+	orders, err := s.GetOrders(ctx, otherID)
+	require.NoError(t, err)
+	assert.Empty(t, orders)
+
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orderID, UserID: otherID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	assert.ErrorIs(t, err, storage.ErrOrderNotFound)
+
+	// адрес может указать получатель, отменить — любой из двоих; монеты возвращаются дарителю
+	require.NoError(t, s.SetShippingAddress(ctx, recipientID, orderID, "Москва, Лесная 7", []string{storage.OrderPlaced}))
+	order, err := s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orderID, UserID: recipientID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, gift, order.Gift)
+
+	balance, err = s.GetBalance(ctx, buyerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+	balance, err = s.GetBalance(ctx, recipientID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+}
+
+func testInventoryTransfer(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	senderID, sender := newUser(t, s)
+	receiverID, receiver := newUser(t, s)
+
+	pen, err := s.GetMerchItems(ctx, "pen")
+	require.NoError(t, err)
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, senderID, storage.MerchInfo{
+		MerchID: pen.MerchID, Name: "pen", Price: pen.Price, Amount: 3,
+	}))
+	orders, err := s.GetOrders(ctx, senderID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	orderID := orders[0].ID
+
+	transfer := storage.ItemTransfer{MerchID: pen.MerchID, Name: "pen", Quantity: 2}
+	require.NoError(t, s.TransferItemsTransaction(ctx, senderID, receiverID, transfer))
+
+	// больше, чем осталось, передать нельзя, инвентарь не меняется
+	transfer.Quantity = 2
+	assert.ErrorIs(t, s.TransferItemsTransaction(ctx, senderID, receiverID, transfer), storage.ErrNotEnoughItems)
+	assert.ErrorIs(t, s.TransferItemsTransaction(ctx, uuid.New(), receiverID, transfer), storage.ErrNotEnoughItems)
+
+	inventory, err := s.GetInventories(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "pen", Quantity: 1}}, inventory)
+	info, err := s.GetWalletInfo(ctx, receiverID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "pen", Quantity: 2}}, info.Inventory)
+
+	assert.Equal(t, []storage.LedgerEntry{
+		{Kind: storage.LedgerGifted, Item: "pen", Quantity: -2, Counterparty: receiver},
+		{Kind: storage.LedgerAcquired, Item: "pen", Quantity: 3, OrderID: orderID},
+	}, ledger(t, s, senderID))
+	assert.Equal(t, []storage.LedgerEntry{
+		{Kind: storage.LedgerReceived, Item: "pen", Quantity: 2, Counterparty: sender},
+	}, ledger(t, s, receiverID))
+
+	// товар уже передан - заказ не отменить
+	cancel := storage.OrderStatusUpdate{
+		OrderID: orderID, UserID: senderID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	}
+	_, err = s.UpdateOrderStatus(ctx, cancel)
+	assert.ErrorIs(t, err, storage.ErrNotEnoughItems)
+	balance, err := s.GetBalance(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-3*pen.Price, balance)
+
+	// после возврата товара отмена проходит и списывает его из инвентаря
+	require.NoError(t, s.TransferItemsTransaction(ctx, receiverID, senderID, transfer))
+	_, err = s.UpdateOrderStatus(ctx, cancel)
+	require.NoError(t, err)
+
+	inventory, err = s.GetInventories(ctx, senderID)
+	require.NoError(t, err)
+	assert.Empty(t, inventory)
+	balance, err = s.GetBalance(ctx, senderID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+	entries := ledger(t, s, senderID)
+	require.Len(t, entries, 4)
+	assert.Equal(t, storage.LedgerEntry{Kind: storage.LedgerReturned, Item: "pen", Quantity: -3, OrderID: orderID},
+		entries[0])
+}
+
+// testPurchaseAfterTransfer запись журнала о покупке ссылается на свой заказ,
+// даже если перед ней в журнал писали передачи
+func testPurchaseAfterTransfer(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	senderID, sender := newUser(t, s)
+	receiverID, _ := newUser(t, s)
+
+	pen, err := s.GetMerchItems(ctx, "pen")
+	require.NoError(t, err)
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, senderID, storage.MerchInfo{
+		MerchID: pen.MerchID, Name: "pen", Price: pen.Price, Amount: 2,
+	}))
+	require.NoError(t, s.TransferItemsTransaction(ctx, senderID, receiverID,
+		storage.ItemTransfer{MerchID: pen.MerchID, Name: "pen", Quantity: 1}))
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, receiverID, storage.MerchInfo{
+		MerchID: cup.MerchID, Name: "cup", Price: cup.Price, Amount: 1,
+	}))
+	orders, err := s.GetOrders(ctx, receiverID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+
+	assert.Equal(t, []storage.LedgerEntry{
+		{Kind: storage.LedgerAcquired, Item: "cup", Quantity: 1, OrderID: orders[0].ID},
+		{Kind: storage.LedgerReceived, Item: "pen", Quantity: 1, Counterparty: sender},
+	}, ledger(t, s, receiverID))
+}
+
+// ledger журнал инвентаря без идентификаторов и времени записей
+func ledger(t *testing.T, s service.StorageInterface, userID uuid.UUID) []storage.LedgerEntry {
+	entries, err := s.GetInventoryLedger(context.Background(), userID, 10)
+	require.NoError(t, err)
+	for i := range entries {
+		assert.NotZero(t, entries[i].ID)
+		assert.False(t, entries[i].CreatedAt.IsZero())
+		entries[i].ID, entries[i].CreatedAt = 0, time.Time{}
+	}
+	return entries
+}
+
+func testWishlist(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	ownerID, _ := newUser(t, s)
+	colleagueID, colleague := newUser(t, s)
+
+	powerbank, err := s.GetMerchItems(ctx, "powerbank")
+	require.NoError(t, err)
+	book, err := s.GetMerchItems(ctx, "book")
+	require.NoError(t, err)
+
+	first := storage.WishlistItem{EmployeeID: ownerID, ItemID: powerbank.MerchID, Goal: 300}
+	require.NoError(t, s.SaveWish(ctx, &first))
+	assert.NotZero(t, first.ID)
+	second := storage.WishlistItem{EmployeeID: ownerID, ItemID: book.MerchID, Goal: book.Price}
+	require.NoError(t, s.SaveWish(ctx, &second))
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// взнос - перевод монет, засчитывается в товар вишлиста
+	contribution := storage.Contribution{ItemID: powerbank.MerchID, Item: "powerbank", Amount: 30}
+	require.NoError(t, s.ContributeTransaction(ctx, colleagueID, ownerID, contribution))
+	contribution.Amount = signupBonus
+	assert.ErrorIs(t, s.ContributeTransaction(ctx, colleagueID, ownerID, contribution), storage.ErrNotEnoughCoins)
+	assert.ErrorIs(t, s.ContributeTransaction(ctx, colleagueID, ownerID,
+		storage.Contribution{ItemID: book.MerchID + 100, Item: "missing", Amount: 10}), storage.ErrWishNotFound)
+	assert.ErrorIs(t, s.ContributeTransaction(ctx, ownerID, colleagueID,
+		storage.Contribution{ItemID: powerbank.MerchID, Item: "powerbank", Amount: 10}), storage.ErrWishNotFound)
+
+	balance, err := s.GetBalance(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus+30, balance)
+	info, err := s.GetWalletInfo(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.SenderInfo{{Username: colleague, Amount: 30}}, info.Received)
+
+	// повторное добавление меняет цель, взносы остаются
+	again := storage.WishlistItem{EmployeeID: ownerID, ItemID: powerbank.MerchID, Goal: 400}
+	require.NoError(t, s.SaveWish(ctx, &again))
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, 30, again.Contributed)
+
+	wishlist, err := s.GetWishlist(ctx, ownerID)
+	require.NoError(t, err)
+	require.Len(t, wishlist, 2)
+	assert.Equal(t, storage.WishlistItem{ID: first.ID, EmployeeID: ownerID, ItemID: powerbank.MerchID, Item: "powerbank",
+		Price: powerbank.Price, Goal: 400, Contributed: 30}, withoutTime(wishlist[0]))
+	assert.Equal(t, storage.WishlistItem{ID: second.ID, EmployeeID: ownerID, ItemID: book.MerchID, Item: "book",
+		Price: book.Price, Goal: book.Price}, withoutTime(wishlist[1]))
+	assert.False(t, wishlist[0].CreatedAt.IsZero())
+	// информация о кошельке содержит тот же вишлист
+	info, err = s.GetWalletInfo(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Equal(t, wishlist, info.Wishlist)
+
+	require.NoError(t, s.DeleteWish(ctx, ownerID, book.MerchID))
+	assert.ErrorIs(t, s.DeleteWish(ctx, ownerID, book.MerchID), storage.ErrWishNotFound)
+	wishlist, err = s.GetWishlist(ctx, ownerID)
+	require.NoError(t, err)
+	assert.Len(t, wishlist, 1)
+	wishlist, err = s.GetWishlist(ctx, colleagueID)
+	require.NoError(t, err)
+	assert.Empty(t, wishlist)
+}
+
+func withoutTime(w storage.WishlistItem) storage.WishlistItem {
+	w.CreatedAt = time.Time{}
+	return w
+}
+
+func testTeams(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	adminID, admin := newUser(t, s)
+	managerID, manager := newUser(t, s)
+	memberID, member := newUser(t, s)
+	outsiderID, _ := newUser(t, s)
+
+	team := storage.Team{Name: randomUsername()}
+	require.NoError(t, s.CreateTeam(ctx, &team))
+	assert.NotZero(t, team.ID)
+	assert.ErrorIs(t, s.CreateTeam(ctx, &storage.Team{Name: team.Name}), storage.ErrTeamTaken)
+	_, err := s.GetTeam(ctx, team.ID+100)
+	assert.ErrorIs(t, err, storage.ErrTeamNotFound)
+
+	require.NoError(t, s.SetTeamMember(ctx, team.ID, managerID, storage.TeamRoleMember))
+	require.NoError(t, s.SetTeamMember(ctx, team.ID, memberID, storage.TeamRoleMember))
+	require.NoError(t, s.SetTeamMember(ctx, team.ID, outsiderID, storage.TeamRoleMember))
+	// повторное добавление меняет роль
+	require.NoError(t, s.SetTeamMember(ctx, team.ID, managerID, storage.TeamRoleManager))
+	require.NoError(t, s.RemoveTeamMember(ctx, team.ID, outsiderID))
+	assert.ErrorIs(t, s.RemoveTeamMember(ctx, team.ID, outsiderID), storage.ErrNotTeamMember)
+
+	// пополнение списывается с баланса администратора
+	require.NoError(t, s.DepositTeamCoins(ctx, team.ID, adminID, 500))
+	assert.ErrorIs(t, s.DepositTeamCoins(ctx, team.ID+100, adminID, 500), storage.ErrTeamNotFound)
+	assert.ErrorIs(t, s.DepositTeamCoins(ctx, team.ID, adminID, signupBonus), storage.ErrNotEnoughCoins)
+	balance, err := s.GetBalance(ctx, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-500, balance)
+
+	got, err := s.GetTeam(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 500, got.Balance)
+	assert.Equal(t, []storage.TeamMember{
+		{EmployeeID: managerID, Username: manager, Role: storage.TeamRoleManager},
+		{EmployeeID: memberID, Username: member, Role: storage.TeamRoleMember},
+	}, got.Members)
+
+	teams, err := s.GetTeams(ctx, memberID)
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, team.ID, teams[0].ID)
+	teams, err = s.GetTeams(ctx, outsiderID)
+	require.NoError(t, err)
+	assert.Empty(t, teams)
+
+	// распределение не может уйти в минус пула
+	require.NoError(t, s.DistributeTeamCoins(ctx, team.ID, managerID, memberID, 200))
+	assert.ErrorIs(t, s.DistributeTeamCoins(ctx, team.ID, managerID, memberID, 301), storage.ErrNotEnoughCoins)
+	balance, err = s.GetBalance(ctx, memberID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus+200, balance)
+
+	// командная покупка списывает монеты с пула, товар у менеджера
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, managerID, storage.MerchInfo{
+		MerchID: cup.MerchID, Price: cup.Price, Amount: 2, TeamID: team.ID,
+	}))
+	err = s.PurchaseMerchTransaction(ctx, managerID, storage.MerchInfo{
+		MerchID: cup.MerchID, Price: cup.Price, Amount: 300, TeamID: team.ID,
+	})
+	assert.ErrorIs(t, err, storage.ErrNotEnoughCoins)
+
+	balance, err = s.GetBalance(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+	got, err = s.GetTeam(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 300-2*cup.Price, got.Balance)
+	info, err := s.GetWalletInfo(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{{Name: "cup", Quantity: 2}}, info.Inventory)
+
+	orders, err := s.GetOrders(ctx, managerID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, team.ID, orders[0].TeamID)
+	assert.Equal(t, team.Name, orders[0].Team)
+
+	// при отмене монеты возвращаются команде
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orders[0].ID, UserID: managerID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	got, err = s.GetTeam(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 300, got.Balance)
+	balance, err = s.GetBalance(ctx, managerID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	history, err := s.GetTeamHistory(ctx, team.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	kinds := make([]string, 0, len(history))
+	for _, e := range history {
+		kinds = append(kinds, e.Kind)
+	}
+	assert.Equal(t, []string{storage.TeamRefund, storage.TeamPurchase, storage.TeamDistribution, storage.TeamDeposit},
+		kinds)
+	assert.Equal(t, 2*cup.Price, history[0].Amount)
+	assert.Equal(t, orders[0].ID, history[0].OrderID)
+	assert.Equal(t, -2*cup.Price, history[1].Amount)
+	assert.Equal(t, orders[0].ID, history[1].OrderID)
+	assert.Equal(t, -200, history[2].Amount)
+	assert.Equal(t, member, history[2].Employee)
+	assert.Equal(t, admin, history[3].Employee)
+
+	history, err = s.GetTeamHistory(ctx, team.ID, 1)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func testPrices(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	start := time.Now().UTC().Truncate(time.Second)
+	userID, _ := newUser(t, s)
+
+	umbrella, err := s.GetMerchItems(ctx, "umbrella")
+	require.NoError(t, err)
+	// база может быть общей с другими тестами, цена возвращается в конце
+	defer func() {
+		require.NoError(t, s.SetMerchPrice(ctx, *umbrella, time.Now()))
+	}()
+
+	buy := func(price int) {
+		require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{
+			MerchID: umbrella.MerchID, Name: "umbrella", Price: price, Amount: 2, Discount: 3,
+		}))
+	}
+	buy(umbrella.Price)
+
+	first, second := start.Add(time.Second), start.Add(2*time.Second)
+	require.NoError(t, s.SetMerchPrice(ctx, storage.MerchItem{MerchID: umbrella.MerchID, Name: "umbrella", Price: 111}, first))
+	require.NoError(t, s.SetMerchPrice(ctx, storage.MerchItem{MerchID: umbrella.MerchID, Name: "umbrella", Price: 123}, second))
+	buy(123)
+
+	item, err := s.GetMerchItems(ctx, "umbrella")
+	require.NoError(t, err)
+	assert.Equal(t, 123, item.Price)
+
+	history, err := s.GetPriceHistory(ctx, umbrella.MerchID)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(history), 3)
+	assert.Equal(t, storage.MerchPrice{ItemID: umbrella.MerchID, Price: 123, EffectiveFrom: second}, history[0])
+	assert.Equal(t, storage.MerchPrice{ItemID: umbrella.MerchID, Price: 111, EffectiveFrom: first, EffectiveTo: &second}, history[1])
+	assert.Equal(t, umbrella.Price, history[2].Price)
+	assert.Equal(t, &first, history[2].EffectiveTo)
+
+	// заказы сохраняют цену на момент покупки
+	orders, err := s.GetOrders(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, 123, orders[0].UnitPrice)
+	assert.Equal(t, 123*2-3, orders[0].Total)
+	assert.Equal(t, umbrella.Price, orders[1].UnitPrice)
+
+	report, err := s.GetSalesReport(ctx, start, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Contains(t, report, storage.SalesReportLine{Item: "umbrella", UnitPrice: 123, Orders: 1, Quantity: 2,
+		Gross: 246, Discount: 3, Total: 243})
+	report, err = s.GetSalesReport(ctx, start.Add(-time.Hour), start.Add(-time.Minute))
+	require.NoError(t, err)
+	for _, l := range report {
+		assert.NotEqual(t, 123, l.UnitPrice)
+	}
+}
+
+func testVariants(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	userID, _ := newUser(t, s)
+
+	socks, err := s.GetMerchItems(ctx, "socks")
+	require.NoError(t, err)
+
+	// база может быть общей с другими тестами, артикулы уникальны для запуска
+	prefix := strings.ToUpper(randomUsername())
+	small := storage.MerchVariant{ItemID: socks.MerchID, Item: "socks", SKU: prefix + "-S", Size: "S", Color: "red", Stock: 3}
+	large := storage.MerchVariant{ItemID: socks.MerchID, Item: "socks", SKU: prefix + "-L", Size: "L", Color: "red",
+		Price: 15, Stock: 1}
+	require.NoError(t, s.CreateVariant(ctx, &small))
+	require.NoError(t, s.CreateVariant(ctx, &large))
+	assert.NotZero(t, small.ID)
+	assert.NotEqual(t, small.ID, large.ID)
+
+	taken := storage.MerchVariant{ItemID: socks.MerchID, Item: "socks", SKU: small.SKU, Size: "M"}
+	assert.ErrorIs(t, s.CreateVariant(ctx, &taken), storage.ErrVariantTaken)
+
+	item, err := s.GetMerchItems(ctx, "socks")
+	require.NoError(t, err)
+	assert.Contains(t, item.Variants, small)
+	assert.Contains(t, item.Variants, large)
+
+	buy := func(v storage.MerchVariant, amount int) error {
+		return s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{
+			MerchID: socks.MerchID, Name: "socks", VariantID: v.ID, SKU: v.SKU,
+			Price: v.UnitPrice(socks.Price), Amount: amount,
+		})
+	}
+	require.NoError(t, buy(small, 2))
+	require.NoError(t, buy(large, 1))
+
+	// остатка не хватает - покупка не проходит целиком
+	assert.ErrorIs(t, buy(small, 2), storage.ErrOutOfStock)
+	assert.ErrorIs(t, buy(large, 1), storage.ErrOutOfStock)
+	balance, err := s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-2*socks.Price-15, balance)
+
+	inventory, err := s.GetInventories(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.InventoryItem{
+		{Name: "socks", SKU: large.SKU, Size: "L", Color: "red", Quantity: 1},
+		{Name: "socks", SKU: small.SKU, Size: "S", Color: "red", Quantity: 2},
+	}, inventory)
+
+	info, err := s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, inventory, info.Inventory)
+
+	orders, err := s.GetOrders(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+	assert.Equal(t, large.SKU, orders[0].SKU)
+	assert.Equal(t, 15, orders[0].UnitPrice)
+	assert.Equal(t, small.SKU, orders[1].SKU)
+	assert.Equal(t, "S", orders[1].Size)
+	assert.Equal(t, "red", orders[1].Color)
+
+	// отмена возвращает товар на остаток
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orders[0].ID, UserID: userID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	require.NoError(t, buy(large, 1))
+
+	large.Price, large.Stock = 0, 5
+	require.NoError(t, s.UpdateVariant(ctx, large))
+	item, err = s.GetMerchItems(ctx, "socks")
+	require.NoError(t, err)
+	assert.Contains(t, item.Variants, large)
+
+	missing := storage.MerchVariant{ItemID: socks.MerchID, Item: "socks", SKU: prefix + "-XL", Stock: 1}
+	assert.ErrorIs(t, s.UpdateVariant(ctx, missing), storage.ErrVariantNotFound)
+}
+
+func testPromotions(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	aliceID, _ := newUser(t, s)
+	bobID, _ := newUser(t, s)
+	carolID, _ := newUser(t, s)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+	umbrella, err := s.GetMerchItems(ctx, "umbrella")
+	require.NoError(t, err)
+
+	// база может быть общей с другими тестами, поэтому распродажа на зонт,
+	// а код случайный
+	sale := &storage.Promotion{Kind: storage.DiscountPercent, Value: 10, ItemID: &umbrella.MerchID,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}
+	require.NoError(t, s.CreatePromotion(ctx, sale))
+	assert.NotZero(t, sale.ID)
+
+	code := strings.ToUpper(randomUsername())
+	promo := &storage.Promotion{Code: code, Kind: storage.DiscountFixed, Value: 5,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), MaxRedemptions: 2, PerUserLimit: 1}
+	require.NoError(t, s.CreatePromotion(ctx, promo))
+	assert.ErrorIs(t, s.CreatePromotion(ctx, &storage.Promotion{Code: code, Kind: storage.DiscountFixed, Value: 1,
+		StartsAt: now, EndsAt: now.Add(time.Hour)}), storage.ErrPromotionCodeTaken)
+
+	sales, err := s.GetActiveSales(ctx, umbrella.MerchID, now)
+	require.NoError(t, err)
+	assert.Contains(t, sales, storage.Promotion{ID: sale.ID, Kind: storage.DiscountPercent, Value: 10,
+		ItemID: &umbrella.MerchID, Item: "umbrella", StartsAt: sale.StartsAt, EndsAt: sale.EndsAt, CreatedAt: sale.CreatedAt})
+	sales, err = s.GetActiveSales(ctx, umbrella.MerchID, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	for _, p := range sales {
+		assert.NotEqual(t, sale.ID, p.ID)
+	}
+
+	found, err := s.GetPromotionByCode(ctx, code)
+	require.NoError(t, err)
+	assert.Equal(t, promo.ID, found.ID)
+	assert.Nil(t, found.ItemID)
+	_, err = s.GetPromotionByCode(ctx, "NO-SUCH-CODE")
+	assert.ErrorIs(t, err, storage.ErrPromotionNotFound)
+
+	buy := func(userID uuid.UUID) error {
+		return s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{
+			MerchID: cup.MerchID, Name: "cup", Price: cup.Price, Amount: 1, Discount: 5, PromotionID: promo.ID,
+		})
+	}
+	require.NoError(t, buy(aliceID))
+	// один раз на пользователя
+	assert.ErrorIs(t, buy(aliceID), storage.ErrPromoExhausted)
+	require.NoError(t, buy(bobID))
+	// и два раза всего
+	assert.ErrorIs(t, buy(carolID), storage.ErrPromoExhausted)
+
+	balance, err := s.GetBalance(ctx, aliceID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus-cup.Price+5, balance)
+	balance, err = s.GetBalance(ctx, carolID)
+	require.NoError(t, err)
+	assert.Equal(t, signupBonus, balance)
+
+	orders, err := s.GetOrders(ctx, aliceID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, cup.Price-5, orders[0].Total)
+	assert.Equal(t, 5, orders[0].Discount)
+	assert.Equal(t, code, orders[0].PromoCode)
+
+	promotions, err := s.ListPromotions(ctx)
+	require.NoError(t, err)
+	for _, p := range promotions {
+		if p.ID == promo.ID {
+			assert.Equal(t, 2, p.Redemptions)
+		}
+	}
+
+	// завершенный промокод не погашается
+	other := &storage.Promotion{Code: code + "-2", Kind: storage.DiscountFixed, Value: 5,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour)}
+	require.NoError(t, s.CreatePromotion(ctx, other))
+	require.NoError(t, s.EndPromotion(ctx, other.ID, now.Add(-time.Minute)))
+	err = s.PurchaseMerchTransaction(ctx, carolID, storage.MerchInfo{
+		MerchID: cup.MerchID, Name: "cup", Price: cup.Price, Amount: 1, Discount: 5, PromotionID: other.ID,
+	})
+	assert.ErrorIs(t, err, storage.ErrPromoInvalid)
+	assert.ErrorIs(t, s.EndPromotion(ctx, 1<<40, now), storage.ErrPromotionNotFound)
+
+	// отмененный заказ не расходует лимит на пользователя
+	personal := &storage.Promotion{Code: code + "-3", Kind: storage.DiscountFixed, Value: 5,
+		StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), PerUserLimit: 1}
+	require.NoError(t, s.CreatePromotion(ctx, personal))
+	buyPersonal := func() error {
+		return s.PurchaseMerchTransaction(ctx, carolID, storage.MerchInfo{
+			MerchID: cup.MerchID, Name: "cup", Price: cup.Price, Amount: 1, Discount: 5, PromotionID: personal.ID,
+		})
+	}
+	require.NoError(t, buyPersonal())
+	orders, err = s.GetOrders(ctx, carolID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orders[0].ID, UserID: carolID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	require.NoError(t, buyPersonal())
+	assert.ErrorIs(t, buyPersonal(), storage.ErrPromoExhausted)
+
+	// отмена возвращает погашение, и общий лимит освобождается
+	orders, err = s.GetOrders(ctx, aliceID)
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	_, err = s.UpdateOrderStatus(ctx, storage.OrderStatusUpdate{
+		OrderID: orders[0].ID, UserID: aliceID, From: []string{storage.OrderPlaced}, To: storage.OrderCancelled,
+	})
+	require.NoError(t, err)
+	promotions, err = s.ListPromotions(ctx)
+	require.NoError(t, err)
+	for _, p := range promotions {
+		if p.ID == promo.ID || p.ID == personal.ID {
+			assert.Equal(t, 1, p.Redemptions, p.Code)
+		}
+	}
+	require.NoError(t, buy(carolID))
+}
+
+type outboxStorage interface {
+	ClaimOutboxEvents(ctx context.Context, limit int, lease time.Duration) ([]storage.OutboxEvent, error)
+	MarkOutboxEventsPublished(ctx context.Context, ids []int64) error
+}
+
+// claimAll забирает из outbox все доступные события. База может быть
+// общей с другими тестами, поэтому события фильтруются по пользователям.
+func claimAll(t *testing.T, o outboxStorage, lease time.Duration, userIDs ...uuid.UUID) []storage.OutboxEvent {
+	t.Helper()
+	var claimed []storage.OutboxEvent
+	for {
+		batch, err := o.ClaimOutboxEvents(context.Background(), 100, lease)
+		require.NoError(t, err)
+		if len(batch) == 0 {
+			break
+		}
+		for _, e := range batch {
+			for _, userID := range userIDs {
+				if strings.Contains(string(e.Event.Payload), userID.String()) {
+					claimed = append(claimed, e)
+					break
+				}
+			}
+		}
+	}
+	return claimed
+}
+
+func testOutbox(t *testing.T, s service.StorageInterface) {
+	o, ok := s.(outboxStorage)
+	if !ok {
+		t.Skip("storage has no outbox")
+	}
+	ctx := context.Background()
+	userID, username := newUser(t, s)
+	friendID, _ := newUser(t, s)
+
+	cup, err := s.GetMerchItems(ctx, "cup")
+	require.NoError(t, err)
+
+	require.NoError(t, s.SendCoinsTransaction(ctx, userID, friendID, 100, time.Time{}))
+	require.NoError(t, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: cup.MerchID, Name: cup.Name, Price: cup.Price, Amount: 2}))
+	// отклоненные операции не порождают событий
+	require.ErrorIs(t, s.SendCoinsTransaction(ctx, userID, friendID, signupBonus, time.Time{}), storage.ErrNotEnoughCoins)
+
+	claimed := claimAll(t, o, 50*time.Millisecond, userID, friendID)
+	require.Len(t, claimed, 4)
+
+	var types []string
+	for i, e := range claimed {
+		types = append(types, e.Event.Type)
+		assert.NotEqual(t, uuid.Nil, e.Event.ID)
+		assert.False(t, e.Event.OccurredAt.IsZero())
+		if i > 0 {
+			assert.Greater(t, e.ID, claimed[i-1].ID)
+		}
+	}
+	assert.Equal(t, []string{
+		events.TypeUserRegistered, events.TypeUserRegistered,
+		events.TypeCoinsTransferred, events.TypeMerchPurchased,
+	}, types)
+
+	var registered events.UserRegistered
+	require.NoError(t, json.Unmarshal(claimed[0].Event.Payload, &registered))
+	assert.Equal(t, events.UserRegistered{UserID: userID, Username: username}, registered)
+
+	var transferred events.CoinsTransferred
+	require.NoError(t, json.Unmarshal(claimed[2].Event.Payload, &transferred))
+	assert.Equal(t, events.CoinsTransferred{SenderID: userID, ReceiverID: friendID, Amount: 100}, transferred)
+
+	var purchased events.MerchPurchased
+	require.NoError(t, json.Unmarshal(claimed[3].Event.Payload, &purchased))
+	assert.Equal(t, events.MerchPurchased{
+		UserID: userID, ItemID: cup.MerchID, Item: "cup", Quantity: 2, Price: cup.Price, Total: 2 * cup.Price,
+	}, purchased)
+
+	// пока аренда не истекла, события не выдаются повторно
+	assert.Empty(t, claimAll(t, o, 50*time.Millisecond, userID, friendID))
+
+	// неопубликованные события возвращаются после истечения аренды
+	time.Sleep(100 * time.Millisecond)
+	reclaimed := claimAll(t, o, time.Minute, userID, friendID)
+	require.Len(t, reclaimed, 4)
+	assert.Equal(t, claimed[0].Event.ID, reclaimed[0].Event.ID)
+
+	ids := make([]int64, 0, len(reclaimed))
+	for _, e := range reclaimed {
+		ids = append(ids, e.ID)
+	}
+	require.NoError(t, o.MarkOutboxEventsPublished(ctx, ids))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, claimAll(t, o, time.Minute, userID, friendID))
+}
+
+type webhookStorage interface {
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) error
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []storage.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]storage.WebhookDelivery, error)
+	RedeliverWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+// claimDeliveries забирает готовые доставки webhookID, чужие доставки
+// из общей базы отбрасываются
+func claimDeliveries(t *testing.T, w webhookStorage, lease time.Duration, webhookID uuid.UUID) []storage.WebhookDelivery {
+	t.Helper()
+	var claimed []storage.WebhookDelivery
+	for {
+		batch, err := w.ClaimWebhookDeliveries(context.Background(), 100, lease)
+		require.NoError(t, err)
+		if len(batch) == 0 {
+			break
+		}
+		for _, d := range batch {
+			if d.WebhookID == webhookID {
+				claimed = append(claimed, d)
+			}
+		}
+	}
+	return claimed
+}
+
+func testWebhooks(t *testing.T, s service.StorageInterface) {
+	w, ok := s.(webhookStorage)
+	if !ok {
+		t.Skip("storage has no webhooks")
+	}
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	webhook := storage.Webhook{
+		ID:         uuid.New(),
+		URL:        "http://localhost/hook",
+		Secret:     "secret",
+		EventTypes: []string{events.TypeTransferReceived, events.TypeBalanceLow},
+		CreatedAt:  now,
+	}
+	require.NoError(t, w.CreateWebhook(ctx, webhook))
+
+	webhooks, err := w.ListWebhooks(ctx)
+	require.NoError(t, err)
+	var found *storage.Webhook
+	for i := range webhooks {
+		if webhooks[i].ID == webhook.ID {
+			found = &webhooks[i]
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, webhook.URL, found.URL)
+	assert.Equal(t, webhook.Secret, found.Secret)
+	assert.Equal(t, webhook.EventTypes, found.EventTypes)
+	assert.True(t, webhook.CreatedAt.Equal(found.CreatedAt))
+
+	first := storage.WebhookDelivery{
+		ID: uuid.New(), WebhookID: webhook.ID, EventID: uuid.New(), EventType: events.TypeTransferReceived,
+		Payload: []byte(`{"amount":10}`), Status: storage.DeliveryPending, NextAttemptAt: now.Add(-time.Second), CreatedAt: now,
+	}
+	second := first
+	second.ID, second.EventID, second.CreatedAt = uuid.New(), uuid.New(), now.Add(time.Second)
+	// доставка, время которой еще не пришло
+	later := first
+	later.ID, later.EventID, later.NextAttemptAt, later.CreatedAt = uuid.New(), uuid.New(), now.Add(time.Hour), now.Add(2*time.Second)
+	require.NoError(t, w.CreateWebhookDeliveries(ctx, []storage.WebhookDelivery{first, second, later}))
+
+	claimed := claimDeliveries(t, w, 50*time.Millisecond, webhook.ID)
+	require.Len(t, claimed, 2)
+	assert.ElementsMatch(t, []uuid.UUID{first.ID, second.ID}, []uuid.UUID{claimed[0].ID, claimed[1].ID})
+	assert.JSONEq(t, `{"amount":10}`, string(claimed[0].Payload))
+	// пока аренда не истекла, доставки не выдаются повторно
+	assert.Empty(t, claimDeliveries(t, w, 50*time.Millisecond, webhook.ID))
+
+	deliveredAt := now
+	delivered := first
+	delivered.Status, delivered.Attempts, delivered.LastStatusCode, delivered.DeliveredAt = storage.DeliveryDelivered, 1, 200, &deliveredAt
+	require.NoError(t, w.UpdateWebhookDelivery(ctx, delivered))
+
+	dead := second
+	dead.Status, dead.Attempts, dead.LastError, dead.LastStatusCode = storage.DeliveryDead, 5, "status 500", 500
+	require.NoError(t, w.UpdateWebhookDelivery(ctx, dead))
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, claimDeliveries(t, w, time.Minute, webhook.ID))
+
+	all, err := w.ListWebhookDeliveries(ctx, webhook.ID, "", 10)
+	require.NoError(t, err)
+	require.Len(t, all, 3)
+	assert.Equal(t, []uuid.UUID{later.ID, second.ID, first.ID}, []uuid.UUID{all[0].ID, all[1].ID, all[2].ID})
+	require.NotNil(t, all[2].DeliveredAt)
+	assert.Nil(t, all[1].DeliveredAt)
+
+	deadList, err := w.ListWebhookDeliveries(ctx, webhook.ID, storage.DeliveryDead, 10)
+	require.NoError(t, err)
+	require.Len(t, deadList, 1)
+	assert.Equal(t, "status 500", deadList[0].LastError)
+	assert.Equal(t, 500, deadList[0].LastStatusCode)
+	assert.Equal(t, 5, deadList[0].Attempts)
+
+	// ручная повторная доставка возвращает доставку в очередь
+	require.NoError(t, w.RedeliverWebhookDelivery(ctx, second.ID))
+	redelivered := claimDeliveries(t, w, time.Minute, webhook.ID)
+	require.Len(t, redelivered, 1)
+	assert.Equal(t, second.ID, redelivered[0].ID)
+	assert.Equal(t, 0, redelivered[0].Attempts)
+
+	assert.ErrorIs(t, w.RedeliverWebhookDelivery(ctx, uuid.New()), storage.ErrDeliveryNotFound)
+	assert.ErrorIs(t, w.UpdateWebhookDelivery(ctx, storage.WebhookDelivery{ID: uuid.New()}), storage.ErrDeliveryNotFound)
+
+	require.NoError(t, w.DeleteWebhook(ctx, webhook.ID))
+	assert.ErrorIs(t, w.DeleteWebhook(ctx, webhook.ID), storage.ErrWebhookNotFound)
+	all, err = w.ListWebhookDeliveries(ctx, webhook.ID, "", 10)
+	require.NoError(t, err)
+	assert.Empty(t, all)
+}
+
+func testTenants(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	acme := storage.Tenant{Slug: randomUsername(), Name: "Acme", SignupBonus: 300, Admins: []string{"boss"}}
+	require.NoError(t, s.CreateTenant(ctx, &acme, nil))
+	assert.NotZero(t, acme.ID)
+	assert.ErrorIs(t, s.CreateTenant(ctx, &storage.Tenant{Slug: acme.Slug, Name: "Copy"}, nil), storage.ErrTenantTaken)
+	small := storage.Tenant{Slug: randomUsername(), Name: "Small"}
+	require.NoError(t, s.CreateTenant(ctx, &small, []storage.MerchItem{{Name: "mug", Price: 15}}))
+
+	got, err := s.GetTenantBySlug(ctx, acme.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, got.ID)
+	assert.Equal(t, 300, got.SignupBonus)
+	assert.Equal(t, []string{"boss"}, got.Admins)
+	_, err = s.GetTenantBySlug(ctx, randomUsername())
+	assert.ErrorIs(t, err, storage.ErrTenantNotFound)
+	_, err = s.GetTenant(ctx, 1<<40)
+	assert.ErrorIs(t, err, storage.ErrTenantNotFound)
+
+	// сотрудники компании, роли которых следуют за списком администраторов
+	acmeCtx := storage.WithTenant(ctx, acme.ID)
+	deputy := randomUsername()
+	deputyID, err := s.NewUser(acmeCtx, deputy, "hash", signupOnboarding(0))
+	require.NoError(t, err)
+	bossID, err := s.NewUser(acmeCtx, randomUsername(), "hash", &storage.Onboarding{Role: storage.RoleAdmin})
+	require.NoError(t, err)
+	// тезка в компании по умолчанию не затрагивается
+	namesakeID, err := s.NewUser(ctx, deputy, "hash", signupOnboarding(0))
+	require.NoError(t, err)
+	role := func(ctx context.Context, userID uuid.UUID) string {
+		user, err := s.GetUser4UserID(ctx, userID)
+		require.NoError(t, err)
+		return user.Role
+	}
+
+	acme.Name = "Acme Inc"
+	acme.BudgetPeriod = "week"
+	acme.Admins = []string{"boss", "deputy"}
+	changed, err := s.UpdateTenant(ctx, acme, []string{strings.ToUpper(deputy)})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uuid.UUID{deputyID, bossID}, changed)
+	got, err = s.GetTenant(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Inc", got.Name)
+	assert.Equal(t, "week", got.BudgetPeriod)
+	assert.Equal(t, []string{"boss", "deputy"}, got.Admins)
+	assert.Equal(t, storage.RoleAdmin, role(acmeCtx, deputyID))
+	assert.Equal(t, storage.RoleEmployee, role(acmeCtx, bossID))
+	assert.Equal(t, storage.RoleEmployee, role(ctx, namesakeID))
+
+	// пустой список снимает роль со всех
+	changed, err = s.UpdateTenant(ctx, acme, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{deputyID}, changed)
+	assert.Equal(t, storage.RoleEmployee, role(acmeCtx, deputyID))
+	_, err = s.UpdateTenant(ctx, storage.Tenant{ID: 1 << 40, Name: "x"}, nil)
+	assert.ErrorIs(t, err, storage.ErrTenantNotFound)
+
+	tenants, err := s.ListTenants(ctx)
+	require.NoError(t, err)
+	var slugs []string
+	for _, tenant := range tenants {
+		slugs = append(slugs, tenant.Slug)
+	}
+	assert.Contains(t, slugs, acme.Slug)
+	assert.Contains(t, slugs, small.Slug)
+
+	// одно имя в разных компаниях - разные сотрудники со своим бонусом
+	smallCtx := storage.WithTenant(ctx, small.ID)
+	_, username := newUser(t, s)
+	acmeUserID, err := s.NewUser(acmeCtx, username, "hash", signupOnboarding(300))
+	require.NoError(t, err)
+	balance, err := s.GetBalance(acmeCtx, acmeUserID)
+	require.NoError(t, err)
+	assert.Equal(t, 300, balance)
+	// кошелек несет период бюджета из настроек своей компании
+	info, err := s.GetWalletInfo(acmeCtx, acmeUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "week", info.Budget.TenantPeriod)
+	_, err = s.FindUser(smallCtx, username)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = s.GetUserAuthData(smallCtx, username)
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+
+	// без catalog копируется каталог компании по умолчанию
+	_, err = s.GetMerchItems(acmeCtx, "cup")
+	assert.NoError(t, err)
+	_, err = s.GetMerchItems(smallCtx, "cup")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	mug, err := s.GetMerchItems(smallCtx, "mug")
+	require.NoError(t, err)
+	assert.Equal(t, 15, mug.Price)
+	_, err = s.GetMerchItems(ctx, "mug")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// названия команд уникальны внутри компании
+	teamName := randomUsername()
+	require.NoError(t, s.CreateTeam(ctx, &storage.Team{Name: teamName}))
+	acmeTeam := storage.Team{Name: teamName}
+	require.NoError(t, s.CreateTeam(acmeCtx, &acmeTeam))
+	_, err = s.GetTeam(smallCtx, acmeTeam.ID)
+	assert.ErrorIs(t, err, storage.ErrTeamNotFound)
+
+	// состав и история команды не меняются и не читаются из другой компании
+	require.NoError(t, s.SetTeamMember(acmeCtx, acmeTeam.ID, acmeUserID, storage.TeamRoleMember))
+	require.NoError(t, s.DepositTeamCoins(acmeCtx, acmeTeam.ID, acmeUserID, 100))
+	smallUserID, err := s.NewUser(smallCtx, randomUsername(), "hash", signupOnboarding(0))
+	require.NoError(t, err)
+	assert.ErrorIs(t, s.SetTeamMember(smallCtx, acmeTeam.ID, smallUserID, storage.TeamRoleManager),
+		storage.ErrTeamNotFound)
+	assert.ErrorIs(t, s.RemoveTeamMember(smallCtx, acmeTeam.ID, acmeUserID), storage.ErrNotTeamMember)
+	history, err := s.GetTeamHistory(smallCtx, acmeTeam.ID, 10)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	gotTeam, err := s.GetTeam(acmeCtx, acmeTeam.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []storage.TeamMember{
+		{EmployeeID: acmeUserID, Username: username, Role: storage.TeamRoleMember},
+	}, gotTeam.Members)
+	history, err = s.GetTeamHistory(acmeCtx, acmeTeam.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	if o, ok := s.(outboxStorage); ok {
+		claimed := claimAll(t, o, time.Minute, acmeUserID)
+		require.NotEmpty(t, claimed)
+		assert.Equal(t, acme.ID, claimed[0].Event.TenantID)
+	}
+}
+
+func testOnboarding(t *testing.T, s service.StorageInterface) {
+	ctx := context.Background()
+	username := randomUsername()
+
+	// отложенный бонус не попадает на баланс сразу
+	delayed := &storage.Onboarding{Role: storage.RoleEmployee, Bonus: 700, VestsAt: time.Now().AddDate(0, 0, 7)}
+	userID, err := s.NewUser(ctx, username, "hash", delayed)
+	require.NoError(t, err)
+	assert.NotZero(t, delayed.ID)
+	assert.Equal(t, userID, delayed.EmployeeID)
+	assert.Nil(t, delayed.VestedAt)
+	info, err := s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, info.Balance)
+
+	// то же имя в другом регистре - повторная регистрация, второй бонус не выдается
+	again := signupOnboarding(signupBonus)
+	otherID, err := s.NewUser(ctx, strings.ToUpper(username), "hash", again)
+	require.NoError(t, err)
+	assert.Zero(t, again.Bonus)
+	balance, err := s.GetBalance(ctx, otherID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, balance)
+
+	vestedFor := func(now time.Time) []storage.Onboarding {
+		vested, err := s.VestSignupBonuses(ctx, now, 1000)
+		require.NoError(t, err)
+		var mine []storage.Onboarding
+		for _, o := range vested {
+			if o.EmployeeID == userID || o.EmployeeID == otherID {
+				mine = append(mine, o)
+			}
+		}
+		return mine
+	}
+	assert.Empty(t, vestedFor(time.Now()))
+
+	vested := vestedFor(time.Now().AddDate(0, 0, 8))
+	require.Len(t, vested, 1)
+	assert.Equal(t, storage.DefaultTenantID, vested[0].TenantID)
+	assert.Equal(t, strings.ToLower(username), vested[0].Username)
+	assert.Equal(t, 700, vested[0].Bonus)
+	assert.NotNil(t, vested[0].VestedAt)
+	info, err = s.GetWalletInfo(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 700, info.Balance)
+
+	// выданный бонус не выдается повторно
+	assert.Empty(t, vestedFor(time.Now().AddDate(0, 0, 9)))
+	balance, err = s.GetBalance(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, 700, balance)
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var teamEntryColumns = []string{"tenant_id", "team_id", "kind", "amount", "employee_id", "purchase_id", "created_at"}
+
+// CreateTeam создает команду с пустым кошельком, заполняет ID и CreatedAt
+func (q *Queries) CreateTeam(ctx context.Context, team *Team) error {
+	team.CreatedAt = time.Now().UTC()
+	err := q.builder().Insert("teams").
+		Columns("tenant_id", "name", "created_at").
+		Values(TenantID(ctx), team.Name, team.CreatedAt).
+		Suffix("RETURNING team_id").
+		RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&team.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTeamTaken
+		}
+		q.logger(ctx).Error("CreateTeam QueryRowContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetTeam команда с участниками. Читает с primary: по составу команды
+// проверяются права на операции с ее кошельком.
+func (q *Queries) GetTeam(ctx context.Context, teamID int64) (*Team, error) {
+	teams, err := q.queryTeams(ctx, "GetTeam", q.teamsQuery().
+		Where(sq.Eq{"teams.team_id": teamID}).
+		Where(tenantEq(ctx, "teams")).
+		RunWith(q.traced(q.db)))
+	if err != nil {
+		return nil, err
+	}
+	if len(teams) == 0 {
+		return nil, ErrTeamNotFound
+	}
+	return &teams[0], nil
+}
+
+// GetTeams команды, в которых состоит сотрудник
+func (q *Queries) GetTeams(ctx context.Context, userID uuid.UUID) ([]Team, error) {
+	return q.queryTeams(ctx, "GetTeams", q.teamsQuery().
+		Where(sq.Expr("teams.team_id IN (SELECT team_id FROM team_members WHERE employee_id = ?)", userID)).
+		RunWith(q.reader(ctx)))
+}
+
+// SetTeamMember добавляет сотрудника в команду или меняет его роль. Команда и
+// сотрудник ищутся в текущей компании, иначе ErrTeamNotFound.
+func (q *Queries) SetTeamMember(ctx context.Context, teamID int64, userID uuid.UUID, role string) error {
+	member := sq.Select("teams.team_id", "teams.tenant_id", "employees.employee_id").
+		Column("?", role).
+		From("teams").
+		Join("employees ON employees.tenant_id = teams.tenant_id").
+		Where(sq.Eq{"teams.team_id": teamID, "employees.employee_id": userID}).
+		Where(tenantEq(ctx, "teams"))
+	result, err := q.builder().Insert("team_members").
+		Columns("team_id", "tenant_id", "employee_id", "role").
+		Select(member).
+		Suffix("ON CONFLICT (team_id, employee_id) DO UPDATE SET role = excluded.role").
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("SetTeamMember ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrTeamNotFound)
+}
+
+func (q *Queries) RemoveTeamMember(ctx context.Context, teamID int64, userID uuid.UUID) error {
+	result, err := q.builder().Delete("team_members").
+		Where(sq.Eq{"team_id": teamID, "employee_id": userID}).
+		Where(tenantEq(ctx, "")).
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("RemoveTeamMember ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrNotTeamMember)
+}
+
+// DepositTeamCoins переводит монеты с баланса actorID в кошелек команды.
+// Монеты не создаются: без нужной суммы на балансе ErrNotEnoughCoins.
+func (q *Queries) DepositTeamCoins(ctx context.Context, teamID int64, actorID uuid.UUID, amount int) error {
+	event, err := events.New(events.TypeTeamCoinsDeposited, events.TeamCoinsDeposited{
+		TeamID:  teamID,
+		ActorID: actorID,
+		Amount:  amount,
+	})
+	if err != nil {
+		q.logger(ctx).Error("DepositTeamCoins events.New error:", zap.Error(err))
+		return err
+	}
+
+	statements := []sq.Sqlizer{
+		q.balanceDebit(actorID, amount),
+		q.teamEntry(TenantID(ctx), teamID, TeamDeposit, amount, actorID, nil, time.Now().UTC()),
+		q.outboxInsert(TenantID(ctx), event),
+	}
+	if err = q.teamTx(ctx, teamID, amount, statements); err != nil {
+		if isCheckViolation(err) {
+			return ErrNotEnoughCoins
+		}
+		if errors.Is(err, ErrTeamNotFound) {
+			return err
+		}
+		q.logger(ctx).Error("DepositTeamCoins error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// DistributeTeamCoins переводит монеты из пула команды на баланс участника
+func (q *Queries) DistributeTeamCoins(ctx context.Context, teamID int64, managerID uuid.UUID, receiverID uuid.UUID,
+	amount int) error {
+	event, err := events.New(events.TypeTeamCoinsDistributed, events.TeamCoinsDistributed{
+		TeamID:     teamID,
+		ManagerID:  managerID,
+		ReceiverID: receiverID,
+		Amount:     amount,
+	})
+	if err != nil {
+		q.logger(ctx).Error("DistributeTeamCoins events.New error:", zap.Error(err))
+		return err
+	}
+
+	receiverBalanceQuery := q.builder().Update("wallets").
+		Set("balance", sq.Expr("balance + ?", amount)).
+		Where(sq.Eq{"employee_id": receiverID})
+	statements := []sq.Sqlizer{
+		receiverBalanceQuery,
+		q.teamEntry(TenantID(ctx), teamID, TeamDistribution, -amount, receiverID, nil, time.Now().UTC()),
+		q.outboxInsert(TenantID(ctx), event),
+	}
+	if err = q.teamTx(ctx, teamID, -amount, statements); err != nil {
+		if isCheckViolation(err) {
+			return ErrNotEnoughCoins
+		}
+		if errors.Is(err, ErrTeamNotFound) {
+			return err
+		}
+		q.logger(ctx).Error("DistributeTeamCoins error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetTeamHistory история кошелька команды, новые записи первыми
+func (q *Queries) GetTeamHistory(ctx context.Context, teamID int64, limit int) ([]TeamEntry, error) {
+	sqlQuery := q.builder().Select("entry_id", "kind", "amount", "COALESCE(username, '')",
+		"COALESCE(purchase_id, 0)", "team_transactions.created_at").
+		From("team_transactions").
+		LeftJoin("employees ON employees.employee_id = team_transactions.employee_id").
+		Where(sq.Eq{"team_id": teamID}).
+		Where(tenantEq(ctx, "team_transactions")).
+		OrderBy("entry_id DESC").
+		Limit(uint64(limit))
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("GetTeamHistory QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []TeamEntry
+	for rows.Next() {
+		var e TeamEntry
+		if err := rows.Scan(&e.ID, &e.Kind, &e.Amount, &e.Employee, &e.OrderID, &e.CreatedAt); err != nil {
+			q.logger(ctx).Error("GetTeamHistory rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetTeamHistory rows error:", zap.Error(err))
+		return nil, err
+	}
+	return entries, nil
+}
+
+// teamEntry запись истории командного кошелька, purchaseID nil - операция без заказа
+func (q *Queries) teamEntry(tenant int64, teamID int64, kind string, amount int, employeeID uuid.UUID,
+	purchaseID any, at time.Time) sq.InsertBuilder {
+	return q.builder().Insert("team_transactions").
+		Columns(teamEntryColumns...).
+		Values(tenant, teamID, kind, amount, employeeID, purchaseID, at)
+}
+
+// teamTx меняет баланс команды на delta и выполняет запросы в одной транзакции.
+// Строка команды блокируется первой, как строка вишлиста при взносе.
+func (q *Queries) teamTx(ctx context.Context, teamID int64, delta int, statements []sq.Sqlizer) (err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	result, err := q.builder().Update("teams").
+		Set("balance", sq.Expr("balance + ?", delta)).
+		Where(sq.Eq{"team_id": teamID}).
+		Where(tenantEq(ctx, "")).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err = checkAffected(result, ErrTeamNotFound); err != nil {
+		return err
+	}
+
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// teamsQuery команды с участниками, по строке на участника. Менеджеры идут первыми.
+func (q *Queries) teamsQuery() sq.SelectBuilder {
+	return q.builder().Select("teams.team_id", "name", "balance", "teams.created_at", "team_members.employee_id",
+		"COALESCE(username, '')", "COALESCE(team_members.role, '')").
+		From("teams").
+		LeftJoin("team_members ON team_members.team_id = teams.team_id").
+		LeftJoin("employees ON employees.employee_id = team_members.employee_id").
+		OrderBy("teams.team_id", "team_members.role", "username")
+}
+
+func (q *Queries) queryTeams(ctx context.Context, operation string, sqlQuery sq.SelectBuilder) ([]Team, error) {
+	rows, err := sqlQuery.QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error(operation+" QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		var m TeamMember
+		var memberID uuid.NullUUID
+		err := rows.Scan(&t.ID, &t.Name, &t.Balance, &t.CreatedAt, &memberID, &m.Username, &m.Role)
+		if err != nil {
+			q.logger(ctx).Error(operation+" rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		if len(teams) == 0 || teams[len(teams)-1].ID != t.ID {
+			t.CreatedAt = t.CreatedAt.UTC()
+			teams = append(teams, t)
+		}
+		if memberID.Valid {
+			m.EmployeeID = memberID.UUID
+			last := &teams[len(teams)-1]
+			last.Members = append(last.Members, m)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error(operation+" rows error:", zap.Error(err))
+		return nil, err
+	}
+	return teams, nil
+}
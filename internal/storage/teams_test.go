@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTeam(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	insertQuery := `INSERT INTO teams \(tenant_id,name,created_at\) VALUES \(\$1,\$2,\$3\) RETURNING team_id`
+	mock.ExpectQuery(insertQuery).WithArgs(DefaultTenantID, "backend", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"team_id"}).AddRow(3))
+	mock.ExpectQuery(insertQuery).WithArgs(DefaultTenantID, "backend", sqlmock.AnyArg()).
+		WillReturnError(&pgconn.PgError{Code: "23505"})
+
+	team := Team{Name: "backend"}
+	assert.NoError(t, queries.CreateTeam(ctx, &team))
+	assert.Equal(t, int64(3), team.ID)
+	assert.ErrorIs(t, queries.CreateTeam(ctx, &Team{Name: "backend"}), ErrTeamTaken)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTeam(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	managerID := uuid.New()
+	memberID := uuid.New()
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	columns := []string{"team_id", "name", "balance", "created_at", "employee_id", "username", "role"}
+	teamQuery := `SELECT teams.team_id, name, balance, teams.created_at, team_members.employee_id, ` +
+		`COALESCE\(username, ''\), COALESCE\(team_members.role, ''\) FROM teams ` +
+		`LEFT JOIN team_members ON team_members.team_id = teams.team_id ` +
+		`LEFT JOIN employees ON employees.employee_id = team_members.employee_id ` +
+		`WHERE teams.team_id = \$1 AND teams.tenant_id = \$2 ORDER BY teams.team_id, team_members.role, username`
+
+	mock.ExpectQuery(teamQuery).WithArgs(3, DefaultTenantID).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(3, "backend", 500, createdAt, managerID, "alice", TeamRoleManager).
+		AddRow(3, "backend", 500, createdAt, memberID, "bob", TeamRoleMember))
+	// команда без участников приходит одной строкой с NULL
+	mock.ExpectQuery(teamQuery).WithArgs(4, DefaultTenantID).WillReturnRows(sqlmock.NewRows(columns).
+		AddRow(4, "empty", 0, createdAt, nil, "", ""))
+	mock.ExpectQuery(teamQuery).WithArgs(5, DefaultTenantID).WillReturnRows(sqlmock.NewRows(columns))
+
+	team, err := queries.GetTeam(ctx, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, &Team{ID: 3, Name: "backend", Balance: 500, CreatedAt: createdAt, Members: []TeamMember{
+		{EmployeeID: managerID, Username: "alice", Role: TeamRoleManager},
+		{EmployeeID: memberID, Username: "bob", Role: TeamRoleMember},
+	}}, team)
+
+	team, err = queries.GetTeam(ctx, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, &Team{ID: 4, Name: "empty", CreatedAt: createdAt}, team)
+
+	_, err = queries.GetTeam(ctx, 5)
+	assert.ErrorIs(t, err, ErrTeamNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTeamMembers(t *testing.T) {
+	ctx := WithTenant(context.Background(), 2)
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	setQuery := `INSERT INTO team_members \(team_id,tenant_id,employee_id,role\) ` +
+		`SELECT teams.team_id, teams.tenant_id, employees.employee_id, \$1 FROM teams ` +
+		`JOIN employees ON employees.tenant_id = teams.tenant_id ` +
+		`WHERE employees.employee_id = \$2 AND teams.team_id = \$3 AND teams.tenant_id = \$4 ` +
+		`ON CONFLICT \(team_id, employee_id\) DO UPDATE SET role = excluded.role`
+
+	mock.ExpectExec(setQuery).WithArgs(TeamRoleManager, userID, 3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	assert.NoError(t, queries.SetTeamMember(ctx, 3, userID, TeamRoleManager))
+	// команда другой компании не находится
+	mock.ExpectExec(setQuery).WithArgs(TeamRoleManager, userID, 4, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, queries.SetTeamMember(ctx, 4, userID, TeamRoleManager), ErrTeamNotFound)
+
+	mock.ExpectExec(`DELETE FROM team_members WHERE employee_id = \$1 AND team_id = \$2 AND tenant_id = \$3`).
+		WithArgs(userID, 4, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, queries.RemoveTeamMember(ctx, 4, userID), ErrNotTeamMember)
+
+	mock.ExpectQuery(`SELECT entry_id, .* FROM team_transactions .* `+
+		`WHERE team_id = \$1 AND team_transactions.tenant_id = \$2 ORDER BY entry_id DESC LIMIT 5`).
+		WithArgs(4, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"entry_id", "kind", "amount", "username", "purchase_id", "created_at"}))
+	entries, err := queries.GetTeamHistory(ctx, 4, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDepositTeamCoins(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	adminID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	teamQuery := `UPDATE teams SET balance = balance \+ \$1 WHERE team_id = \$2 AND tenant_id = \$3`
+	debitQuery := `UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`
+
+	t.Run("Success", func(t *testing.T) {
+		// монеты переходят с баланса администратора, а не создаются
+		mock.ExpectBegin()
+		mock.ExpectExec(teamQuery).WithArgs(100, 3, DefaultTenantID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(debitQuery).WithArgs(100, adminID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO team_transactions`).
+			WithArgs(DefaultTenantID, 3, TeamDeposit, 100, adminID, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO outbox`).
+			WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeTeamCoinsDeposited, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, queries.DepositTeamCoins(ctx, 3, adminID, 100))
+	})
+
+	t.Run("NotEnoughCoins", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(teamQuery).WithArgs(5000, 3, DefaultTenantID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(debitQuery).WithArgs(5000, adminID).WillReturnError(&pgconn.PgError{Code: "23514"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, queries.DepositTeamCoins(ctx, 3, adminID, 5000), ErrNotEnoughCoins)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDistributeTeamCoins(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	managerID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	receiverID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	teamQuery := `UPDATE teams SET balance = balance \+ \$1 WHERE team_id = \$2 AND tenant_id = \$3`
+
+	t.Run("Success", func(t *testing.T) {
+		// строка команды блокируется первой
+		mock.ExpectBegin()
+		mock.ExpectExec(teamQuery).WithArgs(-50, 3, DefaultTenantID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+			WithArgs(50, receiverID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO team_transactions \(tenant_id,team_id,kind,amount,employee_id,purchase_id,created_at\) `+
+			`VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7\)`).
+			WithArgs(DefaultTenantID, 3, TeamDistribution, -50, receiverID, nil, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO outbox \(tenant_id,event_id,event_type,payload,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`).
+			WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeTeamCoinsDistributed, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, queries.DistributeTeamCoins(ctx, 3, managerID, receiverID, 50))
+	})
+
+	t.Run("TeamNotFound", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(teamQuery).WithArgs(-50, 3, DefaultTenantID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, queries.DistributeTeamCoins(ctx, 3, managerID, receiverID, 50), ErrTeamNotFound)
+	})
+
+	t.Run("NotEnoughCoins", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(teamQuery).WithArgs(-50, 3, DefaultTenantID).WillReturnError(&pgconn.PgError{Code: "23514"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, queries.DistributeTeamCoins(ctx, 3, managerID, receiverID, 50), ErrNotEnoughCoins)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
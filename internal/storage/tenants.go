@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DefaultTenantID компания, созданная схемой. Запросы без компании в контексте
+// работают с ней, поэтому развертывание с одной компанией ничего не настраивает.
+const DefaultTenantID int64 = 1
+
+var tenantColumns = []string{"tenant_id", "slug", "name", "signup_bonus", "admin_signup_bonus", "bonus_vesting_days",
+	"budget_period", "admins", "created_at"}
+
+type tenantKey struct{}
+
+// WithTenant ограничивает все запросы в контексте компанией tenantID
+func WithTenant(ctx context.Context, tenantID int64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantID компания запроса, DefaultTenantID если не задана
+func TenantID(ctx context.Context) int64 {
+	if tenantID, ok := ctx.Value(tenantKey{}).(int64); ok && tenantID != 0 {
+		return tenantID
+	}
+	return DefaultTenantID
+}
+
+// tenantEq фильтр по компании запроса. table нужен, когда tenant_id есть
+// в нескольких таблицах запроса.
+//
+// Строки, найденные по employee_id сотрудника своей компании (кошелек, инвентарь,
+// переводы), отдельно не фильтруются: ID сотрудника уже проверен при входе.
+func tenantEq(ctx context.Context, table string) sq.Eq {
+	if table != "" {
+		return sq.Eq{table + ".tenant_id": TenantID(ctx)}
+	}
+	return sq.Eq{"tenant_id": TenantID(ctx)}
+}
+
+// CreateTenant создает компанию с каталогом catalog, ID заполняется в tenant.
+// Без catalog компания получает копию каталога компании по умолчанию.
+func (q *Queries) CreateTenant(ctx context.Context, tenant *Tenant, catalog []MerchItem) (err error) {
+	tenant.CreatedAt = time.Now().UTC()
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		q.logger(ctx).Error("CreateTenant BeginTx error:", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	err = q.builder().Insert("tenants").
+		Columns("slug", "name", "signup_bonus", "admin_signup_bonus", "bonus_vesting_days", "budget_period", "admins",
+			"created_at").
+		Values(tenant.Slug, tenant.Name, tenant.SignupBonus, tenant.AdminSignupBonus, tenant.BonusVestingDays,
+			tenant.BudgetPeriod, strings.Join(tenant.Admins, ","), tenant.CreatedAt).
+		Suffix("RETURNING tenant_id").
+		RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&tenant.ID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrTenantTaken
+		}
+		q.logger(ctx).Error("CreateTenant TenantQuery error:", zap.Error(err))
+		return err
+	}
+
+	// вложенный SELECT с плейсхолдерами ?, их заменяет INSERT
+	var statements []sq.Sqlizer
+	switch {
+	case catalog == nil:
+		statements = append(statements, q.builder().Insert("merch_items").
+			Columns("tenant_id", "name", "price").
+			Select(sq.Select().Column("?", tenant.ID).Columns("name", "price").
+				From("merch_items").
+				Where(sq.Eq{"tenant_id": DefaultTenantID}).
+				OrderBy("item_id")))
+	case len(catalog) > 0:
+		items := q.builder().Insert("merch_items").Columns("tenant_id", "name", "price")
+		for _, item := range catalog {
+			items = items.Values(tenant.ID, item.Name, item.Price)
+		}
+		statements = append(statements, items)
+	}
+	// история цен компании начинается с цен каталога
+	statements = append(statements, q.builder().Insert("merch_prices").
+		Columns("tenant_id", "item_id", "price", "effective_from").
+		Select(sq.Select("tenant_id", "item_id", "price").Column("?", tenant.CreatedAt).
+			From("merch_items").
+			Where(sq.Eq{"tenant_id": tenant.ID})))
+
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		q.logger(ctx).Error("CreateTenant CatalogQuery error:", zap.Error(err))
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		q.logger(ctx).Error("CreateTenant Commit error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (q *Queries) GetTenant(ctx context.Context, tenantID int64) (*Tenant, error) {
+	return q.getTenant(ctx, "GetTenant", sq.Eq{"tenant_id": tenantID})
+}
+
+// GetTenantBySlug компания по короткому имени, с которым сотрудники входят в сервис
+func (q *Queries) GetTenantBySlug(ctx context.Context, slug string) (*Tenant, error) {
+	return q.getTenant(ctx, "GetTenantBySlug", sq.Eq{"slug": slug})
+}
+
+func (q *Queries) ListTenants(ctx context.Context) ([]Tenant, error) {
+	rows, err := q.builder().Select(tenantColumns...).
+		From("tenants").
+		OrderBy("tenant_id").
+		RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("ListTenants QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var tenants []Tenant
+	for rows.Next() {
+		tenant, err := scanTenant(rows)
+		if err != nil {
+			q.logger(ctx).Error("ListTenants rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		tenants = append(tenants, *tenant)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("ListTenants rows error:", zap.Error(err))
+		return nil, err
+	}
+	return tenants, nil
+}
+
+// UpdateTenant сохраняет название и настройки компании tenant.ID, включая правила бонуса
+// за регистрацию. slug не меняется. В той же транзакции роль admin получают сотрудники из admins,
+// остальные администраторы компании становятся рядовыми сотрудниками. Возвращает id сотрудников,
+// чья роль изменилась.
+func (q *Queries) UpdateTenant(ctx context.Context, tenant Tenant, admins []string) (_ []uuid.UUID, err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		q.logger(ctx).Error("UpdateTenant BeginTx error:", zap.Error(err))
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	result, err := q.builder().Update("tenants").
+		Set("name", tenant.Name).
+		Set("signup_bonus", tenant.SignupBonus).
+		Set("admin_signup_bonus", tenant.AdminSignupBonus).
+		Set("bonus_vesting_days", tenant.BonusVestingDays).
+		Set("budget_period", tenant.BudgetPeriod).
+		Set("admins", strings.Join(tenant.Admins, ",")).
+		Where(sq.Eq{"tenant_id": tenant.ID}).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("UpdateTenant ExecContext error:", zap.Error(err))
+		return nil, err
+	}
+	if err = checkAffected(result, ErrTenantNotFound); err != nil {
+		return nil, err
+	}
+
+	// имена сравниваются без учета регистра, как при входе
+	lowered := make([]string, 0, len(admins))
+	for _, admin := range admins {
+		lowered = append(lowered, strings.ToLower(admin))
+	}
+	var changed []uuid.UUID
+	for _, update := range []sq.UpdateBuilder{
+		q.builder().Update("employees").
+			Set("role", RoleAdmin).
+			Where(sq.Eq{"tenant_id": tenant.ID, "LOWER(username)": lowered}).
+			Where(sq.NotEq{"role": RoleAdmin}),
+		q.builder().Update("employees").
+			Set("role", RoleEmployee).
+			Where(sq.Eq{"tenant_id": tenant.ID, "role": RoleAdmin}).
+			Where(sq.NotEq{"LOWER(username)": lowered}),
+	} {
+		ids, err := q.updateRoles(ctx, tx, update)
+		if err != nil {
+			q.logger(ctx).Error("UpdateTenant updateRoles error:", zap.Error(err))
+			return nil, err
+		}
+		changed = append(changed, ids...)
+	}
+	if err = tx.Commit(); err != nil {
+		q.logger(ctx).Error("UpdateTenant Commit error:", zap.Error(err))
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (q *Queries) updateRoles(ctx context.Context, tx *sql.Tx, update sq.UpdateBuilder) ([]uuid.UUID, error) {
+	rows, err := update.Suffix("RETURNING employee_id").RunWith(q.traced(tx)).QueryContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (q *Queries) getTenant(ctx context.Context, operation string, where sq.Eq) (*Tenant, error) {
+	row := q.builder().Select(tenantColumns...).
+		From("tenants").
+		Where(where).
+		RunWith(q.traced(q.db)).QueryRowContext(ctx)
+	tenant, err := scanTenant(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenantNotFound
+		}
+		q.logger(ctx).Error(operation+" QueryRowContext error:", zap.Error(err))
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func scanTenant(row sq.RowScanner) (*Tenant, error) {
+	var tenant Tenant
+	var admins string
+	var adminBonus sql.NullInt64
+	err := row.Scan(&tenant.ID, &tenant.Slug, &tenant.Name, &tenant.SignupBonus, &adminBonus, &tenant.BonusVestingDays,
+		&tenant.BudgetPeriod, &admins, &tenant.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if adminBonus.Valid {
+		bonus := int(adminBonus.Int64)
+		tenant.AdminSignupBonus = &bonus
+	}
+	if admins != "" {
+		tenant.Admins = strings.Split(admins, ",")
+	}
+	tenant.CreatedAt = tenant.CreatedAt.UTC()
+	return &tenant, nil
+}
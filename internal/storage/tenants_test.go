@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTenant(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	insertQuery := `INSERT INTO tenants \(slug,name,signup_bonus,admin_signup_bonus,bonus_vesting_days,budget_period,` +
+		`admins,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8\) RETURNING tenant_id`
+	pricesQuery := `INSERT INTO merch_prices \(tenant_id,item_id,price,effective_from\) ` +
+		`SELECT tenant_id, item_id, price, \$1 FROM merch_items WHERE tenant_id = \$2`
+
+	// без каталога копируется каталог компании по умолчанию
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WithArgs("acme", "Acme", 300, 1000, 7, "week", "boss,deputy",
+		sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow(2))
+	mock.ExpectExec(`INSERT INTO merch_items \(tenant_id,name,price\) SELECT \$1, name, price FROM merch_items `+
+		`WHERE tenant_id = \$2 ORDER BY item_id`).WithArgs(int64(2), DefaultTenantID).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(pricesQuery).WithArgs(sqlmock.AnyArg(), int64(2)).WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WithArgs("small", "Small", 0, nil, 0, "", "", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"tenant_id"}).AddRow(3))
+	mock.ExpectExec(`INSERT INTO merch_items \(tenant_id,name,price\) VALUES \(\$1,\$2,\$3\)`).
+		WithArgs(int64(3), "mug", 15).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(pricesQuery).WithArgs(sqlmock.AnyArg(), int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(insertQuery).WillReturnError(&pgconn.PgError{Code: "23505"})
+	mock.ExpectRollback()
+
+	adminBonus := 1000
+	acme := Tenant{Slug: "acme", Name: "Acme", SignupBonus: 300, AdminSignupBonus: &adminBonus, BonusVestingDays: 7,
+		BudgetPeriod: "week", Admins: []string{"boss", "deputy"}}
+	assert.NoError(t, queries.CreateTenant(ctx, &acme, nil))
+	assert.Equal(t, int64(2), acme.ID)
+	small := Tenant{Slug: "small", Name: "Small"}
+	assert.NoError(t, queries.CreateTenant(ctx, &small, []MerchItem{{Name: "mug", Price: 15}}))
+	assert.ErrorIs(t, queries.CreateTenant(ctx, &Tenant{Slug: "acme", Name: "Copy"}, nil), ErrTenantTaken)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTenantBySlug(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	query := `SELECT tenant_id, slug, name, signup_bonus, admin_signup_bonus, bonus_vesting_days, budget_period, ` +
+		`admins, created_at FROM tenants WHERE slug = \$1`
+	mock.ExpectQuery(query).WithArgs("acme").WillReturnRows(sqlmock.NewRows(tenantColumns).
+		AddRow(2, "acme", "Acme", 300, nil, 3, "", "boss,deputy", createdAt))
+	mock.ExpectQuery(query).WithArgs("nope").WillReturnError(sql.ErrNoRows)
+
+	tenant, err := queries.GetTenantBySlug(ctx, "acme")
+	assert.NoError(t, err)
+	assert.Equal(t, &Tenant{ID: 2, Slug: "acme", Name: "Acme", SignupBonus: 300, BonusVestingDays: 3,
+		Admins: []string{"boss", "deputy"}, CreatedAt: createdAt}, tenant)
+	_, err = queries.GetTenantBySlug(ctx, "nope")
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateTenant(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	updateQuery := `UPDATE tenants SET name = \$1, signup_bonus = \$2, admin_signup_bonus = \$3, ` +
+		`bonus_vesting_days = \$4, budget_period = \$5, admins = \$6 WHERE tenant_id = \$7`
+	deputyID, bossID := uuid.New(), uuid.New()
+
+	// роли меняются в той же транзакции, что и список администраторов
+	mock.ExpectBegin()
+	mock.ExpectExec(updateQuery).WithArgs("Acme", 300, nil, 0, "week", "Deputy", int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE employees SET role = \$1 WHERE LOWER\(username\) IN \(\$2\) AND tenant_id = \$3 `+
+		`AND role <> \$4 RETURNING employee_id`).WithArgs(RoleAdmin, "deputy", int64(2), RoleAdmin).
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(deputyID))
+	mock.ExpectQuery(`UPDATE employees SET role = \$1 WHERE role = \$2 AND tenant_id = \$3 `+
+		`AND LOWER\(username\) NOT IN \(\$4\) RETURNING employee_id`).
+		WithArgs(RoleEmployee, RoleAdmin, int64(2), "deputy").
+		WillReturnRows(sqlmock.NewRows([]string{"employee_id"}).AddRow(bossID))
+	mock.ExpectCommit()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(updateQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	tenant := Tenant{ID: 2, Name: "Acme", SignupBonus: 300, BudgetPeriod: "week", Admins: []string{"Deputy"}}
+	changed, err := queries.UpdateTenant(ctx, tenant, tenant.Admins)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{deputyID, bossID}, changed)
+	_, err = queries.UpdateTenant(ctx, Tenant{ID: 9, Name: "x"}, nil)
+	assert.ErrorIs(t, err, ErrTenantNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
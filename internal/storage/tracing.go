@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/logging"
+	"github.com/Vic07Region/avito-shop/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// tracedRunner создает span на каждый запрос squirrel.
+// В span пишется только текст запроса, аргументы не попадают в трейс.
+type tracedRunner struct {
+	sq.StdSqlCtx
+	system string
+	// instance имя реплики, пусто для primary
+	instance string
+}
+
+func (q *Queries) traced(runner sq.StdSqlCtx) tracedRunner {
+	return tracedRunner{StdSqlCtx: runner, system: q.dbSystem()}
+}
+
+func (r tracedRunner) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := r.startSpan(ctx, query)
+	result, err := r.StdSqlCtx.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (r tracedRunner) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := r.startSpan(ctx, query)
+	rows, err := r.StdSqlCtx.QueryContext(ctx, query, args...)
+	tracing.End(span, err)
+	return rows, err
+}
+
+func (r tracedRunner) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := r.startSpan(ctx, query)
+	row := r.StdSqlCtx.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (r tracedRunner) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	attributes := []attribute.KeyValue{
+		attribute.String("db.system", r.system),
+		attribute.String("db.statement", query),
+	}
+	if r.instance != "" {
+		attributes = append(attributes, attribute.String("db.instance", r.instance))
+	}
+	return tracing.Tracer().Start(ctx, "db."+strings.ToUpper(operation),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attributes...),
+	)
+}
+
+func (q *Queries) logger(ctx context.Context) *zap.Logger {
+	return tracing.Logger(ctx, logging.FromContext(ctx, q.log))
+}
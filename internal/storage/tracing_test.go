@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestQuerySpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectQuery(`SELECT balance FROM wallets WHERE employee_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"balance"}).AddRow(100))
+
+	_, err := queries.GetBalance(ctx, userID)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "db.SELECT", spans[0].Name())
+
+	var statement string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "db.statement" {
+			statement = attr.Value.AsString()
+		}
+		// аргументы запроса не должны попадать в трейс
+		assert.NotContains(t, attr.Value.Emit(), userID.String())
+	}
+	assert.Equal(t, "SELECT balance FROM wallets WHERE employee_id = $1", statement)
+}
@@ -4,37 +4,40 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"github.com/lib/pq" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
 	"go.uber.org/zap"
-	"sync"
+	"time"
 
 	sq "github.com/Masterminds/squirrel" //nolint:gci
 	"github.com/google/uuid"
 )
 
 func (q *Queries) GetBalance(ctx context.Context, userID uuid.UUID) (int, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	sqlQuery := sqlBuilder.Select("balance").
 		From("wallets").
 		Where(sq.Eq{"employee_id": userID})
 	var balance int
-	if err := sqlQuery.RunWith(q.db).QueryRowContext(ctx).Scan(&balance); err != nil {
-		q.log.Error("GetBalance QueryRowContext error:", zap.Error(err))
+	if err := sqlQuery.RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&balance); err != nil {
+		q.logger(ctx).Error("GetBalance QueryRowContext error:", zap.Error(err))
 		return 0, err
 	}
 	return balance, nil
 }
 
 func (q *Queries) GetInventories(ctx context.Context, userID uuid.UUID) ([]InventoryItem, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-	sqlQuery := sqlBuilder.Select("name", "SUM(quantity) as quantity").
-		From("purchases").
+	sqlBuilder := q.builder()
+	sqlQuery := sqlBuilder.Select("name", "COALESCE(sku, '')", "COALESCE(size, '')", "COALESCE(color, '')",
+		"quantity").
+		From("inventory").
 		InnerJoin("merch_items using(item_id)").
+		LeftJoin("merch_variants ON merch_variants.variant_id = inventory.variant_id").
 		Where(sq.Eq{"employee_id": userID}).
-		GroupBy("name").OrderBy("name")
-	rows, err := sqlQuery.RunWith(q.db).QueryContext(ctx)
+		Where(sq.Gt{"quantity": 0}).
+		OrderBy("name", "sku")
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
 	if err != nil {
-		q.log.Error("GetInventories QueryContext error:", zap.Error(err))
+		q.logger(ctx).Error("GetInventories QueryContext error:", zap.Error(err))
 		return nil, err
 	}
 
@@ -44,15 +47,15 @@ func (q *Queries) GetInventories(ctx context.Context, userID uuid.UUID) ([]Inven
 
 	for rows.Next() {
 		var i InventoryItem
-		if err := rows.Scan(&i.Name, &i.Quantity); err != nil {
-			q.log.Error("GetInventories rows.Scan error:", zap.Error(err))
+		if err := rows.Scan(&i.Name, &i.SKU, &i.Size, &i.Color, &i.Quantity); err != nil {
+			q.logger(ctx).Error("GetInventories rows.Scan error:", zap.Error(err))
 			return nil, err
 		}
 		inventoryList = append(inventoryList, i)
 	}
 
 	if err = rows.Err(); err != nil {
-		q.log.Error("GetInventories rows error:", zap.Error(err))
+		q.logger(ctx).Error("GetInventories rows error:", zap.Error(err))
 		return nil, err
 	}
 
@@ -60,7 +63,7 @@ func (q *Queries) GetInventories(ctx context.Context, userID uuid.UUID) ([]Inven
 }
 
 func (q *Queries) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]SenderInfo, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	sqlQuery := sqlBuilder.Select("username", "sum(amount)").
 		From("transactions").
 		InnerJoin("employees on employee_id = sender_id").
@@ -68,9 +71,9 @@ func (q *Queries) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]Sen
 		GroupBy("username").
 		OrderBy("username")
 
-	rows, err := sqlQuery.RunWith(q.db).QueryContext(ctx)
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
 	if err != nil {
-		q.log.Error("GetSendedCoins QueryContext error:", zap.Error(err))
+		q.logger(ctx).Error("GetSendedCoins QueryContext error:", zap.Error(err))
 		return nil, err
 	}
 
@@ -80,13 +83,13 @@ func (q *Queries) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]Sen
 	for rows.Next() {
 		var i SenderInfo
 		if err := rows.Scan(&i.Username, &i.Amount); err != nil {
-			q.log.Error("GetSendedCoins rows.Scan error:", zap.Error(err))
+			q.logger(ctx).Error("GetSendedCoins rows.Scan error:", zap.Error(err))
 			return nil, err
 		}
 		senderInfoList = append(senderInfoList, i)
 	}
 	if err = rows.Err(); err != nil {
-		q.log.Error("GetReceivedCoins rows error:", zap.Error(err))
+		q.logger(ctx).Error("GetReceivedCoins rows error:", zap.Error(err))
 		return nil, err
 	}
 
@@ -94,7 +97,7 @@ func (q *Queries) GetReceivedCoins(ctx context.Context, userID uuid.UUID) ([]Sen
 }
 
 func (q *Queries) GetSendedCoins(ctx context.Context, userID uuid.UUID) ([]SenderInfo, error) {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+	sqlBuilder := q.builder()
 	sqlQuery := sqlBuilder.Select("username", "sum(amount)").
 		From("transactions").
 		InnerJoin("employees on employee_id = receiver_id").
@@ -102,9 +105,9 @@ func (q *Queries) GetSendedCoins(ctx context.Context, userID uuid.UUID) ([]Sende
 		GroupBy("username").
 		OrderBy("username")
 
-	rows, err := sqlQuery.RunWith(q.db).QueryContext(ctx)
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
 	if err != nil {
-		q.log.Error("GetSendedCoins QueryContext error:", zap.Error(err))
+		q.logger(ctx).Error("GetSendedCoins QueryContext error:", zap.Error(err))
 		return nil, err
 	}
 
@@ -114,221 +117,299 @@ func (q *Queries) GetSendedCoins(ctx context.Context, userID uuid.UUID) ([]Sende
 	for rows.Next() {
 		var i SenderInfo
 		if err := rows.Scan(&i.Username, &i.Amount); err != nil {
-			q.log.Error("GetSendedCoins rows.Scan error:", zap.Error(err))
+			q.logger(ctx).Error("GetSendedCoins rows.Scan error:", zap.Error(err))
 			return nil, err
 		}
 		senderInfoList = append(senderInfoList, i)
 	}
 	if err = rows.Err(); err != nil {
-		q.log.Error("GetSendedCoins rows error:", zap.Error(err))
+		q.logger(ctx).Error("GetSendedCoins rows error:", zap.Error(err))
 		return nil, err
 	}
 
 	return senderInfoList, nil
 }
 
-func (q *Queries) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int) error {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+// walletInfoQuery собирает вишлист, баланс, бюджет, инвентарь и историю переводов одним запросом.
+// Один statement видит один snapshot, поэтому баланс, бюджет, вишлист и история согласованы.
+// Варианты товара (sku, size, color) заполнены только у инвентаря, id, item_id и price - только
+// у вишлиста. У бюджета name - период из настроек компании, amount - лимит, extra - остаток,
+// at - начало периода остатка. Вишлист идет первым: sqlite берет тип колонки at из первого SELECT и разбирает время.
+const walletInfoQuery = `SELECT 'wishlist' AS kind, name, '' AS sku, '' AS size, '' AS color, goal AS amount, contributed AS extra, wish_id AS id, item_id, price, wishlist.created_at AS at FROM wishlist INNER JOIN merch_items using(item_id) WHERE employee_id = ?
+UNION ALL
+SELECT 'balance', '', '', '', '', balance, 0, 0, 0, 0, NULL FROM wallets WHERE employee_id = ?
+UNION ALL
+SELECT 'budget', tenants.budget_period, '', '', '', budget_limit, budget, 0, 0, 0, wallets.budget_period FROM wallets INNER JOIN tenants ON tenants.tenant_id = wallets.tenant_id WHERE employee_id = ?
+UNION ALL
+SELECT 'inventory', name, COALESCE(sku, ''), COALESCE(size, ''), COALESCE(color, ''), quantity, 0, 0, 0, 0, NULL FROM inventory INNER JOIN merch_items using(item_id) LEFT JOIN merch_variants ON merch_variants.variant_id = inventory.variant_id WHERE employee_id = ? AND quantity > 0
+UNION ALL
+SELECT 'received', username, '', '', '', SUM(amount), 0, 0, 0, 0, NULL FROM transactions INNER JOIN employees on employee_id = sender_id WHERE receiver_id = ? GROUP BY username
+UNION ALL
+SELECT 'sent', username, '', '', '', SUM(amount), 0, 0, 0, 0, NULL FROM transactions INNER JOIN employees on employee_id = receiver_id WHERE sender_id = ? GROUP BY username
+ORDER BY kind, id, name, sku`
+
+func (q *Queries) GetWalletInfo(ctx context.Context, userID uuid.UUID) (*WalletInfo, error) {
+	query, err := q.placeholders().ReplacePlaceholders(walletInfoQuery)
+	if err != nil {
+		q.logger(ctx).Error("GetWalletInfo ReplacePlaceholders error:", zap.Error(err))
+		return nil, err
+	}
 
-	txOptions := sql.TxOptions{
-		Isolation: sql.LevelSerializable,
-		ReadOnly:  false,
+	rows, err := q.traced(q.db).QueryContext(ctx, query, userID, userID, userID, userID, userID, userID)
+	if err != nil {
+		q.logger(ctx).Error("GetWalletInfo QueryContext error:", zap.Error(err))
+		return nil, err
 	}
 
-	var wg sync.WaitGroup
-	errChan := make(chan error, 3)
-	wg.Add(3)
+	defer rows.Close()
 
-	tx, err := q.db.BeginTx(ctx, &txOptions)
+	var walletInfo WalletInfo
+	hasWallet := false
+	for rows.Next() {
+		var kind, name, sku, size, color string
+		var amount, extra, itemID, price int
+		var id int64
+		var at sql.NullTime
+		err := rows.Scan(&kind, &name, &sku, &size, &color, &amount, &extra, &id, &itemID, &price, &at)
+		if err != nil {
+			q.logger(ctx).Error("GetWalletInfo rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		switch kind {
+		case "balance":
+			walletInfo.Balance = amount
+			hasWallet = true
+		case "budget":
+			walletInfo.Budget = WalletBudget{Limit: amount, Budget: extra, Period: at.Time.UTC(), TenantPeriod: name}
+		case "wishlist":
+			walletInfo.Wishlist = append(walletInfo.Wishlist, WishlistItem{
+				ID: id, EmployeeID: userID, ItemID: itemID, Item: name, Price: price, Goal: amount,
+				Contributed: extra, CreatedAt: at.Time.UTC(),
+			})
+		case "inventory":
+			walletInfo.Inventory = append(walletInfo.Inventory, InventoryItem{
+				Name: name, SKU: sku, Size: size, Color: color, Quantity: amount,
+			})
+		case "received":
+			walletInfo.Received = append(walletInfo.Received, SenderInfo{Username: name, Amount: amount})
+		case "sent":
+			walletInfo.Sent = append(walletInfo.Sent, SenderInfo{Username: name, Amount: amount})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetWalletInfo rows error:", zap.Error(err))
+		return nil, err
+	}
+
+	if !hasWallet {
+		return nil, sql.ErrNoRows
+	}
+	return &walletInfo, nil
+}
+
+// SendCoinsTransaction переводит монеты коллеге. Сначала тратится бюджет на благодарности
+// отправителя, остаток списывается с баланса. period - начало текущего периода бюджета.
+func (q *Queries) SendCoinsTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID, amount int,
+	period time.Time) error {
+	event, err := events.New(events.TypeCoinsTransferred, events.CoinsTransferred{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Amount:     amount,
+	})
 	if err != nil {
-		q.log.Error("SendCoins BeginTX error:", zap.Error(err))
+		q.logger(ctx).Error("SendCoins events.New error:", zap.Error(err))
 		return err
 	}
 
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
+	tenant := TenantID(ctx)
+	statements := q.coinTransfer(tenant, q.budgetDebit(senderID, amount, period), senderID, receiverID, amount)
+	err = q.execAtomic(ctx, "SendCoins", append(statements, q.outboxInsert(tenant, event))...)
+	if err != nil {
+		if isCheckViolation(err) {
+			return ErrNotEnoughCoins
 		}
-	}()
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+		q.logger(ctx).Error("SendCoins execAtomic error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
 
-	SenderBalanceQuery := sqlBuilder.Update("wallets").
-		Set("balance", sq.Expr("balance - ?", amount)).
-		Where(sq.Eq{"employee_id": senderID})
+// coinTransfer запросы перевода монет: списание у отправителя (senderDebit),
+// зачисление получателю и запись в transactions
+func (q *Queries) coinTransfer(tenant int64, senderDebit sq.Sqlizer, senderID uuid.UUID, receiverID uuid.UUID,
+	amount int) []sq.Sqlizer {
+	sqlBuilder := q.builder()
 
 	ReceiverBalanceQuery := sqlBuilder.Update("wallets").
 		Set("balance", sq.Expr("balance + ?", amount)).
 		Where(sq.Eq{"employee_id": receiverID})
+
 	TransactionQuery := sqlBuilder.Insert("transactions").
-		Columns("sender_id", "receiver_id", "amount").
-		Values(senderID, receiverID, amount)
-
-	var pgErr *pq.Error
-
-	go func() {
-		defer wg.Done()
-		if _, err := SenderBalanceQuery.RunWith(tx).ExecContext(ctx); err != nil {
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "23514" {
-					q.log.Error("SendCoins SenderBalanceQuery error:", zap.Error(ErrNotEnoughCoins))
-					errChan <- ErrNotEnoughCoins
-					cancel()
-					return
-				}
-				if pgErr.Code == "25P02" {
-					q.log.Error("SendCoins SenderBalanceQuery error:", zap.Error(err))
-					cancel()
-					return
-				}
-
-			}
-			q.log.Error("SendCoins SenderBalanceQuery error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-	}()
+		Columns("tenant_id", "sender_id", "receiver_id", "amount").
+		Values(tenant, senderID, receiverID, amount)
 
-	go func() {
-		defer wg.Done()
-		if _, err := ReceiverBalanceQuery.RunWith(tx).ExecContext(ctx); err != nil {
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "25P02" {
-					q.log.Error("SendCoins SenderBalanceQuery error:", zap.Error(err))
-					cancel()
-					return
-				}
-
-			}
-			q.log.Error("SendCoins ReceiverBalanceQuery error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-	}()
+	// кошельки блокируются в одном порядке, чтобы встречные переводы не ловили deadlock
+	balanceQueries := []sq.Sqlizer{senderDebit, ReceiverBalanceQuery}
+	if receiverID.String() < senderID.String() {
+		balanceQueries[0], balanceQueries[1] = balanceQueries[1], balanceQueries[0]
+	}
+	return append(balanceQueries, TransactionQuery)
+}
 
-	go func() {
-		defer wg.Done()
-		if _, err := TransactionQuery.RunWith(tx).ExecContext(ctx); err != nil {
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "25P02" {
-					q.log.Error("SendCoins SenderBalanceQuery error:", zap.Error(err))
-					cancel()
-					return
-				}
-
-			}
-			q.log.Error("SendCoins TransactionQuery error:", zap.Error(err))
-			errChan <- err
-			cancel()
-		}
-	}()
+func (q *Queries) PurchaseMerchTransaction(ctx context.Context, userID uuid.UUID, merch MerchInfo) error {
+	sqlBuilder := q.builder()
 
-	wg.Wait()
-	close(errChan)
+	total := merch.Price*merch.Amount - merch.Discount
 
-	for err := range errChan {
-		if err != nil {
-			return err
-		}
+	// командная покупка списывается с кошелька команды, та же проверка баланса через CHECK
+	buyerBalanceQuery := sqlBuilder.Update("wallets").
+		Set("balance", sq.Expr("balance - ?", total)).
+		Where(sq.Eq{"employee_id": userID})
+	var teamID *int64
+	if merch.TeamID != 0 {
+		teamID = &merch.TeamID
+		buyerBalanceQuery = sqlBuilder.Update("teams").
+			Set("balance", sq.Expr("balance - ?", total)).
+			Where(sq.Eq{"team_id": merch.TeamID}).
+			Where(tenantEq(ctx, ""))
 	}
 
-	if err := tx.Commit(); err != nil {
-		q.log.Error("SendCoins Commit error:", zap.Error(err))
+	var promotionID *int64
+	if merch.PromotionID != 0 {
+		promotionID = &merch.PromotionID
+	}
+	var variantID *int
+	if merch.VariantID != 0 {
+		variantID = &merch.VariantID
+	}
+	// у подарка заказ оформляется на получателя, покупатель остается в buyer_id
+	ownerID, ledgerKind := userID, LedgerAcquired
+	var buyerID, recipientID *uuid.UUID
+	if merch.RecipientID != uuid.Nil {
+		ownerID, ledgerKind = merch.RecipientID, LedgerReceived
+		buyerID, recipientID = &userID, &merch.RecipientID
+	}
+	now := time.Now().UTC()
+	tenant := TenantID(ctx)
+	purchaseQuery := sqlBuilder.Insert("purchases").
+		Columns("tenant_id", "employee_id", "buyer_id", "team_id", "gift_message", "item_id", "variant_id", "quantity",
+			"unit_price", "total", "discount", "promotion_id", "status", "purchase_date", "updated_at").
+		Values(tenant, ownerID, buyerID, teamID, merch.GiftMessage, merch.MerchID, variantID, merch.Amount,
+			merch.Price, total, merch.Discount, promotionID, OrderPlaced, now, now)
+	inventoryQuery := q.inventoryAdd(tenant, ownerID, merch.MerchID, variantID, merch.Amount)
+
+	event, err := events.New(events.TypeMerchPurchased, events.MerchPurchased{
+		UserID:      userID,
+		ItemID:      merch.MerchID,
+		Item:        merch.Name,
+		SKU:         merch.SKU,
+		Quantity:    merch.Amount,
+		Price:       merch.Price,
+		Discount:    merch.Discount,
+		Total:       total,
+		RecipientID: recipientID,
+		Message:     merch.GiftMessage,
+		TeamID:      teamID,
+	})
+	if err != nil {
+		q.logger(ctx).Error("PurchaseMerch events.New error:", zap.Error(err))
 		return err
 	}
-	return nil
-}
 
-func (q *Queries) PurchaseMerchTransaction(ctx context.Context, userID uuid.UUID, merch MerchInfo) error {
-	sqlBuilder := sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
-
-	txOptions := sql.TxOptions{
-		Isolation: sql.LevelSerializable,
-		ReadOnly:  false,
+	// журнал и запись команды ссылаются на id вставленной покупки
+	statements := func(purchaseID any) []sq.Sqlizer {
+		ledgerQuery := sqlBuilder.Insert("inventory_ledger").
+			Columns(ledgerColumns...).
+			Values(tenant, ownerID, merch.MerchID, variantID, ledgerKind, merch.Amount, purchaseID, buyerID, now)
+		statements := []sq.Sqlizer{ledgerQuery}
+		if merch.TeamID != 0 {
+			statements = append(statements, q.teamEntry(tenant, merch.TeamID, TeamPurchase, -total, userID, purchaseID, now))
+		}
+		return append(statements, inventoryQuery, q.outboxInsert(tenant, event))
+	}
+	if q.pool != nil && merch.PromotionID == 0 && merch.VariantID == 0 {
+		// без промокода и варианта промежуточных проверок нет, покупка уходит одним batch
+		batch := append([]sq.Sqlizer{buyerBalanceQuery, purchaseQuery}, statements(insertedPurchaseID())...)
+		err = q.execBatch(ctx, "PurchaseMerch", batch)
+	} else {
+		err = q.purchaseTx(ctx, userID, merch, now, buyerBalanceQuery, purchaseQuery, statements)
 	}
-
-	var pgErr *pq.Error
-
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	tx, err := q.db.BeginTx(ctx, &txOptions)
 	if err != nil {
-		q.log.Error("SendCoins BeginTX error:", zap.Error(err))
+		if isCheckViolation(err) {
+			return ErrNotEnoughCoins
+		}
+		if errors.Is(err, ErrPromoInvalid) || errors.Is(err, ErrPromoExhausted) || errors.Is(err, ErrOutOfStock) {
+			return err
+		}
+		q.logger(ctx).Error("PurchaseMerch error:", zap.Error(err))
 		return err
 	}
+	return nil
+}
 
+// purchaseTx погашает промокод, списывает остаток варианта и монеты, вставляет
+// покупку и выполняет зависящие от нее запросы в одной транзакции. Нужна, когда
+// между запросами есть проверки, и для драйверов без pgx.Batch. Промокод
+// блокируется раньше варианта, а вариант раньше кошелька, поэтому параллельные
+// покупки не ловят deadlock.
+func (q *Queries) purchaseTx(ctx context.Context, userID uuid.UUID, merch MerchInfo, at time.Time,
+	debit sq.Sqlizer, purchase sq.InsertBuilder, statements func(purchaseID any) []sq.Sqlizer) (err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
-	total := merch.Price * merch.Amount
-
-	buyerBalanceQuery := sqlBuilder.Update("wallets").
-		Set("balance", sq.Expr("balance - ?", total)).
-		Where(sq.Eq{"employee_id": userID})
-
-	purchaseQuery := sqlBuilder.Insert("purchases").
-		Columns("employee_id", "item_id", "quantity").
-		Values(userID, merch.MerchID, merch.Amount)
-
-	var wg sync.WaitGroup
-	errChan := make(chan error, 2)
-	wg.Add(2)
-
-	go func() {
-		defer wg.Done()
-		if _, err := buyerBalanceQuery.RunWith(tx).ExecContext(ctx); err != nil {
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "23514" {
-					q.log.Error("SendCoins buyerBalanceQuery error:", zap.Error(ErrNotEnoughCoins))
-					errChan <- ErrNotEnoughCoins
-					cancel()
-					return
-				}
-				if pgErr.Code == "25P02" {
-					q.log.Error("PurchaseMerch buyerBalanceQuery error:", zap.Error(err))
-					cancel()
-					return
-				}
-			}
-			q.log.Error("PurchaseMerch buyerBalanceQuery error:", zap.Error(err))
-			errChan <- err
-			cancel()
+			err = errors.Join(err, rollbackIgnoreDone(tx))
 		}
 	}()
 
-	go func() {
-		defer wg.Done()
-		if _, err := purchaseQuery.RunWith(tx).ExecContext(ctx); err != nil {
-			if errors.As(err, &pgErr) {
-				if pgErr.Code == "25P02" {
-					q.log.Error("PurchaseMerch purchaseQuery error:", zap.Error(err))
-					cancel()
-					return
-				}
-			}
-			q.log.Error("PurchaseMerch purchaseQuery error:", zap.Error(err))
-			errChan <- err
-			cancel()
+	if merch.PromotionID != 0 {
+		if err = q.redeemPromo(ctx, tx, userID, merch.PromotionID, at); err != nil {
+			return err
 		}
-	}()
-
-	wg.Wait()
-	close(errChan)
-
-	for err := range errChan {
-		if err != nil {
+	}
+	if merch.VariantID != 0 {
+		if err = q.reserveStock(ctx, tx, merch.VariantID, merch.Amount); err != nil {
 			return err
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		q.log.Error("PurchaseMerch Commit error:", zap.Error(err))
+	if err = q.execStatements(ctx, tx, []sq.Sqlizer{debit}); err != nil {
 		return err
 	}
-	return nil
+	var purchaseID int64
+	err = purchase.Suffix("RETURNING purchase_id").
+		RunWith(q.traced(tx)).QueryRowContext(ctx).Scan(&purchaseID)
+	if err != nil {
+		return err
+	}
+	if err = q.execStatements(ctx, tx, statements(purchaseID)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// balanceDebit списание монет с баланса
+func (q *Queries) balanceDebit(userID uuid.UUID, amount int) sq.UpdateBuilder {
+	return q.builder().Update("wallets").
+		Set("balance", sq.Expr("balance - ?", amount)).
+		Where(sq.Eq{"employee_id": userID})
+}
+
+// budgetDebit списание перевода: сначала из бюджета, остаток с баланса. Бюджет,
+// оставшийся с прошлого периода, сначала восполняется до budget_limit.
+func (q *Queries) budgetDebit(userID uuid.UUID, amount int, period time.Time) sq.UpdateBuilder {
+	budget := q.currentBudget(period)
+	spent := sq.Expr("CASE WHEN ? < ? THEN ? ELSE ? END", budget, amount, budget, amount)
+	return q.builder().Update("wallets").
+		Set("balance", sq.Expr("balance - (? - ?)", amount, spent)).
+		Set("budget", sq.Expr("? - ?", budget, spent)).
+		Set("budget_period", period).
+		Where(sq.Eq{"employee_id": userID})
+}
+
+// currentBudget остаток бюджета в периоде, который начался в period
+func (q *Queries) currentBudget(period time.Time) sq.Sqlizer {
+	return sq.Expr("CASE WHEN budget_period >= ? THEN budget ELSE budget_limit END", period)
 }
@@ -0,0 +1,87 @@
+package storage_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/service"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// BenchmarkWalletInfo сравнивает GetWalletInfo с прежним путем /api/info:
+// четыре параллельных запроса, каждый на своем соединении из пула.
+//
+//	go test -run '^$' -bench WalletInfo ./internal/storage
+func BenchmarkWalletInfo(b *testing.B) {
+	b.Run("sqlite", func(b *testing.B) { benchmarkWalletInfo(b, newSQLite(b)) })
+	b.Run("postgres", func(b *testing.B) { benchmarkWalletInfo(b, newPostgres(b)) })
+}
+
+func benchmarkWalletInfo(b *testing.B, s service.StorageInterface) {
+	ctx := context.Background()
+	userID := seedWalletHistory(b, s)
+
+	b.Run("FourQueries", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				require.NoError(b, fourQueriesWalletInfo(ctx, s, userID))
+			}
+		})
+	})
+	b.Run("SingleQuery", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_, err := s.GetWalletInfo(ctx, userID)
+				require.NoError(b, err)
+			}
+		})
+	})
+}
+
+func fourQueriesWalletInfo(ctx context.Context, s service.StorageInterface, userID uuid.UUID) error {
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	wg.Add(4)
+	go func() { defer wg.Done(); _, errs[0] = s.GetBalance(ctx, userID) }()
+	go func() { defer wg.Done(); _, errs[1] = s.GetInventories(ctx, userID) }()
+	go func() { defer wg.Done(); _, errs[2] = s.GetSendedCoins(ctx, userID) }()
+	go func() { defer wg.Done(); _, errs[3] = s.GetReceivedCoins(ctx, userID) }()
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedWalletHistory создает пользователя с покупками и переводами нескольким коллегам
+func seedWalletHistory(tb testing.TB, s service.StorageInterface) uuid.UUID {
+	tb.Helper()
+	ctx := context.Background()
+
+	newUser := func() uuid.UUID {
+		username := "b" + strings.ReplaceAll(uuid.NewString(), "-", "")[:16]
+		userID, err := s.NewUser(ctx, username, "hash", &storage.Onboarding{Bonus: 1000})
+		require.NoError(tb, err)
+		return userID
+	}
+
+	userID := newUser()
+	for range 10 {
+		friendID := newUser()
+		require.NoError(tb, s.SendCoinsTransaction(ctx, userID, friendID, 10, time.Time{}))
+		require.NoError(tb, s.SendCoinsTransaction(ctx, friendID, userID, 5, time.Time{}))
+	}
+	for _, name := range []string{"cup", "pen", "socks"} {
+		item, err := s.GetMerchItems(ctx, name)
+		require.NoError(tb, err)
+		require.NoError(tb, s.PurchaseMerchTransaction(ctx, userID, storage.MerchInfo{MerchID: item.MerchID, Price: item.Price, Amount: 1}))
+	}
+	return userID
+}
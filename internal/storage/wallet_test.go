@@ -2,10 +2,16 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -35,11 +41,11 @@ func TestGetInventories(t *testing.T) {
 
 	userID := uuid.New()
 
-	mock.ExpectQuery(`SELECT name, SUM\(quantity\) as quantity FROM purchases INNER JOIN merch_items using\(item_id\) WHERE employee_id = \$1 GROUP BY name ORDER BY name`).
-		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"name", "quantity"}).
-			AddRow("Item1", 2).
-			AddRow("Item2", 5))
+	mock.ExpectQuery(`SELECT name, COALESCE\(sku, ''\), COALESCE\(size, ''\), COALESCE\(color, ''\), quantity FROM inventory INNER JOIN merch_items using\(item_id\) LEFT JOIN merch_variants ON merch_variants.variant_id = inventory.variant_id WHERE employee_id = \$1 AND quantity > \$2 ORDER BY name, sku`).
+		WithArgs(userID, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "sku", "size", "color", "quantity"}).
+			AddRow("Item1", "", "", "", 2).
+			AddRow("Item2", "ITEM2-M", "M", "", 5))
 
 	inventories, err := queries.GetInventories(ctx, userID)
 	assert.NoError(t, err)
@@ -47,6 +53,8 @@ func TestGetInventories(t *testing.T) {
 	assert.Equal(t, "Item1", inventories[0].Name)
 	assert.Equal(t, 2, inventories[0].Quantity)
 	assert.Equal(t, "Item2", inventories[1].Name)
+	assert.Equal(t, "ITEM2-M", inventories[1].SKU)
+	assert.Equal(t, "M", inventories[1].Size)
 	assert.Equal(t, 5, inventories[1].Quantity)
 
 	assert.NoError(t, mock.ExpectationsWereMet()) // Проверка, что все ожидания выполнены
@@ -131,3 +139,189 @@ func TestGetSendedCoins_NoRows(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+// budgetDebitQuery списание перевода: сначала бюджет текущего периода, остаток с баланса
+var budgetDebitQuery = regexp.QuoteMeta(`UPDATE wallets SET ` +
+	`balance = balance - ($1 - CASE WHEN CASE WHEN budget_period >= $2 THEN budget ELSE budget_limit END < $3 ` +
+	`THEN CASE WHEN budget_period >= $4 THEN budget ELSE budget_limit END ELSE $5 END), ` +
+	`budget = CASE WHEN budget_period >= $6 THEN budget ELSE budget_limit END - ` +
+	`CASE WHEN CASE WHEN budget_period >= $7 THEN budget ELSE budget_limit END < $8 ` +
+	`THEN CASE WHEN budget_period >= $9 THEN budget ELSE budget_limit END ELSE $10 END, ` +
+	`budget_period = $11 WHERE employee_id = $12`)
+
+func budgetDebitArgs(userID uuid.UUID, amount int, period time.Time) []driver.Value {
+	return []driver.Value{amount, period, amount, period, amount, period, period, amount, period, amount, period, userID}
+}
+
+func TestSendCoinsTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	senderID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	receiverID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	period := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// кошелек с меньшим uuid обновляется первым
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+		WithArgs(100, receiverID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// сначала тратится бюджет, оставшийся с прошлого периода бюджет восполняется до лимита
+	mock.ExpectExec(budgetDebitQuery).
+		WithArgs(budgetDebitArgs(senderID, 100, period)...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO transactions \(tenant_id,sender_id,receiver_id,amount\) VALUES \(\$1,\$2,\$3,\$4\)`).
+		WithArgs(DefaultTenantID, senderID, receiverID, 100).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// событие пишется в той же транзакции
+	mock.ExpectExec(`INSERT INTO outbox \(tenant_id,event_id,event_type,payload,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`).
+		WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeCoinsTransferred, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := queries.SendCoinsTransaction(ctx, senderID, receiverID, 100, period)
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSendCoinsTransaction_NotEnoughCoins(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	senderID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	receiverID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	period := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(budgetDebitQuery).
+		WithArgs(budgetDebitArgs(senderID, 5000, period)...).
+		WillReturnError(&pgconn.PgError{Code: "23514"})
+	mock.ExpectRollback()
+
+	err := queries.SendCoinsTransaction(ctx, senderID, receiverID, 5000, period)
+	assert.ErrorIs(t, err, ErrNotEnoughCoins)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseMerchTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`).
+		WithArgs(40, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO purchases \(tenant_id,employee_id,buyer_id,team_id,gift_message,item_id,variant_id,quantity,unit_price,total,discount,promotion_id,status,purchase_date,updated_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9,\$10,\$11,\$12,\$13,\$14,\$15\) RETURNING purchase_id`).
+		WithArgs(DefaultTenantID, userID, nil, nil, "", 3, nil, 2, 20, 40, 0, nil, OrderPlaced, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"purchase_id"}).AddRow(17))
+	mock.ExpectExec(`INSERT INTO inventory_ledger \(tenant_id,employee_id,item_id,variant_id,kind,quantity,purchase_id,counterparty_id,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9\)`).
+		WithArgs(DefaultTenantID, userID, 3, nil, LedgerAcquired, 2, int64(17), nil, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory \(employee_id,tenant_id,item_id,variant_id,quantity\) VALUES \(\$1,\$2,\$3,\$4,\$5\) ON CONFLICT \(employee_id, item_id, COALESCE\(variant_id, 0\)\) DO UPDATE SET quantity = inventory.quantity \+ excluded.quantity`).
+		WithArgs(userID, DefaultTenantID, 3, nil, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeMerchPurchased,
+			`{"userId":"`+userID.String()+`","itemId":3,"item":"cup","quantity":2,"price":20,"discount":0,"total":40}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := queries.PurchaseMerchTransaction(ctx, userID, MerchInfo{MerchID: 3, Name: "cup", Price: 20, Amount: 2})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurchaseMerchTransaction_Gift(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	buyerID := uuid.New()
+	recipientID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`).
+		WithArgs(20, buyerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// товар записывается получателю, плательщик — в buyer_id
+	mock.ExpectQuery(`INSERT INTO purchases`).
+		WithArgs(DefaultTenantID, recipientID, buyerID, nil, "Спасибо!", 3, nil, 1, 20, 20, 0, nil, OrderPlaced, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"purchase_id"}).AddRow(18))
+	mock.ExpectExec(`INSERT INTO inventory_ledger`).
+		WithArgs(DefaultTenantID, recipientID, 3, nil, LedgerReceived, 1, int64(18), buyerID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO inventory`).
+		WithArgs(recipientID, DefaultTenantID, 3, nil, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO outbox`).
+		WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeMerchPurchased,
+			`{"userId":"`+buyerID.String()+`","itemId":3,"item":"cup","quantity":1,"price":20,"discount":0,"total":20,`+
+				`"recipientId":"`+recipientID.String()+`","message":"Спасибо!"}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := queries.PurchaseMerchTransaction(ctx, buyerID, MerchInfo{
+		MerchID: 3, Name: "cup", Price: 20, Amount: 1, RecipientID: recipientID, GiftMessage: "Спасибо!",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWalletInfo(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	createdAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	period := time.Date(2025, 3, 17, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`SELECT 'wishlist' AS kind, name, '' AS sku, '' AS size, '' AS color, goal AS amount, contributed AS extra, wish_id AS id, item_id, price, wishlist.created_at AS at FROM wishlist INNER JOIN merch_items using\(item_id\) WHERE employee_id = \$1 UNION ALL SELECT 'balance', .* FROM wallets WHERE employee_id = \$2 UNION ALL .* WHERE sender_id = \$6 GROUP BY username ORDER BY kind, id, name, sku`).
+		WithArgs(userID, userID, userID, userID, userID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "name", "sku", "size", "color", "amount", "extra", "id", "item_id", "price", "at"}).
+			AddRow("balance", "", "", "", "", 870, 0, 0, 0, 0, nil).
+			AddRow("budget", "week", "", "", "", 200, 150, 0, 0, 0, period).
+			AddRow("inventory", "cup", "", "", "", 1, 0, 0, 0, 0, nil).
+			AddRow("inventory", "hoody", "HOODY-M", "M", "black", 1, 0, 0, 0, 0, nil).
+			AddRow("received", "Alice", "", "", "", 20, 0, 0, 0, 0, nil).
+			AddRow("sent", "Bob", "", "", "", 130, 0, 0, 0, 0, nil).
+			AddRow("wishlist", "powerbank", "", "", "", 300, 30, 7, 3, 200, createdAt))
+
+	info, err := queries.GetWalletInfo(ctx, userID)
+	assert.NoError(t, err)
+	assert.Equal(t, &WalletInfo{
+		Balance:   870,
+		Inventory: []InventoryItem{{Name: "cup", Quantity: 1}, {Name: "hoody", SKU: "HOODY-M", Size: "M", Color: "black", Quantity: 1}},
+		Received:  []SenderInfo{{Username: "Alice", Amount: 20}},
+		Sent:      []SenderInfo{{Username: "Bob", Amount: 130}},
+		Wishlist: []WishlistItem{{ID: 7, EmployeeID: userID, ItemID: 3, Item: "powerbank", Price: 200, Goal: 300,
+			Contributed: 30, CreatedAt: createdAt}},
+		Budget: WalletBudget{Limit: 200, Budget: 150, Period: period, TenantPeriod: "week"},
+	}, info)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetWalletInfo_NoWallet(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	mock.ExpectQuery(`SELECT 'wishlist' AS kind`).
+		WithArgs(userID, userID, userID, userID, userID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "name", "sku", "size", "color", "amount", "extra", "id", "item_id", "price", "at"}))
+
+	_, err := queries.GetWalletInfo(ctx, userID)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var webhookDeliveryColumns = []string{
+	"delivery_id", "tenant_id", "webhook_id", "event_id", "event_type", "payload", "status", "attempts",
+	"next_attempt_at", "last_error", "last_status_code", "created_at", "delivered_at",
+}
+
+// claimWebhookDeliveriesQuery берет доставки, время которых пришло, и переносит
+// следующую попытку на время аренды, чтобы их не взял другой инстанс
+const claimWebhookDeliveriesQuery = `UPDATE webhook_deliveries SET next_attempt_at = ?
+WHERE delivery_id IN (
+	SELECT delivery_id FROM webhook_deliveries
+	WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY next_attempt_at LIMIT ?%s
+)
+RETURNING `
+
+func (q *Queries) CreateWebhook(ctx context.Context, webhook Webhook) error {
+	sqlQuery := q.builder().Insert("webhooks").
+		Columns("webhook_id", "tenant_id", "url", "secret", "event_types", "created_at").
+		Values(webhook.ID, TenantID(ctx), webhook.URL, webhook.Secret, strings.Join(webhook.EventTypes, ","), webhook.CreatedAt)
+	if _, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx); err != nil {
+		q.logger(ctx).Error("CreateWebhook ExecContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (q *Queries) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	sqlQuery := q.builder().Select("webhook_id", "url", "secret", "event_types", "created_at").
+		From("webhooks").
+		Where(tenantEq(ctx, "")).
+		OrderBy("created_at", "webhook_id")
+
+	rows, err := sqlQuery.RunWith(q.traced(q.db)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("ListWebhooks QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var webhooks []Webhook
+	for rows.Next() {
+		var w Webhook
+		var eventTypes string
+		if err := rows.Scan(&w.ID, &w.URL, &w.Secret, &eventTypes, &w.CreatedAt); err != nil {
+			q.logger(ctx).Error("ListWebhooks rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		w.EventTypes = strings.Split(eventTypes, ",")
+		w.CreatedAt = w.CreatedAt.UTC()
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("ListWebhooks rows error:", zap.Error(err))
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook удаляет webhook вместе с журналом доставок
+func (q *Queries) DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error {
+	sqlQuery := q.builder().Delete("webhooks").Where(sq.Eq{"webhook_id": webhookID}).Where(tenantEq(ctx, ""))
+	result, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("DeleteWebhook ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrWebhookNotFound)
+}
+
+func (q *Queries) CreateWebhookDeliveries(ctx context.Context, deliveries []WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	sqlQuery := q.builder().Insert("webhook_deliveries").
+		Columns("delivery_id", "tenant_id", "webhook_id", "event_id", "event_type", "payload", "status",
+			"next_attempt_at", "created_at")
+	for _, d := range deliveries {
+		sqlQuery = sqlQuery.Values(d.ID, TenantID(ctx), d.WebhookID, d.EventID, d.EventType, string(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt)
+	}
+	if _, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx); err != nil {
+		q.logger(ctx).Error("CreateWebhookDeliveries ExecContext error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries возвращает до limit доставок, готовых к очередной попытке
+func (q *Queries) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error) {
+	query, err := q.placeholders().ReplacePlaceholders(
+		sprintfSkipLocked(claimWebhookDeliveriesQuery, !q.isSQLite()) + strings.Join(webhookDeliveryColumns, ", "))
+	if err != nil {
+		q.logger(ctx).Error("ClaimWebhookDeliveries ReplacePlaceholders error:", zap.Error(err))
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	rows, err := q.traced(q.db).QueryContext(ctx, query, now.Add(lease), DeliveryPending, now, limit)
+	if err != nil {
+		q.logger(ctx).Error("ClaimWebhookDeliveries QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+	return q.scanWebhookDeliveries(ctx, rows)
+}
+
+// UpdateWebhookDelivery сохраняет результат попытки доставки
+func (q *Queries) UpdateWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error {
+	sqlQuery := q.builder().Update("webhook_deliveries").
+		Set("status", delivery.Status).
+		Set("attempts", delivery.Attempts).
+		Set("next_attempt_at", delivery.NextAttemptAt).
+		Set("last_error", delivery.LastError).
+		Set("last_status_code", delivery.LastStatusCode).
+		Set("delivered_at", delivery.DeliveredAt).
+		Where(sq.Eq{"delivery_id": delivery.ID})
+	result, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("UpdateWebhookDelivery ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrDeliveryNotFound)
+}
+
+// ListWebhookDeliveries журнал доставок webhook, новые первыми. Пустой status - все статусы.
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]WebhookDelivery, error) {
+	sqlQuery := q.builder().Select(webhookDeliveryColumns...).
+		From("webhook_deliveries").
+		Where(sq.Eq{"webhook_id": webhookID}).
+		Where(tenantEq(ctx, "")).
+		OrderBy("created_at DESC", "delivery_id").
+		Limit(uint64(limit))
+	if status != "" {
+		sqlQuery = sqlQuery.Where(sq.Eq{"status": status})
+	}
+
+	rows, err := sqlQuery.RunWith(q.traced(q.db)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("ListWebhookDeliveries QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+	return q.scanWebhookDeliveries(ctx, rows)
+}
+
+// RedeliverWebhookDelivery ставит доставку в очередь заново с полным набором попыток
+func (q *Queries) RedeliverWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error {
+	sqlQuery := q.builder().Update("webhook_deliveries").
+		Set("status", DeliveryPending).
+		Set("attempts", 0).
+		Set("next_attempt_at", time.Now().UTC()).
+		Where(sq.Eq{"delivery_id": deliveryID}).
+		Where(tenantEq(ctx, ""))
+	result, err := sqlQuery.RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("RedeliverWebhookDelivery ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrDeliveryNotFound)
+}
+
+func (q *Queries) scanWebhookDeliveries(ctx context.Context, rows *sql.Rows) ([]WebhookDelivery, error) {
+	defer rows.Close()
+
+	var deliveries []WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		var payload string
+		var deliveredAt sql.NullTime
+		err := rows.Scan(&d.ID, &d.TenantID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastError, &d.LastStatusCode, &d.CreatedAt, &deliveredAt)
+		if err != nil {
+			q.logger(ctx).Error("scanWebhookDeliveries rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		d.Payload = []byte(payload)
+		d.NextAttemptAt = d.NextAttemptAt.UTC()
+		d.CreatedAt = d.CreatedAt.UTC()
+		if deliveredAt.Valid {
+			t := deliveredAt.Time.UTC()
+			d.DeliveredAt = &t
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		q.logger(ctx).Error("scanWebhookDeliveries rows error:", zap.Error(err))
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func checkAffected(result sql.Result, notFound error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
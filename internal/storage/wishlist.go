@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SaveWish добавляет товар в вишлист или меняет цель, если товар уже там.
+// Заполняет ID, Contributed и CreatedAt сохраненной записи.
+func (q *Queries) SaveWish(ctx context.Context, wish *WishlistItem) error {
+	sqlQuery := q.builder().Insert("wishlist").
+		Columns("tenant_id", "employee_id", "item_id", "goal", "created_at").
+		Values(TenantID(ctx), wish.EmployeeID, wish.ItemID, wish.Goal, time.Now().UTC()).
+		Suffix("ON CONFLICT (employee_id, item_id) DO UPDATE SET goal = excluded.goal " +
+			"RETURNING wish_id, contributed, created_at")
+
+	err := sqlQuery.RunWith(q.traced(q.db)).QueryRowContext(ctx).Scan(&wish.ID, &wish.Contributed, &wish.CreatedAt)
+	if err != nil {
+		q.logger(ctx).Error("SaveWish QueryRowContext error:", zap.Error(err))
+		return err
+	}
+	wish.CreatedAt = wish.CreatedAt.UTC()
+	return nil
+}
+
+// GetWishlist вишлист сотрудника в порядке добавления
+func (q *Queries) GetWishlist(ctx context.Context, userID uuid.UUID) ([]WishlistItem, error) {
+	sqlQuery := q.builder().Select("wish_id", "item_id", "name", "price", "goal", "contributed",
+		"wishlist.created_at").
+		From("wishlist").
+		InnerJoin("merch_items using(item_id)").
+		Where(sq.Eq{"employee_id": userID}).
+		OrderBy("wish_id")
+
+	rows, err := sqlQuery.RunWith(q.reader(ctx)).QueryContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("GetWishlist QueryContext error:", zap.Error(err))
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var wishlist []WishlistItem
+	for rows.Next() {
+		w := WishlistItem{EmployeeID: userID}
+		err := rows.Scan(&w.ID, &w.ItemID, &w.Item, &w.Price, &w.Goal, &w.Contributed, &w.CreatedAt)
+		if err != nil {
+			q.logger(ctx).Error("GetWishlist rows.Scan error:", zap.Error(err))
+			return nil, err
+		}
+		w.CreatedAt = w.CreatedAt.UTC()
+		wishlist = append(wishlist, w)
+	}
+	if err = rows.Err(); err != nil {
+		q.logger(ctx).Error("GetWishlist rows error:", zap.Error(err))
+		return nil, err
+	}
+	return wishlist, nil
+}
+
+func (q *Queries) DeleteWish(ctx context.Context, userID uuid.UUID, itemID int) error {
+	result, err := q.builder().Delete("wishlist").
+		Where(sq.Eq{"employee_id": userID, "item_id": itemID}).
+		RunWith(q.traced(q.db)).ExecContext(ctx)
+	if err != nil {
+		q.logger(ctx).Error("DeleteWish ExecContext error:", zap.Error(err))
+		return err
+	}
+	return checkAffected(result, ErrWishNotFound)
+}
+
+// ContributeTransaction переводит монеты получателю как SendCoinsTransaction и
+// засчитывает их во взносы на товар из его вишлиста. Взнос списывается только с баланса,
+// бюджет на благодарности не тратится.
+func (q *Queries) ContributeTransaction(ctx context.Context, senderID uuid.UUID, receiverID uuid.UUID,
+	contribution Contribution) error {
+	event, err := events.New(events.TypeWishlistContributed, events.WishlistContributed{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		ItemID:     contribution.ItemID,
+		Item:       contribution.Item,
+		Amount:     contribution.Amount,
+	})
+	if err != nil {
+		q.logger(ctx).Error("Contribute events.New error:", zap.Error(err))
+		return err
+	}
+
+	tenant := TenantID(ctx)
+	statements := q.coinTransfer(tenant, q.balanceDebit(senderID, contribution.Amount), senderID, receiverID,
+		contribution.Amount)
+	statements = append(statements, q.outboxInsert(tenant, event))
+	err = q.contributeTx(ctx, receiverID, contribution, statements)
+	if err != nil {
+		if isCheckViolation(err) {
+			return ErrNotEnoughCoins
+		}
+		if errors.Is(err, ErrWishNotFound) {
+			return err
+		}
+		q.logger(ctx).Error("Contribute error:", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// contributeTx засчитывает взнос в строку вишлиста и выполняет запросы перевода в одной
+// транзакции. Строка вишлиста блокируется раньше кошельков, как вариант при покупке.
+func (q *Queries) contributeTx(ctx context.Context, receiverID uuid.UUID, contribution Contribution,
+	statements []sq.Sqlizer) (err error) {
+	tx, err := q.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, rollbackIgnoreDone(tx))
+		}
+	}()
+
+	result, err := q.builder().Update("wishlist").
+		Set("contributed", sq.Expr("contributed + ?", contribution.Amount)).
+		Where(sq.Eq{"employee_id": receiverID, "item_id": contribution.ItemID}).
+		RunWith(q.traced(tx)).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	if err = checkAffected(result, ErrWishNotFound); err != nil {
+		return err
+	}
+
+	if err = q.execStatements(ctx, tx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
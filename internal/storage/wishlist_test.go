@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveWish(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	createdAt := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	// повторное добавление меняет цель, взносы сохраняются
+	mock.ExpectQuery(`INSERT INTO wishlist \(tenant_id,employee_id,item_id,goal,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\) `+
+		`ON CONFLICT \(employee_id, item_id\) DO UPDATE SET goal = excluded.goal RETURNING wish_id, contributed, created_at`).
+		WithArgs(DefaultTenantID, userID, 5, 300, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"wish_id", "contributed", "created_at"}).AddRow(7, 40, createdAt))
+
+	wish := WishlistItem{EmployeeID: userID, ItemID: 5, Goal: 300}
+	assert.NoError(t, queries.SaveWish(ctx, &wish))
+	assert.Equal(t, WishlistItem{ID: 7, EmployeeID: userID, ItemID: 5, Goal: 300, Contributed: 40, CreatedAt: createdAt}, wish)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteWish(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	deleteQuery := `DELETE FROM wishlist WHERE employee_id = \$1 AND item_id = \$2`
+	mock.ExpectExec(deleteQuery).WithArgs(userID, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(deleteQuery).WithArgs(userID, 6).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, queries.DeleteWish(ctx, userID, 5))
+	assert.ErrorIs(t, queries.DeleteWish(ctx, userID, 6), ErrWishNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestContributeTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, mock, queries := setupMockDB(t)
+	defer db.Close()
+
+	senderID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	receiverID := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	contribution := Contribution{ItemID: 5, Item: "powerbank", Amount: 30}
+	wishQuery := `UPDATE wishlist SET contributed = contributed \+ \$1 WHERE employee_id = \$2 AND item_id = \$3`
+
+	t.Run("Success", func(t *testing.T) {
+		// строка вишлиста блокируется раньше кошельков
+		mock.ExpectBegin()
+		mock.ExpectExec(wishQuery).WithArgs(30, receiverID, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`).
+			WithArgs(30, senderID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE wallets SET balance = balance \+ \$1 WHERE employee_id = \$2`).
+			WithArgs(30, receiverID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO transactions \(tenant_id,sender_id,receiver_id,amount\) VALUES \(\$1,\$2,\$3,\$4\)`).
+			WithArgs(DefaultTenantID, senderID, receiverID, 30).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO outbox \(tenant_id,event_id,event_type,payload,created_at\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`).
+			WithArgs(DefaultTenantID, sqlmock.AnyArg(), events.TypeWishlistContributed, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, queries.ContributeTransaction(ctx, senderID, receiverID, contribution))
+	})
+
+	t.Run("NotInWishlist", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(wishQuery).WithArgs(30, receiverID, 5).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, queries.ContributeTransaction(ctx, senderID, receiverID, contribution), ErrWishNotFound)
+	})
+
+	t.Run("NotEnoughCoins", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(wishQuery).WithArgs(30, receiverID, 5).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE wallets SET balance = balance - \$1 WHERE employee_id = \$2`).
+			WithArgs(30, senderID).
+			WillReturnError(&pgconn.PgError{Code: "23514"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, queries.ContributeTransaction(ctx, senderID, receiverID, contribution), ErrNotEnoughCoins)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
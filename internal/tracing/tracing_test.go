@@ -0,0 +1,21 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	restore := zap.ReplaceGlobals(zap.New(core))
+	defer restore()
+
+	// без логгера записи уходят в глобальный, а не теряются
+	Logger(context.Background(), nil).Info("global")
+
+	assert.Equal(t, 1, logs.FilterMessage("global").Len())
+}
@@ -15,13 +15,16 @@ var (
 	ErrorExpiredOrNotActive = errors.New("token is either expired or not active yet")
 )
 
+// Claims TenantID компания пользователя, 0 в токенах, выданных до появления компаний
 type Claims struct {
-	UserID uuid.UUID `json:"userID"`
+	UserID   uuid.UUID `json:"userID"`
+	TenantID int64     `json:"tenantID,omitempty"`
 	jwt.StandardClaims
 }
 
 type User struct {
-	UserID uuid.UUID
+	UserID   uuid.UUID
+	TenantID int64
 }
 
 func GenerateJWT(user User) (string, error) {
@@ -45,7 +48,8 @@ func GenerateJWT(user User) (string, error) {
 	expirationTime := time.Now().Add(time.Duration(intExpirationTime) * time.Hour)
 
 	claims := &Claims{
-		UserID: user.UserID,
+		UserID:   user.UserID,
+		TenantID: user.TenantID,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(),
 		},
@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"balance.low"}`)
+	// совпадает с printf '1700000000.<body>' | openssl dgst -sha256 -hmac secret
+	signature := Sign("secret", 1700000000, body)
+	assert.Equal(t, "sha256=ba3e79ec28b871fac7f3530f6e69fc398b82bfa49ec8e1930533f8a9a5b06eba", signature)
+
+	assert.True(t, Verify("secret", 1700000000, body, signature))
+	assert.False(t, Verify("other", 1700000000, body, signature))
+	assert.False(t, Verify("secret", 1700000001, body, signature))
+	assert.False(t, Verify("secret", 1700000000, []byte(`{}`), signature))
+}
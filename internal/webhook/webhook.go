@@ -0,0 +1,221 @@
+// Package webhook отправляет уведомления на зарегистрированные администраторами
+// адреса.
+//
+// Каждое уведомление сохраняется как доставка для всех подписанных webhooks.
+// Доставки отправляет фоновый worker: тело подписывается HMAC-SHA256 секретом
+// webhook, при ошибке попытка повторяется с экспоненциальной задержкой, после
+// MaxAttempts неудачных попыток доставка становится dead и повторяется только
+// вручную.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/logging"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// EventTypes события, на которые можно подписать webhook
+var EventTypes = []string{
+	events.TypeTransferReceived,
+	events.TypePurchaseMade,
+	events.TypeGiftReceived,
+	events.TypeItemReceived,
+	events.TypeContributionReceived,
+	events.TypeTeamCoinsReceived,
+	events.TypeBalanceLow,
+	events.TypeOrderStatusChanged,
+	events.TypeUserWelcome,
+	events.TypeBonusVested,
+}
+
+// deliveriesLimit сколько последних доставок возвращает журнал
+const deliveriesLimit = 100
+
+var (
+	ErrWebhookNotFound  = apperr.New(apperr.CodeWebhookNotFound, "webhook not found")
+	ErrDeliveryNotFound = apperr.New(apperr.CodeDeliveryNotFound, "webhook delivery not found")
+)
+
+type Storage interface {
+	CreateWebhook(ctx context.Context, webhook storage.Webhook) error
+	ListWebhooks(ctx context.Context) ([]storage.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookID uuid.UUID) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []storage.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]storage.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, status string, limit int) ([]storage.WebhookDelivery, error)
+	RedeliverWebhookDelivery(ctx context.Context, deliveryID uuid.UUID) error
+}
+
+type Config struct {
+	// MaxAttempts после стольких неудачных попыток доставка становится dead
+	MaxAttempts int
+	// Backoff задержка перед второй попыткой, дальше удваивается до MaxBackoff
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease время, на которое доставка закрепляется за worker
+	Lease time.Duration
+}
+
+type Service struct {
+	storage Storage
+	client  *http.Client
+	config  Config
+	log     *zap.Logger
+	now     func() time.Time
+}
+
+func New(storage Storage, client *http.Client, config Config, zapLogger *zap.Logger) *Service {
+	return &Service{
+		storage: storage,
+		client:  client,
+		config:  config,
+		log:     zapLogger,
+		now:     time.Now,
+	}
+}
+
+// Register создает webhook. Секрет для проверки подписи генерируется здесь
+// и возвращается вызывающему вместе с webhook.
+func (s *Service) Register(ctx context.Context, rawURL string, eventTypes []string) (*storage.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, apperr.ErrValidation.WithDetail("url must be an absolute http(s) url")
+	}
+	if len(eventTypes) == 0 {
+		return nil, apperr.ErrValidation.WithDetail("eventTypes must not be empty")
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return nil, apperr.ErrValidation.WithDetail("unknown event type " + eventType)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	webhook := storage.Webhook{
+		ID:         uuid.New(),
+		URL:        u.String(),
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		CreatedAt:  s.now().UTC(),
+	}
+	if err := s.storage.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]storage.Webhook, error) {
+	return s.storage.ListWebhooks(ctx)
+}
+
+func (s *Service) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	err := s.storage.DeleteWebhook(ctx, webhookID)
+	if errors.Is(err, storage.ErrWebhookNotFound) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// Deliveries журнал доставок webhook, новые первыми. Пустой status - все статусы.
+func (s *Service) Deliveries(ctx context.Context, webhookID uuid.UUID, status string) ([]storage.WebhookDelivery, error) {
+	switch status {
+	case "", storage.DeliveryPending, storage.DeliveryDelivered, storage.DeliveryDead:
+	default:
+		return nil, apperr.ErrValidation.WithDetail("unknown delivery status " + status)
+	}
+	webhooks, err := s.storage.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(webhooks, func(w storage.Webhook) bool { return w.ID == webhookID }) {
+		return nil, ErrWebhookNotFound
+	}
+	return s.storage.ListWebhookDeliveries(ctx, webhookID, status, deliveriesLimit)
+}
+
+// Redeliver возвращает доставку в очередь с полным набором попыток
+func (s *Service) Redeliver(ctx context.Context, deliveryID uuid.UUID) error {
+	err := s.storage.RedeliverWebhookDelivery(ctx, deliveryID)
+	if errors.Is(err, storage.ErrDeliveryNotFound) {
+		return ErrDeliveryNotFound
+	}
+	return err
+}
+
+// Notify ставит уведомление в очередь доставки всем подписанным webhooks.
+// Операция, о которой уведомляем, уже выполнена, поэтому ошибки только
+// логируются и не возвращаются вызывающему.
+func (s *Service) Notify(ctx context.Context, eventType string, payload any) {
+	// на остальные события webhook подписать нельзя
+	if !slices.Contains(EventTypes, eventType) {
+		return
+	}
+	// уведомление не должно теряться из-за отмены запроса клиентом
+	ctx = context.WithoutCancel(ctx)
+
+	webhooks, err := s.storage.ListWebhooks(ctx)
+	if err != nil {
+		s.logger(ctx).Error("webhook Notify ListWebhooks error:", zap.Error(err))
+		return
+	}
+
+	var deliveries []storage.WebhookDelivery
+	var body []byte
+	var eventID uuid.UUID
+	for _, w := range webhooks {
+		if !slices.Contains(w.EventTypes, eventType) {
+			continue
+		}
+		if body == nil {
+			event, err := events.New(eventType, payload)
+			if err != nil {
+				s.logger(ctx).Error("webhook Notify events.New error:", zap.Error(err))
+				return
+			}
+			event.TenantID = storage.TenantID(ctx)
+			if body, err = json.Marshal(event); err != nil {
+				s.logger(ctx).Error("webhook Notify json.Marshal error:", zap.Error(err))
+				return
+			}
+			eventID = event.ID
+		}
+		now := s.now().UTC()
+		deliveries = append(deliveries, storage.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     w.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       body,
+			Status:        storage.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	if err := s.storage.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		s.logger(ctx).Error("webhook Notify CreateWebhookDeliveries error:", zap.Error(err))
+	}
+}
+
+func (s *Service) logger(ctx context.Context) *zap.Logger {
+	return logging.FromContext(ctx, s.log)
+}
@@ -0,0 +1,195 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/apperr"
+	"github.com/Vic07Region/avito-shop/internal/events"
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/Vic07Region/avito-shop/internal/storage/memory"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver тестовый получатель webhooks, отвечает статусами из statuses по порядку
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestService(t *testing.T, config Config) (*Service, *memory.Storage, *receiver, string) {
+	t.Helper()
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	s := memory.New()
+	return New(s, server.Client(), config, nil), s, r, server.URL
+}
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	srv, _, _, url := newTestService(t, Config{})
+
+	_, err := srv.Register(ctx, "ftp://example.com", []string{events.TypeBalanceLow})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = srv.Register(ctx, url, nil)
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	_, err = srv.Register(ctx, url, []string{"user.registered"})
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+
+	webhook, err := srv.Register(ctx, url, []string{events.TypeBalanceLow})
+	require.NoError(t, err)
+	assert.Len(t, webhook.Secret, 64)
+
+	webhooks, err := srv.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, webhook.ID, webhooks[0].ID)
+
+	_, err = srv.Deliveries(ctx, uuid.New(), "")
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	_, err = srv.Deliveries(ctx, webhook.ID, "unknown")
+	assert.ErrorIs(t, err, apperr.ErrValidation)
+	assert.ErrorIs(t, srv.Redeliver(ctx, uuid.New()), ErrDeliveryNotFound)
+
+	require.NoError(t, srv.Delete(ctx, webhook.ID))
+	assert.ErrorIs(t, srv.Delete(ctx, webhook.ID), ErrWebhookNotFound)
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	srv, _, r, url := newTestService(t, Config{MaxAttempts: 3, Backoff: time.Minute, MaxBackoff: time.Hour, BatchSize: 10, Lease: time.Minute})
+
+	subscribed, err := srv.Register(ctx, url, []string{events.TypeTransferReceived})
+	require.NoError(t, err)
+	_, err = srv.Register(ctx, url+"/other", []string{events.TypeBalanceLow})
+	require.NoError(t, err)
+
+	payload := events.TransferReceived{SenderID: uuid.New(), Sender: "alice", ReceiverID: uuid.New(), Receiver: "bob", Amount: 10}
+	srv.Notify(ctx, events.TypeTransferReceived, payload)
+
+	sent, err := srv.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	// доставка получена только подписанным webhook
+	require.Len(t, r.requests, 1)
+	req, body := r.requests[0], r.bodies[0]
+	assert.Equal(t, "/", req.URL.Path)
+	assert.Equal(t, events.TypeTransferReceived, req.Header.Get(HeaderEvent))
+	timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	require.NoError(t, err)
+	assert.True(t, Verify(subscribed.Secret, timestamp, body, req.Header.Get(HeaderSignature)))
+
+	var event events.Event
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, events.TypeTransferReceived, event.Type)
+	var received events.TransferReceived
+	require.NoError(t, json.Unmarshal(event.Payload, &received))
+	assert.Equal(t, payload, received)
+
+	deliveries, err := srv.Deliveries(ctx, subscribed.ID, storage.DeliveryDelivered)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, req.Header.Get(HeaderDelivery), deliveries[0].ID.String())
+	assert.Equal(t, event.ID, deliveries[0].EventID)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].LastStatusCode)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	srv, _, r, url := newTestService(t, Config{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 3 * time.Second, BatchSize: 10, Lease: time.Minute})
+	r.statuses = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+	webhook, err := srv.Register(ctx, url, []string{events.TypeBalanceLow})
+	require.NoError(t, err)
+	srv.Notify(ctx, events.TypeBalanceLow, events.BalanceLow{UserID: uuid.New(), Balance: 10, Threshold: 100})
+
+	// часы worker сдвигаются вперед, чтобы не ждать задержек в тесте
+	now := time.Now()
+	srv.now = func() time.Time { return now }
+
+	var delays []time.Duration
+	for attempt := 1; attempt <= 3; attempt++ {
+		sent, err := srv.Flush(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 1, sent, "attempt %d", attempt)
+
+		deliveries, err := srv.Deliveries(ctx, webhook.ID, "")
+		require.NoError(t, err)
+		require.Len(t, deliveries, 1)
+		d := deliveries[0]
+		assert.Equal(t, attempt, d.Attempts)
+		assert.Equal(t, r.requests[attempt-1].Header.Get(HeaderDelivery), d.ID.String())
+		if attempt < 3 {
+			assert.Equal(t, storage.DeliveryPending, d.Status)
+			delays = append(delays, d.NextAttemptAt.Sub(now.UTC()))
+			// следующая попытка еще не наступила
+			sent, err = srv.Flush(ctx)
+			require.NoError(t, err)
+			assert.Zero(t, sent)
+			// сдвигаем время попытки в прошлое напрямую в хранилище
+			d.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+			require.NoError(t, srv.storage.UpdateWebhookDelivery(ctx, d))
+		} else {
+			assert.Equal(t, storage.DeliveryDead, d.Status)
+			assert.Equal(t, http.StatusServiceUnavailable, d.LastStatusCode)
+			assert.Contains(t, d.LastError, "503")
+		}
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, delays)
+
+	// dead доставка не отправляется, пока ее не повторят вручную
+	sent, err := srv.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	dead, err := srv.Deliveries(ctx, webhook.ID, storage.DeliveryDead)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	require.NoError(t, srv.Redeliver(ctx, dead[0].ID))
+
+	sent, err = srv.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	delivered, err := srv.Deliveries(ctx, webhook.ID, storage.DeliveryDelivered)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, 1, delivered[0].Attempts)
+	assert.Len(t, r.requests, 4)
+}
+
+func TestBackoff(t *testing.T) {
+	srv := New(nil, nil, Config{Backoff: time.Second, MaxBackoff: 5 * time.Second}, nil)
+	var delays []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		delays = append(delays, srv.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, delays)
+}
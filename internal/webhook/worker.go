@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Vic07Region/avito-shop/internal/metrics" //nolint:gci
+	"github.com/Vic07Region/avito-shop/internal/storage"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxErrorLength ограничивает текст ошибки, сохраняемый в журнале доставок
+const maxErrorLength = 512
+
+// Run отправляет доставки, пока не отменен ctx
+func (s *Service) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		sent, err := s.Flush(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger(ctx).Error("webhook worker Flush error:", zap.Error(err))
+		}
+		// полная пачка значит, что в очереди, скорее всего, есть еще доставки
+		if err == nil && sent == s.config.BatchSize {
+			timer.Reset(0)
+		} else {
+			timer.Reset(s.config.PollInterval)
+		}
+	}
+}
+
+// Flush отправляет одну пачку доставок параллельно и возвращает число
+// обработанных доставок. В отличие от outbox порядок не гарантируется:
+// получатели webhooks различаются и не должны ждать друг друга.
+func (s *Service) Flush(ctx context.Context) (int, error) {
+	claimed, err := s.storage.ClaimWebhookDeliveries(ctx, s.config.BatchSize, s.config.Lease)
+	if err != nil || len(claimed) == 0 {
+		return 0, err
+	}
+
+	// webhooks принадлежат компании доставки, список читается один раз на компанию
+	webhooks := make(map[uuid.UUID]storage.Webhook)
+	loaded := make(map[int64]bool)
+	for _, d := range claimed {
+		if loaded[d.TenantID] {
+			continue
+		}
+		list, err := s.storage.ListWebhooks(storage.WithTenant(ctx, d.TenantID))
+		if err != nil {
+			return 0, err
+		}
+		for _, w := range list {
+			webhooks[w.ID] = w
+		}
+		loaded[d.TenantID] = true
+	}
+
+	var wg sync.WaitGroup
+	for _, d := range claimed {
+		w, ok := webhooks[d.WebhookID]
+		if !ok {
+			// webhook удален после того, как доставка была взята
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.attempt(ctx, w, d)
+		}()
+	}
+	wg.Wait()
+	return len(claimed), nil
+}
+
+// attempt отправляет доставку и сохраняет результат попытки
+func (s *Service) attempt(ctx context.Context, w storage.Webhook, d storage.WebhookDelivery) {
+	statusCode, sendErr := s.send(ctx, w, d)
+
+	now := s.now().UTC()
+	d.Attempts++
+	d.LastStatusCode = statusCode
+	switch {
+	case sendErr == nil:
+		d.Status = storage.DeliveryDelivered
+		d.LastError = ""
+		d.DeliveredAt = &now
+		metrics.WebhookDeliveries.WithLabelValues(d.EventType, "delivered").Inc()
+	case d.Attempts >= s.config.MaxAttempts:
+		d.Status = storage.DeliveryDead
+		d.LastError = truncate(sendErr.Error(), maxErrorLength)
+		metrics.WebhookDeliveries.WithLabelValues(d.EventType, "dead").Inc()
+		s.logger(ctx).Warn("webhook delivery is dead:", zap.String("delivery_id", d.ID.String()),
+			zap.String("webhook_id", w.ID.String()), zap.Int("attempts", d.Attempts), zap.Error(sendErr))
+	default:
+		d.LastError = truncate(sendErr.Error(), maxErrorLength)
+		d.NextAttemptAt = now.Add(s.backoff(d.Attempts))
+		metrics.WebhookDeliveries.WithLabelValues(d.EventType, "retry").Inc()
+	}
+
+	if err := s.storage.UpdateWebhookDelivery(ctx, d); err != nil {
+		s.logger(ctx).Error("webhook UpdateWebhookDelivery error:", zap.String("delivery_id", d.ID.String()), zap.Error(err))
+	}
+}
+
+func (s *Service) send(ctx context.Context, w storage.Webhook, d storage.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := s.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, timestamp, d.Payload))
+	req.Header.Set(HeaderDelivery, d.ID.String())
+	req.Header.Set(HeaderEvent, d.EventType)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff задержка после attempts неудачных попыток: Backoff, 2*Backoff, 4*Backoff...
+func (s *Service) backoff(attempts int) time.Duration {
+	delay := s.config.Backoff
+	for i := 1; i < attempts && delay < s.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.config.MaxBackoff)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
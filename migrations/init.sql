@@ -1,13 +1,36 @@
 -- CREATE EXTENSIONS
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
+-- Table: tenants
+-- компания со своими сотрудниками, каталогом и настройками. signup_bonus - монеты новому сотруднику,
+-- admin_signup_bonus - новому администратору компании, NULL - как signup_bonus, bonus_vesting_days -
+-- через сколько дней после регистрации бонус попадает на баланс, budget_period - период бюджета на благодарности, пустой - BUDGET_PERIOD сервиса, admins -
+-- администраторы компании через запятую. tenant_id в остальных таблицах - компания строки,
+-- username, sku, код промокода и название команды уникальны в пределах компании
+CREATE TABLE tenants (
+    tenant_id SERIAL PRIMARY KEY,
+    slug VARCHAR(64) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    signup_bonus INTEGER NOT NULL DEFAULT 1000 CHECK (signup_bonus >= 0),
+    admin_signup_bonus INTEGER CHECK (admin_signup_bonus >= 0),
+    bonus_vesting_days INTEGER NOT NULL DEFAULT 0 CHECK (bonus_vesting_days >= 0),
+    budget_period VARCHAR(16) NOT NULL DEFAULT '',
+    admins TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Table: employees
 CREATE TABLE employees (
     employee_id UUID NOT NULL DEFAULT uuid_generate_v4(),
-    username VARCHAR(255) UNIQUE NOT NULL,
-    email VARCHAR(255) UNIQUE,
+    tenant_id INTEGER NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
     password_hash VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    role VARCHAR(16) NOT NULL DEFAULT 'employee' CHECK (role IN ('employee', 'admin')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, username),
+    UNIQUE (tenant_id, email),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
 );
 
 ALTER TABLE employees ADD PRIMARY KEY (employee_id);
@@ -15,52 +38,318 @@ ALTER TABLE employees ADD PRIMARY KEY (employee_id);
 -- Table: wallets
 CREATE TABLE wallets (
     employee_id UUID PRIMARY KEY,
-    balance INTEGER DEFAULT 1000 CHECK (balance >= 0),
-    FOREIGN KEY (employee_id) REFERENCES employees(employee_id)
+    tenant_id INTEGER NOT NULL,
+    balance INTEGER NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    -- бюджет на благодарности: тратится только на переводы коллегам, в начале
+    -- периода восполняется до budget_limit. budget_period - начало периода, к которому относится budget
+    budget INTEGER NOT NULL DEFAULT 0 CHECK (budget >= 0),
+    budget_limit INTEGER NOT NULL DEFAULT 0 CHECK (budget_limit >= 0),
+    budget_period TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: onboarding
+-- бонус за регистрацию. Выдается один раз на имя сотрудника в компании, запись не удаляется
+-- вместе с сотрудником, поэтому заново созданная учетная запись бонус не получает.
+-- vested_at NULL - бонус ждет vests_at и еще не на балансе
+CREATE TABLE onboarding (
+    onboarding_id BIGSERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    employee_id UUID NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    bonus INTEGER NOT NULL CHECK (bonus >= 0),
+    vests_at TIMESTAMP NOT NULL,
+    vested_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    UNIQUE (tenant_id, username),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
 );
 
 -- Table: merch_items
 CREATE TABLE merch_items (
     item_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: merch_variants
+-- вариант товара (размер, цвет) со своим остатком. price NULL - цена товара из merch_items.
+-- товар с вариантами покупается только с выбором варианта
+CREATE TABLE merch_variants (
+    variant_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    size VARCHAR(16) NOT NULL DEFAULT '',
+    color VARCHAR(32) NOT NULL DEFAULT '',
+    price INTEGER CHECK (price > 0),
+    stock INTEGER NOT NULL DEFAULT 0 CHECK (stock >= 0),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    UNIQUE (tenant_id, sku),
+    UNIQUE (item_id, size, color),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: merch_prices
+-- история цен каталога: цена действует в [effective_from, effective_to),
+-- у текущей цены effective_to IS NULL
+CREATE TABLE merch_prices (
+    price_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    price INTEGER NOT NULL CHECK (price > 0),
+    effective_from TIMESTAMP NOT NULL,
+    effective_to TIMESTAMP,
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: promotions
+-- скидка без кода - распродажа, применяется к покупкам автоматически в период действия;
+-- с кодом - промокод. max_redemptions и per_user_limit: 0 - без ограничения
+CREATE TABLE promotions (
+    promotion_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    code VARCHAR(32),
+    kind VARCHAR(16) NOT NULL,
+    value INTEGER NOT NULL CHECK (value > 0),
+    item_id INTEGER,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    per_user_limit INTEGER NOT NULL DEFAULT 0,
+    redemptions INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, code),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: teams
+-- командный кошелек: balance - общий пул команды, ограничен так же, как wallets
+CREATE TABLE teams (
+    team_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
     name VARCHAR(255) NOT NULL,
-    price INTEGER NOT NULL
+    balance INTEGER NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant_id, name),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: team_members
+-- role: member или manager, менеджер распределяет пул и покупает товар на команду
+CREATE TABLE team_members (
+    team_id INTEGER NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    employee_id UUID NOT NULL,
+    role VARCHAR(16) NOT NULL DEFAULT 'member',
+    PRIMARY KEY (team_id, employee_id),
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
 );
 
 -- Table: purchases
+-- покупка одновременно заказ: status проходит placed -> approved -> shipped -> delivered,
+-- отмена (cancelled) возвращает total на баланс. unit_price - цена каталога на момент покупки,
+-- total = unit_price * quantity - discount. buyer_id заполнен у подарка: платит buyer_id,
+-- товар у employee_id; NULL - покупка для себя. team_id заполнен у командной покупки:
+-- платит команда, товар у менеджера employee_id
 CREATE TABLE purchases (
     purchase_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
     employee_id UUID NOT NULL,
+    buyer_id UUID,
+    team_id INTEGER,
+    gift_message VARCHAR(255) NOT NULL DEFAULT '',
     item_id INTEGER NOT NULL,
     quantity INTEGER NOT NULL,
+    unit_price INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    discount INTEGER NOT NULL DEFAULT 0,
+    promotion_id INTEGER,
+    variant_id INTEGER,
+    status VARCHAR(16) NOT NULL DEFAULT 'placed',
+    shipping_address TEXT NOT NULL DEFAULT '',
     purchase_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
-    FOREIGN KEY (item_id) REFERENCES merch_items(item_id)
+    FOREIGN KEY (buyer_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (promotion_id) REFERENCES promotions(promotion_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
 );
 
 -- Table: transactions
 CREATE TABLE transactions (
     transaction_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
     sender_id UUID NOT NULL,
     receiver_id UUID NOT NULL,
     amount INTEGER NOT NULL,
     transaction_date TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     FOREIGN KEY (sender_id) REFERENCES employees(employee_id),
-    FOREIGN KEY (receiver_id) REFERENCES employees(employee_id)
+    FOREIGN KEY (receiver_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: inventory
+-- товары на руках у сотрудника, как wallets для монет. Пустой variant_id - товар без вариантов
+CREATE TABLE inventory (
+    employee_id UUID NOT NULL,
+    tenant_id INTEGER NOT NULL,
+    item_id INTEGER NOT NULL,
+    variant_id INTEGER,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: inventory_ledger
+-- журнал движения товаров: acquired - покупка, received - подарок или передача от counterparty_id,
+-- gifted - передача counterparty_id, returned - отмена заказа. quantity со знаком, сумма по
+-- сотруднику и товару равна inventory.quantity
+CREATE TABLE inventory_ledger (
+    entry_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    employee_id UUID NOT NULL,
+    item_id INTEGER NOT NULL,
+    variant_id INTEGER,
+    kind VARCHAR(16) NOT NULL,
+    quantity INTEGER NOT NULL,
+    purchase_id INTEGER,
+    counterparty_id UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (variant_id) REFERENCES merch_variants(variant_id),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(purchase_id),
+    FOREIGN KEY (counterparty_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: wishlist
+-- товары, на которые копит сотрудник: goal - цель в монетах, contributed - сколько перевели коллеги
+CREATE TABLE wishlist (
+    wish_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    employee_id UUID NOT NULL,
+    item_id INTEGER NOT NULL,
+    goal INTEGER NOT NULL CHECK (goal > 0),
+    contributed INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (employee_id, item_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (item_id) REFERENCES merch_items(item_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: team_transactions
+-- история командного кошелька: deposit - пополнение, distribution - перевод участнику employee_id,
+-- purchase - командная покупка менеджера employee_id, refund - возврат при отмене заказа.
+-- amount со знаком, сумма по команде равна teams.balance
+CREATE TABLE team_transactions (
+    entry_id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    team_id INTEGER NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    amount INTEGER NOT NULL,
+    employee_id UUID,
+    purchase_id INTEGER,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (team_id) REFERENCES teams(team_id),
+    FOREIGN KEY (employee_id) REFERENCES employees(employee_id),
+    FOREIGN KEY (purchase_id) REFERENCES purchases(purchase_id),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: outbox
+-- доменные события, пишутся в одной транзакции с изменением данных
+CREATE TABLE outbox (
+    outbox_id BIGSERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    event_id UUID UNIQUE NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    published_at TIMESTAMP,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: webhooks
+-- event_types хранится списком через запятую
+CREATE TABLE webhooks (
+    webhook_id UUID PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
+);
+
+-- Table: webhook_deliveries
+CREATE TABLE webhook_deliveries (
+    delivery_id UUID PRIMARY KEY,
+    tenant_id INTEGER NOT NULL,
+    webhook_id UUID NOT NULL,
+    event_id UUID NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_status_code INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(tenant_id)
 );
 
 
 -- Indexes
 CREATE INDEX idx_employees_email ON employees (email);
-CREATE INDEX idx_employees_username ON employees (username);
+CREATE INDEX idx_employees_username ON employees (tenant_id, username);
 CREATE INDEX idx_wallets_employee_id ON wallets (employee_id);
-CREATE INDEX idx_merch_items_name ON merch_items (name);
+CREATE INDEX idx_merch_items_name ON merch_items (tenant_id, name);
 CREATE INDEX idx_purchases_employee_id ON purchases (employee_id);
+CREATE INDEX idx_purchases_buyer_id ON purchases (buyer_id) WHERE buyer_id IS NOT NULL;
+CREATE INDEX idx_purchases_status ON purchases (status, purchase_id);
+CREATE INDEX idx_purchases_purchase_date ON purchases (purchase_date);
+CREATE INDEX idx_purchases_promotion_id ON purchases (promotion_id, employee_id);
+CREATE INDEX idx_merch_variants_item_id ON merch_variants (item_id);
+CREATE UNIQUE INDEX idx_merch_prices_current ON merch_prices (item_id) WHERE effective_to IS NULL;
+CREATE INDEX idx_merch_prices_item_id ON merch_prices (item_id, effective_from);
+CREATE INDEX idx_promotions_sales ON promotions (item_id, ends_at) WHERE code IS NULL;
 CREATE INDEX idx_transactions_sender_id ON transactions (sender_id);
 CREATE INDEX idx_transactions_receiver_id ON transactions (receiver_id);
+CREATE UNIQUE INDEX idx_inventory_holding ON inventory (employee_id, item_id, COALESCE(variant_id, 0));
+CREATE INDEX idx_inventory_ledger_employee_id ON inventory_ledger (employee_id, entry_id);
+CREATE INDEX idx_team_members_employee_id ON team_members (employee_id);
+CREATE INDEX idx_team_transactions_team_id ON team_transactions (team_id, entry_id);
+CREATE INDEX idx_outbox_unpublished ON outbox (outbox_id) WHERE published_at IS NULL;
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX idx_onboarding_vesting ON onboarding (vests_at) WHERE vested_at IS NULL;
 
--- INIT metch data
-INSERT INTO merch_items (name, price)
-VALUES ('t-shirt', 80), ('cup', 20), ('book', 50), ('pen', 10), ('powerbank', 200),
-    ('hoody', 300),('umbrella', 200),('socks', 10),('wallet', 50), ('pink-hoody', 500);
+-- INIT default tenant and metch data
+INSERT INTO tenants (slug, name) VALUES ('default', 'default');
 
+INSERT INTO merch_items (tenant_id, name, price)
+VALUES (1, 't-shirt', 80), (1, 'cup', 20), (1, 'book', 50), (1, 'pen', 10), (1, 'powerbank', 200),
+    (1, 'hoody', 300), (1, 'umbrella', 200), (1, 'socks', 10), (1, 'wallet', 50), (1, 'pink-hoody', 500);
 
+INSERT INTO merch_prices (tenant_id, item_id, price, effective_from)
+SELECT tenant_id, item_id, price, CURRENT_TIMESTAMP FROM merch_items;
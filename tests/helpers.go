@@ -10,6 +10,7 @@ import (
 
 type ErrorResponse struct {
 	Errors string `json:"errors"`
+	Code   string `json:"code"`
 }
 
 type AuthResponse struct {
@@ -81,6 +82,9 @@ func BuyMerch(token string, merchName string) (int, error) {
 	req, _ := http.NewRequest("GET", "http://localhost:8080/api/buy/"+merchName, nil)
 	req.Header.Set("Authorization", "Bearer "+token)
 	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("не удалось выполнить запрос: %w", err)
+	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		var errResp ErrorResponse